@@ -0,0 +1,151 @@
+// Package cmd (api.go) defines the 'api' command, which sends a request to
+// any Microsoft Graph endpoint with the authentication of the current profile
+// and prints the JSON response, for endpoints the other commands do not cover.
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// apiCmd handles 'api <method> <path>'.
+var apiCmd = &cobra.Command{
+	Use:   "api <method> <path>",
+	Short: "Send a request to any Microsoft Graph endpoint",
+	Long: `Sends an authenticated request to a Microsoft Graph endpoint and prints the JSON response.
+
+The path is relative to the Microsoft Graph root of the profile's cloud, such as
+/me/drive/root/children; paths starting with /beta or /v1.0 select that API version. An
+absolute URL on the Microsoft Graph host, such as an @odata.nextLink, is accepted too.
+
+The request is made like every other command's: the access token is refreshed when needed,
+throttled and unavailable responses are retried, and errors are reported as for the other
+commands. With --paginate, every @odata.nextLink of a list endpoint is followed and the
+items of all pages are printed as a single {"value": [...]} document.`,
+	Example: `onedrive-client api GET /me/drive
+onedrive-client api GET /me/drive/root/children --paginate
+onedrive-client api GET "/me/drive/root/search(q='budget')" --header "Prefer: HonorNonIndexedQueriesWarningMayFailRandomly"
+onedrive-client api PATCH /me/drive/items/<item-id> --body rename.json
+echo '{"name": "New Folder", "folder": {}}' | onedrive-client api POST /me/drive/root/children --body -`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := app.NewApp(cmd)
+		if err != nil {
+			return fmt.Errorf("initializing app for 'api': %w", err)
+		}
+		return apiLogic(a, cmd, args)
+	},
+}
+
+// apiLogic contains the core logic for the 'api' command.
+func apiLogic(a *app.App, cmd *cobra.Command, args []string) error {
+	if len(args) < 2 { // Should be caught by Args validation.
+		return fmt.Errorf("both a method and a path are required for 'api'")
+	}
+	bodyFile, _ := cmd.Flags().GetString("body")
+	headers, _ := cmd.Flags().GetStringArray("header")
+	paginate, _ := cmd.Flags().GetBool("paginate")
+
+	req := onedrive.RawRequest{Method: strings.ToUpper(args[0]), Path: args[1]}
+	header, err := parseAPIHeaders(headers)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	if bodyFile != "" {
+		body, err := readAPIBody(cmd, bodyFile)
+		if err != nil {
+			return err
+		}
+		req.Body = bytes.NewReader(body)
+	}
+
+	var response []byte
+	if paginate {
+		if req.Body != nil {
+			return fmt.Errorf("%w: '--paginate' cannot be combined with '--body'", onedrive.ErrInvalidRequest)
+		}
+		response, err = a.SDK.RawAllPages(cmd.Context(), req)
+	} else {
+		var res onedrive.RawResponse
+		res, err = a.SDK.Raw(cmd.Context(), req)
+		response = res.Body
+	}
+	if err != nil {
+		return fmt.Errorf("calling %s %s: %w", req.Method, req.Path, err)
+	}
+	return writeAPIResponse(cmd.OutOrStdout(), response)
+}
+
+// parseAPIHeaders parses '--header' values of the form "Name: value".
+func parseAPIHeaders(values []string) (http.Header, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	header := http.Header{}
+	for _, value := range values {
+		name, content, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%w: header '%s' is not of the form 'Name: value'", onedrive.ErrInvalidRequest, value)
+		}
+		header.Add(name, strings.TrimSpace(content))
+	}
+	return header, nil
+}
+
+// readAPIBody reads the request body from `path`, or from standard input if
+// `path` is "-".
+func readAPIBody(cmd *cobra.Command, path string) ([]byte, error) {
+	if path == "-" {
+		body, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return nil, fmt.Errorf("reading request body from standard input: %w", err)
+		}
+		return body, nil
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading request body from '%s': %w", path, err)
+	}
+	return body, nil
+}
+
+// writeAPIResponse writes a response body to `w`: JSON in the selected output
+// format, indented in table mode, and anything else as received.
+func writeAPIResponse(w io.Writer, body []byte) error {
+	if len(body) == 0 {
+		return nil // For example 204 No Content.
+	}
+	if !json.Valid(body) {
+		_, err := w.Write(body)
+		return err
+	}
+	return ui.Render(w, ui.Result{Data: json.RawMessage(body), Table: func(w io.Writer) {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, body, "", "  "); err != nil {
+			indented.Reset()
+			indented.Write(body)
+		}
+		indented.WriteByte('\n')
+		indented.WriteTo(w)
+	}})
+}
+
+// init registers the 'api' command with the root command.
+func init() {
+	rootCmd.AddCommand(apiCmd)
+	apiCmd.Flags().String("body", "", "File holding the request body, or '-' for standard input; sent as JSON unless --header sets a Content-Type")
+	apiCmd.Flags().StringArrayP("header", "H", nil, "Extra request header as 'Name: value'; can be repeated")
+	apiCmd.Flags().Bool("paginate", false, "Follow every @odata.nextLink of a list endpoint and print the items of all pages")
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// newAPITestCmd returns a command with the flags of 'api' set to `flags`.
+func newAPITestCmd(t *testing.T, flags map[string][]string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.Flags().String("body", "", "")
+	cmd.Flags().StringArrayP("header", "H", nil, "")
+	cmd.Flags().Bool("paginate", false, "")
+	for name, values := range flags {
+		for _, value := range values {
+			require.NoError(t, cmd.Flags().Set(name, value))
+		}
+	}
+	return cmd
+}
+
+func TestAPILogic(t *testing.T) {
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	require.NoError(t, os.WriteFile(bodyFile, []byte(`{"name":"new.txt"}`), 0o600))
+
+	var got onedrive.RawRequest
+	var gotBody string
+	mockSDK := &MockSDK{
+		RawFunc: func(ctx context.Context, req onedrive.RawRequest) (onedrive.RawResponse, error) {
+			got = req
+			if req.Body != nil {
+				var buf bytes.Buffer
+				buf.ReadFrom(req.Body)
+				gotBody = buf.String()
+			}
+			return onedrive.RawResponse{StatusCode: http.StatusOK, Body: []byte(`{"id":"123","name":"new.txt"}`)}, nil
+		},
+	}
+	a := newTestApp(mockSDK)
+	cmd := newAPITestCmd(t, map[string][]string{"body": {bodyFile}, "header": {"If-Match: etag-1", "Prefer:respond-async"}})
+
+	output := captureOutput(t, func() {
+		require.NoError(t, apiLogic(a, cmd, []string{"patch", "/me/drive/items/123"}))
+	})
+
+	assert.Equal(t, "PATCH", got.Method)
+	assert.Equal(t, "/me/drive/items/123", got.Path)
+	assert.Equal(t, "etag-1", got.Header.Get("If-Match"))
+	assert.Equal(t, "respond-async", got.Header.Get("Prefer"))
+	assert.Equal(t, `{"name":"new.txt"}`, gotBody)
+	assert.Contains(t, output, "{\n  \"id\": \"123\",\n  \"name\": \"new.txt\"\n}\n")
+}
+
+func TestAPILogicBodyFromStdin(t *testing.T) {
+	var gotBody string
+	mockSDK := &MockSDK{
+		RawFunc: func(ctx context.Context, req onedrive.RawRequest) (onedrive.RawResponse, error) {
+			var buf bytes.Buffer
+			buf.ReadFrom(req.Body)
+			gotBody = buf.String()
+			return onedrive.RawResponse{StatusCode: http.StatusNoContent}, nil
+		},
+	}
+	cmd := newAPITestCmd(t, map[string][]string{"body": {"-"}})
+	cmd.SetIn(strings.NewReader(`{"folder":{}}`))
+
+	output := captureOutput(t, func() {
+		require.NoError(t, apiLogic(newTestApp(mockSDK), cmd, []string{"POST", "/me/drive/root/children"}))
+	})
+	assert.Equal(t, `{"folder":{}}`, gotBody)
+	assert.Empty(t, output, "a response without a body prints nothing")
+}
+
+func TestAPILogicPaginate(t *testing.T) {
+	mockSDK := &MockSDK{
+		RawAllPagesFunc: func(ctx context.Context, req onedrive.RawRequest) (json.RawMessage, error) {
+			assert.Equal(t, "/me/drive/root/children", req.Path)
+			return json.RawMessage(`{"value":[{"id":"a"},{"id":"b"}]}`), nil
+		},
+	}
+	ui.SetRenderer(mustRenderer(t, ui.FormatTemplate, `{{range .value}}{{.id}} {{end}}`))
+	t.Cleanup(func() { ui.SetRenderer(mustRenderer(t, ui.FormatTable, "")) })
+	cmd := newAPITestCmd(t, map[string][]string{"paginate": {"true"}})
+
+	output := captureOutput(t, func() {
+		require.NoError(t, apiLogic(newTestApp(mockSDK), cmd, []string{"GET", "/me/drive/root/children"}))
+	})
+	assert.Contains(t, output, "a b ")
+}
+
+func TestAPILogicErrors(t *testing.T) {
+	mockSDK := &MockSDK{
+		RawFunc: func(ctx context.Context, req onedrive.RawRequest) (onedrive.RawResponse, error) {
+			return onedrive.RawResponse{}, onedrive.ErrResourceNotFound
+		},
+	}
+	a := newTestApp(mockSDK)
+
+	err := apiLogic(a, newAPITestCmd(t, nil), []string{"GET", "/me/drive/items/gone"})
+	assert.ErrorIs(t, err, onedrive.ErrResourceNotFound)
+
+	err = apiLogic(a, newAPITestCmd(t, map[string][]string{"header": {"no-colon"}}), []string{"GET", "/me"})
+	assert.ErrorIs(t, err, onedrive.ErrInvalidRequest)
+
+	err = apiLogic(a, newAPITestCmd(t, map[string][]string{"body": {filepath.Join(t.TempDir(), "missing.json")}}), []string{"POST", "/me"})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// mustRenderer returns the renderer of `format`.
+func mustRenderer(t *testing.T, format, tmpl string) ui.Renderer {
+	t.Helper()
+	r, err := ui.NewRenderer(format, nil, tmpl)
+	require.NoError(t, err)
+	return r
+}
@@ -5,31 +5,28 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tonimelisma/onedrive-client/internal/app"
 	"github.com/tonimelisma/onedrive-client/internal/config"
 	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
 	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
-// getAuthSessionFilePath is a helper function to construct the path to the
-// authentication session file. This file stores temporary state during the
-// device code flow.
-func getAuthSessionFilePath() (string, error) {
-	// Uses the application's standard configuration directory.
-	configDir, err := config.GetConfigDir()
-	if err != nil {
-		return "", fmt.Errorf("getting config directory for auth session: %w", err)
-	}
-	// Auth session is stored in a 'sessions' subdirectory.
-	sessionDir := filepath.Join(configDir, "sessions")
-	return filepath.Join(sessionDir, "auth_session.json"), nil
-}
+// browserLoginTimeout bounds how long 'auth login --browser' waits for the
+// user to finish signing in.
+const browserLoginTimeout = 5 * time.Minute
 
 // authCmd represents the base 'auth' command.
 // It serves as a parent for subcommands like 'login', 'logout', 'status'.
@@ -45,13 +42,39 @@ var authCmd = &cobra.Command{
 // and enter a code to authorize the application.
 var authLoginCmd = &cobra.Command{
 	Use:   "login",
-	Short: "Authenticate with Microsoft OneDrive using Device Code Flow",
+	Short: "Authenticate with Microsoft OneDrive using Device Code Flow or the browser",
 	Long: `Starts the authentication process with Microsoft OneDrive.
 You will be prompted to visit a URL in a web browser and enter a unique code
 to authorize this application to access your OneDrive data.
 
+With --browser, signs in through your web browser instead: a temporary
+listener on localhost receives the sign-in redirect, and the command waits
+until sign-in completes. Use it where conditional access policies block the
+device code flow. The browser must run on the same machine.
+
+With --from-env, stores the refresh token given in ONEDRIVE_REFRESH_TOKEN (or
+the ONEDRIVE_CREDENTIALS_FD document) in the profile, replacing any existing
+login. Without it, credentials from the environment are used for each run
+without being written to disk; see 'auth token' for the variables.
+
 This command does not require you to be previously logged in. If an existing
-login session or a pending login attempt is found, it will advise accordingly.`,
+login session or a pending login attempt is found, it will advise accordingly.
+
+The sign-in settings are saved in the profile and used for every later run:
+--client-id signs in with your own app registration, --tenant restricts
+sign-in to a tenant ("organizations", "consumers", a tenant ID or a domain),
+--cloud selects a national cloud (usgov, usgov-dod, china), and --read-only or
+--scopes change the permissions requested. To change them, log out and log in
+again with the new flags:
+
+  onedrive-client auth login --client-id 00000000-0000-0000-0000-000000000000 \
+      --tenant contoso.onmicrosoft.com --read-only
+
+To keep several accounts side by side, log in to a named profile with
+--profile; the profile is created if it does not exist yet:
+
+  onedrive-client auth login --profile work
+  onedrive-client --profile work items list /`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Load current configuration to check existing token.
 		cfg, err := config.LoadOrCreate()
@@ -59,38 +82,64 @@ login session or a pending login attempt is found, it will advise accordingly.`,
 			return fmt.Errorf("loading configuration for login: %w", err)
 		}
 
+		if fromEnv, _ := cmd.Flags().GetBool("from-env"); fromEnv {
+			auth, err := applyLoginAuthFlags(cmd, cfg)
+			if err != nil {
+				return err
+			}
+			return authLoginFromEnvLogic(cmd, cfg, auth)
+		}
+
 		// Prevent starting a new login if already authenticated.
-		if cfg.Token.AccessToken != "" {
-			fmt.Println("You are already logged in. To switch accounts or re-authenticate, please run 'onedrive-client auth logout' first.")
+		if cfg.HasToken() {
+			fmt.Printf("You are already logged in (profile '%s'). To switch accounts or re-authenticate, please run 'onedrive-client auth logout' first, or log in to another profile with --profile.\n", cfg.Profile())
 			return nil
 		}
 
-		// Check if an auth session file already exists (indicating a pending login).
-		authSessionPath, err := getAuthSessionFilePath()
+		// Create session manager for auth state management, in the profile's own directory.
+		sessionMgr, err := session.NewManagerForProfile(cfg.Profile())
+		if err != nil {
+			return fmt.Errorf("creating session manager: %w", err)
+		}
+
+		// Check if an auth session already exists (indicating a pending login).
+		pending, err := sessionMgr.LoadAuthState()
 		if err != nil {
-			return fmt.Errorf("getting auth session path for login: %w", err)
+			return fmt.Errorf("checking for a pending login: %w", err)
 		}
-		if _, err := os.Stat(authSessionPath); !os.IsNotExist(err) {
-			// A session file exists, meaning a login was started but not completed.
+		if pending != nil {
+			// A login was started but not completed.
 			fmt.Println("A login attempt is already pending. Please complete it by visiting the previously provided URL and entering the code.")
 			fmt.Println("Alternatively, run 'onedrive-client auth logout' to cancel the pending attempt and start over.")
 			return nil
 		}
 
+		// Sign in with the settings given on the command line, if any.
+		auth, err := applyLoginAuthFlags(cmd, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Record the profile and its sign-in settings in the configuration
+		// file, so that a new profile shows up in 'profile list' while its
+		// login is still pending, and so that completing the login uses the
+		// same settings.
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("saving configuration for profile '%s': %w", cfg.Profile(), err)
+		}
+
+		if browser, _ := cmd.Flags().GetBool("browser"); browser {
+			return authLoginBrowserLogic(cmd, cfg)
+		}
+
 		// Get debug flag status from command line.
 		debug, _ := cmd.Flags().GetBool("debug")
 		// Initiate the device code flow via the SDK.
-		deviceCodeResp, err := onedrive.InitiateDeviceCodeFlow(config.ClientID, debug)
+		deviceCodeResp, err := onedrive.InitiateDeviceCodeFlow(auth, debug)
 		if err != nil {
 			return fmt.Errorf("login initiation failed: %w", err)
 		}
 
-		// Create session manager for auth state management
-		sessionMgr, err := session.NewManager()
-		if err != nil {
-			return fmt.Errorf("creating session manager: %w", err)
-		}
-
 		// Persist the device code flow state (device_code, user_code, etc.) to the session file.
 		// This allows other commands to complete the authentication if the user authorizes later.
 		authState := &session.AuthState{
@@ -141,8 +190,14 @@ var authLogoutCmd = &cobra.Command{
 var authStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Display the current authentication status",
-	Long:  `Checks if you are currently logged in. If authenticated, it displays your user information. If a login is pending, it provides instructions.`,
+	Long: `Checks if you are currently logged in. If authenticated, it displays your user information. If a login is pending, it provides instructions.
+
+With --all, reports the status of every profile instead of only the active one.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			return authStatusAllLogic(cmd)
+		}
+
 		// Attempt to initialize the app. This will try to complete any pending login.
 		a, err := app.NewApp(cmd)
 		if err != nil {
@@ -172,11 +227,310 @@ var authStatusCmd = &cobra.Command{
 	},
 }
 
+// openBrowser opens the authorization URL for 'auth login --browser'.
+// It is a variable so that tests can complete the sign-in without a browser.
+var openBrowser = app.OpenBrowser
+
+// authLoginBrowserLogic signs in to the profile of `cfg` through the web
+// browser, waiting until the authorization redirect arrives or the user
+// interrupts the command.
+func authLoginBrowserLogic(cmd *cobra.Command, cfg *config.Configuration) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, browserLoginTimeout)
+	defer cancel()
+
+	showURL := func(authURL string) error {
+		fmt.Println("Opening your web browser to sign in. If it does not open, visit this URL:")
+		fmt.Println(authURL)
+		if err := openBrowser(authURL); err != nil {
+			cfg.DebugPrintf("Could not open browser: %v", err)
+		}
+		fmt.Println("\nWaiting for sign-in to complete...")
+		return nil
+	}
+	if err := app.LoginWithBrowser(ctx, cfg, showURL); err != nil {
+		return fmt.Errorf("browser login failed: %w", err)
+	}
+	fmt.Println("Login successful!")
+	return nil
+}
+
+// applyLoginAuthFlags updates the sign-in settings of `cfg` with the login
+// flags given on the command line, and returns them in the form the SDK's
+// sign-in functions take. Settings not given keep the values saved in the
+// profile.
+func applyLoginAuthFlags(cmd *cobra.Command, cfg *config.Configuration) (onedrive.AuthConfig, error) {
+	flags := cmd.Flags()
+	if flags.Changed("client-id") {
+		cfg.Auth.ClientID, _ = flags.GetString("client-id")
+	}
+	if flags.Changed("tenant") {
+		cfg.Auth.Tenant, _ = flags.GetString("tenant")
+	}
+	if flags.Changed("cloud") {
+		cfg.Auth.Cloud, _ = flags.GetString("cloud")
+	}
+	if flags.Changed("scopes") {
+		cfg.Auth.Scopes, _ = flags.GetStringSlice("scopes")
+		cfg.Auth.ReadOnly = false
+	}
+	if flags.Changed("read-only") {
+		cfg.Auth.ReadOnly, _ = flags.GetBool("read-only")
+		if cfg.Auth.ReadOnly && !flags.Changed("scopes") {
+			cfg.Auth.Scopes = nil
+		}
+	}
+	return app.SDKAuthConfig(cfg)
+}
+
+// authLoginFromEnvLogic stores the refresh token supplied through the
+// environment in the profile of `cfg`, after checking that it can be redeemed
+// with the profile's sign-in settings `auth`.
+func authLoginFromEnvLogic(cmd *cobra.Command, cfg *config.Configuration, auth onedrive.AuthConfig) error {
+	creds, err := config.EnvCredentials()
+	if err != nil {
+		return fmt.Errorf("reading credentials from environment: %w", err)
+	}
+	if creds == nil || creds.RefreshToken == "" {
+		return fmt.Errorf("no refresh token in the environment; set %s or %s", config.EnvRefreshToken, config.EnvCredentialsFD)
+	}
+	if creds.ClientID != "" && creds.ClientID != auth.ClientID {
+		return fmt.Errorf("only refresh tokens issued to the profile's application (%s) can be stored; unset %s or log in with --client-id", auth.ClientID, config.EnvClientID)
+	}
+
+	token, err := onedrive.RefreshAccessToken(cmd.Context(), auth, creds.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("redeeming refresh token from environment: %w", err)
+	}
+	cfg.ClearToken() // Replaces any existing login, including an encrypted one.
+	cfg.Token = *token
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("saving token for profile '%s': %w", cfg.Profile(), err)
+	}
+	fmt.Printf("Login successful! The token from the environment is saved in profile '%s'.\n", cfg.Profile())
+	return nil
+}
+
+// authTokenLogic prints a valid access token, refreshing it first if needed.
+func authTokenLogic(a *app.App, cmd *cobra.Command) error {
+	token, err := a.SDK.AccessToken(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("getting access token: %w", err)
+	}
+	fmt.Println(token.AccessToken)
+	return nil
+}
+
+// authStatusAllLogic reports the authentication status of every profile,
+// marking the active one with '*'. Each profile is checked independently, so
+// a failure in one of them does not hide the others.
+func authStatusAllLogic(cmd *cobra.Command) error {
+	cfg, err := config.LoadOrCreate()
+	if err != nil {
+		return fmt.Errorf("loading configuration for status: %w", err)
+	}
+
+	var list profileStatusList
+	for _, name := range cfg.ProfileNames() {
+		list.Value = append(list.Value, profileStatus{Profile: name, Current: name == cfg.Profile(), Status: profileAuthStatus(cmd, name)})
+	}
+	return ui.Render(cmd.OutOrStdout(), ui.Result{Data: list, Columns: profileStatusColumns, Table: func(w io.Writer) {
+		for _, p := range list.Value {
+			marker := " "
+			if p.Current {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%s %s: %s\n", marker, p.Profile, p.Status)
+		}
+	}})
+}
+
+// profileAuthStatus returns a one-line description of the authentication
+// status of the profile called `name`.
+func profileAuthStatus(cmd *cobra.Command, name string) string {
+	a, err := app.NewAppForProfile(cmd, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrLoginPending):
+			return "login pending"
+		case errors.Is(err, onedrive.ErrReauthRequired):
+			return "not logged in"
+		default:
+			return fmt.Sprintf("error: %v", err)
+		}
+	}
+	user, err := a.GetMe(cmd.Context())
+	if err != nil {
+		return fmt.Sprintf("error: could not retrieve user information: %v", err)
+	}
+	return fmt.Sprintf("logged in as %s (%s)", user.DisplayName, user.UserPrincipalName)
+}
+
+// authEncryptCmd handles the 'auth encrypt' command.
+// It encrypts the profile's stored token with a passphrase or a key file.
+var authEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the stored token with a passphrase or a key file",
+	Long: `Encrypts the OAuth token stored in the profile, so that the configuration
+file alone no longer grants access to your OneDrive.
+
+By default the token is encrypted with a passphrase, read from
+ONEDRIVE_TOKEN_PASSPHRASE or typed twice on the terminal. Every later command
+then needs the passphrase, from the same variable or the terminal.
+
+With --key-file, the token is encrypted with the contents of a key file
+instead, which is created with a random key if it does not exist. Keep the
+key file apart from the configuration, for example on removable media; its
+path is recorded in the profile and can be overridden with
+ONEDRIVE_TOKEN_KEY_FILE.
+
+Refreshed tokens are encrypted again before they are saved. 'auth decrypt'
+stores the token in plaintext again; 'auth logout' removes it along with its
+encryption.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadOrCreate()
+		if err != nil {
+			return fmt.Errorf("loading configuration for encryption: %w", err)
+		}
+		keyFile, _ := cmd.Flags().GetString("key-file")
+		return authEncryptLogic(cfg, keyFile)
+	},
+}
+
+// authDecryptCmd handles the 'auth decrypt' command.
+// It stores an encrypted token in plaintext again.
+var authDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Store an encrypted token in plaintext again",
+	Long: `Decrypts the OAuth token stored in the profile with its passphrase or key
+file, and stores it in plaintext, protected by file permissions only.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadOrCreate()
+		if err != nil {
+			return fmt.Errorf("loading configuration for decryption: %w", err)
+		}
+		return authDecryptLogic(cfg)
+	},
+}
+
+// authEncryptLogic encrypts the token of `cfg` with a passphrase, or with the
+// key file at `keyFile` if set, creating the key file if needed.
+func authEncryptLogic(cfg *config.Configuration, keyFile string) error {
+	if !cfg.HasToken() {
+		return fmt.Errorf("profile '%s' has no token to encrypt; run 'onedrive-client auth login' first", cfg.Profile())
+	}
+	if cfg.TokenEncrypted() {
+		return fmt.Errorf("the token of profile '%s' is already encrypted; run 'onedrive-client auth decrypt' first to change how", cfg.Profile())
+	}
+
+	var secret config.TokenSecret
+	if keyFile != "" {
+		absPath, err := filepath.Abs(keyFile)
+		if err != nil {
+			return fmt.Errorf("resolving key file path '%s': %w", keyFile, err)
+		}
+		if _, err := os.Stat(absPath); os.IsNotExist(err) {
+			if err := config.GenerateKeyFile(absPath); err != nil {
+				return err
+			}
+			fmt.Printf("Created key file %s.\n", absPath)
+		}
+		secret.KeyFile = absPath
+	} else {
+		passphrase, err := app.NewPassphrase(cfg.Profile())
+		if err != nil {
+			return err
+		}
+		secret.Passphrase = passphrase
+	}
+
+	if err := cfg.EncryptToken(secret); err != nil {
+		return fmt.Errorf("encrypting token: %w", err)
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("saving encrypted token for profile '%s': %w", cfg.Profile(), err)
+	}
+	if secret.KeyFile != "" {
+		fmt.Printf("The token of profile '%s' is now encrypted with key file %s.\n", cfg.Profile(), secret.KeyFile)
+	} else {
+		fmt.Printf("The token of profile '%s' is now encrypted with a passphrase.\n", cfg.Profile())
+	}
+	return nil
+}
+
+// authDecryptLogic stores the encrypted token of `cfg` in plaintext again.
+func authDecryptLogic(cfg *config.Configuration) error {
+	if !cfg.TokenEncrypted() {
+		return fmt.Errorf("the token of profile '%s' is not encrypted", cfg.Profile())
+	}
+	if err := app.UnlockToken(cfg); err != nil {
+		return err
+	}
+	if err := cfg.DecryptToken(); err != nil {
+		return fmt.Errorf("decrypting token: %w", err)
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("saving decrypted token for profile '%s': %w", cfg.Profile(), err)
+	}
+	fmt.Printf("The token of profile '%s' is now stored in plaintext.\n", cfg.Profile())
+	return nil
+}
+
+// authTokenCmd handles the 'auth token' command.
+// It prints an access token for other tools that call Microsoft Graph directly.
+var authTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Print a valid access token for use by other tools",
+	Long: `Prints a valid access token, refreshing it first if it has expired, so that
+other tools can call Microsoft Graph with the same login:
+
+  curl -H "Authorization: Bearer $(onedrive-client auth token)" https://graph.microsoft.com/v1.0/me
+
+The token comes from the profile's login, or from credentials supplied through
+the environment, which take precedence and are never written to disk:
+
+  ONEDRIVE_REFRESH_TOKEN       refresh token of a user
+  ONEDRIVE_TENANT_ID           tenant of an app registration, for app-only access
+  ONEDRIVE_CLIENT_ID           client ID of the app registration
+  ONEDRIVE_CLIENT_SECRET       client secret, for app-only access
+  ONEDRIVE_CLIENT_CERTIFICATE  PEM file with certificate and key, for app-only access
+  ONEDRIVE_DRIVE_ID            drive to use when --drive is not given (required for app-only access)
+  ONEDRIVE_CREDENTIALS_FD      file descriptor to read the above as a JSON document from, using
+                               the keys refresh_token, tenant_id, client_id, client_secret,
+                               client_certificate and drive_id`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := app.NewApp(cmd)
+		if err != nil {
+			if errors.Is(err, onedrive.ErrReauthRequired) {
+				return fmt.Errorf("you are not logged in; run 'onedrive-client auth login' or supply credentials through the environment: %w", err)
+			}
+			return fmt.Errorf("initializing app for 'auth token': %w", err)
+		}
+		return authTokenLogic(a, cmd)
+	},
+}
+
 // init registers the 'auth' command and its subcommands with the root command.
 func init() {
 	rootCmd.AddCommand(authCmd)
 	authCmd.AddCommand(authLoginCmd)
 	authCmd.AddCommand(authLogoutCmd)
 	authCmd.AddCommand(authStatusCmd)
-	// Flags specific to auth subcommands could be added here if needed.
+	authCmd.AddCommand(authTokenCmd)
+	authCmd.AddCommand(authEncryptCmd)
+	authCmd.AddCommand(authDecryptCmd)
+
+	authLoginCmd.Flags().Bool("from-env", false, "Store the refresh token supplied through the environment in the profile")
+	authLoginCmd.Flags().Bool("browser", false, "Sign in through the web browser with a localhost redirect instead of the device code flow")
+	authLoginCmd.Flags().String("client-id", "", "Application (client) ID of your own app registration to sign in with")
+	authLoginCmd.Flags().String("tenant", "", "Tenant to sign in to: common, organizations, consumers, a tenant ID or a domain (default common)")
+	authLoginCmd.Flags().String("cloud", "", "Microsoft cloud to sign in to: "+strings.Join(onedrive.CloudNames(), ", ")+" (default global)")
+	authLoginCmd.Flags().StringSlice("scopes", nil, "OAuth2 scopes to request instead of the defaults (comma-separated)")
+	authLoginCmd.Flags().Bool("read-only", false, "Request read-only access to files (Files.Read.All)")
+	authStatusCmd.Flags().Bool("all", false, "Show the status of every profile")
+	authEncryptCmd.Flags().String("key-file", "", "Encrypt with the contents of this file instead of a passphrase; created if missing")
 }
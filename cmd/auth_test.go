@@ -1,18 +1,23 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/app"
 	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
 	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
@@ -27,6 +32,21 @@ func setupAuthTest(t *testing.T) (string, func()) {
 	return tempDir, func() {}
 }
 
+// useTestEndpoints points the sign-in and Microsoft Graph endpoints of the
+// default profile at the test server `serverURL`, with Graph under /v1.0/.
+func useTestEndpoints(t *testing.T, serverURL string) {
+	t.Helper()
+	cfg, err := config.LoadOrCreate()
+	require.NoError(t, err)
+	cfg.Auth.Endpoints = &onedrive.Endpoints{
+		AuthURL:   serverURL,
+		TokenURL:  serverURL,
+		DeviceURL: serverURL,
+		GraphURL:  serverURL + "/v1.0/",
+	}
+	require.NoError(t, cfg.Save())
+}
+
 func TestAuthLogin(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -41,11 +61,11 @@ func TestAuthLogin(t *testing.T) {
 		json.NewEncoder(w).Encode(resp)
 	}))
 	defer server.Close()
-	onedrive.SetCustomEndpoints(server.URL, server.URL, server.URL)
 
 	t.Run("should start login and create session file", func(t *testing.T) {
 		_, cleanup := setupAuthTest(t)
 		defer cleanup()
+		useTestEndpoints(t, server.URL)
 
 		run := func() {
 			rootCmd.SetArgs([]string{"auth", "login"})
@@ -80,6 +100,247 @@ func TestAuthLogin(t *testing.T) {
 	})
 }
 
+func TestAuthLoginSettings(t *testing.T) {
+	var request url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		request = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(onedrive.DeviceCodeResponse{UserCode: "TESTCODE", DeviceCode: "test-device-code", ExpiresIn: 900, Interval: 1})
+	}))
+	defer server.Close()
+
+	_, cleanup := setupAuthTest(t)
+	defer cleanup()
+	useTestEndpoints(t, server.URL)
+	defer authLoginCmd.Flags().Set("client-id", "")
+	defer authLoginCmd.Flags().Set("read-only", "false")
+
+	captureOutput(t, func() {
+		rootCmd.SetArgs([]string{"auth", "login", "--client-id", "my-app-id", "--read-only"})
+		rootCmd.Execute()
+	})
+
+	assert.Equal(t, "my-app-id", request.Get("client_id"))
+	assert.Contains(t, request.Get("scope"), "files.read.all")
+	assert.NotContains(t, request.Get("scope"), "files.readwrite.all")
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "my-app-id", cfg.Auth.ClientID)
+	assert.True(t, cfg.Auth.ReadOnly)
+}
+
+func TestAuthLoginBrowser(t *testing.T) {
+	var exchange url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		exchange = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "browser-token", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	_, cleanup := setupAuthTest(t)
+	defer cleanup()
+	useTestEndpoints(t, server.URL)
+	defer authLoginCmd.Flags().Set("browser", "false")
+
+	// Stand in for the browser: sign in and follow the redirect to the listener.
+	originalOpenBrowser := openBrowser
+	defer func() { openBrowser = originalOpenBrowser }()
+	openBrowser = func(authURL string) error {
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		query := u.Query()
+		res, err := http.Get(query.Get("redirect_uri") + "?code=browser-code&state=" + url.QueryEscape(query.Get("state")))
+		require.NoError(t, err)
+		return res.Body.Close()
+	}
+
+	output := captureOutput(t, func() {
+		rootCmd.SetArgs([]string{"auth", "login", "--browser"})
+		rootCmd.Execute()
+	})
+
+	assert.Contains(t, output, "Login successful!")
+	assert.Equal(t, "authorization_code", exchange.Get("grant_type"))
+	assert.Equal(t, "browser-code", exchange.Get("code"))
+	assert.NotEmpty(t, exchange.Get("code_verifier"))
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "browser-token", cfg.Token.AccessToken)
+}
+
+func TestAuthToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "env-refresh-token", r.PostForm.Get("refresh_token"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "env-access-token", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	t.Run("should print a token from environment credentials without writing to disk", func(t *testing.T) {
+		tempDir, cleanup := setupAuthTest(t)
+		defer cleanup()
+		useTestEndpoints(t, server.URL)
+		t.Setenv(config.EnvRefreshToken, "env-refresh-token")
+		configBefore, err := os.ReadFile(filepath.Join(tempDir, "config.json"))
+		require.NoError(t, err)
+
+		output := captureOutput(t, func() {
+			rootCmd.SetArgs([]string{"auth", "token"})
+			rootCmd.Execute()
+		})
+		assert.True(t, strings.HasPrefix(output, "env-access-token\n"), output)
+		configAfter, err := os.ReadFile(filepath.Join(tempDir, "config.json"))
+		require.NoError(t, err)
+		assert.Equal(t, string(configBefore), string(configAfter))
+		assert.NoDirExists(t, filepath.Join(tempDir, "sessions"))
+	})
+
+	t.Run("should store the environment token with login --from-env", func(t *testing.T) {
+		_, cleanup := setupAuthTest(t)
+		defer cleanup()
+		useTestEndpoints(t, server.URL)
+		defer authLoginCmd.Flags().Set("from-env", "false")
+		t.Setenv(config.EnvRefreshToken, "env-refresh-token")
+
+		output := captureOutput(t, func() {
+			rootCmd.SetArgs([]string{"auth", "login", "--from-env"})
+			rootCmd.Execute()
+		})
+		assert.Contains(t, output, "Login successful!")
+		cfg, err := config.Load()
+		require.NoError(t, err)
+		assert.Equal(t, "env-access-token", cfg.Token.AccessToken)
+	})
+
+	t.Run("should print the token of the SDK", func(t *testing.T) {
+		mockSDK := &MockSDK{
+			AccessTokenFunc: func(ctx context.Context) (*onedrive.Token, error) {
+				return &onedrive.Token{AccessToken: "sdk-token"}, nil
+			},
+		}
+		output := captureOutput(t, func() {
+			require.NoError(t, authTokenLogic(newTestApp(mockSDK), authTokenCmd))
+		})
+		assert.Equal(t, "sdk-token\n", output)
+	})
+}
+
+func TestAuthEncrypt(t *testing.T) {
+	tempDir, cleanup := setupAuthTest(t)
+	defer cleanup()
+	cfg, err := config.LoadOrCreate()
+	require.NoError(t, err)
+	cfg.Token = onedrive.Token{AccessToken: "stored-access", RefreshToken: "stored-refresh", Expiry: time.Now().Add(time.Hour)}
+	require.NoError(t, cfg.Save())
+	configPath := filepath.Join(tempDir, "config.json")
+
+	t.Run("should reject mismatched passphrases", func(t *testing.T) {
+		originalReadPassphrase := app.ReadPassphrase
+		defer func() { app.ReadPassphrase = originalReadPassphrase }()
+		answers := []string{"first", "second"}
+		app.ReadPassphrase = func(prompt string) (string, error) {
+			answer := answers[0]
+			answers = answers[1:]
+			return answer, nil
+		}
+
+		cfg, err := config.Load()
+		require.NoError(t, err)
+		assert.ErrorIs(t, authEncryptLogic(cfg, ""), app.ErrPassphraseMismatch)
+	})
+
+	t.Run("should encrypt, use and decrypt the token", func(t *testing.T) {
+		t.Setenv(config.EnvTokenPassphrase, "jump-host-passphrase")
+
+		output := captureOutput(t, func() {
+			rootCmd.SetArgs([]string{"auth", "encrypt"})
+			rootCmd.Execute()
+		})
+		assert.Contains(t, output, "now encrypted with a passphrase")
+		data, err := os.ReadFile(configPath)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "stored-refresh")
+
+		output = captureOutput(t, func() {
+			rootCmd.SetArgs([]string{"auth", "token"})
+			rootCmd.Execute()
+		})
+		assert.True(t, strings.HasPrefix(output, "stored-access\n"), output)
+
+		output = captureOutput(t, func() {
+			rootCmd.SetArgs([]string{"auth", "decrypt"})
+			rootCmd.Execute()
+		})
+		assert.Contains(t, output, "now stored in plaintext")
+		data, err = os.ReadFile(configPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "stored-refresh")
+	})
+
+	t.Run("should encrypt with a new key file", func(t *testing.T) {
+		keyFile := filepath.Join(t.TempDir(), "token.key")
+		cfg, err := config.Load()
+		require.NoError(t, err)
+		output := captureOutput(t, func() {
+			require.NoError(t, authEncryptLogic(cfg, keyFile))
+		})
+		assert.Contains(t, output, "Created key file "+keyFile)
+		assert.FileExists(t, keyFile)
+
+		status, err := profileLoginStatus(config.DefaultProfile)
+		require.NoError(t, err)
+		assert.Equal(t, "logged in (token encrypted)", status)
+
+		cfg, err = config.Load()
+		require.NoError(t, err)
+		captureOutput(t, func() {
+			require.NoError(t, authDecryptLogic(cfg))
+		})
+	})
+}
+
+func TestEncryptedTokenUnlockedOnce(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"value":[]}`))
+	}))
+	defer server.Close()
+
+	_, cleanup := setupAuthTest(t)
+	defer cleanup()
+	useTestEndpoints(t, server.URL)
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	cfg.Token = onedrive.Token{AccessToken: "stored-access", RefreshToken: "stored-refresh", Expiry: time.Now().Add(time.Hour)}
+	t.Setenv(config.EnvTokenPassphrase, "jump-host-passphrase")
+	captureOutput(t, func() {
+		require.NoError(t, authEncryptLogic(cfg, ""))
+	})
+
+	// Without the passphrase in the environment, it is typed by the user.
+	t.Setenv(config.EnvTokenPassphrase, "")
+	originalReadPassphrase := app.ReadPassphrase
+	defer func() { app.ReadPassphrase = originalReadPassphrase }()
+	prompts := 0
+	app.ReadPassphrase = func(prompt string) (string, error) {
+		prompts++
+		return "jump-host-passphrase", nil
+	}
+
+	captureOutput(t, func() {
+		rootCmd.SetArgs([]string{"items", "list", "/"})
+		require.NoError(t, rootCmd.Execute())
+	})
+	assert.Equal(t, 1, prompts, "the passphrase should be asked for once per command")
+	assert.Equal(t, []string{"/v1.0/me/drive/root/children"}, requested)
+}
+
 func TestAuthStatus(t *testing.T) {
 	t.Run("should report logged out", func(t *testing.T) {
 		_, cleanup := setupAuthTest(t)
@@ -137,11 +398,10 @@ func TestAuthStatus(t *testing.T) {
 			}
 		}))
 		defer server.Close()
-		onedrive.SetCustomEndpoints(server.URL, server.URL, server.URL)
-		onedrive.SetCustomGraphEndpoint(server.URL + "/v1.0/")
 
 		_, cleanup := setupAuthTest(t)
 		defer cleanup()
+		useTestEndpoints(t, server.URL)
 
 		// 1. Start the login
 		captureOutput(t, func() {
@@ -168,6 +428,31 @@ func TestAuthStatus(t *testing.T) {
 		sessionFilePath := filepath.Join(configDir, "sessions", "auth_session.json")
 		assert.NoFileExists(t, sessionFilePath)
 	})
+
+	t.Run("should report every profile with --all", func(t *testing.T) {
+		_, cleanup := setupAuthTest(t)
+		defer cleanup()
+		defer authStatusCmd.Flags().Set("all", "false")
+
+		work, err := config.LoadProfileOrCreate("work")
+		require.NoError(t, err)
+		require.NoError(t, work.Save())
+
+		output := captureOutput(t, func() {
+			rootCmd.SetArgs([]string{"auth", "status", "--all"})
+			rootCmd.Execute()
+		})
+		assert.Contains(t, output, "* default: not logged in")
+		assert.Contains(t, output, "  work: not logged in")
+
+		output = captureOutput(t, func() {
+			rootCmd.SetArgs([]string{"auth", "status", "--all", "-o", "csv"})
+			rootCmd.Execute()
+		})
+		rootCmd.PersistentFlags().Set("output", ui.FormatTable)
+		ui.SetRenderer(mustRenderer(t, ui.FormatTable, ""))
+		assert.Contains(t, output, "profile,current,status\ndefault,true,not logged in\nwork,false,not logged in\n")
+	})
 }
 
 func TestAuthLogout(t *testing.T) {
@@ -214,10 +499,10 @@ func TestAuthFileLocking(t *testing.T) {
 			json.NewEncoder(w).Encode(resp)
 		}))
 		defer server.Close()
-		onedrive.SetCustomEndpoints(server.URL, server.URL, server.URL)
 
 		_, cleanup := setupAuthTest(t)
 		defer cleanup()
+		useTestEndpoints(t, server.URL)
 
 		// Start first login
 		captureOutput(t, func() {
@@ -242,10 +527,10 @@ func TestAuthErrorHandling(t *testing.T) {
 			w.Write([]byte("Server error"))
 		}))
 		defer server.Close()
-		onedrive.SetCustomEndpoints(server.URL, server.URL, server.URL)
 
 		_, cleanup := setupAuthTest(t)
 		defer cleanup()
+		useTestEndpoints(t, server.URL)
 
 		// Test the command directly without going through Execute()
 		rootCmd.SetArgs([]string{"auth", "login"})
@@ -278,10 +563,10 @@ func TestAuthErrorHandling(t *testing.T) {
 			}
 		}))
 		defer server.Close()
-		onedrive.SetCustomEndpoints(server.URL, server.URL, server.URL)
 
 		_, cleanup := setupAuthTest(t)
 		defer cleanup()
+		useTestEndpoints(t, server.URL)
 
 		// Start login
 		captureOutput(t, func() {
@@ -0,0 +1,109 @@
+// Package cmd (browse.go) defines the 'browse' command: a full-screen
+// terminal browser of the drive with a queue of downloads, deletions and
+// moves.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/shell"
+	"github.com/tonimelisma/onedrive-client/internal/tui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+	"golang.org/x/term"
+)
+
+// browseCmd handles 'browse'.
+var browseCmd = &cobra.Command{
+	Use:   "browse [remote-path]",
+	Short: "Browse the drive in a full-screen terminal interface",
+	Long: `Opens a full-screen browser of the drive at remote-path, or at the root.
+
+The arrow keys (or j and k) move the cursor, Enter opens a folder and Left or Backspace goes back
+to the parent folder. The panel on the right shows the size, dates and ID of the selected item;
+'t' loads the sizes of its thumbnails.
+
+Space marks an item and 'a' marks every item of the folder. 'd' downloads the marked files, or the
+selected one, into --dest; 'x' deletes them after asking; 'm' moves them to a folder that is typed
+in, relative to the current folder unless it starts with "/". These operations run one after
+another in the background and are shown in the transfer panel at the bottom. 'q' quits, asking
+first while operations are pending.
+
+The browser needs a terminal; use the 'items' commands or 'shell' in scripts.`,
+	Example: `onedrive-client browse
+onedrive-client browse /Documents --dest ~/Downloads`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := app.NewApp(cmd)
+		if err != nil {
+			return fmt.Errorf("initializing app for 'browse': %w", err)
+		}
+		return browseLogic(a, cmd, args)
+	},
+}
+
+// browseLogic runs the browser on the terminal of the process.
+func browseLogic(a *app.App, cmd *cobra.Command, args []string) error {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return fmt.Errorf("%w: 'browse' needs a terminal", onedrive.ErrInvalidRequest)
+	}
+
+	browser, err := newBrowser(a, cmd, args)
+	if err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return fmt.Errorf("switching the terminal to raw mode: %w", err)
+	}
+	defer term.Restore(in, state) //nolint:errcheck // Nothing to do if restoring fails.
+
+	size := func() (int, int) {
+		width, height, err := term.GetSize(out)
+		if err != nil {
+			return 80, 24
+		}
+		return width, height
+	}
+	return browser.Run(cmd.Context(), os.Stdin, os.Stdout, size)
+}
+
+// newBrowser returns a browser configured by the flags of `cmd`, listing the
+// folder given in `args`.
+func newBrowser(a *app.App, cmd *cobra.Command, args []string) (*tui.Browser, error) {
+	dest, _ := cmd.Flags().GetString("dest")
+	connections, _ := cmd.Flags().GetInt("connections")
+	if connections < 1 {
+		return nil, fmt.Errorf("%w: --connections must be at least 1", onedrive.ErrInvalidRequest)
+	}
+
+	permissions := config.DefaultDownloadConfig()
+	if a.Config != nil {
+		permissions = a.Config.Download
+	}
+	browser := tui.NewBrowser(a.SDK, tui.Options{
+		DownloadDir:    dest,
+		Connections:    connections,
+		DirPermissions: permissions.DirectoryPermissions,
+	})
+
+	dir := "/"
+	if len(args) > 0 {
+		dir = shell.Resolve("/", args[0])
+	}
+	if err := browser.Open(cmd.Context(), dir); err != nil {
+		return nil, err
+	}
+	return browser, nil
+}
+
+func init() {
+	rootCmd.AddCommand(browseCmd)
+	browseCmd.Flags().String("dest", ".", "Local folder that downloaded files are saved in")
+	browseCmd.Flags().Int("connections", 1, "Parallel connections per downloaded file")
+}
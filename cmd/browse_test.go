@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/tui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// browseTestFolders are the remote folders of the browser tests.
+var browseTestFolders = map[string][]onedrive.DriveItem{
+	"/": {
+		{Name: "report.pdf", ID: "id-report", Size: 2048, File: &onedrive.FileFacet{MimeType: "application/pdf"},
+			LastModifiedDateTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)},
+		{Name: "Photos", ID: "id-photos", Folder: &onedrive.FolderFacet{ChildCount: 1}},
+		{Name: "archive.zip", ID: "id-archive", Size: 1 << 20},
+		{Name: "Archive", ID: "id-archive-folder", Folder: &onedrive.FolderFacet{}},
+	},
+	"/Photos": {
+		{Name: "beach.jpg", ID: "id-beach", Size: 4096, Image: &onedrive.ImageFacet{Width: 800, Height: 600}},
+	},
+}
+
+// browseRecorder records the calls of the browser tests.
+type browseRecorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *browseRecorder) add(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *browseRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func newBrowseMockSDK(calls *browseRecorder) *MockSDK {
+	return &MockSDK{
+		GetDriveItemChildrenByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItemList, error) {
+			items, ok := browseTestFolders[path]
+			if !ok {
+				return onedrive.DriveItemList{}, onedrive.ErrResourceNotFound
+			}
+			return onedrive.DriveItemList{Value: items}, nil
+		},
+		DeleteDriveItemFunc: func(ctx context.Context, path string) error {
+			calls.add("delete " + path)
+			return nil
+		},
+		MoveDriveItemFunc: func(ctx context.Context, sourcePath, destinationParentPath string) (onedrive.DriveItem, error) {
+			calls.add("move " + sourcePath + " " + destinationParentPath)
+			return onedrive.DriveItem{}, nil
+		},
+		GetThumbnailsFunc: func(ctx context.Context, remotePath string) (onedrive.ThumbnailSetList, error) {
+			calls.add("thumbnails " + remotePath)
+			return onedrive.ThumbnailSetList{Value: []onedrive.ThumbnailSet{{
+				Small:  &onedrive.Thumbnail{Width: 96, Height: 96},
+				Medium: &onedrive.Thumbnail{Width: 176, Height: 176},
+			}}}, nil
+		},
+	}
+}
+
+// newBrowseTestBrowser returns a browser of the root of `mockSDK`, with the
+// flags of 'browse' set to `flags`.
+func newBrowseTestBrowser(t *testing.T, mockSDK *MockSDK, args []string, flags ...string) *tui.Browser {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.Flags().String("dest", ".", "")
+	cmd.Flags().Int("connections", 1, "")
+	require.NoError(t, cmd.Flags().Parse(flags))
+	cmd.SetContext(context.Background())
+	browser, err := newBrowser(newTestApp(mockSDK), cmd, args)
+	require.NoError(t, err)
+	return browser
+}
+
+// pressKeys sends the key presses in `keys` to the browser.
+func pressKeys(b *tui.Browser, keys string) {
+	for _, ev := range tui.DecodeKeys([]byte(keys)) {
+		b.HandleKey(context.Background(), ev)
+	}
+}
+
+// drawBrowser draws the browser on a virtual terminal and returns its text.
+func drawBrowser(b *tui.Browser) string {
+	screen := tui.NewScreen(100, 24)
+	b.Draw(screen)
+	return screen.String()
+}
+
+// runQueue runs the queued jobs of the browser to completion.
+func runQueue(t *testing.T, b *tui.Browser) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.Queue().Start(ctx)
+	b.Queue().Wait()
+	b.Refresh(ctx)
+}
+
+func TestBrowseListsFolder(t *testing.T) {
+	b := newBrowseTestBrowser(t, newBrowseMockSDK(&browseRecorder{}), nil)
+	screen := drawBrowser(b)
+
+	lines := strings.Split(screen, "\n")
+	assert.Contains(t, lines[0], "OneDrive /")
+	assert.Contains(t, lines[0], "4 items")
+	// Folders come first, then files by name.
+	assert.Contains(t, lines[1], "Archive/")
+	assert.Contains(t, lines[2], "Photos/")
+	assert.Contains(t, lines[3], "archive.zip")
+	assert.Contains(t, lines[3], "1.0 MiB")
+	assert.Contains(t, lines[4], "report.pdf")
+	assert.Contains(t, lines[4], "2024-05-01 12:00")
+	assert.Contains(t, screen, "Transfers: none")
+	assert.Contains(t, screen, "Enter open")
+
+	// The details panel shows the item under the cursor.
+	pressKeys(b, "jjj")
+	screen = drawBrowser(b)
+	assert.Contains(t, screen, "Type: File, application/pdf")
+	assert.Contains(t, screen, "Size: 2.0 KiB (2048 bytes)")
+	assert.Contains(t, screen, "ID: id-report")
+	assert.Contains(t, screen, "Thumbnails: press t to load")
+}
+
+func TestBrowseNavigation(t *testing.T) {
+	b := newBrowseTestBrowser(t, newBrowseMockSDK(&browseRecorder{}), nil)
+
+	pressKeys(b, "j\r")
+	assert.Equal(t, "/Photos", b.Cwd())
+	screen := drawBrowser(b)
+	assert.Contains(t, screen, "beach.jpg")
+	assert.Contains(t, screen, "Image: 800x600")
+
+	// Going back puts the cursor on the folder that was left.
+	pressKeys(b, "\x1b[D")
+	assert.Equal(t, "/", b.Cwd())
+	assert.Contains(t, drawBrowser(b), "Path: /Photos")
+
+	// Opening a file does nothing.
+	pressKeys(b, "jj\r")
+	assert.Equal(t, "/", b.Cwd())
+
+	b = newBrowseTestBrowser(t, newBrowseMockSDK(&browseRecorder{}), []string{"Photos"})
+	assert.Equal(t, "/Photos", b.Cwd())
+}
+
+func TestBrowseDelete(t *testing.T) {
+	calls := &browseRecorder{}
+	b := newBrowseTestBrowser(t, newBrowseMockSDK(calls), nil)
+
+	pressKeys(b, "jj  ")
+	assert.Contains(t, drawBrowser(b), "2 marked")
+	pressKeys(b, "x")
+	assert.Contains(t, drawBrowser(b), "Delete 2 items? (y/n)")
+	pressKeys(b, "y")
+
+	screen := drawBrowser(b)
+	assert.Contains(t, screen, "Queued 2 deletion(s).")
+	assert.Contains(t, screen, "Transfers: 0 running, 2 queued, 0 done, 0 failed")
+
+	runQueue(t, b)
+	assert.Equal(t, []string{"delete /archive.zip", "delete /report.pdf"}, calls.get())
+	screen = drawBrowser(b)
+	assert.Contains(t, screen, "2 done")
+	assert.Contains(t, screen, "✓ delete report.pdf")
+
+	// Declining the prompt deletes nothing.
+	pressKeys(b, "xn")
+	runQueue(t, b)
+	assert.Len(t, calls.get(), 2)
+}
+
+func TestBrowseDownload(t *testing.T) {
+	dest := t.TempDir()
+	mockSDK := newBrowseMockSDK(&browseRecorder{})
+	mockSDK.DownloadFileParallelFunc = func(ctx context.Context, remotePath, localPath string, connections int, progress onedrive.ProgressFunc) error {
+		assert.Equal(t, "/report.pdf", remotePath)
+		assert.Equal(t, 4, connections)
+		progress(2048, 2048)
+		return os.WriteFile(localPath, []byte("pdf"), 0o600)
+	}
+	b := newBrowseTestBrowser(t, mockSDK, nil, "--dest", dest, "--connections", "4")
+
+	// Folders are skipped.
+	pressKeys(b, " \x1b[F d")
+	assert.Contains(t, drawBrowser(b), "Queued 1 download(s)")
+	assert.Contains(t, drawBrowser(b), "Skipped 1 folder(s)")
+
+	runQueue(t, b)
+	content, err := os.ReadFile(filepath.Join(dest, "report.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, "pdf", string(content))
+	assert.Contains(t, drawBrowser(b), "✓ download report.pdf")
+
+	// Existing files are not overwritten.
+	pressKeys(b, "d")
+	runQueue(t, b)
+	assert.Contains(t, drawBrowser(b), "✗ download report.pdf")
+}
+
+func TestBrowseMove(t *testing.T) {
+	calls := &browseRecorder{}
+	b := newBrowseTestBrowser(t, newBrowseMockSDK(calls), []string{"/Photos"})
+
+	pressKeys(b, "m../Archiv\x7fve\r")
+	runQueue(t, b)
+	assert.Equal(t, []string{"move /Photos/beach.jpg /Archive"}, calls.get())
+	assert.Contains(t, drawBrowser(b), "✓ move beach.jpg to /Archive")
+}
+
+func TestBrowseThumbnails(t *testing.T) {
+	calls := &browseRecorder{}
+	b := newBrowseTestBrowser(t, newBrowseMockSDK(calls), []string{"/Photos"})
+
+	pressKeys(b, "t")
+	assert.Equal(t, []string{"thumbnails /Photos/beach.jpg"}, calls.get())
+	assert.Contains(t, drawBrowser(b), "Thumbnails: small 96x96, medium 176x17")
+}
+
+func TestBrowseRun(t *testing.T) {
+	b := newBrowseTestBrowser(t, newBrowseMockSDK(&browseRecorder{}), nil)
+	var out bytes.Buffer
+	size := func() (int, int) { return 100, 24 }
+
+	require.NoError(t, b.Run(context.Background(), strings.NewReader("jq"), &out, size))
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[?1049h"))
+	assert.True(t, strings.HasSuffix(out.String(), "\x1b[?1049l"))
+	assert.Contains(t, out.String(), "report.pdf")
+}
+
+func TestBrowseErrors(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("dest", ".", "")
+	cmd.Flags().Int("connections", 0, "")
+	cmd.SetContext(context.Background())
+	mockSDK := newBrowseMockSDK(&browseRecorder{})
+
+	_, err := newBrowser(newTestApp(mockSDK), cmd, nil)
+	assert.ErrorIs(t, err, onedrive.ErrInvalidRequest)
+
+	require.NoError(t, cmd.Flags().Set("connections", "1"))
+	_, err = newBrowser(newTestApp(mockSDK), cmd, []string{"/missing"})
+	assert.True(t, errors.Is(err, onedrive.ErrResourceNotFound))
+
+	// Without a terminal, 'browse' refuses to start.
+	err = browseLogic(newTestApp(mockSDK), cmd, nil)
+	assert.ErrorIs(t, err, onedrive.ErrInvalidRequest)
+}
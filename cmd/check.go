@@ -0,0 +1,110 @@
+// Package cmd (check.go) defines the 'check' command, which verifies that a
+// local directory and a OneDrive folder hold the same files without
+// transferring any data.
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/sync"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+)
+
+// checkCmd handles 'check <local-dir> <remote-dir>'.
+// It compares both trees and fails if they differ.
+var checkCmd = &cobra.Command{
+	Use:   "check <local-dir> <remote-dir>",
+	Short: "Verify that a local directory and a OneDrive folder hold the same files",
+	Long: `Compares a local directory with a OneDrive folder without transferring or changing anything.
+Both trees are walked, and every file is reported that exists on only one side, whose size
+differs, or whose content hash differs. Local hashes are computed on the fly and compared with
+the SHA256, SHA1 or QuickXorHash reported by OneDrive; files for which OneDrive reports no hash
+are compared by size only.
+
+Each difference is printed on its own line as "<type> <path>", with the path relative to both
+roots and the type one of:
+
+  only-local     the file exists only in the local directory
+  only-remote    the file exists only in the OneDrive folder
+  size-differs   the sizes differ
+  hash-differs   the sizes match but the content does not
+  kind-differs   the path is a file on one side and a folder on the other
+
+With --json (or --output json), a single JSON document with the differences and the counts is
+printed instead; --output yaml, csv and template render the same document.
+A summary goes to stderr, and the command exits with a non-zero status if any difference was
+found, so it can be used to verify a backup in scripts and CI.`,
+	Example: `onedrive-client check ~/Backups/2024 /Backups/2024
+onedrive-client check ./site /Sites/www --json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := app.NewApp(cmd)
+		if err != nil {
+			return fmt.Errorf("initializing app for 'check': %w", err)
+		}
+		return checkLogic(a, cmd, args)
+	},
+}
+
+// checkLogic contains the core logic for the 'check' command.
+func checkLogic(a *app.App, cmd *cobra.Command, args []string) error {
+	if len(args) < 2 { // Should be caught by Args validation.
+		return fmt.Errorf("both local directory and remote folder are required for 'check'")
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("parsing '--json' flag: %w", err)
+	}
+
+	localRoot, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("resolving local directory '%s': %w", args[0], err)
+	}
+
+	result, err := sync.Check(cmd.Context(), a.SDK, localRoot, args[1])
+	if err != nil {
+		return fmt.Errorf("checking '%s' against '%s': %w", localRoot, args[1], err)
+	}
+
+	out := cmd.OutOrStdout()
+	if asJSON {
+		if err := writeCheckJSON(out, result); err != nil {
+			return err
+		}
+	} else if err := ui.Render(out, ui.Result{Data: result, Table: func(w io.Writer) {
+		for _, d := range result.Differences {
+			fmt.Fprintf(w, "%s %s\n", d.Type, d.Path)
+		}
+	}}); err != nil {
+		return err
+	}
+
+	log.Printf("Checked '%s' against '%s': %d file(s) compared, %d identical (%d by size only), %d difference(s).",
+		result.LocalRoot, result.RemoteRoot, result.Compared, result.Matched, result.SizeOnly, len(result.Differences))
+	if n := len(result.Differences); n > 0 {
+		return fmt.Errorf("%d difference(s) found between '%s' and '%s'", n, result.LocalRoot, result.RemoteRoot)
+	}
+	return nil
+}
+
+// writeCheckJSON writes the result of a check as an indented JSON document.
+func writeCheckJSON(w io.Writer, result *sync.CheckResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("encoding check result as JSON: %w", err)
+	}
+	return nil
+}
+
+// init registers the 'check' command with the root command.
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.Flags().Bool("json", false, "Print the differences and counts as a JSON document")
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/sync"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+func newCheckTestCmd(asJSON bool, out *bytes.Buffer) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", asJSON, "")
+	cmd.SetOut(out)
+	cmd.SetContext(context.Background())
+	return cmd
+}
+
+// checkTestSDK serves "/Backup" as the remote folder with the given items
+// below it, all reported in a single delta page.
+func checkTestSDK(items ...onedrive.DriveItem) *MockSDK {
+	return &MockSDK{
+		GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+			return onedrive.DriveItem{ID: "root", Name: "Backup", Folder: &onedrive.FolderFacet{}}, nil
+		},
+		GetDeltaFunc: func(ctx context.Context, deltaToken string) (onedrive.DeltaResponse, error) {
+			return onedrive.DeltaResponse{Value: items, DeltaLink: "https://graph.microsoft.com/v1.0/me/drive/root/delta?token=abc"}, nil
+		},
+	}
+}
+
+func checkRemoteFile(id, parentID, name string, size int64, quickXor string) onedrive.DriveItem {
+	item := onedrive.DriveItem{ID: id, Name: name, Size: size, File: &onedrive.FileFacet{}}
+	item.ParentReference.ID = parentID
+	if quickXor != "" {
+		item.File.Hashes = &onedrive.FileHashes{QuickXorHash: quickXor}
+	}
+	return item
+}
+
+func TestCheckLogic(t *testing.T) {
+	localDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(localDir, "docs"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "docs", "same.txt"), []byte("hello"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "docs", "changed.txt"), []byte("hellO"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "grown.txt"), []byte("hello!"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "local-only.txt"), []byte("x"), 0600))
+
+	docs := onedrive.DriveItem{ID: "d1", Name: "docs", Folder: &onedrive.FolderFacet{}}
+	docs.ParentReference.ID = "root"
+	helloHash := "aCgDG9jwBgAAAAAABQAAAAAAAAA=" // QuickXorHash of "hello".
+
+	t.Run("reports every difference and fails", func(t *testing.T) {
+		sdk := checkTestSDK(
+			docs,
+			checkRemoteFile("f1", "d1", "same.txt", 5, helloHash),
+			checkRemoteFile("f2", "d1", "changed.txt", 5, helloHash),
+			checkRemoteFile("f3", "root", "grown.txt", 5, helloHash),
+			checkRemoteFile("f4", "root", "remote-only.txt", 3, ""),
+		)
+		var out bytes.Buffer
+		var err error
+		captureOutput(t, func() {
+			err = checkLogic(newTestApp(sdk), newCheckTestCmd(false, &out), []string{localDir, "/Backup"})
+		})
+		assert.ErrorContains(t, err, "4 difference(s) found")
+		assert.Equal(t, "hash-differs docs/changed.txt\n"+
+			"size-differs grown.txt\n"+
+			"only-local local-only.txt\n"+
+			"only-remote remote-only.txt\n", out.String())
+	})
+
+	t.Run("prints JSON and succeeds when both sides match", func(t *testing.T) {
+		matching := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(matching, "same.txt"), []byte("hello"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(matching, "unhashed.txt"), []byte("abc"), 0600))
+		sdk := checkTestSDK(
+			checkRemoteFile("f1", "root", "same.txt", 5, helloHash),
+			checkRemoteFile("f2", "root", "unhashed.txt", 3, ""),
+		)
+		var out bytes.Buffer
+		var err error
+		captureOutput(t, func() {
+			err = checkLogic(newTestApp(sdk), newCheckTestCmd(true, &out), []string{matching, "/Backup"})
+		})
+		require.NoError(t, err)
+
+		var result sync.CheckResult
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		assert.Equal(t, "/Backup", result.RemoteRoot)
+		assert.Equal(t, 2, result.Compared)
+		assert.Equal(t, 2, result.Matched)
+		assert.Equal(t, 1, result.SizeOnly)
+		assert.Empty(t, result.Differences)
+	})
+
+	t.Run("fails if the local directory does not exist", func(t *testing.T) {
+		var out bytes.Buffer
+		err := checkLogic(newTestApp(checkTestSDK()), newCheckTestCmd(false, &out), []string{filepath.Join(localDir, "missing"), "/Backup"})
+		assert.ErrorContains(t, err, "accessing local directory")
+	})
+}
@@ -5,11 +5,21 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/sync"
 	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
 // drivesCmd represents the base 'drives' command.
@@ -17,7 +27,8 @@ import (
 var drivesCmd = &cobra.Command{
 	Use:   "drives",
 	Short: "Manage and inspect OneDrive drives",
-	Long:  `Provides commands to list available drives, check quota, view drive activities, search, and access special drive views like recent or shared items.`,
+	Long: `Provides commands to list available drives, check quota, view drive activities, search, and access special drive views like recent or shared items.
+Commands that act on a single drive use your default drive, or the drive selected with the global --drive flag.`,
 }
 
 // drivesListCmd handles 'drives list'.
@@ -25,8 +36,9 @@ var drivesCmd = &cobra.Command{
 var drivesListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all available OneDrive drives",
-	Long:  `Lists all OneDrive drives that the authenticated user has access to, including personal drives, OneDrive for Business, and SharePoint document libraries.`,
-	Args:  cobra.NoArgs, // This command takes no arguments.
+	Long: `Lists all OneDrive drives that the authenticated user has access to, including personal drives, OneDrive for Business, and SharePoint document libraries.
+Pass a drive's ID to the global --drive flag to work with it, e.g. 'onedrive-client --drive <drive-id> items list /'.`,
+	Args: cobra.NoArgs, // This command takes no arguments.
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Initialize the application context, which handles configuration and SDK setup.
 		a, err := app.NewApp(cmd)
@@ -42,7 +54,7 @@ var drivesListCmd = &cobra.Command{
 var drivesQuotaCmd = &cobra.Command{
 	Use:   "quota",
 	Short: "Get storage quota for the default drive",
-	Long:  `Displays the total, used, and remaining storage quota for the user's default OneDrive drive.`,
+	Long:  `Displays the total, used, and remaining storage quota for the user's default OneDrive drive, or for the drive selected with --drive.`,
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		a, err := app.NewApp(cmd)
@@ -94,8 +106,11 @@ var drivesActivitiesCmd = &cobra.Command{
 			return fmt.Errorf("getting drive activities: %w", err)
 		}
 
-		// Display the activities and any next page information.
-		ui.DisplayActivities(activities)
+		// The link to the next page is a field of structured output, and a hint otherwise.
+		activities.NextLink = nextLink
+		if err := ui.DisplayActivities(activities); err != nil {
+			return err
+		}
 		ui.HandleNextPageInfo(nextLink, paging.FetchAll)
 
 		return nil
@@ -142,7 +157,19 @@ var drivesDeltaCmd = &cobra.Command{
 	Long: `Tracks changes to items in your default OneDrive using delta queries.
 An initial call (without a delta-token) returns all items and a new delta-token.
 Subsequent calls with the previously obtained delta-token return only items that have changed.
-This is useful for building synchronization logic.`,
+This is useful for building synchronization logic.
+
+With --watch, the command keeps running and prints each change on its own line as it arrives.
+Every poll follows @odata.nextLink pages through to the final delta link, whose token is saved
+in the sync state store so a restarted watch resumes where it stopped. Between polls it waits
+according to the 'polling' configuration: the interval starts at initial_interval, grows by
+multiplier up to max_interval while nothing changes, and resets when changes arrive. After
+max_attempts consecutive failed polls (0 = never) the command exits with an error; an expired
+token, after which the drive is listed again from the start, counts as a failed poll. Pass
+'latest' as the token to start from the current state without listing existing items.`,
+	Example: `onedrive-client drives delta
+onedrive-client drives delta --watch
+onedrive-client drives delta latest --watch`,
 	Args: cobra.MaximumNArgs(1), // Accepts zero or one argument (the delta-token).
 	RunE: func(cmd *cobra.Command, args []string) error {
 		a, err := app.NewApp(cmd)
@@ -208,8 +235,7 @@ func drivesListLogic(a *app.App, cmd *cobra.Command) error {
 	if err != nil {
 		return fmt.Errorf("fetching drives list: %w", err)
 	}
-	ui.DisplayDrives(drives)
-	return nil
+	return ui.DisplayDrives(drives)
 }
 
 // drivesQuotaLogic contains the core logic for the 'drives quota' command.
@@ -218,8 +244,7 @@ func drivesQuotaLogic(a *app.App, cmd *cobra.Command) error {
 	if err != nil {
 		return fmt.Errorf("fetching default drive quota: %w", err)
 	}
-	ui.DisplayQuota(drive)
-	return nil
+	return ui.DisplayQuota(drive)
 }
 
 // drivesGetLogic contains the core logic for the 'drives get' command.
@@ -228,8 +253,7 @@ func drivesGetLogic(a *app.App, cmd *cobra.Command, driveID string) error {
 	if err != nil {
 		return fmt.Errorf("fetching drive by ID '%s': %w", driveID, err)
 	}
-	ui.DisplayDrive(drive)
-	return nil
+	return ui.DisplayDrive(drive)
 }
 
 // drivesRootLogic contains the core logic for the 'drives root' command.
@@ -238,8 +262,7 @@ func drivesRootLogic(a *app.App, cmd *cobra.Command) error {
 	if err != nil {
 		return fmt.Errorf("fetching root drive items: %w", err)
 	}
-	ui.DisplayItems(items)
-	return nil
+	return ui.DisplayItems(items)
 }
 
 // drivesSearchLogic contains the core logic for the 'drives search' command.
@@ -257,7 +280,11 @@ func drivesSearchLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("searching drive with query '%s': %w", query, err)
 	}
 
-	ui.DisplaySearchResults(items)
+	// The link to the next page is a field of structured output, and a hint otherwise.
+	items.NextLink = nextLink
+	if err := ui.DisplaySearchResults(items); err != nil {
+		return err
+	}
 	ui.HandleNextPageInfo(nextLink, paging.FetchAll)
 	return nil
 }
@@ -269,13 +296,149 @@ func drivesDeltaLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		deltaToken = args[0]
 	}
 
+	// The flag is absent when the logic is driven directly, as in tests.
+	if watch, _ := cmd.Flags().GetBool("watch"); watch {
+		store, err := sync.NewStore()
+		if err != nil {
+			return fmt.Errorf("opening sync state store: %w", err)
+		}
+		return drivesDeltaWatchLogic(a, cmd, store, deltaToken)
+	}
+
 	delta, err := a.SDK.GetDelta(cmd.Context(), deltaToken)
 	if err != nil {
 		return fmt.Errorf("fetching delta changes (token: '%s'): %w", deltaToken, err)
 	}
 
-	ui.DisplayDeltaItems(delta)
-	return nil
+	return ui.DisplayDeltaItems(delta)
+}
+
+// drivesDeltaWatchLogic contains the core logic for 'drives delta --watch'. It polls for
+// changes until interrupted, saving the latest delta link in `store` after every poll.
+func drivesDeltaWatchLogic(a *app.App, cmd *cobra.Command, store *sync.Store, deltaToken string) error {
+	drive, err := a.SDK.GetDefaultDrive(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("getting default drive for 'drives delta --watch': %w", err)
+	}
+	key := sync.NewKey(drive.ID, "/", "")
+	unlock, err := store.Lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if deltaToken == "" {
+		state, err := store.Load(key)
+		if err != nil {
+			return fmt.Errorf("loading saved delta link for %s: %w", key, err)
+		}
+		if state != nil && state.DeltaLink != "" {
+			deltaToken = onedrive.ExtractDeltaToken(state.DeltaLink)
+			log.Printf("Resuming from the delta link saved at %s.", state.LastSync.Local().Format(time.RFC1123))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	polling := a.Config.Polling
+	interval := polling.InitialInterval
+	failures := 0
+	// The banner goes to the log, as standard output carries the changes.
+	log.Println("Watching the default drive for changes. Press Ctrl+C to stop.")
+	// A change that cannot be printed, such as with a broken output template, ends the
+	// watch rather than counting as a failed poll.
+	var displayErr error
+	display := func(item onedrive.DriveItem) error {
+		displayErr = ui.DisplayDeltaChange(item)
+		return displayErr
+	}
+	for {
+		changes, deltaLink, err := followDelta(ctx, a.SDK, deltaToken, display)
+
+		switch {
+		case displayErr != nil:
+			return displayErr
+		case err != nil && ctx.Err() != nil:
+			return nil // Interrupted mid-poll; the saved link still points before it.
+		case errors.Is(err, onedrive.ErrSyncStateExpired):
+			// An expiry counts as a failed poll, so a drive whose listings keep
+			// expiring is not listed again and again without a pause.
+			failures++
+			if polling.MaxAttempts > 0 && failures >= polling.MaxAttempts {
+				return fmt.Errorf("polling for changes failed %d time(s) in a row: %w", failures, err)
+			}
+			log.Println("The delta token has expired; listing the drive again from the start.")
+			deltaToken = ""
+			interval = nextPollInterval(interval, polling)
+		case err != nil:
+			failures++
+			if polling.MaxAttempts > 0 && failures >= polling.MaxAttempts {
+				return fmt.Errorf("polling for changes failed %d time(s) in a row: %w", failures, err)
+			}
+			log.Printf("Warning: polling for changes failed (attempt %d): %v", failures, err)
+			interval = nextPollInterval(interval, polling)
+		default:
+			failures = 0
+			state := &sync.State{Key: key, DeltaLink: deltaLink, LastSync: time.Now()}
+			if err := store.Save(state); err != nil {
+				return fmt.Errorf("saving delta link for %s: %w", key, err)
+			}
+			deltaToken = onedrive.ExtractDeltaToken(deltaLink)
+			if changes > 0 {
+				interval = polling.InitialInterval
+			} else {
+				interval = nextPollInterval(interval, polling)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// followDelta fetches every page of changes since `deltaToken`, passing each changed item
+// to `emit` as its page arrives, and returns the number of changes and the final delta link.
+// It stops at the first error returned by `emit`.
+func followDelta(ctx context.Context, sdk app.SDK, deltaToken string, emit func(onedrive.DriveItem) error) (int, string, error) {
+	changes := 0
+	token := deltaToken
+	for {
+		delta, err := sdk.GetDelta(ctx, token)
+		if err != nil {
+			return changes, "", fmt.Errorf("fetching delta changes (token: '%s'): %w", token, err)
+		}
+		for i := range delta.Value {
+			if err := emit(delta.Value[i]); err != nil {
+				return changes, "", err
+			}
+		}
+		changes += len(delta.Value)
+
+		if delta.NextLink == "" {
+			if delta.DeltaLink == "" {
+				return changes, "", fmt.Errorf("delta response carries neither a next link nor a delta link")
+			}
+			return changes, delta.DeltaLink, nil
+		}
+		token = onedrive.ExtractDeltaToken(delta.NextLink)
+		if token == "" {
+			return changes, "", fmt.Errorf("delta next link carries no token: %s", delta.NextLink)
+		}
+	}
+}
+
+// nextPollInterval grows a polling interval by the configured multiplier, capped at the
+// configured maximum.
+func nextPollInterval(interval time.Duration, polling config.PollingConfig) time.Duration {
+	next := time.Duration(float64(interval) * polling.Multiplier)
+	if next > polling.MaxInterval {
+		return polling.MaxInterval
+	}
+	return next
 }
 
 // drivesSpecialLogic contains the core logic for the 'drives special' command.
@@ -285,8 +448,7 @@ func drivesSpecialLogic(a *app.App, cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("fetching special folder '%s': %w", folderName, err)
 	}
-	ui.DisplaySpecialFolder(item, folderName)
-	return nil
+	return ui.DisplaySpecialFolder(item, folderName)
 }
 
 // drivesRecentLogic contains the core logic for the 'drives recent' command.
@@ -295,8 +457,7 @@ func drivesRecentLogic(a *app.App, cmd *cobra.Command) error {
 	if err != nil {
 		return fmt.Errorf("fetching recent items: %w", err)
 	}
-	ui.DisplayRecentItems(items)
-	return nil
+	return ui.DisplayRecentItems(items)
 }
 
 // drivesSharedLogic contains the core logic for the 'drives shared' command.
@@ -305,8 +466,7 @@ func drivesSharedLogic(a *app.App, cmd *cobra.Command) error {
 	if err != nil {
 		return fmt.Errorf("getting items shared with you: %w", err)
 	}
-	ui.DisplaySharedItems(items)
-	return nil
+	return ui.DisplaySharedItems(items)
 }
 
 // init registers the 'drives' command and its subcommands with the root command.
@@ -324,6 +484,8 @@ func init() {
 	drivesCmd.AddCommand(drivesRecentCmd)
 	drivesCmd.AddCommand(drivesSharedCmd)
 
+	drivesDeltaCmd.Flags().Bool("watch", false, "Keep polling and print each change as it arrives")
+
 	// Add pagination flags to commands that support paginated responses.
 	ui.AddPagingFlags(drivesActivitiesCmd)
 	ui.AddPagingFlags(drivesSearchCmd)
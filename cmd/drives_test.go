@@ -4,9 +4,14 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/sync"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
 	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
@@ -206,6 +211,116 @@ func TestDrivesDeltaLogic(t *testing.T) {
 	}
 }
 
+func TestDrivesDeltaWatchLogic(t *testing.T) {
+	useTempSyncStore(t)
+	store, err := sync.NewStore()
+	require.NoError(t, err)
+
+	link := func(token string) string {
+		return "https://graph.microsoft.com/v1.0/me/drive/root/delta?token=" + token
+	}
+	type step struct {
+		resp onedrive.DeltaResponse
+		err  error
+	}
+
+	// run drives the watch through `steps`, one per GetDelta call, and stops
+	// it after the last one. It returns the tokens requested and the output.
+	run := func(token string, steps []step) ([]string, string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var tokens []string
+		mockSDK := &MockSDK{
+			GetDefaultDriveFunc: func(ctx context.Context) (onedrive.Drive, error) {
+				return onedrive.Drive{ID: "drive1"}, nil
+			},
+			GetDeltaFunc: func(ctx context.Context, deltaToken string) (onedrive.DeltaResponse, error) {
+				tokens = append(tokens, deltaToken)
+				if len(tokens) == len(steps) {
+					cancel()
+				}
+				s := steps[len(tokens)-1]
+				return s.resp, s.err
+			},
+		}
+		testApp := newTestApp(mockSDK)
+		testApp.Config = &config.Configuration{Polling: config.PollingConfig{
+			InitialInterval: time.Millisecond,
+			MaxInterval:     5 * time.Millisecond,
+			Multiplier:      2,
+		}}
+		cmd := &cobra.Command{}
+		cmd.SetContext(ctx)
+		output := captureOutput(t, func() {
+			assert.NoError(t, drivesDeltaWatchLogic(testApp, cmd, store, token))
+		})
+		return tokens, output
+	}
+
+	// Pages are followed to the delta link, a failed poll is retried from the
+	// same token, and each change is printed as it arrives.
+	tokens, output := run("", []step{
+		{resp: onedrive.DeltaResponse{Value: []onedrive.DriveItem{{ID: "1", Name: "first.txt"}}, NextLink: link("page2")}},
+		{resp: onedrive.DeltaResponse{Value: []onedrive.DriveItem{{ID: "2", Name: "second.txt"}}, DeltaLink: link("d1")}},
+		{err: onedrive.ErrRetryLater},
+		{resp: onedrive.DeltaResponse{DeltaLink: link("d2")}},
+		{resp: onedrive.DeltaResponse{DeltaLink: link("d3")}},
+	})
+	assert.Equal(t, []string{"", "page2", "d1", "d1", "d2"}, tokens)
+	assert.Contains(t, output, "first.txt")
+	assert.Contains(t, output, "second.txt")
+	assert.Contains(t, output, "polling for changes failed")
+
+	state, err := store.Load(sync.NewKey("drive1", "/", ""))
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.Equal(t, link("d3"), state.DeltaLink)
+
+	// A restarted watch resumes from the saved delta link.
+	tokens, _ = run("", []step{{resp: onedrive.DeltaResponse{DeltaLink: link("d4")}}})
+	assert.Equal(t, []string{"d3"}, tokens)
+
+	// An expired token starts over with a full listing.
+	tokens, _ = run("stale", []step{
+		{err: onedrive.ErrSyncStateExpired},
+		{resp: onedrive.DeltaResponse{DeltaLink: link("d5")}},
+	})
+	assert.Equal(t, []string{"stale", ""}, tokens)
+
+	// Listings that keep expiring are retried with backoff until the attempts
+	// run out.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var polls []time.Time
+	mockSDK := &MockSDK{
+		GetDefaultDriveFunc: func(ctx context.Context) (onedrive.Drive, error) {
+			return onedrive.Drive{ID: "drive1"}, nil
+		},
+		GetDeltaFunc: func(ctx context.Context, deltaToken string) (onedrive.DeltaResponse, error) {
+			polls = append(polls, time.Now())
+			if len(polls) == 10 {
+				cancel() // Without backoff the watch would never stop.
+			}
+			return onedrive.DeltaResponse{}, onedrive.ErrSyncStateExpired
+		},
+	}
+	testApp := newTestApp(mockSDK)
+	testApp.Config = &config.Configuration{Polling: config.PollingConfig{
+		InitialInterval: 20 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+		MaxAttempts:     3,
+	}}
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	captureOutput(t, func() {
+		err = drivesDeltaWatchLogic(testApp, cmd, store, "stale")
+	})
+	assert.ErrorIs(t, err, onedrive.ErrSyncStateExpired)
+	require.Len(t, polls, 3)
+	assert.GreaterOrEqual(t, polls[1].Sub(polls[0]), 20*time.Millisecond, "the watch waits before listing again")
+}
+
 func TestDrivesSpecialLogic(t *testing.T) {
 	mockSDK := &MockSDK{
 		GetSpecialFolderFunc: func(ctx context.Context, folderName string) (onedrive.DriveItem, error) {
@@ -257,3 +372,30 @@ func TestDrivesRootLogic(t *testing.T) {
 	err := drivesRootLogic(a, cmd)
 	assert.NoError(t, err)
 }
+
+func TestDrivesSearchLogicStructuredOutput(t *testing.T) {
+	mockSDK := &MockSDK{
+		SearchDriveItemsWithPagingFunc: func(ctx context.Context, query string, paging onedrive.Paging) (onedrive.DriveItemList, string, error) {
+			return onedrive.DriveItemList{Value: []onedrive.DriveItem{{ID: "id-1", Name: "report.pdf"}}}, "https://graph.example/next", nil
+		},
+	}
+	a := newTestApp(mockSDK)
+	cmd := &cobra.Command{}
+	ui.AddPagingFlags(cmd)
+
+	renderer, err := ui.NewRenderer(ui.FormatJSON, nil, "")
+	require.NoError(t, err)
+	ui.SetRenderer(renderer)
+	defer func() {
+		table, _ := ui.NewRenderer(ui.FormatTable, nil, "")
+		ui.SetRenderer(table)
+	}()
+
+	output := captureOutput(t, func() {
+		require.NoError(t, drivesSearchLogic(a, cmd, []string{"report"}))
+	})
+
+	assert.Contains(t, output, `"name": "report.pdf"`)
+	assert.Contains(t, output, `"@odata.nextLink": "https://graph.example/next"`, "the next page link is a field")
+	assert.NotContains(t, output, "More items available", "no human hint in structured output")
+}
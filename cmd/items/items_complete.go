@@ -0,0 +1,251 @@
+// Package items (items_complete.go) provides the shell completion of the
+// arguments of the 'items' commands: remote paths are completed from the
+// listing of the folder being typed, and permission IDs from the permissions
+// of the item named before them. Both are cached on disk for a short time,
+// since the shell starts a new process for every press of Tab.
+package items
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/internal/shell"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// completionCacheTTL is how long completion values are used from the disk
+// cache before OneDrive is asked again.
+var completionCacheTTL = session.DefaultCompletionTTL
+
+// completionTimeout bounds the requests of one completion, so that Tab does
+// not hang while OneDrive cannot be reached.
+const completionTimeout = 5 * time.Second
+
+// argKind is what a positional argument of a command holds, for completion.
+type argKind int
+
+const (
+	argOther        argKind = iota // Free text, such as a new name; not completed.
+	argRemotePath                  // A remote file or folder.
+	argRemoteFolder                // A remote folder.
+	argLocalPath                   // A local path, completed by the shell.
+	argPermissionID                // A permission of the item in the first argument.
+)
+
+// completeArgs returns a ValidArgsFunction for a command whose positional
+// arguments are of the kinds `kinds`, in order.
+func completeArgs(kinds ...argKind) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) >= len(kinds) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		switch kinds[len(args)] {
+		case argRemotePath:
+			return completeRemotePath(cmd, toComplete, false)
+		case argRemoteFolder:
+			return completeRemotePath(cmd, toComplete, true)
+		case argLocalPath:
+			return nil, cobra.ShellCompDirectiveDefault
+		case argPermissionID:
+			return completePermissionID(cmd, args[0], toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeRemoteFolderFlag completes a flag whose value is a remote folder.
+func completeRemoteFolderFlag(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return completeRemotePath(cmd, toComplete, true)
+}
+
+// completeRemotePath returns the remote files and folders, or only the
+// folders, that `toComplete` can be completed to. Paths are taken as relative
+// to the root of the drive, as the commands do.
+func completeRemotePath(cmd *cobra.Command, toComplete string, foldersOnly bool) ([]cobra.Completion, cobra.ShellCompDirective) {
+	c, err := newRemoteCompleter(cmd)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var completions []cobra.Completion
+	directive := cobra.ShellCompDirectiveNoFileComp
+	for _, candidate := range shell.NewCompleter(c.listFolder).Complete(c.ctx(), "/", toComplete) {
+		isFolder := strings.HasSuffix(candidate, "/")
+		if foldersOnly && !isFolder {
+			continue
+		}
+		if isFolder {
+			// The folder is completed without a space, so that its
+			// children can be completed next.
+			directive |= cobra.ShellCompDirectiveNoSpace
+		}
+		completions = append(completions, candidate)
+	}
+	return completions, directive
+}
+
+// completePermissionID returns the IDs of the permissions of `remotePath`
+// that start with `toComplete`, described by their roles and grantee.
+func completePermissionID(cmd *cobra.Command, remotePath, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	c, err := newRemoteCompleter(cmd)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveError
+	}
+	values, err := c.cached(c.ctx(), "permissions:"+remotePath, func(ctx context.Context, sdk app.SDK) ([]string, error) {
+		permissions, err := sdk.ListPermissions(ctx, remotePath)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, 0, len(permissions.Value))
+		for _, permission := range permissions.Value {
+			values = append(values, cobra.CompletionWithDesc(permission.ID, describePermission(permission)))
+		}
+		return values, nil
+	})
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var completions []cobra.Completion
+	for _, value := range values {
+		if strings.HasPrefix(value, toComplete) {
+			completions = append(completions, value)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// describePermission summarizes a permission for the description of its ID.
+func describePermission(permission onedrive.Permission) string {
+	description := strings.Join(permission.Roles, ", ")
+	switch {
+	case permission.Link != nil:
+		description += fmt.Sprintf(" (%s link, %s)", permission.Link.Type, permission.Link.Scope)
+	case permission.GrantedToV2 != nil && permission.GrantedToV2.User != nil:
+		description += " (" + permission.GrantedToV2.User.DisplayName + ")"
+	case permission.GrantedToV2 != nil && permission.GrantedToV2.SiteUser != nil:
+		description += " (" + permission.GrantedToV2.SiteUser.DisplayName + ")"
+	}
+	return description
+}
+
+// remoteCompleter fetches completion values from OneDrive through the disk
+// cache of the profile. The client is only created when the cache has no
+// fresh values, as signing in takes longer than reading the cache.
+type remoteCompleter struct {
+	cmd   *cobra.Command
+	cache *session.CompletionCache
+	drive string
+
+	sdk    app.SDK
+	sdkErr error
+}
+
+// newRemoteCompleter returns a remoteCompleter for the profile and drive
+// selected by the flags of `cmd`.
+func newRemoteCompleter(cmd *cobra.Command) (*remoteCompleter, error) {
+	// The root command does not select the profile when completing, as
+	// flags are only parsed for the command being completed.
+	profile, _ := cmd.Flags().GetString("profile")
+	if err := config.SelectProfile(profile); err != nil {
+		return nil, err
+	}
+	mgr, err := session.NewManager()
+	if err != nil {
+		return nil, fmt.Errorf("creating session manager for completion: %w", err)
+	}
+	drive, _ := cmd.Flags().GetString("drive")
+	return &remoteCompleter{
+		cmd:   cmd,
+		cache: mgr.CompletionCache(completionCacheTTL),
+		drive: drive,
+	}, nil
+}
+
+// ctx returns the context of the completion.
+func (c *remoteCompleter) ctx() context.Context {
+	if ctx := c.cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// client returns the SDK of the profile, creating it on first use.
+func (c *remoteCompleter) client() (app.SDK, error) {
+	if c.sdk == nil && c.sdkErr == nil {
+		// A passphrase prompt would block Tab on a prompt the shell does not
+		// show, so completion gives up and uses cached values instead. The
+		// shell's App carried by the context is unlocked already.
+		if app.FromContext(c.ctx()) == nil {
+			if cfg, err := config.LoadOrCreate(); err == nil && app.PassphraseRequired(cfg) {
+				c.sdkErr = fmt.Errorf("%w: set %s to complete remote paths", config.ErrTokenLocked, config.EnvTokenPassphrase)
+				return nil, c.sdkErr
+			}
+		}
+		a, err := app.NewApp(c.cmd)
+		if err != nil {
+			c.sdkErr = fmt.Errorf("initializing app for completion: %w", err)
+		} else {
+			c.sdk = a.SDK
+		}
+	}
+	return c.sdk, c.sdkErr
+}
+
+// listFolder returns the names of the children of the remote folder `dir`,
+// with a trailing "/" for folders.
+func (c *remoteCompleter) listFolder(ctx context.Context, dir string) ([]string, error) {
+	return c.cached(ctx, "children:"+dir, func(ctx context.Context, sdk app.SDK) ([]string, error) {
+		children, err := sdk.GetDriveItemChildrenByPath(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(children.Value))
+		for _, item := range children.Value {
+			name := item.Name
+			if item.Folder != nil {
+				name += "/"
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	})
+}
+
+// cached returns the values cached under `key` while they are fresh, and
+// otherwise fetches and caches them. If fetching fails, for example without
+// network, older cached values are returned instead.
+func (c *remoteCompleter) cached(ctx context.Context, key string, fetch func(ctx context.Context, sdk app.SDK) ([]string, error)) ([]string, error) {
+	key = c.drive + "|" + key // Listings of other drives must not be mixed up.
+	cached, fresh, ok := c.cache.Lookup(key)
+	if ok && fresh {
+		return cached, nil
+	}
+
+	sdk, err := c.client()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+		defer cancel()
+		var values []string
+		if values, err = fetch(ctx, sdk); err == nil {
+			if storeErr := c.cache.Store(key, values); storeErr != nil {
+				cobra.CompDebugln(fmt.Sprintf("caching completions: %v", storeErr), false)
+			}
+			return values, nil
+		}
+	}
+	if ok {
+		cobra.CompDebugln(fmt.Sprintf("using cached completions: %v", err), false)
+		return cached, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,243 @@
+package items
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// newCompletionTestCmd returns a command whose completions use `mockSDK`, as
+// NewApp returns the App carried by its context.
+func newCompletionTestCmd(t *testing.T, mockSDK *MockSDK) *cobra.Command {
+	t.Helper()
+	t.Setenv("ONEDRIVE_CONFIG_PATH", filepath.Join(t.TempDir(), "config.json"))
+	cfg, err := config.LoadOrCreate()
+	require.NoError(t, err)
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("profile", "", "")
+	cmd.Flags().String("drive", "", "")
+	cmd.Flags().Bool("debug", false, "")
+	cmd.SetContext(app.NewContext(context.Background(), &app.App{Config: cfg, SDK: mockSDK}))
+	return cmd
+}
+
+// completionTree returns a mock SDK listing the folders of `tree`, counting
+// the listings in `listings`.
+func completionTree(tree map[string][]onedrive.DriveItem, listings *int) *MockSDK {
+	return &MockSDK{
+		GetDriveItemChildrenByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItemList, error) {
+			*listings++
+			items, ok := tree[path]
+			if !ok {
+				return onedrive.DriveItemList{}, onedrive.ErrResourceNotFound
+			}
+			return onedrive.DriveItemList{Value: items}, nil
+		},
+	}
+}
+
+var completionTestTree = map[string][]onedrive.DriveItem{
+	"/": {
+		{Name: "Documents", Folder: &onedrive.FolderFacet{}},
+		{Name: "Desktop", Folder: &onedrive.FolderFacet{}},
+		{Name: "notes.txt"},
+	},
+	"/Documents": {
+		{Name: "report.pdf"},
+		{Name: "Reports", Folder: &onedrive.FolderFacet{}},
+	},
+}
+
+func TestCompleteRemotePath(t *testing.T) {
+	listings := 0
+	cmd := newCompletionTestCmd(t, completionTree(completionTestTree, &listings))
+	complete := completeArgs(argRemotePath, argLocalPath)
+
+	tests := []struct {
+		name          string
+		toComplete    string
+		want          []string
+		wantDirective cobra.ShellCompDirective
+	}{
+		{"root", "", []string{"Desktop/", "Documents/", "notes.txt"}, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace},
+		{"absolute prefix", "/Do", []string{"/Documents/"}, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace},
+		{"file", "/no", []string{"/notes.txt"}, cobra.ShellCompDirectiveNoFileComp},
+		{"children", "/Documents/rep", []string{"/Documents/Reports/", "/Documents/report.pdf"}, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace},
+		{"relative to the root", "Documents/", []string{"Documents/Reports/", "Documents/report.pdf"}, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace},
+		{"missing folder", "/Missing/", nil, cobra.ShellCompDirectiveNoFileComp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, directive := complete(cmd, nil, tt.toComplete)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantDirective, directive)
+		})
+	}
+
+	// The second argument is local and left to the shell.
+	got, directive := complete(cmd, []string{"/notes.txt"}, "")
+	assert.Empty(t, got)
+	assert.Equal(t, cobra.ShellCompDirectiveDefault, directive)
+
+	// Further arguments are not completed.
+	_, directive = complete(cmd, []string{"/notes.txt", "notes.txt"}, "")
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+}
+
+func TestCompleteRemoteFolder(t *testing.T) {
+	listings := 0
+	cmd := newCompletionTestCmd(t, completionTree(completionTestTree, &listings))
+
+	got, _ := completeArgs(argRemotePath, argRemoteFolder)(cmd, []string{"/notes.txt"}, "/")
+	assert.Equal(t, []string{"/Desktop/", "/Documents/"}, got)
+
+	got, _ = completeRemoteFolderFlag(cmd, nil, "/Documents/")
+	assert.Equal(t, []string{"/Documents/Reports/"}, got)
+}
+
+func TestCompleteRemotePathCache(t *testing.T) {
+	listings := 0
+	mockSDK := completionTree(completionTestTree, &listings)
+	cmd := newCompletionTestCmd(t, mockSDK)
+	complete := completeArgs(argRemotePath)
+
+	// Repeated completions of the same folder are answered from the disk cache.
+	complete(cmd, nil, "/D")
+	complete(cmd, nil, "/Do")
+	assert.Equal(t, 1, listings)
+
+	// Listings are cached per drive.
+	shared := app.FromContext(cmd.Context())
+	otherDrive := &app.App{Config: shared.Config, SDK: mockSDK, DriveID: "other-drive"}
+	cmd.SetContext(app.NewContext(context.Background(), otherDrive))
+	require.NoError(t, cmd.Flags().Set("drive", "other-drive"))
+	complete(cmd, nil, "/D")
+	assert.Equal(t, 2, listings)
+	cmd.SetContext(app.NewContext(context.Background(), shared))
+	require.NoError(t, cmd.Flags().Set("drive", ""))
+
+	mgr, err := session.NewManager()
+	require.NoError(t, err)
+	_, fresh, ok := mgr.CompletionCache(session.DefaultCompletionTTL).Lookup("|children:/")
+	require.True(t, ok)
+	assert.True(t, fresh)
+}
+
+func TestCompleteRemotePathOffline(t *testing.T) {
+	listings := 0
+	mockSDK := completionTree(completionTestTree, &listings)
+	cmd := newCompletionTestCmd(t, mockSDK)
+	complete := completeArgs(argRemotePath)
+
+	// Cached entries expire at once, so every completion asks OneDrive.
+	ttl := completionCacheTTL
+	completionCacheTTL = 0
+	t.Cleanup(func() { completionCacheTTL = ttl })
+
+	got, _ := complete(cmd, nil, "/no")
+	assert.Equal(t, []string{"/notes.txt"}, got)
+	assert.Equal(t, 1, listings)
+
+	// Without network, expired listings are still used.
+	mockSDK.GetDriveItemChildrenByPathFunc = func(ctx context.Context, path string) (onedrive.DriveItemList, error) {
+		return onedrive.DriveItemList{}, errors.New("network is unreachable")
+	}
+	got, _ = complete(cmd, nil, "/no")
+	assert.Equal(t, []string{"/notes.txt"}, got)
+
+	// Folders that were never listed cannot be completed.
+	got, _ = complete(cmd, nil, "/Documents/rep")
+	assert.Empty(t, got)
+}
+
+func TestCompleteRemotePathLockedToken(t *testing.T) {
+	listings := 0
+	cmd := newCompletionTestCmd(t, completionTree(completionTestTree, &listings))
+	complete := completeArgs(argRemotePath)
+
+	ttl := completionCacheTTL
+	completionCacheTTL = 0
+	t.Cleanup(func() { completionCacheTTL = ttl })
+
+	got, _ := complete(cmd, nil, "/no")
+	assert.Equal(t, []string{"/notes.txt"}, got)
+
+	// The profile's token is encrypted with a passphrase that is not in the
+	// environment.
+	cfg, err := config.LoadOrCreate()
+	require.NoError(t, err)
+	cfg.Token = onedrive.Token{AccessToken: "access", RefreshToken: "refresh"}
+	require.NoError(t, cfg.EncryptToken(config.TokenSecret{Passphrase: "secret"}))
+	require.NoError(t, cfg.Save())
+	t.Setenv(config.EnvTokenPassphrase, "")
+	readPassphrase := app.ReadPassphrase
+	t.Cleanup(func() { app.ReadPassphrase = readPassphrase })
+	app.ReadPassphrase = func(prompt string) (string, error) {
+		t.Fatal("completion must not prompt for the passphrase")
+		return "", nil
+	}
+
+	// Outside the shell, completion creates its own App: it must not ask for
+	// the passphrase, and falls back to the cached listing.
+	locked := &cobra.Command{}
+	locked.Flags().String("profile", "", "")
+	locked.Flags().String("drive", "", "")
+	locked.Flags().Bool("debug", false, "")
+	locked.SetContext(context.Background())
+	got, _ = complete(locked, nil, "/no")
+	assert.Equal(t, []string{"/notes.txt"}, got)
+	assert.Equal(t, 1, listings)
+}
+
+func TestCompletePermissionID(t *testing.T) {
+	var listed []string
+	mockSDK := &MockSDK{
+		ListPermissionsFunc: func(ctx context.Context, remotePath string) (onedrive.PermissionList, error) {
+			listed = append(listed, remotePath)
+			permission := onedrive.Permission{ID: "perm-user", Roles: []string{"write"}}
+			permission.GrantedToV2 = &struct {
+				User     *onedrive.Identity `json:"user,omitempty"`
+				SiteUser *onedrive.Identity `json:"siteUser,omitempty"`
+			}{User: &onedrive.Identity{DisplayName: "Ada Lovelace"}}
+			link := onedrive.Permission{ID: "perm-link", Roles: []string{"read"}}
+			link.Link = &struct {
+				Type        string `json:"type"`
+				Scope       string `json:"scope"`
+				WebURL      string `json:"webUrl"`
+				WebHTML     string `json:"webHtml,omitempty"`
+				Application *struct {
+					ID          string `json:"id"`
+					DisplayName string `json:"displayName"`
+				} `json:"application,omitempty"`
+				PreventsDownload bool `json:"preventsDownload,omitempty"`
+			}{Type: "view", Scope: "anonymous"}
+			return onedrive.PermissionList{Value: []onedrive.Permission{permission, link}}, nil
+		},
+	}
+	cmd := newCompletionTestCmd(t, mockSDK)
+	complete := completeArgs(argRemotePath, argPermissionID)
+
+	got, directive := complete(cmd, []string{"/report.pdf"}, "")
+	assert.Equal(t, []string{"perm-user\twrite (Ada Lovelace)", "perm-link\tread (view link, anonymous)"}, got)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+	got, _ = complete(cmd, []string{"/report.pdf"}, "perm-l")
+	assert.Equal(t, []string{"perm-link\tread (view link, anonymous)"}, got)
+	assert.Equal(t, []string{"/report.pdf"}, listed, "the permissions are cached")
+
+	mockSDK.ListPermissionsFunc = func(ctx context.Context, remotePath string) (onedrive.PermissionList, error) {
+		return onedrive.PermissionList{}, onedrive.ErrResourceNotFound
+	}
+	_, directive = complete(cmd, []string{"/missing.pdf"}, "")
+	assert.Equal(t, cobra.ShellCompDirectiveError, directive)
+}
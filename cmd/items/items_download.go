@@ -6,11 +6,16 @@ package items
 import (
 	"fmt"
 	"log"
+	"path/filepath"
 	"strings"
 
+	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/session"
 	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
 // filesDownloadCmd handles 'items download <remote-path> [local-path]'.
@@ -28,54 +33,145 @@ with its original name.
 
 Use the '--format' flag to download the file converted to a different format
 (e.g., downloading a .docx file as .pdf). Supported formats depend on the
-Microsoft Graph API capabilities.`,
+Microsoft Graph API capabilities.
+
+Files larger than 4MB are downloaded in ranges into a '.onedrive-partial' file next to the
+destination, with progress saved after every chunk. If the download is interrupted,
+running the same command again continues from where it stopped, as long as the
+remote file has not changed in the meantime.
+
+Use '--connections N' to fetch a file over N parallel connections instead, which
+helps on high-latency links where a single stream cannot use the full bandwidth.
+The file is split into 8MB ranges that are retried individually if they fail.
+Parallel downloads start over when interrupted.
+
+Use '-r' (--recursive) to download a whole folder. The contents of the remote
+folder are downloaded into the local path (by default, a folder named after the
+remote folder in the current directory), recreating the folder hierarchy with the
+configured directory permissions. Files are downloaded in parallel by --workers
+workers, and any item whose name would escape the local folder is refused.`,
 	Example: `onedrive-client items download /Documents/MyReport.docx ./MyReport_local.docx
 onedrive-client items download /Images/Photo.jpg
-onedrive-client items download /Presentations/Deck.pptx --format pdf`,
+onedrive-client items download /Presentations/Deck.pptx --format pdf
+onedrive-client items download /Videos/Keynote.mp4 --connections 8
+onedrive-client items download -r /Photos/2024 ./photos-2024`,
 	Args: cobra.RangeArgs(1, 2), // Requires 1 (remote-path) or 2 (remote-path, local-path) arguments.
 	RunE: func(cmd *cobra.Command, args []string) error {
 		a, err := app.NewApp(cmd) // Renamed 'app' to 'a'
 		if err != nil {
 			return fmt.Errorf("initializing app for 'items download': %w", err)
 		}
+		return filesDownloadLogic(a, cmd, args)
+	},
+}
 
-		remotePath := args[0]
+// filesDownloadLogic contains the core logic for the 'items download' command.
+func filesDownloadLogic(a *app.App, cmd *cobra.Command, args []string) error {
+	if len(args) == 0 { // Should be caught by Args validation.
+		return fmt.Errorf("remote path for 'download' is required")
+	}
+	remotePath := args[0]
 
-		// Determine the local path for saving the downloaded file.
-		localPath := ""
-		if len(args) > 1 {
-			localPath = args[1] // Use provided local path.
+	// Determine the local path for saving the downloaded file.
+	localPath := ""
+	if len(args) > 1 {
+		localPath = args[1] // Use provided local path.
+	} else if isBareItemID(remotePath) {
+		// An item given by ID is saved under its name in OneDrive.
+		item, err := a.SDK.GetDriveItemByPath(cmd.Context(), remotePath)
+		if err != nil {
+			return fmt.Errorf("getting name of item '%s': %w", remotePath, err)
+		}
+		localPath = item.Name
+	} else {
+		// If no local path is provided, extract the filename from the remote path
+		// and use it in the current directory.
+		parts := strings.Split(strings.TrimSuffix(remotePath, "/"), "/")
+		if len(parts) > 0 && parts[len(parts)-1] != "" {
+			localPath = parts[len(parts)-1]
 		} else {
-			// If no local path is provided, extract the filename from the remote path
-			// and use it in the current directory.
-			parts := strings.Split(remotePath, "/")
-			if len(parts) > 0 {
-				localPath = parts[len(parts)-1]
-			} else {
-				// Should not happen if remotePath is valid, but as a fallback.
-				localPath = "downloaded_file"
-			}
+			// Should not happen if remotePath is valid, but as a fallback.
+			localPath = "downloaded_file"
+		}
+	}
+
+	recursive, _ := cmd.Flags().GetBool("recursive")
+	format, _ := cmd.Flags().GetString("format")
+	connections, _ := cmd.Flags().GetInt("connections")
+	if cmd.Flags().Changed("connections") {
+		if connections < 1 {
+			return fmt.Errorf("--connections must be at least 1, got %d", connections)
+		}
+		if recursive || format != "" {
+			return fmt.Errorf("--connections cannot be combined with --recursive or --format")
 		}
+	}
 
-		// Check if the --format flag is specified for format conversion.
-		format, _ := cmd.Flags().GetString("format")
-		if format != "" {
-			// Download with format conversion.
-			err = a.SDK.DownloadFileAsFormat(cmd.Context(), remotePath, localPath, format)
-			if err != nil {
-				return fmt.Errorf("downloading file '%s' as format '%s' to '%s': %w", remotePath, format, localPath, err)
-			}
-			log.Printf("Successfully downloaded '%s' as format '%s' to '%s'", remotePath, format, localPath)
-		} else {
-			// Standard download without format conversion.
-			err = a.SDK.DownloadFile(cmd.Context(), remotePath, localPath)
-			if err != nil {
-				return fmt.Errorf("downloading file '%s' to '%s': %w", remotePath, localPath, err)
-			}
-			log.Printf("Successfully downloaded '%s' to '%s'", remotePath, localPath)
+	// With --recursive, the remote path is a folder whose tree is downloaded into localPath.
+	if recursive {
+		return filesDownloadFolderLogic(a, cmd, remotePath, localPath)
+	}
+
+	// Check if the --format flag is specified for format conversion.
+	if format != "" {
+		// Download with format conversion.
+		err := a.SDK.DownloadFileAsFormat(cmd.Context(), remotePath, localPath, format)
+		if err != nil {
+			return fmt.Errorf("downloading file '%s' as format '%s' to '%s': %w", remotePath, format, localPath, err)
 		}
-		return nil
-	},
+		log.Printf("Successfully downloaded '%s' as format '%s' to '%s'", remotePath, format, localPath)
+	} else if connections > 1 {
+		// Fetch several byte ranges at once over separate connections.
+		progress, done := newDownloadProgress(localPath)
+		err := a.SDK.DownloadFileParallel(cmd.Context(), remotePath, localPath, connections, progress)
+		done()
+		if err != nil {
+			return fmt.Errorf("downloading file '%s' to '%s' over %d connections: %w", remotePath, localPath, connections, err)
+		}
+		log.Printf("Successfully downloaded '%s' to '%s' over %d connections", remotePath, localPath, connections)
+	} else {
+		// Standard download without format conversion. Large files are downloaded in
+		// ranges and can be resumed by running the same command again.
+		mgr, err := session.NewManager()
+		if err != nil {
+			return fmt.Errorf("creating session manager for download: %w", err)
+		}
+		permissions := config.DefaultDownloadConfig()
+		if a.Config != nil {
+			permissions = a.Config.Download
+		}
+
+		progress, done := newDownloadProgress(localPath)
+		err = downloadFileResumable(cmd.Context(), a.SDK, mgr, remotePath, localPath, permissions.FilePermissions, progress)
+		done()
+		if err != nil {
+			return err
+		}
+		log.Printf("Successfully downloaded '%s' to '%s'", remotePath, localPath)
+	}
+	return nil
+}
+
+// newDownloadProgress returns a progress callback that drives a progress bar for
+// the download to localPath, created once the total size is known, and a function
+// that closes the bar.
+func newDownloadProgress(localPath string) (onedrive.ProgressFunc, func()) {
+	var progressBar *progressbar.ProgressBar
+	progress := func(completed, total int64) {
+		if progressBar == nil {
+			progressBar = ui.NewProgressBar(int(total), "Downloading "+filepath.Base(localPath))
+		}
+		_ = progressBar.Set64(completed)
+	}
+	done := func() {
+		if progressBar == nil {
+			return
+		}
+		if closeErr := progressBar.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close progress bar: %v", closeErr)
+		}
+	}
+	return progress, done
 }
 
 // filesListRootDeprecatedCmd handles 'items list-root-deprecated'.
@@ -103,6 +199,5 @@ func filesListRootDeprecatedLogic(a *app.App, cmd *cobra.Command, args []string)
 	if err != nil {
 		return fmt.Errorf("fetching root drive items (deprecated method): %w", err)
 	}
-	ui.DisplayItems(items)
-	return nil
+	return ui.DisplayItems(items)
 }
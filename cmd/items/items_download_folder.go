@@ -0,0 +1,288 @@
+// Package items (items_download_folder.go) implements recursive folder downloads
+// for 'items download -r'. The remote tree is walked with GetDriveItemChildrenByPath,
+// the folder hierarchy is recreated locally and files are downloaded in parallel.
+package items
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// defaultDownloadWorkers is the number of files downloaded in parallel by a folder download.
+const defaultDownloadWorkers = 4
+
+// folderDownloadJob is a single file queued by a folder download.
+type folderDownloadJob struct {
+	remotePath string
+	localPath  string
+	item       onedrive.DriveItem // Metadata from the folder listing, used to verify the download.
+}
+
+// folderDownloadResult is the outcome of downloading one file of a folder download.
+type folderDownloadResult struct {
+	folderDownloadJob
+	elapsed time.Duration
+	err     error
+}
+
+// filesDownloadFolderLogic downloads the remote folder tree at remoteDir into
+// localDir. Local folders are created with the configured directory permissions,
+// every local path is validated to stay inside localDir, and files are downloaded
+// by a pool of workers. A per-file and aggregate summary is printed, and an error
+// is returned if any file failed.
+func filesDownloadFolderLogic(a *app.App, cmd *cobra.Command, remoteDir, localDir string) error {
+	workers, err := cmd.Flags().GetInt("workers")
+	if err != nil {
+		return fmt.Errorf("parsing '--workers' flag: %w", err)
+	}
+	if workers < 1 {
+		return fmt.Errorf("--workers must be at least 1, got %d", workers)
+	}
+
+	permissions := config.DefaultDownloadConfig()
+	if a.Config != nil {
+		permissions = a.Config.Download
+	}
+
+	root, err := onedrive.SanitizeLocalPath(localDir)
+	if err != nil {
+		return fmt.Errorf("validating local folder '%s': %w", localDir, err)
+	}
+	if err := os.MkdirAll(root, permissions.DirectoryPermissions); err != nil {
+		return fmt.Errorf("creating local folder '%s': %w", root, err)
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	jobs, folders, err := collectFolderDownload(ctx, a.SDK, remoteDir, root, permissions.DirectoryPermissions)
+	if err != nil {
+		return err
+	}
+
+	mgr, err := session.NewManager()
+	if err != nil {
+		return fmt.Errorf("creating session manager for download: %w", err)
+	}
+
+	log.Printf("Downloading %d file(s) from '%s' to '%s' with %d worker(s).", len(jobs), remoteDir, root, workers)
+	start := time.Now()
+	results := downloadFolderFiles(ctx, a.SDK, mgr, jobs, workers, permissions)
+	return displayFolderDownloadSummary(results, folders, root, time.Since(start), workers)
+}
+
+// collectFolderDownload walks the remote tree below remoteDir, creating the
+// matching local folders below localRoot as it goes, and returns the files to
+// download and the number of folders visited. Items that are neither files nor
+// folders (such as OneNote notebooks) are skipped.
+func collectFolderDownload(
+	ctx context.Context, sdk app.SDK, remoteDir, localRoot string, dirPermissions os.FileMode,
+) ([]folderDownloadJob, int, error) {
+	var jobs []folderDownloadJob
+	folders := 0
+	var walk func(remote, local string) error
+	walk = func(remote, local string) error {
+		children, err := sdk.GetDriveItemChildrenByPath(ctx, remote)
+		if err != nil {
+			return fmt.Errorf("listing remote folder '%s': %w", remote, err)
+		}
+		for _, child := range children.Value {
+			childRemote := joinRemotePath(remote, child.Name)
+			childLocal, err := localDownloadPath(localRoot, local, child.Name)
+			if err != nil {
+				return fmt.Errorf("refusing to download '%s': %w", childRemote, err)
+			}
+			switch {
+			case child.Folder != nil:
+				if err := os.MkdirAll(childLocal, dirPermissions); err != nil {
+					return fmt.Errorf("creating local folder '%s': %w", childLocal, err)
+				}
+				folders++
+				if err := walk(childRemote, childLocal); err != nil {
+					return err
+				}
+			case child.File != nil:
+				jobs = append(jobs, folderDownloadJob{remotePath: childRemote, localPath: childLocal, item: child})
+			default:
+				log.Printf("Skipping '%s': not a file or folder.", childRemote)
+			}
+		}
+		return nil
+	}
+	if err := walk(remoteDir, localRoot); err != nil {
+		return nil, 0, err
+	}
+	return jobs, folders, nil
+}
+
+// localDownloadPath returns the local path for the remote item `name` inside the
+// local folder `dir`. The name comes from the server, so the result is checked
+// with SanitizeLocalPath and must stay strictly inside localRoot.
+func localDownloadPath(localRoot, dir, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("%w: invalid item name '%s'", onedrive.ErrPathTraversal, name)
+	}
+	// Concatenate rather than filepath.Join, which would silently resolve "..".
+	sanitized, err := onedrive.SanitizeLocalPath(dir + string(os.PathSeparator) + name)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(localRoot, sanitized)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%w: '%s' is outside '%s'", onedrive.ErrPathTraversal, sanitized, localRoot)
+	}
+	return sanitized, nil
+}
+
+// downloadFolderFiles downloads jobs with the given number of concurrent workers
+// and returns one result per job, in the order of jobs.
+func downloadFolderFiles(
+	ctx context.Context, sdk app.SDK, mgr *session.Manager, jobs []folderDownloadJob, workers int,
+	permissions config.DownloadConfig,
+) []folderDownloadResult {
+	results := make([]folderDownloadResult, len(jobs))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = downloadFolderFile(ctx, sdk, mgr, jobs[i], permissions)
+			}
+		}()
+	}
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// downloadFolderFile downloads a single file of a folder download, verifies it
+// against the size and hash from the folder listing and applies the configured
+// file permissions to it. Files above onedrive.LargeFileThreshold are downloaded
+// resumably.
+func downloadFolderFile(
+	ctx context.Context, sdk app.SDK, mgr *session.Manager, job folderDownloadJob, permissions config.DownloadConfig,
+) (result folderDownloadResult) {
+	result.folderDownloadJob = job
+	if err := ctx.Err(); err != nil {
+		result.err = err
+		return result
+	}
+
+	start := time.Now()
+	defer func() { result.elapsed = time.Since(start) }()
+	if err := onedrive.ValidateDownloadPath(job.localPath, true, permissions.DirectoryPermissions); err != nil {
+		result.err = fmt.Errorf("validating local path '%s': %w", job.localPath, err)
+		return result
+	}
+	if job.item.Size > onedrive.LargeFileThreshold {
+		err := downloadFileResumable(ctx, sdk, mgr, job.remotePath, job.localPath, permissions.FilePermissions, nil)
+		if err != nil {
+			result.err = err
+			return result
+		}
+	} else {
+		if err := sdk.DownloadFile(ctx, job.remotePath, job.localPath); err != nil {
+			result.err = fmt.Errorf("downloading '%s': %w", job.remotePath, err)
+			return result
+		}
+		if err := onedrive.VerifyFile(job.localPath, job.item); err != nil {
+			result.err = fmt.Errorf("verifying download of '%s': %w", job.remotePath, err)
+			return result
+		}
+	}
+	if err := os.Chmod(job.localPath, permissions.FilePermissions); err != nil {
+		log.Printf("Warning: failed to set permissions on '%s': %v", job.localPath, err)
+	}
+	return result
+}
+
+// folderDownloadColumns are the default CSV columns of a folder download summary.
+var folderDownloadColumns = []string{"remotePath", "localPath", "size", "elapsedMs", "error"}
+
+// folderDownloadFile is the structured form of a folderDownloadResult.
+type folderDownloadFile struct {
+	RemotePath string `json:"remotePath"`
+	LocalPath  string `json:"localPath"`
+	Size       int64  `json:"size"`
+	ElapsedMs  int64  `json:"elapsedMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// folderDownloadSummary is the structured form of displayFolderDownloadSummary,
+// with the downloaded files as the "value" list.
+type folderDownloadSummary struct {
+	LocalRoot  string               `json:"localRoot"`
+	Downloaded int                  `json:"downloaded"`
+	Failed     int                  `json:"failed"`
+	Bytes      int64                `json:"bytes"`
+	Folders    int                  `json:"folders"`
+	Workers    int                  `json:"workers"`
+	ElapsedMs  int64                `json:"elapsedMs"`
+	Value      []folderDownloadFile `json:"value"`
+}
+
+// displayFolderDownloadSummary prints one line per downloaded file, sorted by
+// local path, followed by the totals. It returns an error if any file failed.
+func displayFolderDownloadSummary(results []folderDownloadResult, folders int, localRoot string, elapsed time.Duration, workers int) error {
+	sorted := append([]folderDownloadResult(nil), results...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].localPath < sorted[j].localPath })
+
+	summary := folderDownloadSummary{
+		LocalRoot: localRoot,
+		Folders:   folders,
+		Workers:   workers,
+		ElapsedMs: elapsed.Milliseconds(),
+		Value:     make([]folderDownloadFile, 0, len(sorted)),
+	}
+	for _, r := range sorted {
+		file := folderDownloadFile{RemotePath: r.remotePath, LocalPath: r.localPath, Size: r.item.Size, ElapsedMs: r.elapsed.Milliseconds()}
+		if r.err != nil {
+			summary.Failed++
+			file.Error = r.err.Error()
+		} else {
+			summary.Downloaded++
+			summary.Bytes += r.item.Size
+		}
+		summary.Value = append(summary.Value, file)
+	}
+
+	err := ui.Render(os.Stdout, ui.Result{Data: summary, Columns: folderDownloadColumns, Table: func(w io.Writer) {
+		for _, r := range sorted {
+			if r.err != nil {
+				fmt.Fprintf(w, "  FAILED      %s: %v\n", r.remotePath, r.err)
+				continue
+			}
+			fmt.Fprintf(w, "  downloaded  %s -> %s (%d bytes, %s)\n", r.remotePath, r.localPath, r.item.Size, r.elapsed.Round(time.Millisecond))
+		}
+		fmt.Fprintf(w, "Downloaded %d of %d file(s) (%d bytes) to '%s' in %s with %d worker(s); %d folder(s), %d failed.\n",
+			summary.Downloaded, len(sorted), summary.Bytes, localRoot, elapsed.Round(time.Millisecond), workers, folders, summary.Failed)
+	}})
+	if err != nil {
+		return err
+	}
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed to download", summary.Failed, len(sorted))
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Package items (items_download_resumable.go) implements resumable downloads of
+// large files. The file is fetched in byte ranges into a partial file next to the
+// destination, and progress is checkpointed through the session manager so that
+// an interrupted download continues from where it stopped.
+package items
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// downloadChunkSize is the size of each ranged request of a resumable download.
+const downloadChunkSize = 10 * 1024 * 1024
+
+// downloadFileResumable downloads remotePath to localPath. Files up to
+// onedrive.LargeFileThreshold are downloaded in one request; larger files are
+// fetched in ranges into localPath+onedrive.PartialDownloadSuffix, which sync
+// skips, with the completed byte count and download URL saved in `mgr` after
+// every chunk. A rerun resumes from the saved offset unless the remote content
+// has changed since. An expired download URL is refreshed from the item
+// metadata. The result is checked against the size and content hash in the
+// metadata; on a mismatch a partial download is discarded.
+// `progress` may be nil.
+func downloadFileResumable(
+	ctx context.Context, sdk app.SDK, mgr *session.Manager, remotePath, localPath string,
+	filePermissions os.FileMode, progress onedrive.ProgressFunc,
+) error {
+	item, err := sdk.GetDriveItemByPath(ctx, remotePath)
+	if err != nil {
+		return fmt.Errorf("getting metadata for '%s': %w", remotePath, err)
+	}
+	if item.Folder != nil {
+		return fmt.Errorf("'%s' is a folder; use --recursive to download folders", remotePath)
+	}
+	if item.Size <= onedrive.LargeFileThreshold || item.DownloadURL == "" {
+		if err := sdk.DownloadFile(ctx, remotePath, localPath); err != nil {
+			return fmt.Errorf("downloading file '%s' to '%s': %w", remotePath, localPath, err)
+		}
+		if err := onedrive.VerifyFile(localPath, item); err != nil {
+			return fmt.Errorf("verifying download of '%s': %w", remotePath, err)
+		}
+		if progress != nil {
+			progress(item.Size, item.Size)
+		}
+		return nil
+	}
+
+	partialPath := localPath + onedrive.PartialDownloadSuffix
+	state := &session.State{
+		DownloadURL: item.DownloadURL,
+		LocalPath:   localPath,
+		RemotePath:  remotePath,
+		RemoteCTag:  item.CTag,
+	}
+	saved, err := mgr.Load(localPath, remotePath)
+	if err != nil {
+		return fmt.Errorf("loading download session state for '%s': %w", localPath, err)
+	}
+	if saved != nil && saved.DownloadURL != "" {
+		if saved.RemoteCTag == item.CTag && partialSize(partialPath) >= saved.CompletedBytes {
+			state.CompletedBytes = saved.CompletedBytes
+			log.Printf("Resuming download of '%s' to '%s' from %d bytes.", remotePath, localPath, state.CompletedBytes)
+		} else {
+			log.Printf("'%s' changed since the interrupted download; starting over.", remotePath)
+		}
+	}
+
+	file, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY, filePermissions)
+	if err != nil {
+		return fmt.Errorf("opening partial file '%s': %w", partialPath, err)
+	}
+	defer func() {
+		if file == nil {
+			return
+		}
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close file: %v", closeErr)
+		}
+	}()
+	// Anything past the checkpoint was written after the last save and is discarded.
+	if err := file.Truncate(state.CompletedBytes); err != nil {
+		return fmt.Errorf("truncating partial file '%s': %w", partialPath, err)
+	}
+	if _, err := file.Seek(state.CompletedBytes, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking in partial file '%s': %w", partialPath, err)
+	}
+	if progress != nil {
+		progress(state.CompletedBytes, item.Size)
+	}
+
+	refreshed := false
+	for state.CompletedBytes < item.Size {
+		endByte := state.CompletedBytes + downloadChunkSize - 1
+		if endByte >= item.Size {
+			endByte = item.Size - 1
+		}
+		n, err := downloadChunk(ctx, sdk, state.DownloadURL, state.CompletedBytes, endByte, file)
+		state.CompletedBytes += n
+		if errors.Is(err, onedrive.ErrDownloadURLExpired) && !refreshed {
+			// Download URLs are short-lived, so long downloads outlive them.
+			if err = refreshDownloadURL(ctx, sdk, state); err == nil {
+				refreshed = true
+				continue
+			}
+		}
+		if err != nil {
+			saveDownloadState(mgr, state)
+			return fmt.Errorf("downloading bytes %d-%d of '%s': %w", state.CompletedBytes, endByte, remotePath, err)
+		}
+		refreshed = false
+		saveDownloadState(mgr, state)
+		if progress != nil {
+			progress(state.CompletedBytes, item.Size)
+		}
+	}
+
+	closeErr := file.Close()
+	file = nil
+	if closeErr != nil {
+		return fmt.Errorf("closing partial file '%s': %w", partialPath, closeErr)
+	}
+	if err := onedrive.VerifyFile(partialPath, item); err != nil {
+		// The checkpointed bytes cannot be trusted, so the next run starts over.
+		discardPartialDownload(mgr, partialPath, state)
+		return fmt.Errorf("verifying download of '%s': %w", remotePath, err)
+	}
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return fmt.Errorf("moving downloaded file into place at '%s': %w", localPath, err)
+	}
+	if err := mgr.Delete(localPath, remotePath); err != nil {
+		log.Printf("Warning: failed to delete session file for completed download '%s': %v", localPath, err)
+	}
+	return nil
+}
+
+// downloadChunk fetches the inclusive byte range start-end from downloadURL and
+// appends it to w, returning the number of bytes written.
+func downloadChunk(ctx context.Context, sdk app.SDK, downloadURL string, start, end int64, w io.Writer) (int64, error) {
+	body, err := sdk.DownloadFileChunk(ctx, downloadURL, start, end)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := body.Close(); closeErr != nil {
+			log.Printf("Warning: failed to close chunk response body: %v", closeErr)
+		}
+	}()
+	want := end - start + 1
+	n, err := io.Copy(w, io.LimitReader(body, want))
+	if err == nil && n < want {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// refreshDownloadURL replaces the expired download URL in state with a fresh
+// one from the item metadata. It fails if the remote content has changed, as
+// the bytes already downloaded would no longer match.
+func refreshDownloadURL(ctx context.Context, sdk app.SDK, state *session.State) error {
+	item, err := sdk.GetDriveItemByPath(ctx, state.RemotePath)
+	if err != nil {
+		return fmt.Errorf("refreshing download URL for '%s': %w", state.RemotePath, err)
+	}
+	if item.CTag != state.RemoteCTag {
+		return fmt.Errorf("'%s' changed during the download", state.RemotePath)
+	}
+	if item.DownloadURL == "" {
+		return fmt.Errorf("item '%s' has no download URL in its metadata", state.RemotePath)
+	}
+	state.DownloadURL = item.DownloadURL
+	return nil
+}
+
+// saveDownloadState checkpoints a resumable download. Failures are only logged,
+// as they merely cost progress on the next run.
+func saveDownloadState(mgr *session.Manager, state *session.State) {
+	if err := mgr.Save(state); err != nil {
+		log.Printf("Error saving download session state for '%s': %v", state.LocalPath, err)
+	}
+}
+
+// discardPartialDownload removes the partial file and the saved state of a
+// download. Failures are only logged.
+func discardPartialDownload(mgr *session.Manager, partialPath string, state *session.State) {
+	if err := os.Remove(partialPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to remove partial download '%s': %v", partialPath, err)
+	}
+	if err := mgr.Delete(state.LocalPath, state.RemotePath); err != nil {
+		log.Printf("Warning: failed to delete session file for download '%s': %v", state.LocalPath, err)
+	}
+}
+
+// partialSize returns the size of the file at path, or -1 if it cannot be read.
+func partialSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
@@ -0,0 +1,418 @@
+package items
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+func newDownloadTestCmd(t *testing.T, workers string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().BoolP("recursive", "r", false, "")
+	cmd.Flags().Int("workers", defaultDownloadWorkers, "")
+	cmd.Flags().String("format", "", "")
+	require.NoError(t, cmd.Flags().Set("recursive", "true"))
+	require.NoError(t, cmd.Flags().Set("workers", workers))
+	cmd.SetContext(context.Background())
+	return cmd
+}
+
+// remoteTreeSDK serves a fixed remote tree below "/Photos" and writes the
+// remote path into every downloaded file, so a file's size in the tree must be
+// the length of its remote path to pass verification.
+func remoteTreeSDK(tree map[string][]onedrive.DriveItem) *MockSDK {
+	return &MockSDK{
+		GetDriveItemChildrenByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItemList, error) {
+			children, ok := tree[path]
+			if !ok {
+				return onedrive.DriveItemList{}, onedrive.ErrResourceNotFound
+			}
+			return onedrive.DriveItemList{Value: children}, nil
+		},
+		DownloadFileFunc: func(ctx context.Context, remotePath, localPath string) error {
+			if strings.HasSuffix(remotePath, "broken.jpg") {
+				return errors.New("boom")
+			}
+			return os.WriteFile(localPath, []byte(remotePath), 0600)
+		},
+	}
+}
+
+// newPagedListingClient returns an SDK client for a Graph server that lists
+// the children of each folder in `pages` one page at a time, linking the
+// pages with @odata.nextLink as OneDrive does for large folders.
+func newPagedListingClient(t *testing.T, pages map[string][][]onedrive.DriveItem) *onedrive.Client {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		folder := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/me/drive/root:"), ":/children")
+		if r.URL.Path == "/me/drive/root/children" {
+			folder = "/"
+		}
+		folderPages, ok := pages[folder]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		response := map[string]interface{}{"value": folderPages[page]}
+		if page+1 < len(folderPages) {
+			next := url.URL{Path: r.URL.Path, RawQuery: "page=" + strconv.Itoa(page+1)}
+			response["@odata.nextLink"] = server.URL + next.String()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return onedrive.NewClient(context.Background(), &onedrive.Token{AccessToken: "test-token"}, "test-client-id", nil, nil,
+		onedrive.WithEndpoints(onedrive.Endpoints{GraphURL: server.URL + "/"}))
+}
+
+func remoteFile(name string, size int64) onedrive.DriveItem {
+	return onedrive.DriveItem{Name: name, Size: size, File: &onedrive.FileFacet{}}
+}
+
+func remoteFolder(name string) onedrive.DriveItem {
+	return onedrive.DriveItem{Name: name, Folder: &onedrive.FolderFacet{}}
+}
+
+func TestFilesDownloadFolderLogic(t *testing.T) {
+	t.Setenv("ONEDRIVE_CONFIG_PATH", filepath.Join(t.TempDir(), "config.json"))
+
+	t.Run("recreates the remote tree locally", func(t *testing.T) {
+		target := filepath.Join(t.TempDir(), "photos")
+		sdk := remoteTreeSDK(map[string][]onedrive.DriveItem{
+			"/Photos":             {remoteFile("a.jpg", 13), remoteFolder("2024"), {Name: "Notebook"}},
+			"/Photos/2024":        {remoteFile("b.jpg", 18), remoteFolder("empty")},
+			"/Photos/2024/empty":  {},
+			"/Photos/unreachable": {remoteFile("never.jpg", 1)},
+		})
+		a := &app.App{
+			SDK:    sdk,
+			Config: &config.Configuration{Download: config.DownloadConfig{FilePermissions: 0600, DirectoryPermissions: 0750}},
+		}
+
+		require.NoError(t, filesDownloadLogic(a, newDownloadTestCmd(t, "2"), []string{"/Photos", target}))
+
+		content, err := os.ReadFile(filepath.Join(target, "2024", "b.jpg"))
+		require.NoError(t, err)
+		assert.Equal(t, "/Photos/2024/b.jpg", string(content))
+		assert.FileExists(t, filepath.Join(target, "a.jpg"))
+		assert.NoFileExists(t, filepath.Join(target, "Notebook"))
+
+		info, err := os.Stat(filepath.Join(target, "2024", "empty"))
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+		info, err = os.Stat(filepath.Join(target, "2024", "b.jpg"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("reports failed files", func(t *testing.T) {
+		target := t.TempDir()
+		sdk := remoteTreeSDK(map[string][]onedrive.DriveItem{
+			"/Photos": {remoteFile("a.jpg", 13), remoteFile("broken.jpg", 5)},
+		})
+		err := filesDownloadLogic(&app.App{SDK: sdk}, newDownloadTestCmd(t, "4"), []string{"/Photos", target})
+		assert.ErrorContains(t, err, "1 of 2 file(s) failed to download")
+		assert.FileExists(t, filepath.Join(target, "a.jpg"))
+	})
+
+	t.Run("fails files that do not match the listing", func(t *testing.T) {
+		truncated := remoteFile("a.jpg", 13)
+		truncated.File.Hashes = &onedrive.FileHashes{QuickXorHash: "AAAAAAAAAAAAAAAAAAAAAAAAAAA="}
+		sdk := remoteTreeSDK(map[string][]onedrive.DriveItem{
+			"/Photos": {truncated, remoteFile("b.jpg", 100)},
+		})
+		err := filesDownloadLogic(&app.App{SDK: sdk}, newDownloadTestCmd(t, "2"), []string{"/Photos", t.TempDir()})
+		assert.ErrorContains(t, err, "2 of 2 file(s) failed to download")
+	})
+
+	t.Run("refuses names that escape the target folder", func(t *testing.T) {
+		for _, name := range []string{"..", ".", "../evil.sh", `..\evil.sh`, ""} {
+			base := t.TempDir()
+			target := filepath.Join(base, "photos")
+			sdk := remoteTreeSDK(map[string][]onedrive.DriveItem{
+				"/Photos":    {remoteFolder("ok"), remoteFile(name, 1)},
+				"/Photos/ok": {},
+			})
+			err := filesDownloadLogic(&app.App{SDK: sdk}, newDownloadTestCmd(t, "1"), []string{"/Photos", target})
+			assert.ErrorIs(t, err, onedrive.ErrPathTraversal, "name %q", name)
+			assert.NoFileExists(t, filepath.Join(base, "evil.sh"))
+		}
+	})
+
+	t.Run("rejects fewer than one worker", func(t *testing.T) {
+		err := filesDownloadLogic(&app.App{SDK: &MockSDK{}}, newDownloadTestCmd(t, "0"), []string{"/Photos", t.TempDir()})
+		assert.ErrorContains(t, err, "--workers must be at least 1")
+	})
+}
+
+func TestCollectFolderDownloadPages(t *testing.T) {
+	sdk := newPagedListingClient(t, map[string][][]onedrive.DriveItem{
+		"/Photos":      {{remoteFile("a.jpg", 1), remoteFolder("2024")}, {remoteFile("b.jpg", 1)}},
+		"/Photos/2024": {{remoteFile("c.jpg", 1)}, {}, {remoteFile("d.jpg", 1)}},
+	})
+	jobs, folders, err := collectFolderDownload(context.Background(), sdk, "/Photos", t.TempDir(), 0750)
+	require.NoError(t, err)
+
+	var remotePaths []string
+	for _, job := range jobs {
+		remotePaths = append(remotePaths, job.remotePath)
+	}
+	assert.ElementsMatch(t, []string{"/Photos/a.jpg", "/Photos/b.jpg", "/Photos/2024/c.jpg", "/Photos/2024/d.jpg"}, remotePaths)
+	assert.Equal(t, 1, folders)
+}
+
+func TestLocalDownloadPath(t *testing.T) {
+	root := t.TempDir()
+	got, err := localDownloadPath(root, filepath.Join(root, "sub"), "photo.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "sub", "photo.jpg"), got)
+
+	_, err = localDownloadPath(root, root, "..")
+	assert.ErrorIs(t, err, onedrive.ErrPathTraversal)
+}
+
+// rangeSDK serves content through ranged downloads. Each call to
+// GetDriveItemByPath hands out a new download URL; requests with an older URL
+// fail as expired, as does the next request when expire is set. Requests
+// starting at failAt or later fail outright.
+type rangeSDK struct {
+	MockSDK
+	content  []byte
+	cTag     string
+	urls     int
+	expire   bool
+	failAt   int64
+	requests []string
+}
+
+func newRangeSDK(content []byte) *rangeSDK {
+	r := &rangeSDK{content: content, cTag: "c1", failAt: -1}
+	r.GetDriveItemByPathFunc = func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+		r.urls++
+		return onedrive.DriveItem{
+			Name:        "video.mp4",
+			Size:        int64(len(r.content)),
+			CTag:        r.cTag,
+			DownloadURL: fmt.Sprintf("https://download.example/%d", r.urls),
+			File:        &onedrive.FileFacet{},
+		}, nil
+	}
+	r.DownloadFileChunkFunc = func(ctx context.Context, url string, startByte, endByte int64) (io.ReadCloser, error) {
+		r.requests = append(r.requests, fmt.Sprintf("%s %d-%d", url, startByte, endByte))
+		if r.expire || url != fmt.Sprintf("https://download.example/%d", r.urls) {
+			r.expire = false
+			return nil, onedrive.ErrDownloadURLExpired
+		}
+		if r.failAt >= 0 && startByte >= r.failAt {
+			return nil, errors.New("connection reset")
+		}
+		return io.NopCloser(bytes.NewReader(r.content[startByte : endByte+1])), nil
+	}
+	return r
+}
+
+func TestDownloadFileResumable(t *testing.T) {
+	content := make([]byte, downloadChunkSize+downloadChunkSize/2)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	dir := t.TempDir()
+	mgr := session.NewManagerWithConfigDir(t.TempDir())
+	ctx := context.Background()
+
+	t.Run("checkpoints progress and resumes with a refreshed URL", func(t *testing.T) {
+		local := filepath.Join(dir, "video.mp4")
+		sdk := newRangeSDK(content)
+		sdk.failAt = downloadChunkSize
+
+		err := downloadFileResumable(ctx, sdk, mgr, "/Videos/video.mp4", local, 0600, nil)
+		assert.ErrorContains(t, err, "connection reset")
+		state, err := mgr.Load(local, "/Videos/video.mp4")
+		require.NoError(t, err)
+		require.NotNil(t, state)
+		assert.Equal(t, int64(downloadChunkSize), state.CompletedBytes)
+		assert.Equal(t, "https://download.example/1", state.DownloadURL)
+		assert.NoFileExists(t, local)
+
+		// The URL fetched by the rerun expires before the first chunk and is
+		// refreshed. Either way only the missing range is fetched.
+		sdk.failAt = -1
+		sdk.expire = true
+		sdk.requests = nil
+		var lastProgress int64
+		err = downloadFileResumable(ctx, sdk, mgr, "/Videos/video.mp4", local, 0600, func(done, total int64) {
+			lastProgress = done
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			fmt.Sprintf("https://download.example/2 %d-%d", downloadChunkSize, len(content)-1),
+			fmt.Sprintf("https://download.example/3 %d-%d", downloadChunkSize, len(content)-1),
+		}, sdk.requests)
+		assert.Equal(t, int64(len(content)), lastProgress)
+
+		got, err := os.ReadFile(local)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(content, got), "downloaded content differs")
+		assert.NoFileExists(t, local+onedrive.PartialDownloadSuffix)
+		state, err = mgr.Load(local, "/Videos/video.mp4")
+		require.NoError(t, err)
+		assert.Nil(t, state)
+	})
+
+	t.Run("starts over when the remote file changed", func(t *testing.T) {
+		local := filepath.Join(dir, "changed.mp4")
+		require.NoError(t, os.WriteFile(local+onedrive.PartialDownloadSuffix, make([]byte, downloadChunkSize), 0600))
+		require.NoError(t, mgr.Save(&session.State{
+			DownloadURL:    "https://download.example/old",
+			LocalPath:      local,
+			RemotePath:     "/Videos/changed.mp4",
+			CompletedBytes: downloadChunkSize,
+			RemoteCTag:     "c0",
+		}))
+
+		sdk := newRangeSDK(content)
+		require.NoError(t, downloadFileResumable(ctx, sdk, mgr, "/Videos/changed.mp4", local, 0600, nil))
+		assert.Equal(t, fmt.Sprintf("https://download.example/1 0-%d", downloadChunkSize-1), sdk.requests[0])
+		got, err := os.ReadFile(local)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(content, got), "downloaded content differs")
+	})
+
+	t.Run("downloads small files in one request", func(t *testing.T) {
+		var downloaded string
+		sdk := &MockSDK{
+			GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+				return onedrive.DriveItem{Size: 10, DownloadURL: "https://download.example/small", File: &onedrive.FileFacet{}}, nil
+			},
+			DownloadFileFunc: func(ctx context.Context, remotePath, localPath string) error {
+				downloaded = remotePath
+				return os.WriteFile(localPath, []byte("0123456789"), 0600)
+			},
+		}
+		require.NoError(t, downloadFileResumable(ctx, sdk, mgr, "/small.txt", filepath.Join(dir, "small.txt"), 0600, nil))
+		assert.Equal(t, "/small.txt", downloaded)
+	})
+
+	t.Run("discards a download that does not match the metadata", func(t *testing.T) {
+		local := filepath.Join(dir, "corrupt.mp4")
+		sdk := newRangeSDK(content)
+		getItem := sdk.GetDriveItemByPathFunc
+		sdk.GetDriveItemByPathFunc = func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+			item, err := getItem(ctx, path)
+			item.File.Hashes = &onedrive.FileHashes{QuickXorHash: "AAAAAAAAAAAAAAAAAAAAAAAAAAA="}
+			return item, err
+		}
+
+		err := downloadFileResumable(ctx, sdk, mgr, "/Videos/corrupt.mp4", local, 0600, nil)
+		assert.ErrorIs(t, err, onedrive.ErrContentMismatch)
+		assert.NoFileExists(t, local)
+		assert.NoFileExists(t, local+onedrive.PartialDownloadSuffix)
+		state, err := mgr.Load(local, "/Videos/corrupt.mp4")
+		require.NoError(t, err)
+		assert.Nil(t, state)
+	})
+
+	t.Run("rejects folders", func(t *testing.T) {
+		sdk := &MockSDK{
+			GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+				return onedrive.DriveItem{Folder: &onedrive.FolderFacet{}}, nil
+			},
+		}
+		err := downloadFileResumable(ctx, sdk, mgr, "/Videos", filepath.Join(dir, "Videos"), 0600, nil)
+		assert.ErrorContains(t, err, "use --recursive")
+	})
+}
+
+func TestFilesDownloadLogicConnections(t *testing.T) {
+	newCmd := func(t *testing.T, flags map[string]string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().BoolP("recursive", "r", false, "")
+		cmd.Flags().Int("workers", defaultDownloadWorkers, "")
+		cmd.Flags().String("format", "", "")
+		cmd.Flags().Int("connections", 1, "")
+		for name, value := range flags {
+			require.NoError(t, cmd.Flags().Set(name, value))
+		}
+		cmd.SetContext(context.Background())
+		return cmd
+	}
+
+	t.Run("downloads over parallel connections", func(t *testing.T) {
+		var gotConnections int
+		sdk := &MockSDK{
+			DownloadFileParallelFunc: func(ctx context.Context, remotePath, localPath string, connections int, progress onedrive.ProgressFunc) error {
+				assert.Equal(t, "/Videos/talk.mp4", remotePath)
+				assert.Equal(t, "talk.mp4", localPath)
+				gotConnections = connections
+				return nil
+			},
+		}
+		cmd := newCmd(t, map[string]string{"connections": "8"})
+		require.NoError(t, filesDownloadLogic(&app.App{SDK: sdk}, cmd, []string{"/Videos/talk.mp4"}))
+		assert.Equal(t, 8, gotConnections)
+	})
+
+	t.Run("names the local file after an item given by ID", func(t *testing.T) {
+		sdk := &MockSDK{
+			GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+				assert.Equal(t, "id:01TALK", path)
+				return onedrive.DriveItem{ID: "01TALK", Name: "talk.mp4"}, nil
+			},
+			DownloadFileParallelFunc: func(ctx context.Context, remotePath, localPath string, connections int, progress onedrive.ProgressFunc) error {
+				assert.Equal(t, "id:01TALK", remotePath)
+				assert.Equal(t, "talk.mp4", localPath)
+				return nil
+			},
+		}
+		cmd := newCmd(t, map[string]string{"connections": "2"})
+		require.NoError(t, filesDownloadLogic(&app.App{SDK: sdk}, cmd, []string{"id:01TALK"}))
+	})
+
+	t.Run("wraps parallel download errors", func(t *testing.T) {
+		sdk := &MockSDK{
+			DownloadFileParallelFunc: func(ctx context.Context, remotePath, localPath string, connections int, progress onedrive.ProgressFunc) error {
+				return onedrive.ErrResourceNotFound
+			},
+		}
+		cmd := newCmd(t, map[string]string{"connections": "4"})
+		err := filesDownloadLogic(&app.App{SDK: sdk}, cmd, []string{"/Videos/talk.mp4", filepath.Join(t.TempDir(), "talk.mp4")})
+		assert.ErrorIs(t, err, onedrive.ErrResourceNotFound)
+	})
+
+	t.Run("rejects invalid combinations", func(t *testing.T) {
+		tests := []struct {
+			flags   map[string]string
+			wantErr string
+		}{
+			{map[string]string{"connections": "0"}, "--connections must be at least 1"},
+			{map[string]string{"connections": "4", "recursive": "true"}, "cannot be combined"},
+			{map[string]string{"connections": "4", "format": "pdf"}, "cannot be combined"},
+		}
+		for _, tt := range tests {
+			err := filesDownloadLogic(&app.App{SDK: &MockSDK{}}, newCmd(t, tt.flags), []string{"/Videos/talk.mp4"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		}
+	})
+}
@@ -5,6 +5,8 @@ package items
 
 import (
 	"strings"
+
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
 // joinRemotePath constructs a remote path for OneDrive by joining directory and file components.
@@ -25,7 +27,14 @@ import (
 //	joinRemotePath("/", "MyFile.txt")         -> "/MyFile.txt"
 //	joinRemotePath("", "MyFile.txt")          -> "/MyFile.txt"
 //	joinRemotePath("/Folder1/", "/SubFolder/file.doc") -> "/Folder1/SubFolder/file.doc"
+//	joinRemotePath("id:01ABC", "MyFile.txt")   -> "id:01ABC/MyFile.txt"
 func joinRemotePath(dir, file string) string {
+	// Item references ("id:<itemId>") are joined without a leading slash,
+	// which would turn them into an ordinary path.
+	if _, _, ok := onedrive.SplitItemIDPath(dir); ok {
+		return strings.TrimSuffix(dir, "/") + "/" + strings.TrimPrefix(file, "/")
+	}
+
 	// If the directory is the root, the path is just the file prefixed with a slash.
 	if dir == "" || dir == "/" {
 		return "/" + strings.TrimPrefix(file, "/")
@@ -60,3 +69,10 @@ func joinRemotePath(dir, file string) string {
 	}
 	return result
 }
+
+// isBareItemID reports whether remotePath names an item by its ID alone,
+// as "id:<itemId>", so that no name can be derived from it.
+func isBareItemID(remotePath string) bool {
+	_, rest, ok := onedrive.SplitItemIDPath(remotePath)
+	return ok && rest == ""
+}
@@ -216,23 +216,7 @@ func filesCopyStatusLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("checking copy status from URL '%s': %w", monitorURL, err)
 	}
 
-	fmt.Printf("Copy Operation Status (from %s):\n", monitorURL)
-	fmt.Printf("  Status:             %s\n", status.Status)
-	fmt.Printf("  Description:        %s\n", status.StatusDescription)
-	if status.PercentageComplete > 0 || status.Status == "inProgress" { // Show progress if available or in progress
-		fmt.Printf("  Progress:           %d%%\n", status.PercentageComplete)
-	}
-	if status.ResourceID != "" {
-		fmt.Printf("  New Item ID:        %s\n", status.ResourceID)
-	} else if status.ResourceLocation != "" {
-		fmt.Printf("  New Item Location:  %s (Note: This field is deprecated by Graph API)\n", status.ResourceLocation)
-	}
-	if status.Error != nil {
-		fmt.Printf("  Error Code:         %s\n", status.Error.Code)
-		fmt.Printf("  Error Message:      %s\n", status.Error.Message)
-	}
-
-	return nil
+	return ui.DisplayCopyStatus(status, monitorURL)
 }
 
 // filesMvLogic contains the core logic for the 'items mv' command.
@@ -145,8 +145,7 @@ func filesListLogic(a *app.App, cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("listing items in '%s': %w", path, err)
 	}
-	ui.DisplayItems(items)
-	return nil
+	return ui.DisplayItems(items)
 }
 
 // filesStatLogic contains the core logic for the 'items stat' command.
@@ -156,8 +155,7 @@ func filesStatLogic(a *app.App, cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("getting metadata for '%s': %w", path, err)
 	}
-	ui.DisplayDriveItem(item)
-	return nil
+	return ui.DisplayDriveItem(item)
 }
 
 // filesSearchLogic contains the core logic for the 'items search' command.
@@ -181,7 +179,11 @@ func filesSearchLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("searching for '%s' in folder '%s': %w", query, folderPath, err)
 	}
 
-	ui.DisplaySearchResults(items)
+	// The link to the next page is a field of structured output, and a hint otherwise.
+	items.NextLink = nextLink
+	if err := ui.DisplaySearchResults(items); err != nil {
+		return err
+	}
 	ui.HandleNextPageInfo(nextLink, paging.FetchAll)
 	return nil
 }
@@ -192,8 +194,7 @@ func filesVersionsLogic(a *app.App, cmd *cobra.Command, filePath string) error {
 	if err != nil {
 		return fmt.Errorf("listing versions for '%s': %w", filePath, err)
 	}
-	ui.DisplayFileVersions(versions, filePath)
-	return nil
+	return ui.DisplayFileVersions(versions, filePath)
 }
 
 // activitiesLogic contains the core logic for the 'items activities' command.
@@ -210,7 +211,11 @@ func activitiesLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("getting activities for '%s': %w", remotePath, err)
 	}
 
-	ui.DisplayActivities(activities)
+	// The link to the next page is a field of structured output, and a hint otherwise.
+	activities.NextLink = nextLink
+	if err := ui.DisplayActivities(activities); err != nil {
+		return err
+	}
 	ui.HandleNextPageInfo(nextLink, paging.FetchAll)
 	return nil
 }
@@ -227,8 +232,7 @@ func filesThumbnailsLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("getting thumbnails for '%s': %w", remotePath, err)
 	}
 
-	ui.DisplayThumbnails(thumbnails, remotePath)
-	return nil
+	return ui.DisplayThumbnails(thumbnails, remotePath)
 }
 
 // filesPreviewLogic contains the core logic for the 'items preview' command.
@@ -252,6 +256,5 @@ func filesPreviewLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("generating preview for '%s': %w", remotePath, err)
 	}
 
-	ui.DisplayPreview(preview, remotePath)
-	return nil
+	return ui.DisplayPreview(preview, remotePath)
 }
@@ -2,6 +2,7 @@ package items
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"testing"
 
@@ -26,6 +27,17 @@ type MockSDK struct {
 	UpdateDriveItemFunc      func(ctx context.Context, path, newName string) (onedrive.DriveItem, error)
 	MonitorCopyOperationFunc func(ctx context.Context, monitorURL string) (onedrive.CopyOperationStatus, error)
 
+	// Upload operations
+	CreateUploadSessionFunc    func(ctx context.Context, remotePath string) (onedrive.UploadSession, error)
+	UploadChunkFunc            func(ctx context.Context, uploadURL string, startByte, endByte, totalSize int64, chunkData io.Reader) (onedrive.UploadSession, error)
+	GetUploadSessionStatusFunc func(ctx context.Context, uploadURL string) (onedrive.UploadSession, error)
+	UploadFileFunc             func(ctx context.Context, localPath, remotePath string) (onedrive.DriveItem, error)
+
+	// Download operations
+	DownloadFileFunc         func(ctx context.Context, remotePath, localPath string) error
+	DownloadFileChunkFunc    func(ctx context.Context, url string, startByte, endByte int64) (io.ReadCloser, error)
+	DownloadFileParallelFunc func(ctx context.Context, remotePath, localPath string, connections int, progress onedrive.ProgressFunc) error
+
 	// Search operations
 	SearchDriveItemsInFolderFunc func(ctx context.Context, folderPath, query string, paging onedrive.Paging) (onedrive.DriveItemList, string, error)
 
@@ -147,6 +159,14 @@ func (m *MockSDK) GetThumbnails(ctx context.Context, remotePath string) (onedriv
 	return onedrive.ThumbnailSetList{}, nil
 }
 
+func (m *MockSDK) Raw(ctx context.Context, req onedrive.RawRequest) (onedrive.RawResponse, error) {
+	return onedrive.RawResponse{}, nil
+}
+
+func (m *MockSDK) RawAllPages(ctx context.Context, req onedrive.RawRequest) (json.RawMessage, error) {
+	return json.RawMessage(`{"value":[]}`), nil
+}
+
 func (m *MockSDK) PreviewItem(ctx context.Context, remotePath string, request onedrive.PreviewRequest) (onedrive.PreviewResponse, error) {
 	if m.PreviewItemFunc != nil {
 		return m.PreviewItemFunc(ctx, remotePath, request)
@@ -171,30 +191,60 @@ func (m *MockSDK) GetDriveActivities(ctx context.Context, paging onedrive.Paging
 	return onedrive.ActivityList{}, "", nil
 }
 func (m *MockSDK) GetMe(ctx context.Context) (onedrive.User, error) { return onedrive.User{}, nil }
+func (m *MockSDK) AccessToken(ctx context.Context) (*onedrive.Token, error) {
+	return &onedrive.Token{}, nil
+}
 func (m *MockSDK) CreateUploadSession(ctx context.Context, remotePath string) (onedrive.UploadSession, error) {
+	if m.CreateUploadSessionFunc != nil {
+		return m.CreateUploadSessionFunc(ctx, remotePath)
+	}
 	return onedrive.UploadSession{}, nil
 }
 
 func (m *MockSDK) UploadChunk(ctx context.Context, uploadURL string, startByte, endByte, totalSize int64, chunkData io.Reader) (onedrive.UploadSession, error) {
+	if m.UploadChunkFunc != nil {
+		return m.UploadChunkFunc(ctx, uploadURL, startByte, endByte, totalSize, chunkData)
+	}
 	return onedrive.UploadSession{}, nil
 }
 
 func (m *MockSDK) GetUploadSessionStatus(ctx context.Context, uploadURL string) (onedrive.UploadSession, error) {
+	if m.GetUploadSessionStatusFunc != nil {
+		return m.GetUploadSessionStatusFunc(ctx, uploadURL)
+	}
 	return onedrive.UploadSession{}, nil
 }
 func (m *MockSDK) CancelUploadSession(ctx context.Context, uploadURL string) error { return nil }
 func (m *MockSDK) UploadFile(ctx context.Context, localPath, remotePath string) (onedrive.DriveItem, error) {
+	if m.UploadFileFunc != nil {
+		return m.UploadFileFunc(ctx, localPath, remotePath)
+	}
 	return onedrive.DriveItem{}, nil
 }
-func (m *MockSDK) DownloadFile(ctx context.Context, remotePath, localPath string) error { return nil }
+func (m *MockSDK) DownloadFile(ctx context.Context, remotePath, localPath string) error {
+	if m.DownloadFileFunc != nil {
+		return m.DownloadFileFunc(ctx, remotePath, localPath)
+	}
+	return nil
+}
 func (m *MockSDK) DownloadFileAsFormat(ctx context.Context, remotePath, localPath, format string) error {
 	return nil
 }
 
 func (m *MockSDK) DownloadFileChunk(ctx context.Context, url string, startByte, endByte int64) (io.ReadCloser, error) {
+	if m.DownloadFileChunkFunc != nil {
+		return m.DownloadFileChunkFunc(ctx, url, startByte, endByte)
+	}
 	return nil, nil
 }
 
+func (m *MockSDK) DownloadFileParallel(ctx context.Context, remotePath, localPath string, connections int, progress onedrive.ProgressFunc) error {
+	if m.DownloadFileParallelFunc != nil {
+		return m.DownloadFileParallelFunc(ctx, remotePath, localPath, connections, progress)
+	}
+	return nil
+}
+
 func (m *MockSDK) SearchDriveItems(ctx context.Context, query string) (onedrive.DriveItemList, error) {
 	return onedrive.DriveItemList{}, nil
 }
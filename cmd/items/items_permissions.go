@@ -160,8 +160,7 @@ func filesShareLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("creating sharing link for '%s': %w", remotePath, err)
 	}
 
-	ui.DisplaySharingLink(link)
-	return nil
+	return ui.DisplaySharingLink(link)
 }
 
 // filesInviteLogic contains the core logic for the 'items invite' command.
@@ -207,8 +206,7 @@ func filesInviteLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("inviting users to '%s': %w", remotePath, err)
 	}
 
-	ui.DisplayInviteResponse(response, remotePath)
-	return nil
+	return ui.DisplayInviteResponse(response, remotePath)
 }
 
 // filesPermissionsListLogic contains the core logic for 'items permissions list'.
@@ -223,8 +221,7 @@ func filesPermissionsListLogic(a *app.App, cmd *cobra.Command, args []string) er
 		return fmt.Errorf("listing permissions for '%s': %w", remotePath, err)
 	}
 
-	ui.DisplayPermissions(permissions)
-	return nil
+	return ui.DisplayPermissions(permissions)
 }
 
 // filesPermissionsGetLogic contains the core logic for 'items permissions get'.
@@ -241,8 +238,7 @@ func filesPermissionsGetLogic(a *app.App, cmd *cobra.Command, args []string) err
 		return fmt.Errorf("getting permission ID '%s' for '%s': %w", permissionID, remotePath, err)
 	}
 
-	ui.DisplaySinglePermission(permission, remotePath, permissionID)
-	return nil
+	return ui.DisplaySinglePermission(permission, remotePath, permissionID)
 }
 
 // filesPermissionsUpdateLogic contains the core logic for 'items permissions update'.
@@ -286,7 +282,9 @@ func filesPermissionsUpdateLogic(a *app.App, cmd *cobra.Command, args []string)
 		return fmt.Errorf("updating permission ID '%s' for '%s': %w", permissionID, remotePath, err)
 	}
 
-	ui.DisplaySinglePermission(permission, remotePath, permissionID)
+	if err := ui.DisplaySinglePermission(permission, remotePath, permissionID); err != nil {
+		return err
+	}
 	ui.PrintSuccess("Permission '%s' on '%s' updated successfully.", permissionID, remotePath)
 	return nil
 }
@@ -18,7 +18,11 @@ var ItemsCmd = &cobra.Command{
 	Long: `Provides a comprehensive set of subcommands to interact with files and folders
 in your OneDrive. This includes listing, getting metadata (stat), creating folders (mkdir),
 uploading, downloading, deleting (rm), copying (cp), moving (mv), renaming, searching,
-managing sharing links and permissions, viewing versions, activities, thumbnails, and previews.`,
+managing sharing links and permissions, viewing versions, activities, thumbnails, and previews.
+
+Wherever a remote path is expected, an item can also be given by its ID as "id:<itemId>",
+optionally followed by a path relative to that item (e.g. "id:01ABC/Reports/2024.xlsx").
+IDs keep working when items are renamed or moved, and save a lookup on the server.`,
 	// Example: onedrive-client items list /Documents
 	// Example: onedrive-client items upload ./localfile.txt /Backup
 }
@@ -66,9 +70,19 @@ func InitItemsCommands(rootCmd *cobra.Command) {
 	// --wait: Blocks the command until the copy operation completes, rather than returning immediately.
 	filesCopyCmd.Flags().Bool("wait", false, "Wait for copy operation to complete instead of returning immediately")
 
+	// Flags for 'items upload':
+	// --workers: Number of files uploaded in parallel when the local path is a folder.
+	filesUploadCmd.Flags().Int("workers", defaultUploadWorkers, "Number of files to upload in parallel when uploading a folder")
+
 	// Flags for 'items download':
 	// --format: Allows specifying a format for downloading a file (e.g., "pdf" for a docx file).
 	filesDownloadCmd.Flags().String("format", "", "Download file in a specific format (e.g., pdf, jpg)")
+	// --recursive/-r: Downloads a remote folder and everything below it.
+	// --workers: Number of files downloaded in parallel with --recursive.
+	filesDownloadCmd.Flags().BoolP("recursive", "r", false, "Download a folder and all of its contents")
+	filesDownloadCmd.Flags().Int("workers", defaultDownloadWorkers, "Number of files to download in parallel with --recursive")
+	// --connections: Number of parallel ranged requests used to download a single file.
+	filesDownloadCmd.Flags().Int("connections", 1, "Number of parallel connections used to download a single file")
 
 	// Flags for 'items search':
 	// --in: Specifies the folder path to search within. This is mandatory for 'items search'.
@@ -100,6 +114,32 @@ func InitItemsCommands(rootCmd *cobra.Command) {
 	filesPermissionsUpdateCmd.Flags().String("expiration", "", "New expiration date/time in ISO 8601 format (e.g., 'YYYY-MM-DDTHH:MM:SSZ')")
 	filesPermissionsUpdateCmd.Flags().String("password", "", "Set or change the password for a link-based permission")
 
+	// Complete remote paths and permission IDs in the shell (see items_complete.go).
+	filesListCmd.ValidArgsFunction = completeArgs(argRemoteFolder)
+	filesStatCmd.ValidArgsFunction = completeArgs(argRemotePath)
+	filesMkdirCmd.ValidArgsFunction = completeArgs(argRemoteFolder)
+	filesUploadCmd.ValidArgsFunction = completeArgs(argLocalPath, argRemotePath)
+	filesDownloadCmd.ValidArgsFunction = completeArgs(argRemotePath, argLocalPath)
+	filesUploadSimpleCmd.ValidArgsFunction = completeArgs(argLocalPath, argRemotePath)
+	filesRmCmd.ValidArgsFunction = completeArgs(argRemotePath)
+	filesCopyCmd.ValidArgsFunction = completeArgs(argRemotePath, argRemoteFolder, argOther)
+	filesMvCmd.ValidArgsFunction = completeArgs(argRemotePath, argRemoteFolder)
+	filesRenameCmd.ValidArgsFunction = completeArgs(argRemotePath, argOther)
+	filesSearchCmd.ValidArgsFunction = completeArgs(argOther)
+	filesVersionsCmd.ValidArgsFunction = completeArgs(argRemotePath)
+	activitiesCmd.ValidArgsFunction = completeArgs(argRemotePath)
+	filesThumbnailsCmd.ValidArgsFunction = completeArgs(argRemotePath)
+	filesPreviewCmd.ValidArgsFunction = completeArgs(argRemotePath)
+	filesShareCmd.ValidArgsFunction = completeArgs(argRemotePath)
+	filesInviteCmd.ValidArgsFunction = completeArgs(argRemotePath)
+	filesPermissionsListCmd.ValidArgsFunction = completeArgs(argRemotePath)
+	filesPermissionsGetCmd.ValidArgsFunction = completeArgs(argRemotePath, argPermissionID)
+	filesPermissionsUpdateCmd.ValidArgsFunction = completeArgs(argRemotePath, argPermissionID)
+	filesPermissionsDeleteCmd.ValidArgsFunction = completeArgs(argRemotePath, argPermissionID)
+	if err := filesSearchCmd.RegisterFlagCompletionFunc("in", completeRemoteFolderFlag); err != nil {
+		panic(fmt.Sprintf("failed to register completion of '--in' for 'items search': %v", err))
+	}
+
 	// Add standard pagination flags (--top, --all, --next) to commands that support them.
 	// This is handled by a utility function for consistency.
 	ui.AddPagingFlags(activitiesCmd)  // For item activities
@@ -6,19 +6,19 @@
 package items
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath" // Used for path manipulation.
 	"syscall"
-	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tonimelisma/onedrive-client/internal/app"
 	"github.com/tonimelisma/onedrive-client/internal/session"
 	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/internal/upload"
 	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
@@ -40,20 +40,27 @@ Example: onedrive-client items mkdir /Documents/NewProject`,
 	},
 }
 
-// filesUploadCmd handles 'items upload <local-file> [remote-path]'.
-// It uploads a local file to OneDrive, using resumable upload sessions for large files.
+// filesUploadCmd handles 'items upload <local-path> [remote-path]'.
+// It uploads a local file or folder tree to OneDrive, using resumable upload sessions for large files.
 var filesUploadCmd = &cobra.Command{
-	Use:   "upload <local-file-path> [remote-destination-path]",
-	Short: "Upload a file to OneDrive (resumable for large files)",
+	Use:   "upload <local-path> [remote-destination-path]",
+	Short: "Upload a file or folder to OneDrive (resumable for large files)",
 	Long: `Uploads a local file to a specific folder in your OneDrive.
 If the remote destination path is a folder, the file is uploaded into that folder with its original name.
 If the remote destination path is omitted or is "/", the file is uploaded to the root of your OneDrive.
 This command automatically uses resumable upload sessions for files, making it suitable for large files
-and resilient to network interruptions. Progress is saved, and interrupted uploads can be resumed.`,
+and resilient to network interruptions. Progress is saved, and interrupted uploads can be resumed.
+Chunks are read ahead and sent back to back, their size adapts to the measured throughput, and a
+failed chunk is retried from the first byte the upload session still expects.
+
+If the local path is a folder, the whole tree is uploaded into a remote folder of the same name. Remote
+folders are created as needed, and files are uploaded in parallel by --workers workers: small files with
+a simple upload and larger files with resumable sessions. A summary of every file is printed at the end.`,
 	Example: `onedrive-client items upload ./report.docx /Documents
 onedrive-client items upload ./archive.zip /Backup/Archives
+onedrive-client items upload ./project /Code --workers 8
 onedrive-client items upload video.mp4`, // Uploads to root
-	Args: cobra.RangeArgs(1, 2), // Requires local file path, optionally a remote destination path.
+	Args: cobra.RangeArgs(1, 2), // Requires local path, optionally a remote destination path.
 	RunE: func(cmd *cobra.Command, args []string) error {
 		a, err := app.NewApp(cmd)
 		if err != nil {
@@ -122,6 +129,9 @@ func filesMkdirLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("remote folder path for 'mkdir' is required")
 	}
 	remotePath := args[0]
+	if isBareItemID(remotePath) {
+		return fmt.Errorf("'%s' names an existing item; give the new folder as 'id:<parentId>/<name>'", remotePath)
+	}
 
 	// Determine parent path and the name of the folder to be created.
 	// Example: if remotePath is "/Documents/NewFolder", parentPath is "/Documents", folderName is "NewFolder".
@@ -159,7 +169,8 @@ func filesUploadLogic(a *app.App, cmd *cobra.Command, args []string) error {
 	}
 
 	// Verify the local file exists before proceeding.
-	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+	info, err := os.Stat(localPath)
+	if os.IsNotExist(err) {
 		return fmt.Errorf("local file '%s' does not exist", localPath)
 	}
 
@@ -169,171 +180,71 @@ func filesUploadLogic(a *app.App, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("creating session manager for upload: %w", err)
 	}
 
+	// A directory is uploaded recursively into a remote folder of the same name.
+	if info != nil && info.IsDir() {
+		absPath, err := filepath.Abs(localPath)
+		if err != nil {
+			return fmt.Errorf("resolving local folder '%s': %w", localPath, err)
+		}
+		return filesUploadFolderLogic(a, cmd, mgr, absPath, joinRemotePath(remoteDestPath, filepath.Base(absPath)))
+	}
+
 	// Determine the final remote path for the file, including its name.
 	// e.g., if remoteDestPath is "/Documents" and local file is "report.docx",
 	// finalRemotePath becomes "/Documents/report.docx".
 	finalRemotePath := joinRemotePath(remoteDestPath, filepath.Base(localPath))
 
-	// Check if a resumable session already exists for this file combination.
-	state, err := mgr.Load(localPath, finalRemotePath)
-	if err != nil {
-		return fmt.Errorf("loading existing upload session state for '%s': %w", localPath, err)
-	}
-
-	if state != nil && state.UploadURL != "" {
-		// An existing session was found, attempt to resume.
-		log.Printf("Resuming upload for '%s' to '%s' from %d bytes.", localPath, finalRemotePath, state.CompletedBytes)
-		// Reconstruct the UploadSession object from the saved state.
-		uploadSession := onedrive.UploadSession{
-			UploadURL:          state.UploadURL,
-			ExpirationDateTime: state.ExpirationDateTime.Format(time.RFC3339), // Ensure correct format.
-			// NextExpectedRanges will be queried by GetUploadSessionStatus or implicitly handled by UploadChunk.
-		}
-		return uploadFileInChunks(a, cmd, mgr, localPath, finalRemotePath, uploadSession, state.CompletedBytes)
-	}
-	// No existing session, start a new upload.
-	log.Printf("Starting new upload for '%s' to '%s'.", localPath, finalRemotePath)
-	return startNewUpload(a, cmd, mgr, localPath, finalRemotePath)
-}
-
-// startNewUpload initiates a new resumable upload session.
-func startNewUpload(a *app.App, cmd *cobra.Command, mgr *session.Manager, localPath, remotePath string) error {
-	// Create a new upload session with the OneDrive API.
-	uploadSession, err := a.SDK.CreateUploadSession(cmd.Context(), remotePath)
-	if err != nil {
-		return fmt.Errorf("creating new upload session for '%s': %w", remotePath, err)
-	}
-	log.Printf("New upload session created for '%s'. Upload URL: %s", remotePath, uploadSession.UploadURL)
-
-	// Proceed to upload file in chunks using the new session, starting from byte 0.
-	return uploadFileInChunks(a, cmd, mgr, localPath, remotePath, uploadSession, 0)
+	return uploadFileResumable(a, cmd, mgr, localPath, finalRemotePath)
 }
 
-// uploadFileInChunks handles the chunked file upload process for a resumable session.
-// `startFromByte` indicates where to resume if this is a continued upload.
-func uploadFileInChunks(a *app.App, cmd *cobra.Command, mgr *session.Manager, localPath, remotePath string, uploadSession onedrive.UploadSession, startFromByte int64) error {
-	file, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("opening local file '%s' for chunked upload: %w", localPath, err)
-	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			log.Printf("Warning: Failed to close file: %v", closeErr)
-		}
-	}()
-
-	fileInfo, err := file.Stat()
+// uploadFileResumable uploads a file in chunks through a resumable session,
+// resuming a session saved by `mgr` for the same file, with a progress bar.
+// Interrupting the upload (Ctrl+C) saves its progress for the next run.
+func uploadFileResumable(a *app.App, cmd *cobra.Command, mgr *session.Manager, localPath, remotePath string) error {
+	info, err := os.Stat(localPath)
 	if err != nil {
 		return fmt.Errorf("getting file info for '%s': %w", localPath, err)
 	}
-	totalSize := fileInfo.Size()
 
-	// Initialize UI progress bar.
-	progressBar := ui.NewProgressBar(int(totalSize), "Uploading "+filepath.Base(localPath))
-	progressBar.Set(int(startFromByte)) // Set initial progress if resuming.
+	progressBar := ui.NewProgressBar(int(info.Size()), "Uploading "+filepath.Base(localPath))
 	defer func() {
 		if closeErr := progressBar.Close(); closeErr != nil {
 			log.Printf("Warning: Failed to close progress bar: %v", closeErr)
 		}
 	}()
 
-	// Define chunk size (e.g., 5MB). Must be a multiple of 320 KiB (327,680 bytes).
-	// Graph API recommends chunks between 5-10 MiB.
-	const chunkSize = 5 * onedrive.DefaultChunkSize // 1.6 MiB, for faster testing. Use larger for production.
-	currentByte := startFromByte
-
-	// Set up signal handling for graceful interruption (Ctrl+C).
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	defer signal.Stop(sigChan) // Clean up signal notification.
-
-	// Seek to the starting byte if resuming.
-	if currentByte > 0 {
-		if _, seekErr := file.Seek(currentByte, io.SeekStart); seekErr != nil {
-			return fmt.Errorf("seeking to resume position %d in '%s': %w", currentByte, localPath, seekErr)
-		}
+	parent := cmd.Context()
+	if parent == nil {
+		parent = context.Background()
 	}
+	ctx, stop := signal.NotifyContext(parent, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	for currentByte < totalSize {
-		select {
-		case <-sigChan: // Handle interruption signal.
-			log.Println("\nUpload interrupted by user. Saving session state for resumption...")
-			// Save current progress before exiting.
-			expirationTime, _ := time.Parse(time.RFC3339, uploadSession.ExpirationDateTime)
-			state := &session.State{
-				UploadURL:          uploadSession.UploadURL,
-				ExpirationDateTime: expirationTime,
-				LocalPath:          localPath,
-				RemotePath:         remotePath,
-				CompletedBytes:     currentByte,
-			}
-			if saveErr := mgr.Save(state); saveErr != nil {
-				log.Printf("Error saving session state on interruption: %v", saveErr)
-			} else {
-				log.Println("Session state saved.")
-			}
-			return fmt.Errorf("upload interrupted") // Return an error to stop processing.
-		default:
-			// Continue with upload if no signal.
-		}
-
-		// Determine the end byte for the current chunk.
-		endByte := currentByte + chunkSize - 1
-		if endByte >= totalSize {
-			endByte = totalSize - 1 // This is the last chunk.
-		}
-
-		// Read the chunk data from the file.
-		// Use io.LimitReader to ensure we only read up to the chunk size.
-		chunkReader := io.LimitReader(file, endByte-currentByte+1)
-
-		// Upload the current chunk.
-		// The SDK's UploadChunk will handle reading from chunkReader.
-		result, errSdk := a.SDK.UploadChunk(cmd.Context(), uploadSession.UploadURL, currentByte, endByte, totalSize, chunkReader)
-		if errSdk != nil {
-			// On error, save session state for potential resumption.
-			log.Printf("\nError uploading chunk for '%s' (range %d-%d). Saving session state...", localPath, currentByte, endByte)
-			expirationTime, _ := time.Parse(time.RFC3339, uploadSession.ExpirationDateTime)
-			state := &session.State{
-				UploadURL:          uploadSession.UploadURL,
-				ExpirationDateTime: expirationTime,
-				LocalPath:          localPath,
-				RemotePath:         remotePath,
-				CompletedBytes:     currentByte, // Save progress up to the start of the failed chunk.
-			}
-			if saveErr := mgr.Save(state); saveErr != nil {
-				log.Printf("Error saving session state after chunk upload error: %v", saveErr)
-			} else {
-				log.Println("Session state saved for resumption.")
-			}
-			return fmt.Errorf("uploading chunk (bytes %d-%d) for '%s': %w", currentByte, endByte, localPath, errSdk)
-		}
-
-		// Update progress.
-		currentByte = endByte + 1
-		progressBar.Set64(currentByte)
-
-		// The OneDrive API might return a new UploadSession object with updated details (e.g., new expiry).
-		// Or, if this was the final chunk, it might return the DriveItem metadata of the completed file.
-		if result.UploadURL != "" { // If UploadURL is still present, it's likely an intermediate response.
-			uploadSession = result
-		} else if currentByte >= totalSize { // If all bytes are uploaded.
-			// This was the last chunk, and the response might be the DriveItem itself.
-			// The SDK's UploadChunk should ideally parse this correctly if it's a DriveItem.
-			// For now, we assume completion if no error and currentByte >= totalSize.
-			log.Printf("\nFinal chunk for '%s' uploaded.", localPath)
-			break // Exit loop as upload is complete.
+	log.Printf("Uploading '%s' to '%s'.", localPath, remotePath)
+	item, err := upload.Resumable(ctx, a.SDK, mgr, localPath, remotePath, func(completed int64) { _ = progressBar.Set64(completed) })
+	if err != nil {
+		if ctx.Err() != nil && parent.Err() == nil {
+			log.Println("\nUpload interrupted by user; running the command again resumes it.")
+			return fmt.Errorf("upload interrupted")
 		}
+		return err
 	}
-
-	// Upload completed successfully. Clean up the session file.
-	if err := mgr.Delete(localPath, remotePath); err != nil {
-		log.Printf("Warning: failed to delete session file for completed upload '%s': %v", localPath, err)
+	if err := verifyUpload(localPath, remotePath, item); err != nil {
+		return err
 	}
 	log.Printf("\nFile '%s' uploaded successfully to '%s'.", localPath, remotePath)
 	return nil
 }
 
+// verifyUpload checks the local file against the size and content hash the
+// service reports for the item it was uploaded as.
+func verifyUpload(localPath, remotePath string, item onedrive.DriveItem) error {
+	if err := onedrive.VerifyFile(localPath, item); err != nil {
+		return fmt.Errorf("verifying upload of '%s' to '%s': %w", localPath, remotePath, err)
+	}
+	return nil
+}
+
 // filesCancelUploadLogic contains the core logic for 'items cancel-upload'.
 func filesCancelUploadLogic(a *app.App, cmd *cobra.Command, args []string) error {
 	if len(args) == 0 { // Should be caught by Args validation.
@@ -367,20 +278,7 @@ func filesGetUploadStatusLogic(a *app.App, cmd *cobra.Command, args []string) er
 		return fmt.Errorf("getting status for upload session '%s': %w", uploadURL, err)
 	}
 
-	// Display the upload session status information.
-	fmt.Println("Upload Session Status:")
-	fmt.Printf("  Upload URL:          %s\n", status.UploadURL)
-	fmt.Printf("  Expiration DateTime: %s\n", status.ExpirationDateTime)
-	if len(status.NextExpectedRanges) > 0 {
-		fmt.Println("  Next Expected Ranges (start-end, inclusive):")
-		for _, r := range status.NextExpectedRanges {
-			fmt.Printf("    %s\n", r)
-		}
-	} else {
-		// If NextExpectedRanges is empty, it usually means the upload is complete or the session is invalid/expired.
-		fmt.Println("  Status: Upload appears to be complete, or session is no longer active for new ranges.")
-	}
-	return nil
+	return ui.DisplayUploadSession(status)
 }
 
 // filesUploadSimpleLogic contains the core logic for 'items upload-simple'.
@@ -400,6 +298,9 @@ func filesUploadSimpleLogic(a *app.App, cmd *cobra.Command, args []string) error
 	if err != nil {
 		return fmt.Errorf("simple upload of '%s' to '%s' failed: %w", localPath, remotePath, err)
 	}
+	if err := onedrive.VerifyFile(localPath, item); err != nil {
+		return fmt.Errorf("verifying upload of '%s' to '%s': %w", localPath, remotePath, err)
+	}
 	log.Printf("File '%s' uploaded successfully to '%s' using simple upload. Item ID: %s, Size: %d bytes", localPath, remotePath, item.ID, item.Size)
 	return nil
 }
@@ -0,0 +1,302 @@
+// Package items (items_upload_folder.go) implements recursive folder uploads for
+// 'items upload'. The local tree is mirrored by creating the remote folders first
+// and then uploading the files concurrently with a pool of workers.
+package items
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/internal/upload"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// defaultUploadWorkers is the number of files uploaded in parallel by a folder upload.
+const defaultUploadWorkers = 4
+
+// Upload methods reported in the folder upload summary.
+const (
+	uploadMethodSimple    = "simple"
+	uploadMethodResumable = "resumable"
+)
+
+// folderUploadJob is a single file queued by a folder upload.
+type folderUploadJob struct {
+	localPath  string
+	remotePath string
+	size       int64
+}
+
+// folderUploadResult is the outcome of uploading one file of a folder upload.
+type folderUploadResult struct {
+	folderUploadJob
+	method  string
+	elapsed time.Duration
+	err     error
+}
+
+// filesUploadFolderLogic uploads the directory tree at localDir into remoteDir,
+// which is created if needed. Folders are created parents first, then files are
+// uploaded by a pool of workers: small files with a simple upload, larger ones
+// with a resumable session whose state is kept by `mgr`. A per-file and aggregate
+// summary is printed, and an error is returned if any file failed.
+func filesUploadFolderLogic(a *app.App, cmd *cobra.Command, mgr *session.Manager, localDir, remoteDir string) error {
+	workers, err := cmd.Flags().GetInt("workers")
+	if err != nil {
+		return fmt.Errorf("parsing '--workers' flag: %w", err)
+	}
+	if workers < 1 {
+		return fmt.Errorf("--workers must be at least 1, got %d", workers)
+	}
+
+	folders, jobs, err := collectFolderUpload(localDir, remoteDir)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// Interrupting stops new files from starting; resumable uploads in flight
+	// save their session state so that rerunning the command resumes them.
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	created := 0
+	for _, folder := range folders {
+		wasCreated, err := ensureRemoteFolder(ctx, a.SDK, folder)
+		if err != nil {
+			return err
+		}
+		if wasCreated {
+			created++
+		}
+	}
+
+	log.Printf("Uploading %d file(s) from '%s' to '%s' with %d worker(s).", len(jobs), localDir, remoteDir, workers)
+	start := time.Now()
+	results := uploadFolderFiles(ctx, a.SDK, mgr, jobs, workers)
+	return displayFolderUploadSummary(results, created, remoteDir, time.Since(start), workers)
+}
+
+// collectFolderUpload walks localDir and returns the remote folders to create,
+// in parent-first order and starting with remoteDir itself, and the files to
+// upload. Anything that is neither a regular file nor a directory is skipped.
+func collectFolderUpload(localDir, remoteDir string) ([]string, []folderUploadJob, error) {
+	folders := []string{remoteDir}
+	var jobs []folderUploadJob
+	err := filepath.WalkDir(localDir, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if p == localDir {
+			return nil
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		remote := joinRemotePath(remoteDir, filepath.ToSlash(rel))
+		switch {
+		case d.IsDir():
+			folders = append(folders, remote)
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, folderUploadJob{localPath: p, remotePath: remote, size: info.Size()})
+		default:
+			log.Printf("Skipping '%s': not a regular file.", p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading local folder '%s': %w", localDir, err)
+	}
+	return folders, jobs, nil
+}
+
+// ensureRemoteFolder creates the remote folder at remotePath unless it already
+// exists, reporting whether it was created. Its parent must already exist.
+func ensureRemoteFolder(ctx context.Context, sdk app.SDK, remotePath string) (bool, error) {
+	item, err := sdk.GetDriveItemByPath(ctx, remotePath)
+	if err == nil {
+		if item.Folder == nil {
+			return false, fmt.Errorf("remote path '%s' exists and is not a folder", remotePath)
+		}
+		return false, nil
+	}
+	if !errors.Is(err, onedrive.ErrResourceNotFound) || isBareItemID(remotePath) {
+		return false, fmt.Errorf("checking remote folder '%s': %w", remotePath, err)
+	}
+
+	parentPath, folderName := splitRemotePath(remotePath)
+	if _, err := sdk.CreateFolder(ctx, parentPath, folderName); err != nil {
+		return false, fmt.Errorf("creating folder '%s' in '%s': %w", folderName, parentPath, err)
+	}
+	return true, nil
+}
+
+// splitRemotePath splits a remote path into its parent folder and final
+// element, using "/" for the root.
+func splitRemotePath(remotePath string) (string, string) {
+	trimmed := strings.TrimSuffix(remotePath, "/")
+	i := strings.LastIndex(trimmed, "/")
+	if i <= 0 {
+		return "/", strings.TrimPrefix(trimmed, "/")
+	}
+	return trimmed[:i], trimmed[i+1:]
+}
+
+// uploadFolderFiles uploads jobs with the given number of concurrent workers
+// and returns one result per job, in the order of jobs. Jobs not started
+// before ctx is cancelled fail with the context's error.
+func uploadFolderFiles(ctx context.Context, sdk app.SDK, mgr *session.Manager, jobs []folderUploadJob, workers int) []folderUploadResult {
+	results := make([]folderUploadResult, len(jobs))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = uploadFolderFile(ctx, sdk, mgr, jobs[i])
+			}
+		}()
+	}
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// uploadFolderFile uploads a single file of a folder upload, choosing a simple
+// upload up to onedrive.LargeFileThreshold and a resumable session above it,
+// and verifies the result against the size and hash the service reports.
+func uploadFolderFile(ctx context.Context, sdk app.SDK, mgr *session.Manager, job folderUploadJob) folderUploadResult {
+	result := folderUploadResult{folderUploadJob: job, method: uploadMethodSimple}
+	if err := ctx.Err(); err != nil {
+		result.err = err
+		return result
+	}
+
+	start := time.Now()
+	if job.size <= onedrive.LargeFileThreshold {
+		item, err := sdk.UploadFile(ctx, job.localPath, job.remotePath)
+		if err != nil {
+			result.err = fmt.Errorf("simple upload of '%s' failed: %w", job.localPath, err)
+		} else if err := onedrive.VerifyFile(job.localPath, item); err != nil {
+			result.err = fmt.Errorf("verifying upload of '%s': %w", job.localPath, err)
+		}
+	} else {
+		result.method = uploadMethodResumable
+		result.err = uploadFolderFileResumable(ctx, sdk, mgr, job)
+	}
+	result.elapsed = time.Since(start)
+	return result
+}
+
+// uploadFolderFileResumable uploads a large file in chunks through a resumable
+// session, resuming from state saved by `mgr` when there is any. On failure or
+// interruption the progress is saved so that the next run picks up from there.
+func uploadFolderFileResumable(ctx context.Context, sdk app.SDK, mgr *session.Manager, job folderUploadJob) error {
+	item, err := upload.Resumable(ctx, sdk, mgr, job.localPath, job.remotePath, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("upload of '%s' interrupted: %w", job.localPath, err)
+		}
+		return err
+	}
+	return verifyUpload(job.localPath, job.remotePath, item)
+}
+
+// folderUploadColumns are the default CSV columns of a folder upload summary.
+var folderUploadColumns = []string{"remotePath", "size", "method", "elapsedMs", "error"}
+
+// folderUploadFile is the structured form of a folderUploadResult.
+type folderUploadFile struct {
+	LocalPath  string `json:"localPath"`
+	RemotePath string `json:"remotePath"`
+	Size       int64  `json:"size"`
+	Method     string `json:"method,omitempty"`
+	ElapsedMs  int64  `json:"elapsedMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// folderUploadSummary is the structured form of displayFolderUploadSummary,
+// with the uploaded files as the "value" list.
+type folderUploadSummary struct {
+	RemoteDir      string             `json:"remoteDir"`
+	Uploaded       int                `json:"uploaded"`
+	Failed         int                `json:"failed"`
+	Bytes          int64              `json:"bytes"`
+	FoldersCreated int                `json:"foldersCreated"`
+	Workers        int                `json:"workers"`
+	ElapsedMs      int64              `json:"elapsedMs"`
+	Value          []folderUploadFile `json:"value"`
+}
+
+// displayFolderUploadSummary prints one line per uploaded file, sorted by remote
+// path, followed by the totals. It returns an error if any file failed.
+func displayFolderUploadSummary(results []folderUploadResult, created int, remoteDir string, elapsed time.Duration, workers int) error {
+	sorted := append([]folderUploadResult(nil), results...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].remotePath < sorted[j].remotePath })
+
+	summary := folderUploadSummary{
+		RemoteDir:      remoteDir,
+		FoldersCreated: created,
+		Workers:        workers,
+		ElapsedMs:      elapsed.Milliseconds(),
+		Value:          make([]folderUploadFile, 0, len(sorted)),
+	}
+	for _, r := range sorted {
+		file := folderUploadFile{LocalPath: r.localPath, RemotePath: r.remotePath, Size: r.size, Method: r.method, ElapsedMs: r.elapsed.Milliseconds()}
+		if r.err != nil {
+			summary.Failed++
+			file.Error = r.err.Error()
+		} else {
+			summary.Uploaded++
+			summary.Bytes += r.size
+		}
+		summary.Value = append(summary.Value, file)
+	}
+
+	err := ui.Render(os.Stdout, ui.Result{Data: summary, Columns: folderUploadColumns, Table: func(w io.Writer) {
+		for _, r := range sorted {
+			if r.err != nil {
+				fmt.Fprintf(w, "  FAILED    %s: %v\n", r.remotePath, r.err)
+				continue
+			}
+			fmt.Fprintf(w, "  uploaded  %s (%d bytes, %s, %s)\n", r.remotePath, r.size, r.method, r.elapsed.Round(time.Millisecond))
+		}
+		fmt.Fprintf(w, "Uploaded %d of %d file(s) (%d bytes) to '%s' in %s with %d worker(s); %d folder(s) created, %d failed.\n",
+			summary.Uploaded, len(sorted), summary.Bytes, remoteDir, elapsed.Round(time.Millisecond), workers, created, summary.Failed)
+	}})
+	if err != nil {
+		return err
+	}
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed to upload", summary.Failed, len(sorted))
+	}
+	return nil
+}
@@ -2,11 +2,19 @@ package items
 
 import (
 	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/session"
 	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
@@ -26,11 +34,21 @@ func TestFilesMkdirLogic(t *testing.T) {
 			args:    []string{"/parent", "child"},
 			wantErr: false,
 		},
+		{
+			name:    "create folder in a parent given by ID",
+			args:    []string{"id:01PARENT/child"},
+			wantErr: false,
+		},
 		{
 			name:    "empty arguments",
 			args:    []string{},
 			wantErr: true,
 		},
+		{
+			name:    "bare item ID names no new folder",
+			args:    []string{"id:01PARENT"},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -77,6 +95,12 @@ func TestJoinRemotePathHelper(t *testing.T) {
 			arg2:     "file.txt",
 			expected: "/folder/file.txt",
 		},
+		{
+			name:     "item reference",
+			arg1:     "id:01ABC/",
+			arg2:     "file.txt",
+			expected: "id:01ABC/file.txt",
+		},
 	}
 
 	for _, tt := range tests {
@@ -86,3 +110,177 @@ func TestJoinRemotePathHelper(t *testing.T) {
 		})
 	}
 }
+
+// firstUploadChunk is the size of the first chunk of a resumable upload, before
+// the chunk size adapts to the throughput (see internal/upload).
+const firstUploadChunk = 5 * onedrive.DefaultChunkSize
+
+// folderUploadSDK returns a MockSDK for folder upload tests that records every
+// call under a mutex, as files are uploaded concurrently. Only "/Code" exists
+// remotely, and chunk uploads fail from failChunkAt onwards when it is non-negative.
+// Uploaded items are reported without a file facet, which skips verification.
+func folderUploadSDK(mu *sync.Mutex, calls *[]string, chunked *int64, failChunkAt int64) *MockSDK {
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		*calls = append(*calls, call)
+	}
+	return &MockSDK{
+		GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+			if path == "/Code" {
+				return onedrive.DriveItem{Name: "Code", Folder: &onedrive.FolderFacet{}}, nil
+			}
+			if strings.HasSuffix(path, ".bin") {
+				return onedrive.DriveItem{Name: "big.bin"}, nil
+			}
+			return onedrive.DriveItem{}, onedrive.ErrResourceNotFound
+		},
+		CreateFolderFunc: func(ctx context.Context, parentPath, folderName string) (onedrive.DriveItem, error) {
+			record("mkdir " + parentPath + " " + folderName)
+			return onedrive.DriveItem{Name: folderName}, nil
+		},
+		UploadFileFunc: func(ctx context.Context, localPath, remotePath string) (onedrive.DriveItem, error) {
+			if strings.HasSuffix(remotePath, "broken.txt") {
+				return onedrive.DriveItem{}, errors.New("boom")
+			}
+			record("upload " + remotePath)
+			return onedrive.DriveItem{}, nil
+		},
+		CreateUploadSessionFunc: func(ctx context.Context, remotePath string) (onedrive.UploadSession, error) {
+			record("session " + remotePath)
+			return onedrive.UploadSession{UploadURL: "https://upload.example/session"}, nil
+		},
+		UploadChunkFunc: func(ctx context.Context, uploadURL string, startByte, endByte, totalSize int64, chunkData io.Reader) (onedrive.UploadSession, error) {
+			if failChunkAt >= 0 && startByte >= failChunkAt {
+				return onedrive.UploadSession{}, errors.New("connection reset")
+			}
+			n, err := io.Copy(io.Discard, chunkData)
+			if err != nil {
+				return onedrive.UploadSession{}, err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			*chunked += n
+			return onedrive.UploadSession{UploadURL: uploadURL}, nil
+		},
+	}
+}
+
+func TestFilesUploadFolderLogic(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("ONEDRIVE_CONFIG_PATH", filepath.Join(configDir, "config.json"))
+
+	root := filepath.Join(t.TempDir(), "project")
+	bigSize := int64(firstUploadChunk + 1) // Above LargeFileThreshold and two chunks long.
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub", "empty"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("bb"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "big.bin"), make([]byte, bigSize), 0600))
+
+	newCmd := func(workers string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().Int("workers", defaultUploadWorkers, "")
+		require.NoError(t, cmd.Flags().Set("workers", workers))
+		cmd.SetContext(context.Background())
+		return cmd
+	}
+
+	t.Run("uploads the tree into a new remote folder", func(t *testing.T) {
+		var mu sync.Mutex
+		var calls []string
+		var chunked int64
+		a := &app.App{SDK: folderUploadSDK(&mu, &calls, &chunked, -1)}
+
+		require.NoError(t, filesUploadLogic(a, newCmd("3"), []string{root, "/Code"}))
+		assert.ElementsMatch(t, []string{
+			"mkdir /Code project",
+			"mkdir /Code/project sub",
+			"mkdir /Code/project/sub empty",
+			"upload /Code/project/a.txt",
+			"upload /Code/project/sub/b.txt",
+			"session /Code/project/sub/big.bin",
+		}, calls)
+		// Folders are created parents first, before any file is uploaded.
+		assert.Equal(t, []string{"mkdir /Code project", "mkdir /Code/project sub", "mkdir /Code/project/sub empty"}, calls[:3])
+		assert.Equal(t, bigSize, chunked)
+	})
+
+	t.Run("saves progress of a failed resumable upload and resumes it", func(t *testing.T) {
+		var mu sync.Mutex
+		var calls []string
+		var chunked int64
+		failAt := int64(firstUploadChunk)
+		a := &app.App{SDK: folderUploadSDK(&mu, &calls, &chunked, failAt)}
+
+		err := filesUploadLogic(a, newCmd("2"), []string{root, "/Code"})
+		assert.ErrorContains(t, err, "1 of 3 file(s) failed to upload")
+
+		mgr := session.NewManagerWithConfigDir(configDir)
+		bigPath := filepath.Join(root, "sub", "big.bin")
+		state, err := mgr.Load(bigPath, "/Code/project/sub/big.bin")
+		require.NoError(t, err)
+		require.NotNil(t, state)
+		assert.Equal(t, failAt, state.CompletedBytes)
+
+		// The second run continues from the saved offset without a new session.
+		calls = nil
+		chunked = 0
+		a = &app.App{SDK: folderUploadSDK(&mu, &calls, &chunked, -1)}
+		require.NoError(t, filesUploadLogic(a, newCmd("2"), []string{root, "/Code"}))
+		assert.NotContains(t, calls, "session /Code/project/sub/big.bin")
+		assert.Equal(t, bigSize-failAt, chunked)
+
+		state, err = mgr.Load(bigPath, "/Code/project/sub/big.bin")
+		require.NoError(t, err)
+		assert.Nil(t, state)
+	})
+
+	t.Run("reports failed files", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "broken.txt"), []byte("x"), 0600))
+		defer os.Remove(filepath.Join(root, "broken.txt"))
+
+		var mu sync.Mutex
+		var calls []string
+		var chunked int64
+		a := &app.App{SDK: folderUploadSDK(&mu, &calls, &chunked, -1)}
+		err := filesUploadLogic(a, newCmd("4"), []string{root, "/Code"})
+		assert.ErrorContains(t, err, "1 of 4 file(s) failed to upload")
+		assert.Contains(t, calls, "upload /Code/project/a.txt")
+	})
+
+	t.Run("rejects fewer than one worker", func(t *testing.T) {
+		a := &app.App{SDK: &MockSDK{}}
+		err := filesUploadLogic(a, newCmd("0"), []string{root, "/Code"})
+		assert.ErrorContains(t, err, "--workers must be at least 1")
+	})
+}
+
+func TestSplitRemotePath(t *testing.T) {
+	tests := []struct {
+		path, parent, name string
+	}{
+		{"/project", "/", "project"},
+		{"/Code/project", "/Code", "project"},
+		{"/Code/project/", "/Code", "project"},
+	}
+	for _, tt := range tests {
+		parent, name := splitRemotePath(tt.path)
+		assert.Equal(t, tt.parent, parent, tt.path)
+		assert.Equal(t, tt.name, name, tt.path)
+	}
+}
+
+func TestVerifyUpload(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "report.txt")
+	require.NoError(t, os.WriteFile(localPath, []byte("The quick brown fox jumps over the lazy dog"), 0600))
+	uploaded := onedrive.DriveItem{Name: "report.txt", Size: 43, File: &onedrive.FileFacet{
+		Hashes: &onedrive.FileHashes{QuickXorHash: "bMSlbysmxJL6S75XwfMcQZOpcr4="},
+	}}
+
+	require.NoError(t, verifyUpload(localPath, "/report.txt", uploaded))
+
+	truncated := uploaded
+	truncated.Size = 40
+	err := verifyUpload(localPath, "/report.txt", truncated)
+	assert.ErrorIs(t, err, onedrive.ErrContentMismatch)
+}
@@ -0,0 +1,192 @@
+// Package cmd (profile.go) defines the Cobra commands for managing named
+// configuration profiles. Each profile has its own token, settings and
+// session directory, so that several accounts can be used side by side; the
+// global --profile flag selects one for a single command.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// profileCmd represents the base 'profile' command.
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage configuration profiles for multiple accounts",
+	Long: `Provides commands to list, switch between and delete configuration profiles.
+Each profile has its own login, settings and session state. Create a profile by
+logging in to it with 'auth login --profile <name>', and use it for a single
+command with the global --profile flag, or for all commands with 'profile use'.`,
+}
+
+// profileListCmd handles 'profile list'.
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configuration profiles",
+	Long:  `Lists all configuration profiles and whether each is logged in. The active profile is marked with '*'. No network requests are made; use 'auth status --all' to check the accounts behind the profiles.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return profileListLogic(cmd)
+	},
+}
+
+// profileUseCmd handles 'profile use <name>'.
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make a profile the current one",
+	Long:  `Makes the named profile the one used by all commands that are not given the --profile flag.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return profileUseLogic(args[0])
+	},
+}
+
+// profileDeleteCmd handles 'profile delete <name>'.
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a profile, its login and its session state",
+	Long: `Deletes the named profile from the configuration, together with its token,
+pending uploads, downloads and sync state. If it is the current profile, the
+default profile becomes current. The default profile cannot be deleted; use
+'auth logout' to clear its login.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return profileDeleteLogic(args[0])
+	},
+}
+
+// profileStatusColumns are the default CSV columns of a profileStatusList.
+var profileStatusColumns = []string{"profile", "current", "status"}
+
+// profileStatus is the status of one profile in 'profile list' and
+// 'auth status --all'.
+type profileStatus struct {
+	Profile string `json:"profile"`
+	Current bool   `json:"current"` // Whether it is the active profile.
+	Status  string `json:"status"`
+}
+
+// profileStatusList is the result of 'profile list' and 'auth status --all',
+// with the profiles as the "value" list.
+type profileStatusList struct {
+	Value []profileStatus `json:"value"`
+}
+
+// profileListLogic prints every profile with its login status, marking the
+// active one.
+func profileListLogic(cmd *cobra.Command) error {
+	cfg, err := config.LoadOrCreate()
+	if err != nil {
+		return fmt.Errorf("loading configuration for 'profile list': %w", err)
+	}
+
+	var list profileStatusList
+	for _, name := range cfg.ProfileNames() {
+		status, err := profileLoginStatus(name)
+		if err != nil {
+			return err
+		}
+		list.Value = append(list.Value, profileStatus{Profile: name, Current: name == cfg.Profile(), Status: status})
+	}
+	return ui.Render(cmd.OutOrStdout(), ui.Result{Data: list, Columns: profileStatusColumns, Table: func(w io.Writer) {
+		fmt.Fprintf(w, "%-2s%-30s %s\n", "", "Profile", "Status")
+		fmt.Fprintln(w, strings.Repeat("-", onedrive.StandardSeparatorLength))
+		for _, p := range list.Value {
+			marker := ""
+			if p.Current {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%-2s%-30s %s\n", marker, p.Profile, p.Status)
+		}
+	}})
+}
+
+// profileLoginStatus reports whether the profile called `name` holds a token
+// or has a login pending, from local state only.
+func profileLoginStatus(name string) (string, error) {
+	cfg, err := config.LoadProfileOrCreate(name)
+	if err != nil {
+		return "", fmt.Errorf("loading profile '%s': %w", name, err)
+	}
+	if cfg.TokenEncrypted() {
+		return "logged in (token encrypted)", nil
+	}
+	if cfg.HasToken() {
+		return "logged in", nil
+	}
+	mgr, err := session.NewManagerForProfile(name)
+	if err != nil {
+		return "", fmt.Errorf("creating session manager for profile '%s': %w", name, err)
+	}
+	pending, err := mgr.LoadAuthState()
+	if err != nil {
+		return "", fmt.Errorf("checking profile '%s' for a pending login: %w", name, err)
+	}
+	if pending != nil {
+		return "login pending", nil
+	}
+	return "not logged in", nil
+}
+
+// profileUseLogic records `name` as the current profile.
+func profileUseLogic(name string) error {
+	if err := config.ValidateProfileName(name); err != nil {
+		return err
+	}
+	cfg, err := config.LoadOrCreate()
+	if err != nil {
+		return fmt.Errorf("loading configuration for 'profile use': %w", err)
+	}
+	if err := cfg.SetCurrentProfile(name); err != nil {
+		return fmt.Errorf("%w; create it with 'onedrive-client auth login --profile %s'", err, name)
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("saving configuration for 'profile use': %w", err)
+	}
+	ui.Success(fmt.Sprintf("Now using profile '%s'.", name))
+	return nil
+}
+
+// profileDeleteLogic removes the profile called `name` from the configuration
+// and deletes its session directory.
+func profileDeleteLogic(name string) error {
+	if err := config.ValidateProfileName(name); err != nil {
+		return err
+	}
+	cfg, err := config.LoadOrCreate()
+	if err != nil {
+		return fmt.Errorf("loading configuration for 'profile delete': %w", err)
+	}
+	if err := cfg.DeleteProfile(name); err != nil {
+		return err
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("saving configuration for 'profile delete': %w", err)
+	}
+
+	mgr, err := session.NewManagerForProfile(name)
+	if err != nil {
+		return fmt.Errorf("creating session manager for profile '%s': %w", name, err)
+	}
+	if err := os.RemoveAll(mgr.ConfigDir()); err != nil {
+		return fmt.Errorf("removing state directory of profile '%s': %w", name, err)
+	}
+	ui.Success(fmt.Sprintf("Profile '%s' deleted.", name))
+	return nil
+}
+
+// init registers the 'profile' command and its subcommands with the root command.
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+)
+
+func TestProfileCommands(t *testing.T) {
+	tempDir, cleanup := setupAuthTest(t)
+	defer cleanup()
+	t.Cleanup(func() {
+		rootCmd.PersistentFlags().Set("profile", "")
+		config.SelectProfile("")
+	})
+
+	def, err := config.LoadOrCreate()
+	require.NoError(t, err)
+	def.Token.AccessToken = "default-token"
+	require.NoError(t, def.Save())
+	work, err := config.LoadProfileOrCreate("work")
+	require.NoError(t, err)
+	work.Token.AccessToken = "work-token"
+	require.NoError(t, work.Save())
+
+	execute := func(args ...string) string {
+		return captureOutput(t, func() {
+			rootCmd.SetArgs(args)
+			rootCmd.Execute()
+		})
+	}
+
+	output := execute("profile", "list")
+	assert.Regexp(t, `\* default\s+logged in`, output)
+	assert.Regexp(t, `  work\s+logged in`, output)
+
+	output = execute("profile", "use", "work")
+	assert.Contains(t, output, "Now using profile 'work'")
+	output = execute("profile", "list")
+	assert.Regexp(t, `\* work`, output)
+
+	// Structured output lists the profiles as records.
+	output = execute("profile", "list", "-o", "json")
+	rootCmd.PersistentFlags().Set("output", ui.FormatTable)
+	ui.SetRenderer(mustRenderer(t, ui.FormatTable, ""))
+	assert.Contains(t, output, `"profile": "work",
+      "current": true,
+      "status": "logged in"`)
+	assert.NotContains(t, output, "Profile ")
+
+	output = execute("profile", "use", "missing")
+	assert.NotContains(t, output, "Now using profile")
+
+	// The --profile flag overrides the current profile for one command.
+	output = execute("--profile", "default", "profile", "list")
+	assert.Regexp(t, `\* default`, output)
+	rootCmd.PersistentFlags().Set("profile", "")
+
+	workDir := filepath.Join(tempDir, "profiles", "work")
+	require.NoError(t, os.MkdirAll(filepath.Join(workDir, "sessions"), 0o700))
+	output = execute("profile", "delete", "work")
+	assert.Contains(t, output, "Profile 'work' deleted")
+	assert.NoDirExists(t, workDir)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.DefaultProfile}, cfg.ProfileNames())
+	assert.Equal(t, config.DefaultProfile, cfg.CurrentProfile())
+	assert.Equal(t, "default-token", cfg.Token.AccessToken)
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tonimelisma/onedrive-client/cmd/items"
 	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
 	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
@@ -24,7 +26,7 @@ It allows you to manage files, folders, and drives, perform uploads and download
 and manage authentication with your OneDrive account.
 
 Current capabilities include:
-  - Authentication management (login, logout, status)
+  - Authentication management (login, logout, status) with named profiles
   - Listing drives and checking quota
   - File and folder operations (list, stat, mkdir, upload, download, rm, mv, cp, rename)
   - Sharing and permissions management
@@ -35,11 +37,31 @@ This tool aims to provide a comprehensive set of command-line primitives for
 manual and scripted interactions with OneDrive.`,
 	// PersistentPreRunE is executed before any subcommand's RunE.
 	// It's used here to ensure that most commands require authentication,
-	// while exempting the 'auth' command group.
+	// while exempting the 'auth' and 'profile' command groups.
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Exempt 'auth' command and its subcommands (like 'auth login') from auth checks,
+		// Select the configuration profile first, so that every command,
+		// including 'auth' and 'profile', operates on it.
+		profile, _ := cmd.Flags().GetString("profile")
+		if err := config.SelectProfile(profile); err != nil {
+			return err
+		}
+
+		// Select the output format, so that results are rendered as requested.
+		renderer, err := ui.ParseOutputFlags(cmd)
+		if err != nil {
+			return err
+		}
+		ui.SetRenderer(renderer)
+
+		// Completion requests sign in only when they need to, in their
+		// completion functions, and must not print anything else.
+		if cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd {
+			return nil
+		}
+
+		// Exempt the 'auth' and 'profile' subcommands (like 'auth login') from auth checks,
 		// as these commands are used to establish authentication.
-		if cmd.Parent() != nil && cmd.Parent().Name() == "auth" {
+		if cmd.Parent() != nil && (cmd.Parent().Name() == "auth" || cmd.Parent().Name() == "profile") {
 			return nil
 		}
 
@@ -48,7 +70,7 @@ manual and scripted interactions with OneDrive.`,
 		// This is a lightweight check; it doesn't mean every command needs a fully valid token
 		// to *start* (e.g. 'items list' might proceed to tell you to log in), but it ensures
 		// the auth state is evaluated.
-		_, err := app.NewApp(cmd)
+		a, err := app.NewApp(cmd)
 		if err != nil {
 			// If a login is pending (e.g., user ran 'auth login' but hasn't visited the URL),
 			// app.NewApp() returns ErrLoginPending with a user-friendly message.
@@ -68,6 +90,10 @@ manual and scripted interactions with OneDrive.`,
 			// For other unexpected errors during app initialization, propagate them.
 			return err
 		}
+		// Hand the App to the command, so that app.NewApp in its RunE returns
+		// it instead of initializing another one, which would ask for the
+		// passphrase of an encrypted token a second time.
+		shareAppWithCommand(cmd, a)
 		return nil
 	},
 	// Run is executed if `onedrive-client` is called without any subcommands.
@@ -77,6 +103,26 @@ manual and scripted interactions with OneDrive.`,
 	},
 }
 
+// restoreContexts undoes shareAppWithCommand once the command has finished.
+var restoreContexts []func()
+
+// shareAppWithCommand makes `a` the App that app.NewApp returns for `cmd`
+// while it runs. Cobra keeps the context of a command from one execution to
+// the next, so the previous context is put back when the execution ends.
+func shareAppWithCommand(cmd *cobra.Command, a *app.App) {
+	previous := cmd.Context()
+	cmd.SetContext(app.NewContext(previous, a))
+	restoreContexts = append(restoreContexts, func() { cmd.SetContext(previous) })
+}
+
+// restoreCommandContexts runs the pending restoreContexts, newest first.
+func restoreCommandContexts() {
+	for i := len(restoreContexts) - 1; i >= 0; i-- {
+		restoreContexts[i]()
+	}
+	restoreContexts = nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 // It handles errors from command execution, including special handling for ErrLoginPending.
@@ -100,6 +146,10 @@ func init() {
 	// Define global persistent flags applicable to all commands.
 	// Example: rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.onedrive-client.yaml)")
 	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug logging for SDK and internal operations")
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile to use (see 'profile list'); defaults to the current profile")
+	rootCmd.PersistentFlags().String("drive", "", "ID of the drive to operate on (see 'drives list'); defaults to your own OneDrive")
+	ui.AddOutputFlags(rootCmd)
+	cobra.OnFinalize(restoreCommandContexts)
 
 	// Initialize and register the 'items' subcommand and its children.
 	// This modular approach keeps subcommand definitions organized.
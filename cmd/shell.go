@@ -0,0 +1,530 @@
+// Package cmd (shell.go) defines the 'shell' command: an interactive shell
+// that keeps one authenticated client for all the commands it runs, and
+// resolves remote paths against a remote working directory.
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/shell"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// shellCmd handles 'shell'.
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Start an interactive shell with a remote working directory",
+	Long: `Starts an interactive shell that signs in once and keeps the client for every command,
+which makes running many commands in a row much faster.
+
+The shell has a remote working directory, changed with 'cd' and shown with 'pwd'. The short
+commands ls, stat, get, put, mkdir, rm, mv and cp run the corresponding 'items' commands, and
+every other onedrive-client command, such as 'items share' or 'drives quota', can be run as
+well. In all of them, remote paths are relative to the working directory unless they start
+with "/" or "id:".
+
+On a terminal, Tab completes command names and remote names from cached folder listings, and
+the arrow keys recall earlier commands. Ctrl-C interrupts a running command; 'exit', Ctrl-D
+or Ctrl-C at the prompt leaves the shell. Without a terminal, the shell runs the commands read
+from standard input, one per line.
+
+Flags given to 'shell', such as --profile, --drive and --output, apply to every command.`,
+	Example: `onedrive-client shell
+onedrive-client shell --drive <drive-id>
+printf 'cd /Documents\nget report.pdf\n' | onedrive-client shell`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := app.NewApp(cmd)
+		if err != nil {
+			return fmt.Errorf("initializing app for 'shell': %w", err)
+		}
+		return shellLogic(a, cmd, args)
+	},
+}
+
+// shellBuiltins are the commands that the shell handles itself.
+var shellBuiltins = []string{"cd", "pwd", "history", "help", "exit", "quit"}
+
+// shellAliases are the short commands of the shell, and the 'items' commands
+// they run.
+var shellAliases = []struct {
+	name    string
+	command []string
+}{
+	{"ls", []string{"items", "list"}},
+	{"stat", []string{"items", "stat"}},
+	{"get", []string{"items", "download"}},
+	{"put", []string{"items", "upload"}},
+	{"mkdir", []string{"items", "mkdir"}},
+	{"rm", []string{"items", "rm"}},
+	{"mv", []string{"items", "mv"}},
+	{"cp", []string{"items", "copy"}},
+}
+
+// shellRemoteFlags are the flags whose values are remote paths.
+var shellRemoteFlags = map[string]bool{"in": true}
+
+// shellArg describes a positional argument of a command, as named in its
+// usage line.
+type shellArg struct {
+	remote   bool // A remote path, resolved against the working directory.
+	local    bool // A local path.
+	optional bool
+}
+
+// shellFlagValue is the value of a flag when the shell started.
+type shellFlagValue struct {
+	value   string
+	slice   []string
+	changed bool
+}
+
+// shellSession is the state of an interactive shell.
+type shellSession struct {
+	app       *app.App
+	root      *cobra.Command
+	out       io.Writer
+	cwd       string
+	previous  string // Working directory before the last 'cd', for 'cd -'.
+	history   []string
+	completer *shell.Completer
+	flags     map[*pflag.Flag]shellFlagValue
+}
+
+// shellLogic contains the core logic for the 'shell' command.
+func shellLogic(a *app.App, cmd *cobra.Command, _ []string) error {
+	s := newShellSession(a, rootCmd, cmd.OutOrStdout())
+	ctx := app.NewContext(cmd.Context(), a)
+	reader := shell.NewLineReader(cmd.InOrStdin(), cmd.OutOrStdout(), func(line string, pos int) (string, int, []string) {
+		return s.complete(ctx, line, pos)
+	})
+
+	// Errors are printed by the shell, which then reads the next command.
+	silenceErrors, silenceUsage := s.root.SilenceErrors, s.root.SilenceUsage
+	s.root.SilenceErrors, s.root.SilenceUsage = true, true
+	defer func() {
+		s.root.SilenceErrors, s.root.SilenceUsage = silenceErrors, silenceUsage
+		s.root.SetArgs(nil)
+	}()
+
+	for {
+		line, err := reader.ReadLine(s.prompt())
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		exit, err := s.exec(ctx, line)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		if exit {
+			return nil
+		}
+	}
+}
+
+// newShellSession returns a shell at the root folder that runs the commands
+// of `root`. The current values of the flags of `root`, such as --profile,
+// become the values every command starts with.
+func newShellSession(a *app.App, root *cobra.Command, out io.Writer) *shellSession {
+	s := &shellSession{
+		app:   a,
+		root:  root,
+		out:   out,
+		cwd:   "/",
+		flags: make(map[*pflag.Flag]shellFlagValue),
+	}
+	s.completer = shell.NewCompleter(func(ctx context.Context, dir string) ([]string, error) {
+		children, err := a.SDK.GetDriveItemChildrenByPath(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(children.Value))
+		for _, item := range children.Value {
+			if item.Folder != nil {
+				names = append(names, item.Name+"/")
+			} else {
+				names = append(names, item.Name)
+			}
+		}
+		return names, nil
+	})
+	visitShellFlags(root, func(_ *cobra.Command, f *pflag.Flag) {
+		value := shellFlagValue{value: f.Value.String(), changed: f.Changed}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			value.slice = append([]string(nil), slice.GetSlice()...)
+		}
+		s.flags[f] = value
+	})
+	root.InitDefaultHelpCmd() // For 'help <command>'.
+	return s
+}
+
+// prompt returns the prompt showing the working directory.
+func (s *shellSession) prompt() string {
+	return "onedrive:" + s.cwd + "> "
+}
+
+// exec runs a command line. It reports whether the shell should exit.
+func (s *shellSession) exec(ctx context.Context, line string) (bool, error) {
+	words, err := shell.Split(line)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", onedrive.ErrInvalidRequest, err)
+	}
+	if len(words) == 0 {
+		return false, nil
+	}
+	s.history = append(s.history, line)
+
+	switch words[0] {
+	case "exit", "quit":
+		return true, nil
+	case "pwd":
+		fmt.Fprintln(s.out, s.cwd)
+		return false, nil
+	case "cd":
+		return false, s.cd(ctx, words[1:])
+	case "history":
+		for i, entry := range s.history {
+			fmt.Fprintf(s.out, "%5d  %s\n", i+1, entry)
+		}
+		return false, nil
+	case "help":
+		if len(words) == 1 {
+			s.printHelp()
+			return false, nil
+		}
+	case "shell":
+		return false, fmt.Errorf("%w: already in the shell", onedrive.ErrInvalidRequest)
+	}
+
+	args, err := s.commandArgs(words)
+	if err != nil {
+		return false, err
+	}
+	return false, s.run(ctx, args)
+}
+
+// cd changes the working directory to the remote folder given in `args`, to
+// the root folder without arguments, or back with "-".
+func (s *shellSession) cd(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("%w: 'cd' takes a single path", onedrive.ErrInvalidRequest)
+	}
+	target := "/"
+	switch {
+	case len(args) == 1 && args[0] == "-":
+		if s.previous == "" {
+			return fmt.Errorf("%w: no previous directory", onedrive.ErrInvalidRequest)
+		}
+		target = s.previous
+	case len(args) == 1:
+		target = shell.Resolve(s.cwd, args[0])
+	}
+	if target != "/" {
+		item, err := s.app.SDK.GetDriveItemByPath(ctx, target)
+		if err != nil {
+			return fmt.Errorf("changing directory to '%s': %w", target, err)
+		}
+		if item.Folder == nil {
+			return fmt.Errorf("%w: '%s' is not a folder", onedrive.ErrInvalidRequest, target)
+		}
+	}
+	s.previous, s.cwd = s.cwd, target
+	return nil
+}
+
+// printHelp lists the commands of the shell.
+func (s *shellSession) printHelp() {
+	fmt.Fprintln(s.out, "Shell commands:")
+	fmt.Fprintf(s.out, "  %-8s %s\n", "cd", "Change the remote working directory ('cd' alone goes to /, 'cd -' back)")
+	fmt.Fprintf(s.out, "  %-8s %s\n", "pwd", "Print the remote working directory")
+	for _, alias := range shellAliases {
+		target, _, err := s.root.Find(alias.command)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(s.out, "  %-8s %s ('%s')\n", alias.name, target.Short, strings.Join(alias.command, " "))
+	}
+	fmt.Fprintf(s.out, "  %-8s %s\n", "history", "List the commands of this session")
+	fmt.Fprintf(s.out, "  %-8s %s\n", "exit", "Leave the shell")
+	fmt.Fprintln(s.out, "\nEvery other onedrive-client command can be run too, such as 'items share report.pdf view anonymous'")
+	fmt.Fprintln(s.out, "or 'drives quota'; 'help <command>' shows its help. Remote paths are relative to the working directory.")
+}
+
+// commandArgs expands a shell alias in `words` and resolves the remote paths
+// among the arguments against the working directory. An optional remote path
+// that is left out, like the folder of 'ls', becomes the working directory.
+func (s *shellSession) commandArgs(words []string) ([]string, error) {
+	args := expandShellAlias(words)
+	target, rest, err := s.root.Find(args)
+	if err != nil {
+		return nil, err
+	}
+	if target == s.root {
+		return nil, fmt.Errorf("%w: unknown command '%s'; type 'help' for the commands", onedrive.ErrInvalidRequest, words[0])
+	}
+
+	specs := shellArgs(target)
+	resolved := strings.Fields(target.CommandPath())[1:]
+	positional := 0
+	takesValue := false
+	var valueFlag string
+	for i, arg := range rest {
+		switch {
+		case takesValue:
+			if shellRemoteFlags[valueFlag] {
+				arg = shell.Resolve(s.cwd, arg)
+			}
+			takesValue = false
+		case arg == "--":
+			resolved = append(resolved, rest[i:]...)
+			return resolved, nil
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if hasValue && shellRemoteFlags[name] {
+				arg = arg[:len(arg)-len(value)] + shell.Resolve(s.cwd, value)
+			}
+			if !hasValue && flagTakesValue(target, arg) {
+				takesValue, valueFlag = true, name
+			}
+		default:
+			if positional < len(specs) && specs[positional].remote {
+				arg = shell.Resolve(s.cwd, arg)
+			}
+			positional++
+		}
+		resolved = append(resolved, arg)
+	}
+	if positional < len(specs) && specs[positional].remote && specs[positional].optional && s.cwd != "/" {
+		resolved = append(resolved, s.cwd)
+	}
+	return resolved, nil
+}
+
+// run executes the command `args` of the CLI with the shell's App. Ctrl-C
+// cancels the command rather than ending the shell.
+func (s *shellSession) run(ctx context.Context, args []string) error {
+	s.resetFlags()
+	defer s.completer.Invalidate() // The command may have changed remote folders.
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	s.root.SetArgs(args)
+	return s.root.ExecuteContext(ctx)
+}
+
+// resetFlags sets every flag back to its value when the shell started, as
+// cobra keeps the flags of one command line for the next, and clears the
+// contexts cobra keeps for the commands.
+func (s *shellSession) resetFlags() {
+	visitShellFlags(s.root, func(c *cobra.Command, f *pflag.Flag) {
+		c.SetContext(nil)
+		value, ok := s.flags[f]
+		if !ok { // A flag cobra added later, such as --help.
+			value = shellFlagValue{value: f.DefValue}
+			if strings.HasPrefix(f.DefValue, "[") {
+				value.slice = strings.Split(strings.Trim(f.DefValue, "[]"), ",")
+				if len(value.slice) == 1 && value.slice[0] == "" {
+					value.slice = nil
+				}
+			}
+		}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(value.slice)
+		} else {
+			f.Value.Set(value.value)
+		}
+		f.Changed = value.changed
+	})
+}
+
+// complete completes the word before position `pos` of `line`: a command name
+// first, then subcommand names, flags, and remote or local paths depending on
+// the argument.
+func (s *shellSession) complete(ctx context.Context, line string, pos int) (string, int, []string) {
+	before := line[:pos]
+	words := shell.Words(before)
+	// A word ends where the next character would start a new one.
+	start, current := pos, ""
+	if typed := shell.Words(before + "x"); len(typed) == len(words) && len(words) > 0 {
+		last := words[len(words)-1]
+		words, start, current = words[:len(words)-1], last.Start, last.Text
+	}
+
+	var candidates []string
+	if len(words) == 0 {
+		candidates = shell.CompleteWord(current, s.commandNames())
+	} else {
+		candidates = s.completeArg(ctx, words, current)
+	}
+	if len(candidates) == 0 {
+		return line, pos, nil
+	}
+
+	completion := shell.CommonPrefix(candidates)
+	if len(candidates) > 1 && len(completion) <= len(current) {
+		return line, pos, candidates
+	}
+	completion = shell.Quote(completion)
+	if len(candidates) == 1 && !strings.HasSuffix(completion, "/") {
+		completion += " "
+	}
+	return line[:start] + completion + line[pos:], start + len(completion), nil
+}
+
+// completeArg returns the completions of the argument `current` following
+// `words`.
+func (s *shellSession) completeArg(ctx context.Context, words []shell.Word, current string) []string {
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.Text
+	}
+	if texts[0] == "cd" {
+		var folders []string
+		for _, candidate := range s.completer.Complete(ctx, s.cwd, current) {
+			if strings.HasSuffix(candidate, "/") {
+				folders = append(folders, candidate)
+			}
+		}
+		return folders
+	}
+
+	target, rest, err := s.root.Find(expandShellAlias(texts))
+	if err != nil || target == s.root {
+		return nil
+	}
+	if strings.HasPrefix(current, "-") {
+		var flags []string
+		addFlag := func(f *pflag.Flag) {
+			if !f.Hidden {
+				flags = append(flags, "--"+f.Name)
+			}
+		}
+		target.Flags().VisitAll(addFlag)
+		target.InheritedFlags().VisitAll(addFlag)
+		return shell.CompleteWord(current, flags)
+	}
+
+	positional := 0
+	takesValue := false
+	for _, arg := range rest {
+		switch {
+		case takesValue:
+			takesValue = false
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			takesValue = !strings.Contains(arg, "=") && flagTakesValue(target, arg)
+		default:
+			positional++
+		}
+	}
+	if takesValue {
+		return nil // Flag values are not completed.
+	}
+	if positional == 0 && target.HasAvailableSubCommands() {
+		var names []string
+		for _, sub := range target.Commands() {
+			if sub.IsAvailableCommand() {
+				names = append(names, sub.Name())
+			}
+		}
+		return shell.CompleteWord(current, names)
+	}
+	specs := shellArgs(target)
+	switch {
+	case positional >= len(specs):
+		return nil
+	case specs[positional].remote:
+		return s.completer.Complete(ctx, s.cwd, current)
+	case specs[positional].local:
+		return shell.CompleteLocal(current)
+	}
+	return nil
+}
+
+// commandNames returns the names that can start a command line.
+func (s *shellSession) commandNames() []string {
+	names := append([]string(nil), shellBuiltins...)
+	for _, alias := range shellAliases {
+		names = append(names, alias.name)
+	}
+	for _, c := range s.root.Commands() {
+		if c.IsAvailableCommand() && c.Name() != "shell" {
+			names = append(names, c.Name())
+		}
+	}
+	return names
+}
+
+// expandShellAlias replaces a shell alias at the start of `words` with the
+// command it runs.
+func expandShellAlias(words []string) []string {
+	for _, alias := range shellAliases {
+		if len(words) > 0 && words[0] == alias.name {
+			return append(append([]string(nil), alias.command...), words[1:]...)
+		}
+	}
+	return words
+}
+
+// shellArgs describes the positional arguments of `c` from its usage line,
+// where remote paths are named like <remote-path> or [path], and local paths
+// like <local-path>.
+func shellArgs(c *cobra.Command) []shellArg {
+	fields := strings.Fields(c.Use)
+	specs := make([]shellArg, 0, len(fields))
+	for _, field := range fields[1:] {
+		name := strings.Trim(field, "<>[].")
+		spec := shellArg{optional: strings.HasPrefix(field, "[")}
+		switch {
+		case strings.Contains(name, "local"):
+			spec.local = true
+		case strings.Contains(name, "path"):
+			spec.remote = true
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// flagTakesValue reports whether the flag `arg` of `c`, such as "--workers"
+// or "-o", is followed by a separate value.
+func flagTakesValue(c *cobra.Command, arg string) bool {
+	var f *pflag.Flag
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		if f = c.Flags().Lookup(name); f == nil {
+			f = c.InheritedFlags().Lookup(name)
+		}
+	} else if len(arg) == 2 { // Shorthands with an attached value, like "-ojson", take no separate one.
+		if f = c.Flags().ShorthandLookup(arg[1:]); f == nil {
+			f = c.InheritedFlags().ShorthandLookup(arg[1:])
+		}
+	}
+	return f != nil && f.NoOptDefVal == ""
+}
+
+// visitShellFlags calls `fn` for every flag of `c` and its subcommands.
+func visitShellFlags(c *cobra.Command, fn func(*cobra.Command, *pflag.Flag)) {
+	visit := func(f *pflag.Flag) { fn(c, f) }
+	c.Flags().VisitAll(visit)
+	c.PersistentFlags().VisitAll(visit)
+	for _, sub := range c.Commands() {
+		visitShellFlags(sub, fn)
+	}
+}
+
+// init registers the 'shell' command with the root command.
+func init() {
+	rootCmd.AddCommand(shellCmd)
+}
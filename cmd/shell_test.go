@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// newShellTestApp returns an App with a saved configuration and `mockSDK`,
+// as the shell shares it with the commands it runs.
+func newShellTestApp(t *testing.T, mockSDK *MockSDK) *app.App {
+	t.Helper()
+	setupAuthTest(t)
+	cfg, err := config.LoadOrCreate()
+	require.NoError(t, err)
+	return &app.App{Config: cfg, SDK: mockSDK}
+}
+
+// runShellScript runs the lines of `script` in the shell and returns the
+// output.
+func runShellScript(t *testing.T, a *app.App, script string) string {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	cmd.SetIn(strings.NewReader(script))
+	return captureOutput(t, func() {
+		require.NoError(t, shellLogic(a, cmd, nil))
+	})
+}
+
+// shellTestFolders are the remote folders of the shell tests.
+var shellTestFolders = map[string][]onedrive.DriveItem{
+	"/": {
+		{Name: "Documents", Folder: &onedrive.FolderFacet{}},
+		{Name: "Desktop", Folder: &onedrive.FolderFacet{}},
+		{Name: "notes.txt"},
+	},
+	"/Documents": {
+		{Name: "old report.pdf"},
+		{Name: "a.txt"},
+	},
+}
+
+func newShellMockSDK(calls *[]string) *MockSDK {
+	return &MockSDK{
+		GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+			if _, ok := shellTestFolders[path]; ok {
+				return onedrive.DriveItem{Name: filepath.Base(path), Folder: &onedrive.FolderFacet{}}, nil
+			}
+			if path == "/notes.txt" {
+				return onedrive.DriveItem{Name: "notes.txt"}, nil
+			}
+			return onedrive.DriveItem{}, onedrive.ErrResourceNotFound
+		},
+		GetDriveItemChildrenByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItemList, error) {
+			*calls = append(*calls, "list "+path)
+			return onedrive.DriveItemList{Value: shellTestFolders[path]}, nil
+		},
+		DeleteDriveItemFunc: func(ctx context.Context, path string) error {
+			*calls = append(*calls, "rm "+path)
+			return nil
+		},
+		MoveDriveItemFunc: func(ctx context.Context, sourcePath, destinationParentPath string) (onedrive.DriveItem, error) {
+			*calls = append(*calls, "mv "+sourcePath+" "+destinationParentPath)
+			return onedrive.DriveItem{Name: filepath.Base(sourcePath)}, nil
+		},
+	}
+}
+
+func TestShellLogic(t *testing.T) {
+	var calls []string
+	a := newShellTestApp(t, newShellMockSDK(&calls))
+
+	output := runShellScript(t, a, strings.Join([]string{
+		"pwd",
+		"cd Documents",
+		"pwd",
+		"ls",
+		`rm "old report.pdf"`,
+		"cd /missing",
+		"cd /notes.txt",
+		"mv a.txt ../Desktop",
+		"cd ..",
+		"ls",
+		"cd -",
+		"pwd",
+		"frobnicate",
+		"history",
+		"exit",
+		"rm never.txt",
+	}, "\n"))
+
+	assert.Equal(t, []string{
+		"list /Documents",
+		"rm /Documents/old report.pdf",
+		"mv /Documents/a.txt /Desktop",
+		"list /",
+	}, calls)
+	assert.Contains(t, output, "/\n/Documents\n")
+	assert.Contains(t, output, "old report.pdf")
+	assert.Contains(t, output, "changing directory to '/missing'")
+	assert.Contains(t, output, "'/notes.txt' is not a folder")
+	assert.Contains(t, output, "unknown command")
+	assert.Contains(t, output, "   14  history")
+	assert.NotContains(t, output, "never.txt", "nothing runs after 'exit'")
+}
+
+func TestShellResetsFlags(t *testing.T) {
+	mockSDK := &MockSDK{
+		GetDefaultDriveFunc: func(ctx context.Context) (onedrive.Drive, error) {
+			drive := onedrive.Drive{}
+			drive.Quota.State = "normal"
+			return drive, nil
+		},
+	}
+	a := newShellTestApp(t, mockSDK)
+
+	output := runShellScript(t, a, "drives quota -o json\ndrives quota\n")
+
+	assert.Contains(t, output, `"state": "normal"`)
+	assert.Equal(t, 1, strings.Count(output, "Drive Quota Information"), "the second command is back to the table format")
+}
+
+func TestShellComplete(t *testing.T) {
+	var calls []string
+	a := newShellTestApp(t, newShellMockSDK(&calls))
+	s := newShellSession(a, rootCmd, io.Discard)
+	ctx := app.NewContext(context.Background(), a)
+
+	local := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(local, "file.txt"), nil, 0o600))
+
+	tests := []struct {
+		line       string
+		expected   string
+		candidates []string
+	}{
+		{"hist", "history ", nil},
+		{"cd Doc", "cd Documents/", nil},
+		{"cd no", "cd no", nil},
+		{"get Documents/old", `get Documents/old\ report.pdf `, nil},
+		{"ls D", "ls D", []string{"Desktop/", "Documents/"}},
+		{"items sh", "items share ", nil},
+		{"items share no", "items share notes.txt ", nil},
+		{"get --conn", "get --connections ", nil},
+		{"put " + local + "/fi", "put " + local + "/file.txt ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			line, pos, candidates := s.complete(ctx, tt.line, len(tt.line))
+			assert.Equal(t, tt.expected, line)
+			assert.Equal(t, len(tt.expected), pos)
+			assert.Equal(t, tt.candidates, candidates)
+		})
+	}
+
+	line, pos, _ := s.complete(ctx, "rm Doc other", 6)
+	assert.Equal(t, "rm Documents/ other", line, "completes at the cursor")
+	assert.Equal(t, 13, pos)
+	assert.Equal(t, []string{"list /", "list /Documents"}, calls, "listings are cached")
+}
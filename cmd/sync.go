@@ -0,0 +1,441 @@
+// Package cmd (sync.go) defines the 'sync' command, which performs a two-way
+// synchronisation between a local directory and a OneDrive folder using the
+// engine in internal/sync.
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/internal/sync"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// syncCmd handles 'sync <local-dir> <remote-dir>'.
+// It reconciles a local directory and a OneDrive folder in both directions.
+var syncCmd = &cobra.Command{
+	Use:   "sync <local-dir> <remote-dir>",
+	Short: "Synchronise a local directory with a OneDrive folder in both directions",
+	Long: `Performs a two-way synchronisation between a local directory and a OneDrive folder.
+Remote changes are discovered with delta queries and local changes with a directory scan.
+New and modified files are copied in whichever direction they changed, renames and moves
+are replayed on the other side, and folders are created as needed.
+
+Files changed on both sides since the last sync are conflicts. Changes are detected by comparing
+the recorded state of each file with its remote cTag and content hash and its local size and
+modification time. If both versions turn out to have the same content nothing is transferred;
+otherwise the --conflict policy decides what happens:
+
+  keep-both      keep the remote version and save the local one as "name (conflict <time>).ext" (default)
+  prefer-local   upload the local version over the remote one
+  prefer-remote  download the remote version over the local one
+  prefer-newer   keep the version modified last, or both if that cannot be told
+  interactive    ask for each conflict
+
+Every conflict and how it was resolved is listed in the summary.
+
+The state of each synced folder pair, including the delta link used to fetch only new remote
+changes, is kept in the 'syncstate' directory next to the upload sessions and can be inspected
+with 'sync status'. On the first sync of a pair nothing is treated as deleted: the two trees are
+merged. Files present on both sides are compared by content hash when OneDrive reports one:
+equal files are considered in sync and different ones are conflicts. Without a hash, files
+with the same size are considered in sync.`,
+	Example: `onedrive-client sync ~/Documents/Work /Work
+onedrive-client sync ./photos /Pictures/Camera --dry-run
+onedrive-client sync ~/Notes /Notes --conflict prefer-newer`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := app.NewApp(cmd)
+		if err != nil {
+			return fmt.Errorf("initializing app for 'sync': %w", err)
+		}
+		return syncLogic(a, cmd, args)
+	},
+}
+
+// syncStatusCmd handles 'sync status [<local-dir> <remote-dir>]'.
+// It inspects the persisted sync state.
+var syncStatusCmd = &cobra.Command{
+	Use:   "status [<local-dir> <remote-dir>]",
+	Short: "Show the recorded state of synced folders",
+	Long: `Shows the persisted sync state. Without arguments, every recorded folder pair is listed
+with its last sync time and number of tracked items. With a local directory and remote folder,
+the tracked items of that pair are listed as well.`,
+	Example: `onedrive-client sync status
+onedrive-client sync status ~/Documents/Work /Work`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("accepts either no arguments or <local-dir> <remote-dir>, received %d", len(args))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := app.NewApp(cmd)
+		if err != nil {
+			return fmt.Errorf("initializing app for 'sync status': %w", err)
+		}
+		store, err := sync.NewStore()
+		if err != nil {
+			return fmt.Errorf("opening sync state store: %w", err)
+		}
+		return syncStatusLogic(a, cmd, store, args)
+	},
+}
+
+// syncLogic contains the core logic for the 'sync' command.
+func syncLogic(a *app.App, cmd *cobra.Command, args []string) error {
+	if len(args) < 2 { // Should be caught by Args validation.
+		return fmt.Errorf("both local directory and remote folder are required for 'sync'")
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("parsing '--dry-run' flag: %w", err)
+	}
+	conflictFlag, err := cmd.Flags().GetString("conflict")
+	if err != nil {
+		return fmt.Errorf("parsing '--conflict' flag: %w", err)
+	}
+	policy, err := sync.ParseConflictPolicy(conflictFlag)
+	if err != nil {
+		return err
+	}
+
+	localRoot, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("resolving local directory '%s': %w", args[0], err)
+	}
+
+	drive, err := a.SDK.GetDefaultDrive(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("getting default drive for 'sync': %w", err)
+	}
+
+	store, err := sync.NewStore()
+	if err != nil {
+		return fmt.Errorf("opening sync state store: %w", err)
+	}
+	key := sync.NewKey(drive.ID, args[1], localRoot)
+	unlock, err := store.Lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	prev, err := store.Load(key)
+	if err != nil {
+		return fmt.Errorf("loading sync state for %s: %w", key, err)
+	}
+	if prev == nil {
+		prev = &sync.State{Key: key}
+	}
+
+	sessions, err := session.NewManager()
+	if err != nil {
+		return fmt.Errorf("creating session manager for 'sync': %w", err)
+	}
+
+	engine := sync.NewEngine(a.SDK, sync.Options{
+		LocalRoot:  localRoot,
+		RemoteRoot: key.RemoteRoot,
+		DryRun:     dryRun,
+		Conflicts:  policy,
+		Prompt:     newConflictPrompt(cmd.InOrStdin()),
+		Sessions:   sessions,
+	})
+	result, err := engine.Run(cmd.Context(), prev)
+	if err != nil {
+		return fmt.Errorf("syncing '%s' with '%s': %w", localRoot, key.RemoteRoot, err)
+	}
+
+	if !dryRun {
+		if err := store.Save(result.State); err != nil {
+			return fmt.Errorf("saving sync state for %s: %w", key, err)
+		}
+	}
+
+	if err := displaySyncResult(cmd.OutOrStdout(), result, policy); err != nil {
+		return err
+	}
+	if failed := result.Failed(); len(failed) > 0 {
+		return fmt.Errorf("%d sync action(s) failed", len(failed))
+	}
+	return nil
+}
+
+// syncStatusLogic contains the core logic for the 'sync status' command.
+func syncStatusLogic(a *app.App, cmd *cobra.Command, store *sync.Store, args []string) error {
+	if len(args) == 0 {
+		states, err := store.List()
+		if err != nil {
+			return fmt.Errorf("listing sync state: %w", err)
+		}
+		return displaySyncStates(cmd.OutOrStdout(), states, store.Dir())
+	}
+
+	localRoot, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("resolving local directory '%s': %w", args[0], err)
+	}
+	drive, err := a.SDK.GetDefaultDrive(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("getting default drive for 'sync status': %w", err)
+	}
+
+	key := sync.NewKey(drive.ID, args[1], localRoot)
+	state, err := store.Load(key)
+	if err != nil {
+		return fmt.Errorf("loading sync state for %s: %w", key, err)
+	}
+	if state == nil {
+		// There is no state to show: structured output is null.
+		return ui.Render(cmd.OutOrStdout(), ui.Result{Data: state, Columns: syncStateColumns, Table: func(w io.Writer) {
+			fmt.Fprintf(w, "No sync state recorded for %s.\n", key)
+		}})
+	}
+	return displaySyncState(cmd.OutOrStdout(), state)
+}
+
+// newConflictPrompt returns a sync.PromptFunc that asks on stdout and reads
+// the answer from `in`. An empty answer keeps both versions.
+func newConflictPrompt(in io.Reader) sync.PromptFunc {
+	reader := bufio.NewReader(in)
+	return func(c sync.Conflict) (sync.ConflictPolicy, error) {
+		fmt.Printf("\nConflict: '%s' changed on both sides.\n", c.Path)
+		fmt.Printf("  Local:  %d bytes, modified %s\n", c.Local.Size,
+			c.Local.ModTime.Local().Format(onedrive.StandardTimeFormat))
+		fmt.Printf("  Remote: %d bytes, modified %s\n", c.Remote.Size,
+			c.Remote.Modified.Local().Format(onedrive.StandardTimeFormat))
+		for {
+			fmt.Print("Keep [b]oth, [l]ocal, [r]emote, [n]ewer, or [s]kip? [b] ")
+			answer, err := reader.ReadString('\n')
+			if err != nil && (err != io.EOF || answer == "") {
+				return "", fmt.Errorf("reading answer: %w", err)
+			}
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "", "b", "both":
+				return sync.PolicyKeepBoth, nil
+			case "l", "local":
+				return sync.PolicyPreferLocal, nil
+			case "r", "remote":
+				return sync.PolicyPreferRemote, nil
+			case "n", "newer":
+				return sync.PolicyPreferNewer, nil
+			case "s", "skip":
+				return "", nil
+			}
+			if err == io.EOF {
+				return "", fmt.Errorf("unrecognised answer '%s'", strings.TrimSpace(answer))
+			}
+			fmt.Println("Please answer b, l, r, n or s.")
+		}
+	}
+}
+
+// Default CSV columns of the sync results and states.
+var (
+	syncActionColumns = []string{"type", "path", "from", "resolution", "error"}
+	syncStateColumns  = []string{"driveId", "remoteRoot", "localRoot", "lastSync", "items"}
+)
+
+// syncAction is the structured form of a sync.Action, whose error does not
+// encode as JSON.
+type syncAction struct {
+	Type       sync.ActionType `json:"type"`
+	Path       string          `json:"path"`
+	From       string          `json:"from,omitempty"`
+	Resolution sync.Resolution `json:"resolution,omitempty"`
+	Copy       string          `json:"copy,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// newSyncAction returns the structured form of `a`.
+func newSyncAction(a sync.Action) syncAction {
+	action := syncAction{Type: a.Type, Path: a.Path, From: a.From, Resolution: a.Resolution, Copy: a.Copy}
+	if a.Err != nil {
+		action.Error = a.Err.Error()
+	}
+	return action
+}
+
+// syncResult is the structured form of a sync.Result, with its actions as the
+// "value" list.
+type syncResult struct {
+	DryRun bool                `json:"dryRun"`
+	Policy sync.ConflictPolicy `json:"policy"`
+	Value  []syncAction        `json:"value"`
+}
+
+// syncStateSummary is the structured form of one recorded sync state in
+// displaySyncStates.
+type syncStateSummary struct {
+	sync.Key
+	LastSync time.Time `json:"lastSync"`
+	Items    int       `json:"items"`
+}
+
+// syncStateList is the structured form of displaySyncStates.
+type syncStateList struct {
+	Value []syncStateSummary `json:"value"`
+}
+
+// displaySyncStates writes a one-line summary of each recorded sync state to `w`.
+func displaySyncStates(w io.Writer, states []*sync.State, dir string) error {
+	list := syncStateList{Value: make([]syncStateSummary, 0, len(states))}
+	for _, state := range states {
+		list.Value = append(list.Value, syncStateSummary{Key: state.Key, LastSync: state.LastSync, Items: state.ItemCount()})
+	}
+	return ui.Render(w, ui.Result{Data: list, Columns: syncStateColumns, Table: func(w io.Writer) {
+		if len(states) == 0 {
+			fmt.Fprintf(w, "No sync state recorded in %s.\n", dir)
+			return
+		}
+
+		fmt.Fprintf(w, "Recorded sync state (%d found)\n\n", len(states))
+		fmt.Fprintf(w, "%-30s %-40s %-20s %8s\n", "Remote Folder", "Local Directory", "Last Sync", "Items")
+		fmt.Fprintln(w, strings.Repeat("-", onedrive.LongSeparatorLength))
+		for _, state := range states {
+			local := state.LocalRoot
+			if local == "" {
+				local = "(remote changes only)"
+			}
+			fmt.Fprintf(w, "%-30s %-40s %-20s %8d\n", state.RemoteRoot, local,
+				state.LastSync.Local().Format(onedrive.StandardTimeFormat), state.ItemCount())
+		}
+	}})
+}
+
+// displaySyncState writes the details and tracked items of one sync state to `w`.
+func displaySyncState(w io.Writer, state *sync.State) error {
+	return ui.Render(w, ui.Result{Data: state, Columns: syncStateColumns, Table: func(w io.Writer) {
+		fmt.Fprintln(w, "Sync State:")
+		fmt.Fprintf(w, "  Drive ID:         %s\n", state.DriveID)
+		fmt.Fprintf(w, "  Remote Folder:    %s\n", state.RemoteRoot)
+		if state.LocalRoot != "" {
+			fmt.Fprintf(w, "  Local Directory:  %s\n", state.LocalRoot)
+		}
+		fmt.Fprintf(w, "  Last Sync:        %s\n", state.LastSync.Local().Format(time.RFC1123))
+		if state.DeltaLink != "" {
+			fmt.Fprintf(w, "  Delta Link:       %s\n", state.DeltaLink)
+		}
+		fmt.Fprintf(w, "  Tracked Items:    %d\n", state.ItemCount())
+
+		if len(state.Baseline) == 0 {
+			return
+		}
+		paths := make([]string, 0, len(state.Baseline))
+		for p := range state.Baseline {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+
+		fmt.Fprintf(w, "\n%-60.60s %12s %-20s %s\n", "Path", "Size", "Local Modified", "cTag")
+		fmt.Fprintln(w, strings.Repeat("-", onedrive.ExtraLongSeparatorLength))
+		for _, p := range paths {
+			entry := state.Baseline[p]
+			name := p
+			if entry.IsDir {
+				name += "/"
+			}
+			fmt.Fprintf(w, "%-60.60s %12d %-20s %s\n", name, entry.Size,
+				entry.ModTime.Local().Format(onedrive.StandardTimeFormat), entry.CTag)
+		}
+	}})
+}
+
+// displaySyncResult writes each action taken by a sync run to `w`, followed by
+// a summary including how conflicts were resolved under `policy`.
+// It lives here rather than in internal/ui because internal/sync depends on
+// internal/app, which already imports internal/ui.
+func displaySyncResult(w io.Writer, result *sync.Result, policy sync.ConflictPolicy) error {
+	data := syncResult{DryRun: result.DryRun, Policy: policy, Value: make([]syncAction, 0, len(result.Actions))}
+	for _, a := range result.Actions {
+		data.Value = append(data.Value, newSyncAction(a))
+	}
+	return ui.Render(w, ui.Result{Data: data, Columns: syncActionColumns, Table: func(w io.Writer) {
+		printSyncResult(w, result, policy)
+	}})
+}
+
+// printSyncResult writes the table of displaySyncResult.
+func printSyncResult(w io.Writer, result *sync.Result, policy sync.ConflictPolicy) {
+	if len(result.Actions) == 0 {
+		fmt.Fprintln(w, "Everything is up to date.")
+		return
+	}
+
+	if result.DryRun {
+		fmt.Fprintln(w, "Dry run: the following actions would be performed.")
+	}
+	for _, a := range result.Actions {
+		target := a.Path
+		if a.From != "" {
+			target = a.From + " -> " + a.Path
+		}
+		if a.Type == sync.ActionConflict {
+			target += " (" + describeResolution(a) + ")"
+		}
+		if a.Err != nil {
+			fmt.Fprintf(w, "  %-14s %s (failed: %v)\n", a.Type, target, a.Err)
+			continue
+		}
+		fmt.Fprintf(w, "  %-14s %s\n", a.Type, target)
+	}
+
+	fmt.Fprintf(w, "\nSummary: %d uploaded, %d downloaded, %d moved, %d deleted, %d folder(s) created, %d conflict(s), %d failed\n",
+		result.Count(sync.ActionUpload),
+		result.Count(sync.ActionDownload),
+		result.Count(sync.ActionMoveLocal)+result.Count(sync.ActionMoveRemote),
+		result.Count(sync.ActionDeleteLocal)+result.Count(sync.ActionDeleteRemote),
+		result.Count(sync.ActionCreateLocalFolder)+result.Count(sync.ActionCreateRemoteFolder),
+		result.Count(sync.ActionConflict),
+		len(result.Failed()))
+
+	if conflicts := result.Conflicts(); len(conflicts) > 0 {
+		counts := make(map[sync.Resolution]int)
+		for _, c := range conflicts {
+			if c.Err == nil {
+				counts[c.Resolution]++
+			}
+		}
+		fmt.Fprintf(w, "Conflicts (policy %s): %d identical, %d kept local, %d kept remote, %d kept both, %d skipped\n",
+			policy,
+			counts[sync.ResolvedIdentical],
+			counts[sync.ResolvedKeptLocal],
+			counts[sync.ResolvedKeptRemote],
+			counts[sync.ResolvedKeptBoth],
+			counts[sync.ResolvedSkipped])
+	}
+}
+
+// describeResolution explains how a conflict action was, or would be, resolved.
+func describeResolution(a sync.Action) string {
+	switch a.Resolution {
+	case sync.ResolvedIdentical:
+		return "same content on both sides"
+	case sync.ResolvedKeptBoth:
+		return fmt.Sprintf("kept both, local version saved as '%s'", a.Copy)
+	case sync.ResolvedSkipped:
+		return "skipped, left untouched"
+	case "":
+		return "to be resolved interactively"
+	}
+	return string(a.Resolution)
+}
+
+// init registers the 'sync' command with the root command.
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.AddCommand(syncStatusCmd)
+	syncCmd.Flags().Bool("dry-run", false, "Show what would be done without changing anything")
+	syncCmd.Flags().String("conflict", string(sync.PolicyKeepBoth),
+		"How to resolve files changed on both sides: keep-both, prefer-local, prefer-remote, prefer-newer or interactive")
+}
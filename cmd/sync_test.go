@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/sync"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// useTempSyncStore points the sync state store at a temporary directory.
+func useTempSyncStore(t *testing.T) {
+	t.Helper()
+	t.Setenv("ONEDRIVE_CONFIG_PATH", filepath.Join(t.TempDir(), "config.json"))
+}
+
+func newSyncTestCmd() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("dry-run", false, "")
+	cmd.Flags().String("conflict", string(sync.PolicyKeepBoth), "")
+	cmd.SetContext(context.Background())
+	return cmd
+}
+
+func TestSyncLogic(t *testing.T) {
+	useTempSyncStore(t)
+	localDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "local.txt"), []byte("local"), 0600))
+
+	remoteFile := onedrive.DriveItem{ID: "r1", Name: "remote.txt", Size: 6, CTag: "c1", File: &onedrive.FileFacet{}}
+	remoteFile.ParentReference.ID = "root"
+
+	var uploaded, downloaded, tokens []string
+	mockSDK := &MockSDK{
+		GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+			return onedrive.DriveItem{ID: "root", Name: "Work", Folder: &onedrive.FolderFacet{}}, nil
+		},
+		GetDefaultDriveFunc: func(ctx context.Context) (onedrive.Drive, error) {
+			return onedrive.Drive{ID: "drive1"}, nil
+		},
+		GetDeltaFunc: func(ctx context.Context, deltaToken string) (onedrive.DeltaResponse, error) {
+			tokens = append(tokens, deltaToken)
+			if deltaToken != "" {
+				return onedrive.DeltaResponse{DeltaLink: "https://graph.microsoft.com/v1.0/me/drive/root/delta?token=def456"}, nil
+			}
+			return onedrive.DeltaResponse{
+				Value:     []onedrive.DriveItem{remoteFile},
+				DeltaLink: "https://graph.microsoft.com/v1.0/me/drive/root/delta?token=abc123",
+			}, nil
+		},
+		UploadFileFunc: func(ctx context.Context, localPath, remotePath string) (onedrive.DriveItem, error) {
+			uploaded = append(uploaded, remotePath)
+			return onedrive.DriveItem{ID: "l1", Name: "local.txt", CTag: "c2"}, nil
+		},
+		DownloadFileFunc: func(ctx context.Context, remotePath, localPath string) error {
+			downloaded = append(downloaded, remotePath)
+			return os.WriteFile(localPath, []byte("remote"), 0600)
+		},
+	}
+
+	output := captureOutput(t, func() {
+		err := syncLogic(newTestApp(mockSDK), newSyncTestCmd(), []string{localDir, "/Work"})
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, []string{"/Work/local.txt"}, uploaded)
+	assert.Equal(t, []string{"/Work/remote.txt"}, downloaded)
+	content, err := os.ReadFile(filepath.Join(localDir, "remote.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "remote", string(content))
+	assert.NoFileExists(t, filepath.Join(localDir, "remote.txt.onedrive-partial"))
+	assert.Contains(t, output, "1 uploaded, 1 downloaded")
+
+	// A second run resumes from the saved delta link and finds nothing to do.
+	output = captureOutput(t, func() {
+		err := syncLogic(newTestApp(mockSDK), newSyncTestCmd(), []string{localDir, "/Work"})
+		assert.NoError(t, err)
+	})
+	assert.Equal(t, []string{"", "abc123"}, tokens)
+	assert.Len(t, uploaded, 1)
+	assert.Len(t, downloaded, 1)
+	assert.Contains(t, output, "Everything is up to date.")
+
+	// The saved state can be inspected with 'sync status'.
+	store, err := sync.NewStore()
+	require.NoError(t, err)
+	output = captureOutput(t, func() {
+		err := syncStatusLogic(newTestApp(mockSDK), newSyncTestCmd(), store, nil)
+		assert.NoError(t, err)
+	})
+	assert.Contains(t, output, "/Work")
+	assert.Contains(t, output, localDir)
+
+	output = captureOutput(t, func() {
+		err := syncStatusLogic(newTestApp(mockSDK), newSyncTestCmd(), store, []string{localDir, "/Work"})
+		assert.NoError(t, err)
+	})
+	assert.Contains(t, output, "token=def456")
+	assert.Contains(t, output, "local.txt")
+	assert.Contains(t, output, "remote.txt")
+}
+
+func TestSyncLogicDryRun(t *testing.T) {
+	useTempSyncStore(t)
+	localDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "local.txt"), []byte("local"), 0600))
+
+	mockSDK := &MockSDK{
+		GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+			return onedrive.DriveItem{ID: "root", Folder: &onedrive.FolderFacet{}}, nil
+		},
+		UploadFileFunc: func(ctx context.Context, localPath, remotePath string) (onedrive.DriveItem, error) {
+			t.Fatal("dry run must not upload")
+			return onedrive.DriveItem{}, nil
+		},
+	}
+
+	cmd := newSyncTestCmd()
+	require.NoError(t, cmd.Flags().Set("dry-run", "true"))
+	output := captureOutput(t, func() {
+		err := syncLogic(newTestApp(mockSDK), cmd, []string{localDir, "/Work"})
+		assert.NoError(t, err)
+	})
+
+	assert.Contains(t, output, "Dry run")
+	assert.Contains(t, output, "local.txt")
+}
+
+func TestSyncLogicStructuredOutput(t *testing.T) {
+	useTempSyncStore(t)
+	localDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "local.txt"), []byte("local"), 0600))
+
+	mockSDK := &MockSDK{
+		GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+			return onedrive.DriveItem{ID: "root", Folder: &onedrive.FolderFacet{}}, nil
+		},
+	}
+
+	renderer, err := ui.NewRenderer(ui.FormatJSON, nil, "")
+	require.NoError(t, err)
+	ui.SetRenderer(renderer)
+	defer func() {
+		table, _ := ui.NewRenderer(ui.FormatTable, nil, "")
+		ui.SetRenderer(table)
+	}()
+
+	cmd := newSyncTestCmd()
+	require.NoError(t, cmd.Flags().Set("dry-run", "true"))
+	output := captureOutput(t, func() {
+		require.NoError(t, syncLogic(newTestApp(mockSDK), cmd, []string{localDir, "/Work"}))
+	})
+
+	var result syncResult
+	require.NoError(t, json.Unmarshal([]byte(output), &result), "the output is a single JSON document: %s", output)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, sync.PolicyKeepBoth, result.Policy)
+	assert.Equal(t, []syncAction{{Type: sync.ActionUpload, Path: "local.txt"}}, result.Value)
+	assert.NotContains(t, output, "Summary:", "no human summary in structured output")
+
+	store, err := sync.NewStore()
+	require.NoError(t, err)
+	output = captureOutput(t, func() {
+		require.NoError(t, syncStatusLogic(newTestApp(mockSDK), newSyncTestCmd(), store, nil))
+	})
+	assert.JSONEq(t, `{"value": []}`, output)
+
+	// A pair that was never synced has no state: null, not a sentence.
+	output = captureOutput(t, func() {
+		require.NoError(t, syncStatusLogic(newTestApp(mockSDK), newSyncTestCmd(), store, []string{localDir, "/Other"}))
+	})
+	assert.JSONEq(t, `null`, output)
+}
+
+func TestSyncLogicConflicts(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		input       string
+		remoteHash  string
+		expectUp    []string
+		expectDown  []string
+		expectLocal string
+		expectOut   string
+	}{
+		{
+			name:        "keep-both saves the local version as a conflict copy",
+			policy:      "keep-both",
+			expectUp:    []string{"conflict"},
+			expectDown:  []string{"/Work/a.txt"},
+			expectLocal: "remote!",
+			expectOut:   "1 kept both",
+		},
+		{
+			name:        "prefer-local uploads the local version",
+			policy:      "prefer-local",
+			expectUp:    []string{"/Work/a.txt"},
+			expectLocal: "local!",
+			expectOut:   "1 kept local",
+		},
+		{
+			name:        "prefer-remote downloads the remote version",
+			policy:      "prefer-remote",
+			expectDown:  []string{"/Work/a.txt"},
+			expectLocal: "remote!",
+			expectOut:   "1 kept remote",
+		},
+		{
+			name:        "prefer-newer keeps the later remote version",
+			policy:      "prefer-newer",
+			expectDown:  []string{"/Work/a.txt"},
+			expectLocal: "remote!",
+			expectOut:   "1 kept remote",
+		},
+		{
+			name:        "interactive asks which version to keep",
+			policy:      "interactive",
+			input:       "x\nl\n",
+			expectUp:    []string{"/Work/a.txt"},
+			expectLocal: "local!",
+			expectOut:   "Please answer",
+		},
+		{
+			name:        "interactive skip leaves both versions",
+			policy:      "interactive",
+			input:       "s\n",
+			expectLocal: "local!",
+			expectOut:   "1 skipped",
+		},
+		{
+			name:        "matching hashes need no transfer",
+			policy:      "prefer-remote",
+			remoteHash:  "250BC54C829AFAAC8D2CE523D06C2BA5D9FACD1F",
+			expectLocal: "local!",
+			expectOut:   "1 identical",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempSyncStore(t)
+			localDir := t.TempDir()
+			localFile := filepath.Join(localDir, "a.txt")
+			require.NoError(t, os.WriteFile(localFile, []byte("local!"), 0600))
+
+			remoteFile := onedrive.DriveItem{ID: "r1", Name: "a.txt", Size: 7, CTag: "c1", File: &onedrive.FileFacet{}}
+			remoteFile.ParentReference.ID = "root"
+			remoteFile.FileSystemInfo.LastModifiedDateTime = time.Now().Add(time.Hour)
+			if tt.remoteHash != "" {
+				remoteFile.Size = 6
+				remoteFile.File.Hashes = &onedrive.FileHashes{Sha1Hash: tt.remoteHash}
+			}
+
+			var uploaded, downloaded []string
+			mockSDK := &MockSDK{
+				GetDriveItemByPathFunc: func(ctx context.Context, path string) (onedrive.DriveItem, error) {
+					return onedrive.DriveItem{ID: "root", Folder: &onedrive.FolderFacet{}}, nil
+				},
+				GetDefaultDriveFunc: func(ctx context.Context) (onedrive.Drive, error) {
+					return onedrive.Drive{ID: "drive1"}, nil
+				},
+				GetDeltaFunc: func(ctx context.Context, deltaToken string) (onedrive.DeltaResponse, error) {
+					return onedrive.DeltaResponse{Value: []onedrive.DriveItem{remoteFile}, DeltaLink: "https://example.com/delta?token=t1"}, nil
+				},
+				UploadFileFunc: func(ctx context.Context, localPath, remotePath string) (onedrive.DriveItem, error) {
+					uploaded = append(uploaded, remotePath)
+					return onedrive.DriveItem{ID: "u" + remotePath, CTag: "c2"}, nil
+				},
+				DownloadFileFunc: func(ctx context.Context, remotePath, localPath string) error {
+					downloaded = append(downloaded, remotePath)
+					return os.WriteFile(localPath, []byte("remote!"), 0600)
+				},
+			}
+
+			cmd := newSyncTestCmd()
+			require.NoError(t, cmd.Flags().Set("conflict", tt.policy))
+			cmd.SetIn(strings.NewReader(tt.input))
+			output := captureOutput(t, func() {
+				err := syncLogic(newTestApp(mockSDK), cmd, []string{localDir, "/Work"})
+				assert.NoError(t, err)
+			})
+
+			assert.Len(t, uploaded, len(tt.expectUp))
+			for i, want := range tt.expectUp {
+				assert.Contains(t, uploaded[i], want)
+			}
+			assert.Equal(t, tt.expectDown, downloaded)
+			content, err := os.ReadFile(localFile)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectLocal, string(content))
+			assert.Contains(t, output, tt.expectOut)
+
+			if tt.policy == "keep-both" {
+				copies, err := filepath.Glob(filepath.Join(localDir, "a (conflict *).txt"))
+				require.NoError(t, err)
+				require.Len(t, copies, 1)
+				saved, err := os.ReadFile(copies[0])
+				require.NoError(t, err)
+				assert.Equal(t, "local!", string(saved))
+			}
+		})
+	}
+}
+
+func TestSyncLogicInvalidConflictPolicy(t *testing.T) {
+	cmd := newSyncTestCmd()
+	require.NoError(t, cmd.Flags().Set("conflict", "prefer-mine"))
+	err := syncLogic(newTestApp(&MockSDK{}), cmd, []string{t.TempDir(), "/Work"})
+	assert.ErrorContains(t, err, "unknown conflict policy")
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"log"
@@ -17,10 +18,12 @@ type MockSDK struct {
 	GetDrivesFunc                  func(ctx context.Context) (onedrive.DriveList, error)
 	GetDefaultDriveFunc            func(ctx context.Context) (onedrive.Drive, error)
 	GetMeFunc                      func(ctx context.Context) (onedrive.User, error)
+	AccessTokenFunc                func(ctx context.Context) (*onedrive.Token, error)
 	CreateFolderFunc               func(ctx context.Context, parentPath string, folderName string) (onedrive.DriveItem, error)
 	DownloadFileFunc               func(ctx context.Context, remotePath, localPath string) error
 	DownloadFileAsFormatFunc       func(ctx context.Context, remotePath, localPath, format string) error
 	DownloadFileChunkFunc          func(ctx context.Context, url string, startByte, endByte int64) (io.ReadCloser, error)
+	DownloadFileParallelFunc       func(ctx context.Context, remotePath, localPath string, connections int, progress onedrive.ProgressFunc) error
 	GetDriveItemByPathFunc         func(ctx context.Context, path string) (onedrive.DriveItem, error)
 	GetDriveItemChildrenByPathFunc func(ctx context.Context, path string) (onedrive.DriveItemList, error)
 	CreateUploadSessionFunc        func(ctx context.Context, remotePath string) (onedrive.UploadSession, error)
@@ -55,6 +58,8 @@ type MockSDK struct {
 	GetPermissionFunc      func(ctx context.Context, remotePath, permissionID string) (onedrive.Permission, error)
 	UpdatePermissionFunc   func(ctx context.Context, remotePath, permissionID string, request onedrive.UpdatePermissionRequest) (onedrive.Permission, error)
 	DeletePermissionFunc   func(ctx context.Context, remotePath, permissionID string) error
+	RawFunc                func(ctx context.Context, req onedrive.RawRequest) (onedrive.RawResponse, error)
+	RawAllPagesFunc        func(ctx context.Context, req onedrive.RawRequest) (json.RawMessage, error)
 }
 
 func (m *MockSDK) GetDrives(ctx context.Context) (onedrive.DriveList, error) {
@@ -78,6 +83,13 @@ func (m *MockSDK) GetMe(ctx context.Context) (onedrive.User, error) {
 	return onedrive.User{}, nil
 }
 
+func (m *MockSDK) AccessToken(ctx context.Context) (*onedrive.Token, error) {
+	if m.AccessTokenFunc != nil {
+		return m.AccessTokenFunc(ctx)
+	}
+	return &onedrive.Token{}, nil
+}
+
 func (m *MockSDK) CreateFolder(ctx context.Context, parentPath string, folderName string) (onedrive.DriveItem, error) {
 	if m.CreateFolderFunc != nil {
 		return m.CreateFolderFunc(ctx, parentPath, folderName)
@@ -106,6 +118,13 @@ func (m *MockSDK) DownloadFileChunk(ctx context.Context, url string, startByte,
 	return nil, errors.New("not implemented")
 }
 
+func (m *MockSDK) DownloadFileParallel(ctx context.Context, remotePath, localPath string, connections int, progress onedrive.ProgressFunc) error {
+	if m.DownloadFileParallelFunc != nil {
+		return m.DownloadFileParallelFunc(ctx, remotePath, localPath, connections, progress)
+	}
+	return nil
+}
+
 func (m *MockSDK) GetDriveItemByPath(ctx context.Context, path string) (onedrive.DriveItem, error) {
 	if m.GetDriveItemByPathFunc != nil {
 		return m.GetDriveItemByPathFunc(ctx, path)
@@ -301,6 +320,20 @@ func (m *MockSDK) GetThumbnailBySize(ctx context.Context, remotePath, thumbID, s
 	return onedrive.Thumbnail{}, nil
 }
 
+func (m *MockSDK) Raw(ctx context.Context, req onedrive.RawRequest) (onedrive.RawResponse, error) {
+	if m.RawFunc != nil {
+		return m.RawFunc(ctx, req)
+	}
+	return onedrive.RawResponse{}, nil
+}
+
+func (m *MockSDK) RawAllPages(ctx context.Context, req onedrive.RawRequest) (json.RawMessage, error) {
+	if m.RawAllPagesFunc != nil {
+		return m.RawAllPagesFunc(ctx, req)
+	}
+	return json.RawMessage(`{"value":[]}`), nil
+}
+
 func (m *MockSDK) PreviewItem(ctx context.Context, remotePath string, request onedrive.PreviewRequest) (onedrive.PreviewResponse, error) {
 	if m.PreviewItemFunc != nil {
 		return m.PreviewItemFunc(ctx, remotePath, request)
@@ -0,0 +1,136 @@
+// Package cmd (watch.go) defines the 'watch' command, which pushes local
+// filesystem changes to a OneDrive folder as they happen.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/internal/sync"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+)
+
+// watchCmd handles 'watch <local-dir> <remote-dir>'.
+// It mirrors local creates, writes, renames and deletes to OneDrive until interrupted.
+var watchCmd = &cobra.Command{
+	Use:   "watch <local-dir> <remote-dir>",
+	Short: "Push local changes to a OneDrive folder as they happen",
+	Long: `Watches a local directory and mirrors every change to a OneDrive folder until interrupted
+with Ctrl+C. New and modified files are uploaded (large files with resumable chunked uploads),
+new folders are created, deletions are replayed, and renames and moves become server-side moves
+instead of re-uploads.
+
+Changes are pushed once the directory has been quiet for the --debounce interval, so a burst of
+writes from an editor or build produces a single upload. Only changes made while watching are
+pushed and remote changes are not pulled: run 'sync' first if the folders may differ.`,
+	Example: `onedrive-client watch ./build/artifacts /Builds/nightly
+onedrive-client watch ~/Notes /Notes --debounce 5s`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := app.NewApp(cmd)
+		if err != nil {
+			return fmt.Errorf("initializing app for 'watch': %w", err)
+		}
+		return watchLogic(a, cmd, args)
+	},
+}
+
+// watchLogic contains the core logic for the 'watch' command.
+func watchLogic(a *app.App, cmd *cobra.Command, args []string) error {
+	if len(args) < 2 { // Should be caught by Args validation.
+		return fmt.Errorf("both local directory and remote folder are required for 'watch'")
+	}
+	debounce, err := cmd.Flags().GetDuration("debounce")
+	if err != nil {
+		return fmt.Errorf("parsing '--debounce' flag: %w", err)
+	}
+	if debounce <= 0 {
+		return fmt.Errorf("--debounce must be positive, got %s", debounce)
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("parsing '--dry-run' flag: %w", err)
+	}
+
+	localRoot, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("resolving local directory '%s': %w", args[0], err)
+	}
+	remoteRoot := args[1]
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sessions, err := session.NewManager()
+	if err != nil {
+		return fmt.Errorf("creating session manager for 'watch': %w", err)
+	}
+
+	engine := sync.NewEngine(a.SDK, sync.Options{
+		LocalRoot:  localRoot,
+		RemoteRoot: remoteRoot,
+		DryRun:     dryRun,
+		Sessions:   sessions,
+	})
+	out := cmd.OutOrStdout()
+	// Structured output is one document per action, so the messages for
+	// people are left out of it.
+	if !ui.Structured() {
+		fmt.Fprintf(out, "Watching '%s' and pushing changes to '%s'. Press Ctrl+C to stop.\n", localRoot, remoteRoot)
+	}
+	report := func(action sync.Action) {
+		if err := displayWatchAction(out, action, time.Now()); err != nil {
+			log.Printf("Reporting %s of '%s': %v", action.Type, action.Path, err)
+		}
+	}
+	if err := engine.Watch(ctx, debounce, report); err != nil {
+		return fmt.Errorf("watching '%s': %w", localRoot, err)
+	}
+	if !ui.Structured() {
+		fmt.Fprintln(out, "Stopped watching.")
+	}
+	return nil
+}
+
+// watchAction is the structured form of an action pushed by 'watch'.
+type watchAction struct {
+	Time time.Time `json:"time"`
+	syncAction
+}
+
+// watchActionColumns are the default CSV columns of the actions pushed by 'watch'.
+var watchActionColumns = append([]string{"time"}, syncActionColumns...)
+
+// displayWatchAction writes one action pushed by 'watch' at `now` to `w`,
+// with a timestamp.
+func displayWatchAction(w io.Writer, a sync.Action, now time.Time) error {
+	data := watchAction{Time: now, syncAction: newSyncAction(a)}
+	return ui.Render(w, ui.Result{Data: data, Columns: watchActionColumns, Table: func(w io.Writer) {
+		target := a.Path
+		if a.From != "" {
+			target = a.From + " -> " + a.Path
+		}
+		stamp := now.Format(time.TimeOnly)
+		if a.Err != nil {
+			fmt.Fprintf(w, "[%s] %-14s %s (failed: %v)\n", stamp, a.Type, target, a.Err)
+			return
+		}
+		fmt.Fprintf(w, "[%s] %-14s %s\n", stamp, a.Type, target)
+	}})
+}
+
+// init registers the 'watch' command with the root command.
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().Duration("debounce", sync.DefaultDebounce, "How long the directory must be quiet before changes are pushed")
+	watchCmd.Flags().Bool("dry-run", false, "Show what would be pushed without changing anything")
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/sync"
+)
+
+func newWatchTestCmd(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Duration("debounce", sync.DefaultDebounce, "")
+	cmd.Flags().Bool("dry-run", false, "")
+	cmd.SetContext(ctx)
+	return cmd
+}
+
+func TestWatchLogic(t *testing.T) {
+	t.Run("rejects a non-positive debounce", func(t *testing.T) {
+		cmd := newWatchTestCmd(context.Background())
+		require.NoError(t, cmd.Flags().Set("debounce", "0s"))
+		err := watchLogic(newTestApp(&MockSDK{}), cmd, []string{t.TempDir(), "/Work"})
+		assert.ErrorContains(t, err, "--debounce must be positive")
+	})
+
+	t.Run("returns when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		output := captureOutput(t, func() {
+			err := watchLogic(newTestApp(&MockSDK{}), newWatchTestCmd(ctx), []string{t.TempDir(), "/Work"})
+			assert.NoError(t, err)
+		})
+		assert.Contains(t, output, "Watching")
+		assert.Contains(t, output, "Stopped watching.")
+	})
+}
+
+func TestDisplayWatchAction(t *testing.T) {
+	var output bytes.Buffer
+	require.NoError(t, displayWatchAction(&output, sync.Action{Type: sync.ActionMoveRemote, From: "a.txt", Path: "d/a.txt"}, time.Now()))
+	require.NoError(t, displayWatchAction(&output, sync.Action{Type: sync.ActionUpload, Path: "b.txt", Err: errors.New("boom")}, time.Now()))
+	assert.Contains(t, output.String(), "move-remote")
+	assert.Contains(t, output.String(), "a.txt -> d/a.txt")
+	assert.Contains(t, output.String(), "b.txt (failed: boom)")
+}
@@ -27,8 +27,11 @@ var ErrLoginPending = errors.New("login pending")
 // App encapsulates the core application state, including configuration
 // and the OneDrive SDK client.
 type App struct {
-	Config *config.Configuration // Loaded application configuration (tokens, debug settings).
-	SDK    SDK                   // Interface to the OneDrive SDK for making API calls.
+	Config  *config.Configuration // Loaded application configuration (tokens, debug settings).
+	SDK     SDK                   // Interface to the OneDrive SDK for making API calls.
+	DriveID string                // Drive selected with --drive; empty for the user's default drive.
+
+	auth onedrive.AuthConfig // Client ID, endpoints and scopes the profile signs in with.
 }
 
 // NewApp creates and initializes a new App instance.
@@ -37,10 +40,23 @@ type App struct {
 // is handling any pending OAuth device code flow authentications.
 //
 // The `cmd` parameter is the currently executing Cobra command, used here to
-// access global flags like --debug.
+// access global flags like --debug and --drive. The configuration profile is
+// the active one (see config.ActiveProfile).
 func NewApp(cmd *cobra.Command) (*App, error) {
+	return NewAppForProfile(cmd, "")
+}
+
+// NewAppForProfile is NewApp for the configuration profile called `profile`,
+// regardless of the profile selected with --profile. An empty name selects the
+// active profile.
+func NewAppForProfile(cmd *cobra.Command, profile string) (*App, error) {
+	// Commands run by the interactive shell reuse its App and client.
+	if shared := sharedApp(cmd, profile); shared != nil {
+		return shared, nil
+	}
+
 	// Load existing configuration or create a new default one.
-	cfg, err := config.LoadOrCreate()
+	cfg, err := config.LoadProfileOrCreate(profile)
 	if err != nil {
 		return nil, fmt.Errorf("loading application configuration: %w", err)
 	}
@@ -52,8 +68,20 @@ func NewApp(cmd *cobra.Command) (*App, error) {
 		cfg.Debug = true
 	}
 
+	// The --drive flag binds all drive-scoped SDK calls to a drive other than the
+	// user's default one, such as a SharePoint document library.
+	driveID, _ := cmd.Flags().GetString("drive")
+
+	// Resolve the cloud, tenant and scopes the profile signs in with.
+	auth, err := SDKAuthConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	app := &App{
-		Config: cfg,
+		Config:  cfg,
+		DriveID: driveID,
+		auth:    auth,
 	}
 
 	// Initialize the OneDrive SDK. This step also handles authentication.
@@ -72,6 +100,49 @@ func NewApp(cmd *cobra.Command) (*App, error) {
 	return app, nil
 }
 
+// appKey is the context key under which NewContext stores an App.
+type appKey struct{}
+
+// NewContext returns a copy of `ctx` that carries `a`. NewApp returns `a` for
+// commands executed with this context instead of loading the configuration
+// and creating a client again, as long as they ask for the same profile,
+// drive and debug mode. The interactive shell uses this to keep one
+// authenticated client for all the commands it runs.
+func NewContext(ctx context.Context, a *App) context.Context {
+	return context.WithValue(ctx, appKey{}, a)
+}
+
+// FromContext returns the App carried by `ctx`, or nil.
+func FromContext(ctx context.Context) *App {
+	if ctx == nil {
+		return nil
+	}
+	a, _ := ctx.Value(appKey{}).(*App)
+	return a
+}
+
+// sharedApp returns the App carried by the context of `cmd` if it serves the
+// profile, drive and debug mode that `cmd` asks for, and nil otherwise.
+func sharedApp(cmd *cobra.Command, profile string) *App {
+	shared := FromContext(cmd.Context())
+	if shared == nil || shared.Config == nil {
+		return nil
+	}
+	if profile == "" {
+		active, err := config.ActiveProfile()
+		if err != nil {
+			return nil
+		}
+		profile = active
+	}
+	driveID, _ := cmd.Flags().GetString("drive")
+	debug, _ := cmd.Flags().GetBool("debug")
+	if profile != shared.Config.Profile() || driveID != shared.DriveID || (debug && !shared.Config.Debug) {
+		return nil
+	}
+	return shared
+}
+
 // initializeOnedriveSDK sets up the OneDrive SDK client.
 // It checks for a pending device code authentication session and attempts to complete it.
 // If no pending session and no existing token, it returns ErrReauthRequired.
@@ -83,8 +154,24 @@ func (a *App) initializeOnedriveSDK() (SDK, error) {
 		return nil, errors.New("app configuration is nil during SDK initialization")
 	}
 
-	// Create session manager for auth state management
-	sessionMgr, err := session.NewManager()
+	// Credentials supplied through the environment take the place of the
+	// token in the configuration file, and leave the file untouched.
+	creds, err := config.EnvCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials from environment: %w", err)
+	}
+	if creds != nil {
+		return a.initializeFromCredentials(creds)
+	}
+
+	// An encrypted token is decrypted once here; from then on Save encrypts
+	// the token again whenever onNewToken below persists a refreshed one.
+	if err := UnlockToken(a.Config); err != nil {
+		return nil, err
+	}
+
+	// Create session manager for auth state management, in the profile's own directory.
+	sessionMgr, err := session.NewManagerForProfile(a.Config.Profile())
 	if err != nil {
 		return nil, fmt.Errorf("creating session manager: %w", err)
 	}
@@ -102,7 +189,7 @@ func (a *App) initializeOnedriveSDK() (SDK, error) {
 	if pendingAuth != nil {
 		// A pending session exists. Attempt to exchange the device_code for an OAuth token.
 		a.Config.DebugPrintln("Pending authentication session found. Attempting to verify device code...")
-		token, err := onedrive.VerifyDeviceCode(config.ClientID, pendingAuth.DeviceCode, a.Config.Debug)
+		token, err := onedrive.VerifyDeviceCode(a.auth, pendingAuth.DeviceCode, a.Config.Debug)
 		if err != nil {
 			// If authorization is still pending (user hasn't entered code in browser).
 			if errors.Is(err, onedrive.ErrAuthorizationPending) {
@@ -155,28 +242,63 @@ func (a *App) initializeOnedriveSDK() (SDK, error) {
 		return nil
 	}
 
-	// Configure a logger for the SDK. If debug mode is enabled in the app config,
-	// use a structured logger; otherwise, the SDK will use its DefaultLogger (no-op).
-	var sdkLogger onedrive.Logger
+	// Create the OneDrive SDK client instance using the current token (which might be newly acquired or loaded),
+	// the application's client ID, the token refresh callback, the logger, HTTP configuration, and the
+	// profile's endpoints and scopes.
+	// The context.Background() is used for the token source operations within the client.
+	client := onedrive.NewClientWithConfig(context.Background(), &a.Config.Token, a.auth.ClientID, onNewToken, a.sdkLogger(), a.sdkHTTPConfig(), a.sdkClientOptions()...)
+	return a.bindDrive(client), nil
+}
+
+// sdkClientOptions returns the options that point SDK clients at the
+// profile's endpoints and scopes, and create downloads with its permissions.
+func (a *App) sdkClientOptions() []onedrive.ClientOption {
+	return []onedrive.ClientOption{
+		onedrive.WithEndpoints(a.auth.Endpoints),
+		onedrive.WithScopes(a.auth.Scopes),
+		onedrive.WithFilePermissions(a.Config.Download.FilePermissions),
+	}
+}
+
+// sdkLogger returns the logger for the SDK. If debug mode is enabled in the app config,
+// use a structured logger; otherwise, the SDK will use its DefaultLogger (no-op).
+func (a *App) sdkLogger() onedrive.Logger {
 	if a.Config.Debug {
-		sdkLogger = logger.NewDefaultLogger(true) // Debug level logging
 		a.Config.DebugPrintln("Debug mode enabled, SDK logging will be active.")
-	} else {
-		sdkLogger = logger.NewDefaultLogger(false) // Info level logging
+		return logger.NewDefaultLogger(true) // Debug level logging
 	}
+	return logger.NewDefaultLogger(false) // Info level logging
+}
 
-	// Create the OneDrive SDK client instance using the current token (which might be newly acquired or loaded),
-	// the application's client ID, the token refresh callback, the logger, and HTTP configuration.
-	// The context.Background() is used for the token source operations within the client.
-	httpConfig := onedrive.HTTPConfig{
+// sdkHTTPConfig returns the SDK's HTTP configuration from the app config.
+func (a *App) sdkHTTPConfig() onedrive.HTTPConfig {
+	return onedrive.HTTPConfig{
 		Timeout:       a.Config.HTTP.Timeout,
 		RetryAttempts: a.Config.HTTP.RetryAttempts,
 		RetryDelay:    a.Config.HTTP.RetryDelay,
 		MaxRetryDelay: a.Config.HTTP.MaxRetryDelay,
 	}
-	client := onedrive.NewClientWithConfig(context.Background(), &a.Config.Token, config.ClientID, onNewToken, sdkLogger, httpConfig)
+}
+
+// bindDrive binds `client` to the drive selected with --drive, if any.
+func (a *App) bindDrive(client *onedrive.Client) *onedrive.Client {
+	if a.DriveID != "" {
+		client = client.WithDrive(a.DriveID)
+		a.Config.DebugPrintf("OneDrive SDK client bound to drive %s.", a.DriveID)
+	}
 	a.Config.DebugPrintln("OneDrive SDK client initialized.")
-	return client, nil
+	return client
+}
+
+// SDKAuthConfig returns the client ID, endpoints and scopes that the profile
+// of `cfg` signs in with, for the SDK's sign-in functions.
+func SDKAuthConfig(cfg *config.Configuration) (onedrive.AuthConfig, error) {
+	auth, err := cfg.Auth.SDKConfig()
+	if err != nil {
+		return auth, fmt.Errorf("invalid sign-in settings for profile '%s': %w", cfg.Profile(), err)
+	}
+	cfg.DebugPrintf("Using sign-in endpoint %s and Graph endpoint %s.", auth.Endpoints.TokenURL, auth.Endpoints.GraphURL)
+	return auth, nil
 }
 
 // GetMe fetches the profile information of the currently authenticated user.
@@ -196,12 +318,12 @@ func Logout(cfg *config.Configuration) error {
 	}
 
 	// Create session manager for auth state management
-	sessionMgr, err := session.NewManager()
+	sessionMgr, err := session.NewManagerForProfile(cfg.Profile())
 	if err != nil {
 		return fmt.Errorf("creating session manager for logout: %w", err)
 	}
 
-	cfg.Token = onedrive.Token{} // Clear the token fields.
+	cfg.ClearToken() // Clear the token fields, and the token's encryption if any.
 	if err := cfg.Save(); err != nil {
 		// Even if saving fails, proceed to delete auth session to ensure a clean state.
 		log.Printf("Warning: could not clear token from config during logout: %v. Attempting to delete auth session anyway.", err)
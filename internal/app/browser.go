@@ -0,0 +1,81 @@
+// Package app (browser.go) implements interactive login through the user's web
+// browser, using the OAuth2 Authorization Code Grant with PKCE and a loopback
+// redirect listener. It is an alternative to the Device Code Flow for tenants
+// whose conditional access policies block device code sign-in.
+package app
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// LoginWithBrowser signs the user in with the Authorization Code Grant and
+// stores the resulting token in `cfg`. It listens for the authorization
+// redirect on a localhost port, passes the authorization URL to `openURL`
+// (which should open it in a browser, or show it to the user), and waits
+// until the browser is redirected back or `ctx` ends.
+func LoginWithBrowser(ctx context.Context, cfg *config.Configuration, openURL func(authURL string) error) error {
+	state, err := onedrive.NewAuthState()
+	if err != nil {
+		return err
+	}
+	redirect, err := onedrive.ListenLoopback(state)
+	if err != nil {
+		return err
+	}
+	defer redirect.Close()
+
+	auth, err := SDKAuthConfig(cfg)
+	if err != nil {
+		return err
+	}
+	_, oauthConfig := onedrive.GetOauth2Config(auth)
+	oauthConfig.RedirectURL = redirect.RedirectURL()
+	authURL, verifier, err := onedrive.StartAuthenticationWithState(ctx, oauthConfig, state)
+	if err != nil {
+		return fmt.Errorf("building authorization URL: %w", err)
+	}
+	cfg.DebugPrintf("Waiting for authorization redirect on %s", oauthConfig.RedirectURL)
+	if err := openURL(authURL); err != nil {
+		return err
+	}
+
+	code, err := redirect.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("receiving authorization code: %w", err)
+	}
+	token, err := onedrive.CompleteAuthentication(ctx, oauthConfig, code, verifier)
+	if err != nil {
+		return err
+	}
+
+	cfg.Token = *token
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("saving newly acquired token failed: %w", err)
+	}
+	return nil
+}
+
+// OpenBrowser opens `url` in the user's default web browser.
+func OpenBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("opening web browser: %w", err)
+	}
+	// The opener exits as soon as it has handed the URL over; reap it in the background.
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package app (credentials.go) initializes the SDK from credentials supplied
+// through the environment (see config.EnvCredentials), for headless use such
+// as CI pipelines. Tokens acquired this way are kept in memory only; neither
+// the configuration file nor the session directory is written.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// ErrDriveRequired is returned when app-only credentials are used without
+// selecting a drive, since an application has no drive of its own.
+var ErrDriveRequired = errors.New("app-only credentials require a drive; pass --drive or set " + config.EnvDriveID)
+
+// initializeFromCredentials creates an SDK client that authenticates with
+// `creds`: a user's refresh token, or the client secret or certificate of an
+// app registration for app-only access.
+func (a *App) initializeFromCredentials(creds *config.Credentials) (SDK, error) {
+	if a.DriveID == "" {
+		a.DriveID = creds.DriveID
+	}
+
+	if !creds.AppOnly() {
+		a.Config.DebugPrintln("Authenticating with refresh token from environment.")
+		clientID := creds.ClientID
+		if clientID == "" {
+			clientID = a.auth.ClientID
+		}
+		token := &onedrive.Token{RefreshToken: creds.RefreshToken}
+		// No refresh callback: the refreshed token is not persisted.
+		client := onedrive.NewClientWithConfig(context.Background(), token, clientID, nil, a.sdkLogger(), a.sdkHTTPConfig(), a.sdkClientOptions()...)
+		return a.bindDrive(client), nil
+	}
+
+	a.Config.DebugPrintln("Authenticating with app-only client credentials from environment.")
+	if a.DriveID == "" {
+		return nil, ErrDriveRequired
+	}
+	clientCreds := onedrive.ClientCredentials{
+		TenantID:     creds.TenantID,
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		Endpoints:    a.auth.Endpoints,
+	}
+	if creds.ClientCertificate != "" {
+		data, err := os.ReadFile(creds.ClientCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("reading client certificate '%s': %w", creds.ClientCertificate, err)
+		}
+		clientCreds.Certificate, clientCreds.PrivateKey, err = onedrive.ParseCertificatePEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate '%s': %w", creds.ClientCertificate, err)
+		}
+	}
+	source, err := onedrive.NewClientCredentialsTokenSource(context.Background(), clientCreds)
+	if err != nil {
+		return nil, err
+	}
+	client := onedrive.NewClientWithTokenSource(context.Background(), source, a.sdkLogger(), a.sdkHTTPConfig(), a.sdkClientOptions()...)
+	return a.bindDrive(client), nil
+}
@@ -9,6 +9,7 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 
 	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
@@ -25,6 +26,7 @@ import (
 type SDK interface {
 	// User and Drive Information
 	GetMe(ctx context.Context) (onedrive.User, error)
+	AccessToken(ctx context.Context) (*onedrive.Token, error) // Valid access token, refreshed if expired.
 	GetDrives(ctx context.Context) (onedrive.DriveList, error)
 	GetDefaultDrive(ctx context.Context) (onedrive.Drive, error)
 	GetDriveByID(ctx context.Context, driveID string) (onedrive.Drive, error)
@@ -53,6 +55,7 @@ type SDK interface {
 	DownloadFile(ctx context.Context, remotePath, localPath string) error
 	DownloadFileAsFormat(ctx context.Context, remotePath, localPath, format string) error
 	DownloadFileChunk(ctx context.Context, url string, startByte, endByte int64) (io.ReadCloser, error)
+	DownloadFileParallel(ctx context.Context, remotePath, localPath string, connections int, progress onedrive.ProgressFunc) error
 
 	// Search
 	SearchDriveItems(ctx context.Context, query string) (onedrive.DriveItemList, error) // Deprecated in favor of paginated version.
@@ -82,4 +85,8 @@ type SDK interface {
 	GetThumbnails(ctx context.Context, remotePath string) (onedrive.ThumbnailSetList, error)
 	GetThumbnailBySize(ctx context.Context, remotePath, thumbID, size string) (onedrive.Thumbnail, error)
 	PreviewItem(ctx context.Context, remotePath string, request onedrive.PreviewRequest) (onedrive.PreviewResponse, error)
+
+	// Raw Microsoft Graph Requests
+	Raw(ctx context.Context, req onedrive.RawRequest) (onedrive.RawResponse, error)
+	RawAllPages(ctx context.Context, req onedrive.RawRequest) (json.RawMessage, error) // Items of all pages, as {"value": [...]}.
 }
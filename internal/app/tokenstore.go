@@ -0,0 +1,102 @@
+// Package app (tokenstore.go) obtains the secret of an encrypted token (see
+// config.EncryptedToken): the key file recorded in the profile or named by
+// ONEDRIVE_TOKEN_KEY_FILE, or a passphrase from ONEDRIVE_TOKEN_PASSPHRASE or,
+// on a terminal, typed by the user.
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tonimelisma/onedrive-client/internal/config"
+	"golang.org/x/term"
+)
+
+// ErrPassphraseMismatch is returned when a new passphrase and its
+// confirmation differ.
+var ErrPassphraseMismatch = errors.New("passphrases do not match")
+
+// ReadPassphrase prompts for a passphrase without echoing it. It is a
+// variable so that tests can replace the terminal.
+var ReadPassphrase = readPassphraseFromTerminal
+
+// readPassphraseFromTerminal reads a passphrase from the terminal on stdin,
+// showing `prompt` on stderr.
+func readPassphraseFromTerminal(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%w: set %s to supply the passphrase without a terminal", config.ErrTokenLocked, config.EnvTokenPassphrase)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// UnlockToken decrypts the token of `cfg` if it is stored encrypted, asking
+// for the passphrase if needed.
+func UnlockToken(cfg *config.Configuration) error {
+	if !cfg.TokenLocked() {
+		return nil
+	}
+	var secret config.TokenSecret
+	switch kdf, _ := cfg.TokenKDF(); kdf {
+	case config.KDFKeyFile:
+		secret.KeyFile = os.Getenv(config.EnvTokenKeyFile)
+	default:
+		passphrase, err := passphraseFromEnvOrPrompt(fmt.Sprintf("Passphrase for profile '%s': ", cfg.Profile()))
+		if err != nil {
+			return err
+		}
+		secret.Passphrase = passphrase
+	}
+	if err := cfg.UnlockToken(secret); err != nil {
+		return fmt.Errorf("unlocking token of profile '%s': %w", cfg.Profile(), err)
+	}
+	cfg.DebugPrintln("Encrypted token unlocked.")
+	return nil
+}
+
+// PassphraseRequired reports whether UnlockToken would prompt for the
+// passphrase of `cfg`'s token: it is locked with a passphrase, and
+// ONEDRIVE_TOKEN_PASSPHRASE is not set.
+func PassphraseRequired(cfg *config.Configuration) bool {
+	if !cfg.TokenLocked() || os.Getenv(config.EnvTokenPassphrase) != "" {
+		return false
+	}
+	kdf, _ := cfg.TokenKDF()
+	return kdf != config.KDFKeyFile
+}
+
+// NewPassphrase returns the passphrase to encrypt a token with: the one in
+// ONEDRIVE_TOKEN_PASSPHRASE, or one typed twice by the user.
+func NewPassphrase(profile string) (string, error) {
+	if passphrase := os.Getenv(config.EnvTokenPassphrase); passphrase != "" {
+		return passphrase, nil
+	}
+	passphrase, err := ReadPassphrase(fmt.Sprintf("New passphrase for profile '%s': ", profile))
+	if err != nil {
+		return "", err
+	}
+	confirmation, err := ReadPassphrase("Repeat the passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != confirmation {
+		return "", ErrPassphraseMismatch
+	}
+	return passphrase, nil
+}
+
+// passphraseFromEnvOrPrompt returns ONEDRIVE_TOKEN_PASSPHRASE if set, and
+// otherwise prompts for the passphrase with `prompt`.
+func passphraseFromEnvOrPrompt(prompt string) (string, error) {
+	if passphrase := os.Getenv(config.EnvTokenPassphrase); passphrase != "" {
+		return passphrase, nil
+	}
+	return ReadPassphrase(prompt)
+}
@@ -0,0 +1,91 @@
+// Package config (auth.go) holds the per-profile sign-in settings: the app
+// registration to sign in with, the tenant and Microsoft cloud to sign in to,
+// and the scopes to request. All settings are optional; by default the
+// application's own public client ID signs in to the global cloud's "common"
+// tenant with read and write access.
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// AuthConfig holds the sign-in settings of a profile.
+type AuthConfig struct {
+	ClientID string   `json:"client_id,omitempty"` // Application (client) ID of the app registration; defaults to ClientID.
+	Tenant   string   `json:"tenant,omitempty"`    // "common", "organizations", "consumers", a tenant ID or a domain; defaults to "common".
+	Cloud    string   `json:"cloud,omitempty"`     // Microsoft cloud, see onedrive.CloudNames; defaults to "global".
+	Scopes   []string `json:"scopes,omitempty"`    // Scopes to request; defaults to onedrive.DefaultScopes.
+	ReadOnly bool     `json:"read_only,omitempty"` // Request onedrive.ReadOnlyScopes instead of the default scopes.
+
+	// Endpoints overrides individual endpoints of the cloud and tenant, for
+	// example to go through a proxy or to a test server.
+	Endpoints *onedrive.Endpoints `json:"endpoints,omitempty"`
+}
+
+// Validate checks that the settings are consistent and name a known cloud.
+func (a AuthConfig) Validate() error {
+	if _, err := onedrive.LookupCloud(a.Cloud); err != nil {
+		return err
+	}
+	if a.ReadOnly && len(a.Scopes) > 0 {
+		return fmt.Errorf("%w: read-only mode and explicit scopes cannot be combined", onedrive.ErrInvalidRequest)
+	}
+	if strings.ContainsAny(a.Tenant, "/?#") {
+		return fmt.Errorf("%w: invalid tenant '%s'", onedrive.ErrInvalidRequest, a.Tenant)
+	}
+	return nil
+}
+
+// ClientIDOrDefault returns the client ID to sign in with.
+func (a AuthConfig) ClientIDOrDefault() string {
+	if a.ClientID != "" {
+		return a.ClientID
+	}
+	return ClientID
+}
+
+// ScopesOrDefault returns the scopes to request.
+func (a AuthConfig) ScopesOrDefault() []string {
+	switch {
+	case len(a.Scopes) > 0:
+		return a.Scopes
+	case a.ReadOnly:
+		return onedrive.ReadOnlyScopes()
+	default:
+		return onedrive.DefaultScopes()
+	}
+}
+
+// ResolveEndpoints returns the sign-in and Microsoft Graph endpoints of the
+// configured cloud and tenant, with any overrides applied.
+func (a AuthConfig) ResolveEndpoints() (onedrive.Endpoints, error) {
+	cloud, err := onedrive.LookupCloud(a.Cloud)
+	if err != nil {
+		return onedrive.Endpoints{}, err
+	}
+	endpoints := cloud.Endpoints(a.Tenant)
+	if a.Endpoints != nil {
+		endpoints = endpoints.Override(*a.Endpoints)
+	}
+	return endpoints, nil
+}
+
+// SDKConfig validates the settings and returns them in the form the SDK's
+// sign-in functions take.
+func (a AuthConfig) SDKConfig() (onedrive.AuthConfig, error) {
+	if err := a.Validate(); err != nil {
+		return onedrive.AuthConfig{}, err
+	}
+	endpoints, err := a.ResolveEndpoints()
+	if err != nil {
+		return onedrive.AuthConfig{}, err
+	}
+	return onedrive.AuthConfig{
+		ClientID:  a.ClientIDOrDefault(),
+		Endpoints: endpoints,
+		Scopes:    a.ScopesOrDefault(),
+	}, nil
+}
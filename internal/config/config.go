@@ -96,6 +96,8 @@ func DefaultPollingConfig() PollingConfig {
 
 // Configuration holds all the application's persisted settings.
 // It includes the OAuth token for accessing OneDrive and a flag for enabling debug mode.
+// The token and the HTTP, polling and download settings are those of a single
+// profile (see profile.go); Save writes them back to that profile only.
 // A RWMutex is used to ensure thread-safe access and modification, especially during Save.
 type Configuration struct {
 	Token    onedrive.Token `json:"token"`    // OAuth2 token (access, refresh, expiry).
@@ -103,7 +105,16 @@ type Configuration struct {
 	HTTP     HTTPConfig     `json:"http"`     // HTTP client configuration
 	Polling  PollingConfig  `json:"polling"`  // Polling configuration for async operations
 	Download DownloadConfig `json:"download"` // Download file permissions configuration
+	Auth     AuthConfig     `json:"auth"`     // Sign-in settings: client ID, tenant, cloud and scopes
 	mu       sync.RWMutex   // Protects concurrent access to the Configuration struct, particularly for Save.
+
+	profile        string             // Profile the settings above belong to; empty for the default profile.
+	currentProfile string             // Profile used when none is selected; empty for the default profile.
+	profiles       map[string]Profile // Profiles other than the default one, as loaded.
+	removed        []string           // Profiles deleted since loading, removed from the file by Save.
+
+	encryptedToken *EncryptedToken // Sealed token as loaded, if the token is stored encrypted.
+	tokenKey       []byte          // Key of encryptedToken once unlocked; nil while locked.
 }
 
 // DebugPrintln prints a debug message if Debug mode is enabled in the configuration.
@@ -185,7 +196,33 @@ func (c *Configuration) Save() error {
 // This separation allows potential internal use without re-locking if already locked.
 func (c *Configuration) saveUnlocked() error {
 	c.DebugPrintln("Attempting to save configuration...")
-	jsonData, err := json.MarshalIndent(c, "", "  ") // Pretty-print JSON.
+	// Start from the file on disk, so that profiles changed by other processes
+	// since this configuration was loaded (e.g. by a token refresh) are kept.
+	file, err := readConfigFile()
+	if errors.Is(err, ErrConfigNotFound) {
+		file = &fileLayout{}
+	} else if err != nil {
+		return fmt.Errorf("reading configuration before save: %w", err)
+	}
+	settings, err := c.settings()
+	if err != nil {
+		return err
+	}
+	file.Debug = c.Debug
+	file.CurrentProfile = c.currentProfile
+	if c.Profile() == DefaultProfile {
+		file.Profile = settings
+	} else {
+		if file.Profiles == nil {
+			file.Profiles = make(map[string]Profile)
+		}
+		file.Profiles[c.Profile()] = settings
+	}
+	for _, name := range c.removed {
+		delete(file.Profiles, name)
+	}
+
+	jsonData, err := json.MarshalIndent(file, "", "  ") // Pretty-print JSON.
 	if err != nil {
 		return fmt.Errorf("marshaling configuration to JSON: %w", err)
 	}
@@ -219,10 +256,17 @@ func (c *Configuration) saveUnlocked() error {
 	return nil
 }
 
-// Load reads the configuration file from disk and unmarshals it into a Configuration struct.
+// Load reads the configuration file from disk and unmarshals it into a Configuration struct
+// holding the settings of the active profile (see ActiveProfile). A profile that
+// does not exist in the file yet is returned with empty settings.
 // If the configuration file is not found, it returns ErrConfigNotFound.
 func Load() (*Configuration, error) {
-	cfg := &Configuration{} // Initialize with a new Configuration struct.
+	return loadProfile("")
+}
+
+// loadProfile implements Load for the profile called name, or for the active
+// profile if name is empty.
+func loadProfile(name string) (*Configuration, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return nil, fmt.Errorf("getting config file path for load: %w", err)
@@ -235,16 +279,29 @@ func Load() (*Configuration, error) {
 	}
 
 	log.Printf("Debug: Loading configuration from '%s'", configPath)
-	fileData, err := os.ReadFile(configPath)
+	file, err := readConfigFile()
 	if err != nil {
-		return nil, fmt.Errorf("reading configuration file '%s': %w", configPath, err)
+		return nil, err
 	}
 
-	// Unmarshal the JSON data into the Configuration struct.
-	if err = json.Unmarshal(fileData, cfg); err != nil {
-		return nil, fmt.Errorf("unmarshaling configuration JSON from '%s': %w", configPath, err)
+	if name == "" {
+		name = selectedProfileName()
+	}
+	if name == "" {
+		name = file.CurrentProfile
 	}
-	log.Printf("Debug: Configuration loaded successfully. Debug mode: %v", cfg.Debug)
+	cfg := &Configuration{
+		Debug:          file.Debug,
+		profile:        name,
+		currentProfile: file.CurrentProfile,
+		profiles:       file.Profiles,
+	}
+	if cfg.Profile() == DefaultProfile {
+		cfg.applySettings(file.Profile)
+	} else if p, ok := file.Profiles[cfg.profile]; ok {
+		cfg.applySettings(p)
+	}
+	log.Printf("Debug: Configuration loaded successfully. Profile: %s, debug mode: %v", cfg.Profile(), cfg.Debug)
 	return cfg, nil
 }
 
@@ -254,16 +311,27 @@ func Load() (*Configuration, error) {
 // it returns that error. This is useful for application startup where a missing
 // config file is not a fatal error but means starting with defaults.
 func LoadOrCreate() (*Configuration, error) {
-	cfg, err := Load()
+	return LoadProfileOrCreate("")
+}
+
+// LoadProfileOrCreate is LoadOrCreate for the profile called name, regardless
+// of the profile selected for the process. An empty name selects the active
+// profile, as LoadOrCreate does.
+func LoadProfileOrCreate(name string) (*Configuration, error) {
+	cfg, err := loadProfile(name)
 	if err != nil {
 		// If the specific error is ErrConfigNotFound, it means no config file exists.
 		// In this case, return a new, empty Configuration struct with defaults.
 		if errors.Is(err, ErrConfigNotFound) {
 			log.Println("Debug: No existing configuration file found. Creating new default configuration.")
+			if name == "" {
+				name = selectedProfileName()
+			}
 			return &Configuration{
 				HTTP:     DefaultHTTPConfig(),
 				Polling:  DefaultPollingConfig(),
 				Download: DefaultDownloadConfig(),
+				profile:  name,
 			}, nil
 		}
 		// For any other error during Load, propagate it.
@@ -0,0 +1,116 @@
+// Package config (credentials.go) reads credentials for non-interactive use,
+// such as CI pipelines, from the environment instead of the configuration
+// file. Credentials come from environment variables, or from a JSON document
+// read from an inherited file descriptor, so that secrets never need to be
+// written to disk. Environment variables take precedence over the document.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Environment variables holding credentials for non-interactive use.
+const (
+	EnvRefreshToken      = "ONEDRIVE_REFRESH_TOKEN"      // Refresh token of a user, obtained e.g. with 'auth login' elsewhere.
+	EnvClientID          = "ONEDRIVE_CLIENT_ID"          // Application (client) ID; defaults to ClientID for refresh tokens.
+	EnvTenantID          = "ONEDRIVE_TENANT_ID"          // Tenant of the app registration, for app-only access.
+	EnvClientSecret      = "ONEDRIVE_CLIENT_SECRET"      // Client secret, for app-only access.
+	EnvClientCertificate = "ONEDRIVE_CLIENT_CERTIFICATE" // Path to a PEM file with certificate and private key, for app-only access.
+	EnvDriveID           = "ONEDRIVE_DRIVE_ID"           // Drive to operate on when --drive is not given.
+	EnvCredentialsFD     = "ONEDRIVE_CREDENTIALS_FD"     // File descriptor to read a JSON credentials document from.
+)
+
+// Credentials holds credentials supplied through the environment. The JSON
+// field names are those of the document read from EnvCredentialsFD.
+type Credentials struct {
+	RefreshToken      string `json:"refresh_token,omitempty"`
+	ClientID          string `json:"client_id,omitempty"`
+	TenantID          string `json:"tenant_id,omitempty"`
+	ClientSecret      string `json:"client_secret,omitempty"`
+	ClientCertificate string `json:"client_certificate,omitempty"` // Path to a PEM file.
+	DriveID           string `json:"drive_id,omitempty"`
+}
+
+// AppOnly reports whether the credentials are for the Client Credentials
+// Grant (app-only access) rather than a user's refresh token.
+func (c *Credentials) AppOnly() bool {
+	return c.RefreshToken == "" && (c.ClientSecret != "" || c.ClientCertificate != "")
+}
+
+// fdCredentials caches the document read from EnvCredentialsFD, because a
+// file descriptor can only be read once per process.
+var (
+	fdCredentialsOnce sync.Once
+	fdCredentials     Credentials
+	fdCredentialsErr  error
+)
+
+// EnvCredentials returns the credentials supplied through the environment,
+// or nil if there are none, in which case the token in the configuration
+// file is used.
+func EnvCredentials() (*Credentials, error) {
+	fdCredentialsOnce.Do(func() {
+		fdCredentials, fdCredentialsErr = readFDCredentials(os.Getenv(EnvCredentialsFD))
+	})
+	if fdCredentialsErr != nil {
+		return nil, fdCredentialsErr
+	}
+	creds := fdCredentials
+	return applyEnvCredentials(&creds)
+}
+
+// readFDCredentials reads a JSON credentials document from the file
+// descriptor numbered `fdValue`, if set.
+func readFDCredentials(fdValue string) (Credentials, error) {
+	var creds Credentials
+	if fdValue == "" {
+		return creds, nil
+	}
+	fd, err := strconv.Atoi(fdValue)
+	if err != nil || fd < 0 {
+		return creds, fmt.Errorf("%s must be a file descriptor number, got '%s'", EnvCredentialsFD, fdValue)
+	}
+	file := os.NewFile(uintptr(fd), "credentials")
+	if file == nil {
+		return creds, fmt.Errorf("%s: invalid file descriptor %d", EnvCredentialsFD, fd)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return creds, fmt.Errorf("reading credentials from file descriptor %d: %w", fd, err)
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return creds, fmt.Errorf("parsing credentials from file descriptor %d: %w", fd, err)
+	}
+	return creds, nil
+}
+
+// applyEnvCredentials applies the environment variables on top of `creds`
+// and returns the result, or nil if it holds no credentials.
+func applyEnvCredentials(creds *Credentials) (*Credentials, error) {
+	for env, field := range map[string]*string{
+		EnvRefreshToken:      &creds.RefreshToken,
+		EnvClientID:          &creds.ClientID,
+		EnvTenantID:          &creds.TenantID,
+		EnvClientSecret:      &creds.ClientSecret,
+		EnvClientCertificate: &creds.ClientCertificate,
+		EnvDriveID:           &creds.DriveID,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+
+	if creds.RefreshToken == "" && creds.ClientSecret == "" && creds.ClientCertificate == "" {
+		return nil, nil
+	}
+	if creds.AppOnly() && (creds.TenantID == "" || creds.ClientID == "") {
+		return nil, fmt.Errorf("app-only credentials require %s and %s", EnvTenantID, EnvClientID)
+	}
+	return creds, nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEnvCredentials(t *testing.T) {
+	creds, err := applyEnvCredentials(&Credentials{})
+	require.NoError(t, err)
+	assert.Nil(t, creds, "no credentials without environment variables")
+
+	t.Setenv(EnvRefreshToken, "refresh")
+	creds, err = applyEnvCredentials(&Credentials{DriveID: "from-document"})
+	require.NoError(t, err)
+	assert.Equal(t, "refresh", creds.RefreshToken)
+	assert.Equal(t, "from-document", creds.DriveID)
+	assert.False(t, creds.AppOnly())
+
+	t.Setenv(EnvRefreshToken, "")
+	t.Setenv(EnvClientSecret, "secret")
+	_, err = applyEnvCredentials(&Credentials{})
+	assert.Error(t, err, "app-only credentials need a tenant and client ID")
+
+	t.Setenv(EnvTenantID, "tenant")
+	t.Setenv(EnvClientID, "app")
+	creds, err = applyEnvCredentials(&Credentials{})
+	require.NoError(t, err)
+	assert.True(t, creds.AppOnly())
+}
+
+func TestReadFDCredentials(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = w.WriteString(`{"refresh_token":"from-fd","drive_id":"drive-1"}`)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	creds, err := readFDCredentials(strconv.Itoa(int(r.Fd())))
+	require.NoError(t, err)
+	assert.Equal(t, "from-fd", creds.RefreshToken)
+	assert.Equal(t, "drive-1", creds.DriveID)
+
+	_, err = readFDCredentials("not-a-number")
+	assert.Error(t, err)
+}
@@ -0,0 +1,242 @@
+// Package config (profile.go) adds named profiles to the configuration, so
+// that several accounts can be used side by side. Each profile has its own
+// token and sign-in, HTTP, polling and download settings, all kept in the one
+// configuration file. The "default" profile lives at the top level of the
+// file, exactly where configuration files written before profiles existed
+// keep their settings; other profiles are stored below "profiles".
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// DefaultProfile is the name of the profile used when none is selected.
+const DefaultProfile = "default"
+
+// profilesDir is the subdirectory of the configuration directory that holds
+// the session and sync state of profiles other than the default one.
+const profilesDir = "profiles"
+
+var (
+	// ErrProfileNotFound is returned when a named profile does not exist.
+	ErrProfileNotFound = errors.New("profile not found")
+	// ErrInvalidProfileName is returned for profile names that cannot be used as a directory name.
+	ErrInvalidProfileName = errors.New("invalid profile name")
+)
+
+// validProfileName restricts profile names to characters that are safe in
+// directory names on every platform.
+var validProfileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// selectedProfile is the profile chosen for this process with SelectProfile,
+// typically from the global --profile flag. Empty means the file's current profile.
+var (
+	selectedProfile   string
+	selectedProfileMu sync.RWMutex
+)
+
+// Profile holds the settings that are kept separately for each profile.
+type Profile struct {
+	Token          onedrive.Token  `json:"token"`                     // OAuth2 token (access, refresh, expiry); empty if encrypted.
+	EncryptedToken *EncryptedToken `json:"encrypted_token,omitempty"` // Token encrypted at rest, see tokenstore.go.
+	HTTP           HTTPConfig      `json:"http"`                      // HTTP client configuration
+	Polling        PollingConfig   `json:"polling"`                   // Polling configuration for async operations
+	Download       DownloadConfig  `json:"download"`                  // Download file permissions configuration
+	Auth           AuthConfig      `json:"auth"`                      // Sign-in settings: client ID, tenant, cloud and scopes
+}
+
+// fileLayout is the on-disk layout of the configuration file.
+type fileLayout struct {
+	Profile                           // Settings of the default profile, at the top level.
+	Debug          bool               `json:"debug"`
+	CurrentProfile string             `json:"current_profile,omitempty"` // Profile used when none is selected.
+	Profiles       map[string]Profile `json:"profiles,omitempty"`        // Profiles other than the default one.
+}
+
+// ValidateProfileName reports whether name can be used as a profile name.
+func ValidateProfileName(name string) error {
+	if !validProfileName.MatchString(name) {
+		return fmt.Errorf("%w '%s': use letters, digits, '.', '_' and '-', starting with a letter or digit", ErrInvalidProfileName, name)
+	}
+	return nil
+}
+
+// SelectProfile makes name the profile used by Load, LoadOrCreate and the
+// session manager for the rest of the process, overriding the current profile
+// recorded in the configuration file. An empty name restores that default.
+func SelectProfile(name string) error {
+	if name != "" {
+		if err := ValidateProfileName(name); err != nil {
+			return err
+		}
+	}
+	selectedProfileMu.Lock()
+	defer selectedProfileMu.Unlock()
+	selectedProfile = name
+	return nil
+}
+
+// ActiveProfile returns the name of the profile in use: the one chosen with
+// SelectProfile, or else the current profile recorded in the configuration
+// file, or else DefaultProfile.
+func ActiveProfile() (string, error) {
+	if name := selectedProfileName(); name != "" {
+		return name, nil
+	}
+	file, err := readConfigFile()
+	if err != nil {
+		if errors.Is(err, ErrConfigNotFound) {
+			return DefaultProfile, nil
+		}
+		return "", err
+	}
+	if file.CurrentProfile == "" {
+		return DefaultProfile, nil
+	}
+	return file.CurrentProfile, nil
+}
+
+// selectedProfileName returns the profile chosen with SelectProfile, if any.
+func selectedProfileName() string {
+	selectedProfileMu.RLock()
+	defer selectedProfileMu.RUnlock()
+	return selectedProfile
+}
+
+// ProfileStateDir returns the directory below the configuration directory
+// `configDir` that holds the session and sync state of `profile`. The default
+// profile uses `configDir` itself, as before profiles existed.
+func ProfileStateDir(configDir, profile string) string {
+	if profile == "" || profile == DefaultProfile {
+		return configDir
+	}
+	return filepath.Join(configDir, profilesDir, profile)
+}
+
+// readConfigFile reads and decodes the configuration file, returning
+// ErrConfigNotFound if it does not exist.
+func readConfigFile() (*fileLayout, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("getting config file path for load: %w", err)
+	}
+	fileData, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrConfigNotFound
+		}
+		return nil, fmt.Errorf("reading configuration file '%s': %w", configPath, err)
+	}
+	file := &fileLayout{}
+	if err := json.Unmarshal(fileData, file); err != nil {
+		return nil, fmt.Errorf("unmarshaling configuration JSON from '%s': %w", configPath, err)
+	}
+	return file, nil
+}
+
+// Profile returns the name of the profile whose settings the configuration holds.
+func (c *Configuration) Profile() string {
+	if c.profile == "" {
+		return DefaultProfile
+	}
+	return c.profile
+}
+
+// CurrentProfile returns the profile used when none is selected with --profile.
+func (c *Configuration) CurrentProfile() string {
+	if c.currentProfile == "" {
+		return DefaultProfile
+	}
+	return c.currentProfile
+}
+
+// ProfileNames returns the names of all profiles, sorted, including the
+// default profile and the active one even if it has not been saved yet.
+func (c *Configuration) ProfileNames() []string {
+	names := []string{DefaultProfile}
+	for name := range c.profiles {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	if _, ok := c.profiles[c.Profile()]; !ok && c.Profile() != DefaultProfile {
+		names = append(names, c.Profile())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasProfile reports whether a profile called name exists. The default
+// profile always exists.
+func (c *Configuration) HasProfile(name string) bool {
+	if name == DefaultProfile || name == c.Profile() {
+		return true
+	}
+	_, ok := c.profiles[name]
+	return ok
+}
+
+// SetCurrentProfile records name as the profile to use when none is selected
+// with --profile. The change is persisted by Save.
+func (c *Configuration) SetCurrentProfile(name string) error {
+	if !c.HasProfile(name) {
+		return fmt.Errorf("%w: '%s'", ErrProfileNotFound, name)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.currentProfile = name
+	if name == DefaultProfile {
+		c.currentProfile = ""
+	}
+	return nil
+}
+
+// DeleteProfile removes the profile called name. The default profile cannot
+// be deleted. If name is the current profile, the default profile becomes
+// current. The change is persisted by Save.
+func (c *Configuration) DeleteProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the %s profile cannot be deleted; use 'auth logout' to clear its token", DefaultProfile)
+	}
+	if _, ok := c.profiles[name]; !ok {
+		return fmt.Errorf("%w: '%s'", ErrProfileNotFound, name)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.profiles, name)
+	c.removed = append(c.removed, name)
+	if c.currentProfile == name {
+		c.currentProfile = ""
+	}
+	return nil
+}
+
+// settings returns the per-profile settings held by the configuration, with
+// the token encrypted if the profile's token is stored encrypted.
+func (c *Configuration) settings() (Profile, error) {
+	token, encrypted, err := c.storedToken()
+	if err != nil {
+		return Profile{}, fmt.Errorf("encrypting token: %w", err)
+	}
+	return Profile{Token: token, EncryptedToken: encrypted, HTTP: c.HTTP, Polling: c.Polling, Download: c.Download, Auth: c.Auth}, nil
+}
+
+// applySettings replaces the per-profile settings held by the configuration.
+func (c *Configuration) applySettings(p Profile) {
+	c.Token = p.Token
+	c.encryptedToken = p.EncryptedToken
+	c.tokenKey = nil
+	c.HTTP = p.HTTP
+	c.Polling = p.Polling
+	c.Download = p.Download
+	c.Auth = p.Auth
+}
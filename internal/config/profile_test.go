@@ -0,0 +1,132 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+func TestValidateProfileName(t *testing.T) {
+	for _, name := range []string{"default", "work", "Work-2", "a.b_c"} {
+		assert.NoError(t, ValidateProfileName(name), name)
+	}
+	for _, name := range []string{"", ".hidden", "-x", "a/b", "../up", "with space"} {
+		assert.ErrorIs(t, ValidateProfileName(name), ErrInvalidProfileName, name)
+	}
+}
+
+func TestProfilesSaveAndLoad(t *testing.T) {
+	tempConfigFile := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("ONEDRIVE_CONFIG_PATH", tempConfigFile)
+	t.Cleanup(func() { SelectProfile("") })
+
+	def, err := LoadOrCreate()
+	require.NoError(t, err)
+	def.Token.AccessToken = "default-token"
+	require.NoError(t, def.Save())
+
+	work, err := LoadProfileOrCreate("work")
+	require.NoError(t, err)
+	assert.Equal(t, "work", work.Profile())
+	assert.Empty(t, work.Token.AccessToken)
+	work.Token.AccessToken = "work-token"
+	work.HTTP.RetryAttempts = 7
+	require.NoError(t, work.Save())
+
+	// The default profile stays at the top level, where older versions expect it.
+	data, err := os.ReadFile(tempConfigFile)
+	require.NoError(t, err)
+	var raw map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Contains(t, string(raw["token"]), "default-token")
+	assert.Contains(t, string(raw["profiles"]), "work-token")
+
+	def, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultProfile, def.Profile())
+	assert.Equal(t, "default-token", def.Token.AccessToken)
+	assert.Equal(t, []string{"default", "work"}, def.ProfileNames())
+
+	require.NoError(t, SelectProfile("work"))
+	work, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "work-token", work.Token.AccessToken)
+	assert.Equal(t, 7, work.HTTP.RetryAttempts)
+	require.NoError(t, SelectProfile(""))
+
+	// Making a profile current applies it to later loads.
+	require.NoError(t, def.SetCurrentProfile("work"))
+	require.NoError(t, def.Save())
+	active, err := ActiveProfile()
+	require.NoError(t, err)
+	assert.Equal(t, "work", active)
+	assert.ErrorIs(t, def.SetCurrentProfile("missing"), ErrProfileNotFound)
+
+	// Deleting the current profile falls back to the default one.
+	cfg, err := LoadProfileOrCreate(DefaultProfile)
+	require.NoError(t, err)
+	assert.Error(t, cfg.DeleteProfile(DefaultProfile))
+	require.NoError(t, cfg.DeleteProfile("work"))
+	require.NoError(t, cfg.Save())
+	active, err = ActiveProfile()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultProfile, active)
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default"}, cfg.ProfileNames())
+	assert.Equal(t, "default-token", cfg.Token.AccessToken)
+}
+
+func TestProfileStateDir(t *testing.T) {
+	assert.Equal(t, "/cfg", ProfileStateDir("/cfg", DefaultProfile))
+	assert.Equal(t, "/cfg", ProfileStateDir("/cfg", ""))
+	assert.Equal(t, filepath.Join("/cfg", "profiles", "work"), ProfileStateDir("/cfg", "work"))
+}
+
+func TestProfileAuthSettings(t *testing.T) {
+	tempConfigFile := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("ONEDRIVE_CONFIG_PATH", tempConfigFile)
+	t.Cleanup(func() { SelectProfile("") })
+
+	gov, err := LoadProfileOrCreate("gov")
+	require.NoError(t, err)
+	gov.Auth = AuthConfig{
+		ClientID: "own-app", Tenant: "contoso.onmicrosoft.us", Cloud: "usgov", ReadOnly: true,
+		Endpoints: &onedrive.Endpoints{GraphURL: "http://localhost:8080/"},
+	}
+	require.NoError(t, gov.Save())
+
+	require.NoError(t, SelectProfile("gov"))
+	gov, err = Load()
+	require.NoError(t, err)
+	auth, err := gov.Auth.SDKConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "own-app", auth.ClientID)
+	assert.Equal(t, onedrive.ReadOnlyScopes(), auth.Scopes)
+	assert.Equal(t, "https://login.microsoftonline.us/contoso.onmicrosoft.us/oauth2/v2.0/token", auth.Endpoints.TokenURL)
+	assert.Equal(t, "http://localhost:8080/", auth.Endpoints.GraphURL)
+
+	require.NoError(t, SelectProfile(""))
+	def, err := Load()
+	require.NoError(t, err)
+	auth, err = def.Auth.SDKConfig()
+	require.NoError(t, err)
+	assert.Equal(t, ClientID, auth.ClientID)
+	assert.Equal(t, onedrive.DefaultEndpoints(), auth.Endpoints)
+}
+
+func TestAuthConfigValidate(t *testing.T) {
+	assert.NoError(t, AuthConfig{}.Validate())
+	assert.NoError(t, AuthConfig{Tenant: "organizations", Cloud: "china"}.Validate())
+	assert.Error(t, AuthConfig{Cloud: "moon"}.Validate())
+	assert.Error(t, AuthConfig{Tenant: "a/b"}.Validate())
+	assert.Error(t, AuthConfig{ReadOnly: true, Scopes: []string{"files.read"}}.Validate())
+
+	assert.Contains(t, AuthConfig{ReadOnly: true}.ScopesOrDefault(), "files.read.all")
+	assert.Equal(t, []string{"openid"}, AuthConfig{Scopes: []string{"openid"}}.ScopesOrDefault())
+}
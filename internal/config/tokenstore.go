@@ -0,0 +1,294 @@
+// Package config (tokenstore.go) optionally encrypts a profile's OAuth token
+// at rest, so that the refresh token in the configuration file is useless to
+// anyone who can read the file but does not know the secret. The secret is
+// either a passphrase, stretched into a key with scrypt, or a key file. The
+// token is sealed with AES-256-GCM.
+//
+// An encrypted profile is unlocked once per process with UnlockToken; from
+// then on Token holds the plaintext token in memory, and Save seals it afresh
+// every time it is written, including after token refreshes.
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Environment variables supplying the secret of an encrypted token.
+const (
+	EnvTokenPassphrase = "ONEDRIVE_TOKEN_PASSPHRASE" // Passphrase of a passphrase-encrypted token.
+	EnvTokenKeyFile    = "ONEDRIVE_TOKEN_KEY_FILE"   // Path to the key file, overriding the one recorded in the profile.
+)
+
+// Key derivation methods of an EncryptedToken.
+const (
+	KDFScrypt  = "scrypt"   // Key derived from a passphrase with scrypt.
+	KDFKeyFile = "key-file" // Key derived from the contents of a key file.
+)
+
+// scrypt parameters for new passphrase-encrypted tokens. N=2^15 takes about
+// 100ms on current hardware, which is paid once per command.
+const (
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	tokenKeyBytes = 32 // AES-256.
+	tokenSaltSize = 16
+)
+
+// minKeyFileSize is the smallest key file accepted, in bytes.
+const minKeyFileSize = 32
+
+var (
+	// ErrTokenLocked is returned when an encrypted token is needed but no
+	// passphrase or key file was supplied to unlock it.
+	ErrTokenLocked = errors.New("token is encrypted")
+	// ErrTokenDecryption is returned when an encrypted token cannot be
+	// decrypted, typically because the passphrase or key file is wrong.
+	ErrTokenDecryption = errors.New("decrypting token failed: wrong passphrase or key file")
+)
+
+// EncryptedToken is the sealed form of a profile's token, as stored in the
+// configuration file in place of the plaintext token.
+type EncryptedToken struct {
+	KDF        string `json:"kdf"`                // KDFScrypt or KDFKeyFile.
+	KeyFile    string `json:"key_file,omitempty"` // Path of the key file, for KDFKeyFile.
+	Salt       []byte `json:"salt,omitempty"`     // scrypt salt.
+	N          int    `json:"n,omitempty"`        // scrypt CPU/memory cost.
+	R          int    `json:"r,omitempty"`        // scrypt block size.
+	P          int    `json:"p,omitempty"`        // scrypt parallelism.
+	Nonce      []byte `json:"nonce"`              // AES-GCM nonce, new for every save.
+	Ciphertext []byte `json:"ciphertext"`         // AES-GCM sealed JSON of the token.
+}
+
+// TokenSecret is the secret a token is encrypted with: a passphrase or the
+// path of a key file.
+type TokenSecret struct {
+	Passphrase string
+	KeyFile    string
+}
+
+// TokenEncrypted reports whether the profile's token is stored encrypted.
+func (c *Configuration) TokenEncrypted() bool {
+	return c.encryptedToken != nil
+}
+
+// TokenKDF returns the key derivation method of the encrypted token, and for
+// KDFKeyFile the path of the key file recorded with it.
+func (c *Configuration) TokenKDF() (kdf, keyFile string) {
+	if c.encryptedToken == nil {
+		return "", ""
+	}
+	return c.encryptedToken.KDF, c.encryptedToken.KeyFile
+}
+
+// TokenLocked reports whether the token is encrypted and has not been
+// unlocked with UnlockToken yet.
+func (c *Configuration) TokenLocked() bool {
+	return c.encryptedToken != nil && c.tokenKey == nil
+}
+
+// HasToken reports whether the profile holds a token, encrypted or not.
+func (c *Configuration) HasToken() bool {
+	return c.Token.AccessToken != "" || c.encryptedToken != nil
+}
+
+// UnlockToken decrypts the profile's token with `secret` into Token, and
+// keeps the key in memory so that Save can encrypt refreshed tokens.
+func (c *Configuration) UnlockToken(secret TokenSecret) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.encryptedToken == nil {
+		return nil
+	}
+	key, err := deriveTokenKey(c.encryptedToken, secret)
+	if err != nil {
+		return err
+	}
+	token, err := openToken(c.encryptedToken, key)
+	if err != nil {
+		return err
+	}
+	c.Token = *token
+	c.tokenKey = key
+	return nil
+}
+
+// EncryptToken makes Save store the token encrypted with `secret` from now
+// on. For a key file, `secret.KeyFile` is recorded in the profile.
+func (c *Configuration) EncryptToken(secret TokenSecret) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.TokenLocked() {
+		return ErrTokenLocked
+	}
+	enc := &EncryptedToken{}
+	if secret.KeyFile != "" {
+		enc.KDF = KDFKeyFile
+		enc.KeyFile = secret.KeyFile
+	} else {
+		if secret.Passphrase == "" {
+			return fmt.Errorf("%w: an empty passphrase cannot protect a token", onedrive.ErrInvalidRequest)
+		}
+		enc.KDF = KDFScrypt
+		enc.N, enc.R, enc.P = scryptN, scryptR, scryptP
+		enc.Salt = make([]byte, tokenSaltSize)
+		if _, err := rand.Read(enc.Salt); err != nil {
+			return fmt.Errorf("generating salt: %w", err)
+		}
+	}
+	key, err := deriveTokenKey(enc, secret)
+	if err != nil {
+		return err
+	}
+	c.encryptedToken = enc
+	c.tokenKey = key
+	return nil
+}
+
+// DecryptToken makes Save store the token in plaintext again. An encrypted
+// token must be unlocked first.
+func (c *Configuration) DecryptToken() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.TokenLocked() {
+		return ErrTokenLocked
+	}
+	c.encryptedToken = nil
+	c.tokenKey = nil
+	return nil
+}
+
+// ClearToken removes the token, and with it any encryption, so that a locked
+// profile can be logged out without its secret.
+func (c *Configuration) ClearToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Token = onedrive.Token{}
+	c.encryptedToken = nil
+	c.tokenKey = nil
+}
+
+// storedToken returns the token and its encrypted form as they are to be
+// written to the configuration file. A locked token is written back as it
+// was loaded.
+func (c *Configuration) storedToken() (onedrive.Token, *EncryptedToken, error) {
+	if c.encryptedToken == nil {
+		return c.Token, nil, nil
+	}
+	if c.tokenKey == nil {
+		return onedrive.Token{}, c.encryptedToken, nil
+	}
+	sealed, err := sealToken(c.encryptedToken, c.tokenKey, &c.Token)
+	if err != nil {
+		return onedrive.Token{}, nil, err
+	}
+	return onedrive.Token{}, sealed, nil
+}
+
+// GenerateKeyFile writes a new random key file to `path`, readable by the
+// owner only. It fails if the file exists.
+func GenerateKeyFile(path string) error {
+	key := make([]byte, tokenKeyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("generating key: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, onedrive.PermSecureFile)
+	if err != nil {
+		return fmt.Errorf("creating key file '%s': %w", path, err)
+	}
+	if _, err := file.Write(key); err != nil {
+		file.Close()
+		return fmt.Errorf("writing key file '%s': %w", path, err)
+	}
+	return file.Close()
+}
+
+// deriveTokenKey derives the AES key of `enc` from `secret`.
+func deriveTokenKey(enc *EncryptedToken, secret TokenSecret) ([]byte, error) {
+	switch enc.KDF {
+	case KDFScrypt:
+		if secret.Passphrase == "" {
+			return nil, fmt.Errorf("%w: a passphrase is required", ErrTokenLocked)
+		}
+		key, err := scrypt.Key([]byte(secret.Passphrase), enc.Salt, enc.N, enc.R, enc.P, tokenKeyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("deriving key from passphrase: %w", err)
+		}
+		return key, nil
+	case KDFKeyFile:
+		path := secret.KeyFile
+		if path == "" {
+			path = enc.KeyFile
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading key file: %w", ErrTokenLocked, err)
+		}
+		if len(data) < minKeyFileSize {
+			return nil, fmt.Errorf("%w: key file '%s' must hold at least %d bytes", onedrive.ErrInvalidRequest, path, minKeyFileSize)
+		}
+		key := sha256.Sum256(data)
+		return key[:], nil
+	default:
+		return nil, fmt.Errorf("%w: unknown token key derivation '%s'", onedrive.ErrInvalidRequest, enc.KDF)
+	}
+}
+
+// sealToken returns a copy of `enc` holding `token` encrypted with `key`
+// under a new nonce.
+func sealToken(enc *EncryptedToken, key []byte, token *onedrive.Token) (*EncryptedToken, error) {
+	aead, err := newTokenAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("encoding token: %w", err)
+	}
+	sealed := *enc
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, nil)
+	return &sealed, nil
+}
+
+// openToken decrypts the token held by `enc` with `key`.
+func openToken(enc *EncryptedToken, key []byte) (*onedrive.Token, error) {
+	aead, err := newTokenAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(enc.Nonce) != aead.NonceSize() {
+		return nil, ErrTokenDecryption
+	}
+	plaintext, err := aead.Open(nil, enc.Nonce, enc.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrTokenDecryption
+	}
+	var token onedrive.Token
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, fmt.Errorf("decoding decrypted token: %w", err)
+	}
+	return &token, nil
+}
+
+// newTokenAEAD returns AES-256-GCM keyed with `key`.
+func newTokenAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating token cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
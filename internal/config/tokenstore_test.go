@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// newTokenStoreConfig saves a configuration holding a token in a temporary
+// directory and returns it.
+func newTokenStoreConfig(t *testing.T) (*Configuration, string) {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("ONEDRIVE_CONFIG_PATH", configPath)
+	cfg, err := LoadOrCreate()
+	require.NoError(t, err)
+	cfg.Token = onedrive.Token{AccessToken: "secret-access", RefreshToken: "secret-refresh", Expiry: time.Now().Add(time.Hour)}
+	require.NoError(t, cfg.Save())
+	return cfg, configPath
+}
+
+func TestTokenEncryptionWithPassphrase(t *testing.T) {
+	cfg, configPath := newTokenStoreConfig(t)
+
+	require.NoError(t, cfg.EncryptToken(TokenSecret{Passphrase: "correct horse"}))
+	require.NoError(t, cfg.Save())
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret-refresh")
+	assert.Contains(t, string(data), `"kdf": "scrypt"`)
+
+	loaded, err := Load()
+	require.NoError(t, err)
+	assert.True(t, loaded.HasToken())
+	assert.True(t, loaded.TokenLocked())
+	assert.Empty(t, loaded.Token.RefreshToken)
+	assert.ErrorIs(t, loaded.UnlockToken(TokenSecret{Passphrase: "wrong"}), ErrTokenDecryption)
+	assert.ErrorIs(t, loaded.UnlockToken(TokenSecret{}), ErrTokenLocked)
+
+	// Saving a locked configuration keeps the encrypted token.
+	require.NoError(t, loaded.Save())
+	loaded, err = Load()
+	require.NoError(t, err)
+	require.NoError(t, loaded.UnlockToken(TokenSecret{Passphrase: "correct horse"}))
+	assert.Equal(t, "secret-refresh", loaded.Token.RefreshToken)
+
+	// A refreshed token is encrypted again on save.
+	loaded.Token.RefreshToken = "refreshed"
+	require.NoError(t, loaded.Save())
+	data, err = os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "refreshed")
+	reloaded, err := Load()
+	require.NoError(t, err)
+	require.NoError(t, reloaded.UnlockToken(TokenSecret{Passphrase: "correct horse"}))
+	assert.Equal(t, "refreshed", reloaded.Token.RefreshToken)
+
+	require.NoError(t, reloaded.DecryptToken())
+	require.NoError(t, reloaded.Save())
+	data, err = os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "refreshed")
+	assert.NotContains(t, string(data), "encrypted_token")
+}
+
+func TestTokenEncryptionWithKeyFile(t *testing.T) {
+	cfg, _ := newTokenStoreConfig(t)
+	keyFile := filepath.Join(t.TempDir(), "token.key")
+	require.NoError(t, GenerateKeyFile(keyFile))
+	assert.Error(t, GenerateKeyFile(keyFile), "an existing key file must not be overwritten")
+
+	require.NoError(t, cfg.EncryptToken(TokenSecret{KeyFile: keyFile}))
+	require.NoError(t, cfg.Save())
+
+	loaded, err := Load()
+	require.NoError(t, err)
+	kdf, recorded := loaded.TokenKDF()
+	assert.Equal(t, KDFKeyFile, kdf)
+	assert.Equal(t, keyFile, recorded)
+	require.NoError(t, loaded.UnlockToken(TokenSecret{}))
+	assert.Equal(t, "secret-access", loaded.Token.AccessToken)
+
+	// Another key file does not open the token.
+	otherKey := filepath.Join(t.TempDir(), "other.key")
+	require.NoError(t, GenerateKeyFile(otherKey))
+	loaded, err = Load()
+	require.NoError(t, err)
+	assert.ErrorIs(t, loaded.UnlockToken(TokenSecret{KeyFile: otherKey}), ErrTokenDecryption)
+}
+
+func TestClearTokenRemovesEncryption(t *testing.T) {
+	cfg, _ := newTokenStoreConfig(t)
+	require.NoError(t, cfg.EncryptToken(TokenSecret{Passphrase: "pw"}))
+	require.NoError(t, cfg.Save())
+
+	loaded, err := Load()
+	require.NoError(t, err)
+	loaded.ClearToken()
+	require.NoError(t, loaded.Save())
+
+	loaded, err = Load()
+	require.NoError(t, err)
+	assert.False(t, loaded.HasToken())
+	assert.False(t, loaded.TokenEncrypted())
+}
@@ -0,0 +1,133 @@
+// Package session (completion.go) keeps a small on-disk cache of the values
+// offered by shell completion, such as the names in remote folders. Shells run
+// a new onedrive-client process for every press of Tab, so the cache is what
+// keeps repeated completions fast, and it still answers, with older values,
+// when OneDrive cannot be reached.
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+const (
+	// completionCacheFile is the name of the cache file in the profile's directory.
+	completionCacheFile = "completion_cache.json"
+	// completionCacheMaxAge is how long an entry is kept at all; older entries
+	// are dropped the next time the cache is written.
+	completionCacheMaxAge = 7 * 24 * time.Hour
+	// completionCacheMaxEntries bounds the size of the cache file.
+	completionCacheMaxEntries = 500
+)
+
+// DefaultCompletionTTL is how long cached completion values are used without
+// asking OneDrive again.
+const DefaultCompletionTTL = 30 * time.Second
+
+// completionEntry is the cached value of one key.
+type completionEntry struct {
+	Values  []string  `json:"values"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// CompletionCache caches lists of completion values by key.
+type CompletionCache struct {
+	path string
+	ttl  time.Duration
+}
+
+// CompletionCache returns the completion cache of the Manager's profile, whose
+// entries are fresh for `ttl`.
+func (m *Manager) CompletionCache(ttl time.Duration) *CompletionCache {
+	return &CompletionCache{path: filepath.Join(m.configDir, completionCacheFile), ttl: ttl}
+}
+
+// Lookup returns the values cached under `key`, and whether they were fetched
+// within the TTL. `ok` is false if nothing is cached under `key`.
+func (c *CompletionCache) Lookup(key string) (values []string, fresh, ok bool) {
+	entries := c.read()
+	entry, ok := entries[key]
+	if !ok {
+		return nil, false, false
+	}
+	return entry.Values, time.Since(entry.Fetched) < c.ttl, true
+}
+
+// Store caches `values` under `key`. If another process is writing the cache
+// at the same time, the values are not cached.
+func (c *CompletionCache) Store(key string, values []string) error {
+	if err := os.MkdirAll(filepath.Dir(c.path), onedrive.PermSecureDir); err != nil {
+		return fmt.Errorf("creating completion cache directory: %w", err)
+	}
+	lock := flock.New(c.path + ".lock")
+	locked, err := lock.TryLock()
+	if err != nil {
+		return fmt.Errorf("acquiring file lock for completion cache '%s': %w", c.path, err)
+	}
+	if !locked {
+		return nil // Another completion is writing the cache; this one is not needed.
+	}
+	defer func() {
+		if unlockErr := lock.Unlock(); unlockErr != nil {
+			log.Printf("Warning: Failed to unlock completion cache lock: %v", unlockErr)
+		}
+	}()
+
+	entries := c.read()
+	now := time.Now()
+	entries[key] = completionEntry{Values: values, Fetched: now}
+	pruneCompletionEntries(entries, now)
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("marshaling completion cache: %w", err)
+	}
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, onedrive.PermSecureFile); err != nil {
+		return fmt.Errorf("writing completion cache '%s': %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("renaming completion cache '%s' to '%s': %w", tmpPath, c.path, err)
+	}
+	return nil
+}
+
+// read returns the entries of the cache file. A missing or unreadable cache
+// is empty, as it only saves requests.
+func (c *CompletionCache) read() map[string]completionEntry {
+	entries := make(map[string]completionEntry)
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return make(map[string]completionEntry)
+	}
+	return entries
+}
+
+// pruneCompletionEntries drops entries older than completionCacheMaxAge and,
+// beyond completionCacheMaxEntries, the oldest ones.
+func pruneCompletionEntries(entries map[string]completionEntry, now time.Time) {
+	for key, entry := range entries {
+		if now.Sub(entry.Fetched) > completionCacheMaxAge {
+			delete(entries, key)
+		}
+	}
+	for len(entries) > completionCacheMaxEntries {
+		oldest := ""
+		for key, entry := range entries {
+			if oldest == "" || entry.Fetched.Before(entries[oldest].Fetched) {
+				oldest = key
+			}
+		}
+		delete(entries, oldest)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/gofrs/flock"
+	"github.com/tonimelisma/onedrive-client/internal/config"
 	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
@@ -28,6 +29,7 @@ type State struct {
 	LocalPath          string    `json:"localPath"`             // Path to the local file.
 	RemotePath         string    `json:"remotePath"`            // Path to the remote file on OneDrive.
 	CompletedBytes     int64     `json:"completedBytes"`        // Number of bytes successfully transferred.
+	RemoteCTag         string    `json:"remoteCTag,omitempty"`  // Content tag of the remote item a download started from.
 	// TotalSize can be added if needed for progress calculation, though often derived from local file info.
 	// TotalSize          int64     `json:"totalSize,omitempty"`
 }
@@ -38,16 +40,28 @@ type Manager struct {
 	configDir string // Base directory for configuration, sessions will be in a 'sessions' subdirectory.
 }
 
-// NewManager creates a new session Manager.
+// NewManager creates a new session Manager for the active configuration profile
+// (see config.ActiveProfile).
 // It determines the session directory based on standard user config paths or
 // the `ONEDRIVE_CONFIG_PATH` environment variable (to align with where the main
 // config.json might be stored, especially for testing or custom setups).
 func NewManager() (*Manager, error) {
+	profile, err := config.ActiveProfile()
+	if err != nil {
+		return nil, fmt.Errorf("determining active profile for session manager: %w", err)
+	}
+	return NewManagerForProfile(profile)
+}
+
+// NewManagerForProfile creates a session Manager for the named configuration
+// profile. Each profile other than the default one keeps its sessions, and
+// the other state stored alongside them, in a directory of its own.
+func NewManagerForProfile(profile string) (*Manager, error) {
 	// If ONEDRIVE_CONFIG_PATH is set, use its directory as the base for the 'sessions' subdir.
 	// This keeps session files co-located with a custom config file, which is good for isolation (e.g., tests).
 	if customPath := os.Getenv("ONEDRIVE_CONFIG_PATH"); customPath != "" {
 		return &Manager{
-			configDir: filepath.Dir(customPath), // Use the directory of the custom config file path.
+			configDir: config.ProfileStateDir(filepath.Dir(customPath), profile), // Use the directory of the custom config file path.
 		}, nil
 	}
 
@@ -57,7 +71,7 @@ func NewManager() (*Manager, error) {
 		return nil, fmt.Errorf("getting user config directory for session manager: %w", err)
 	}
 	return &Manager{
-		configDir: filepath.Join(userConfigDir, "onedrive-client"),
+		configDir: config.ProfileStateDir(filepath.Join(userConfigDir, "onedrive-client"), profile),
 	}, nil
 }
 
@@ -67,6 +81,12 @@ func NewManagerWithConfigDir(configDir string) *Manager {
 	return &Manager{configDir: configDir}
 }
 
+// ConfigDir returns the base directory the Manager stores its 'sessions' subdirectory in.
+// Other per-user state, such as the sync state store, is kept alongside it.
+func (m *Manager) ConfigDir() string {
+	return m.configDir
+}
+
 // getSessionDir returns the full path to the 'sessions' subdirectory where session files are stored.
 func (m *Manager) getSessionDir() string {
 	return filepath.Join(m.configDir, "sessions")
@@ -0,0 +1,148 @@
+// Package shell (complete.go) completes command names, remote paths and local
+// paths in the interactive shell. Remote folder listings are cached, so that
+// pressing Tab repeatedly does not query OneDrive every time.
+package shell
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Lister returns the names of the children of the remote folder `dir`, with
+// a trailing "/" for folders.
+type Lister func(ctx context.Context, dir string) ([]string, error)
+
+// Completer completes remote paths from cached folder listings.
+type Completer struct {
+	list Lister
+
+	mu       sync.Mutex
+	children map[string][]string // Names of the children of each listed folder.
+}
+
+// NewCompleter returns a Completer that lists remote folders with `list`.
+func NewCompleter(list Lister) *Completer {
+	return &Completer{list: list, children: make(map[string][]string)}
+}
+
+// Children returns the names of the children of the remote folder `dir`,
+// listing it only if it is not cached yet.
+func (c *Completer) Children(ctx context.Context, dir string) ([]string, error) {
+	c.mu.Lock()
+	names, ok := c.children[dir]
+	c.mu.Unlock()
+	if ok {
+		return names, nil
+	}
+	names, err := c.list(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.children[dir] = names
+	c.mu.Unlock()
+	return names, nil
+}
+
+// Invalidate forgets all cached listings, after a command that may have
+// changed the contents of remote folders.
+func (c *Completer) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.children = make(map[string][]string)
+}
+
+// Complete returns the completions of the remote path `word`, relative to the
+// remote working directory `cwd`. As OneDrive names are case-insensitive, so
+// is the match. Completions keep the folder part of `word` as typed; folders
+// end in "/".
+func (c *Completer) Complete(ctx context.Context, cwd, word string) []string {
+	dir, base := splitDir(word)
+	if strings.HasPrefix(word, "id:") && !strings.Contains(word, "/") {
+		return nil // The ID itself cannot be completed.
+	}
+	listed := cwd
+	if dir != "" {
+		listed = Resolve(cwd, dir)
+	}
+	names, err := c.Children(ctx, listed)
+	if err != nil {
+		return nil
+	}
+	return matching(dir, base, names, true)
+}
+
+// CompleteLocal returns the completions of the local path `word`.
+func CompleteLocal(word string) []string {
+	dir, base := splitDir(word)
+	listed := dir
+	if listed == "" {
+		listed = "."
+	}
+	entries, err := os.ReadDir(filepath.FromSlash(listed))
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	return matching(dir, base, names, false)
+}
+
+// CompleteWord returns the completions of `word` among `names`.
+func CompleteWord(word string, names []string) []string {
+	return matching("", word, names, false)
+}
+
+// CommonPrefix returns the longest common prefix of `words`.
+func CommonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for !utf8.ValidString(prefix) { // Do not end in the middle of a character.
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// splitDir splits a typed path into its folder part, including the final
+// slash, and the name being typed.
+func splitDir(word string) (dir, base string) {
+	i := strings.LastIndex(word, "/")
+	return word[:i+1], word[i+1:]
+}
+
+// matching returns `dir` joined with each of `names` that starts with `base`,
+// sorted, ignoring case if `fold` is set. Hidden names are only offered if
+// `base` starts with a dot.
+func matching(dir, base string, names []string, fold bool) []string {
+	var matches []string
+	for _, name := range names {
+		prefix := name[:min(len(base), len(name))]
+		if prefix != base && !(fold && strings.EqualFold(prefix, base)) {
+			continue
+		}
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		matches = append(matches, dir+name)
+	}
+	sort.Strings(matches)
+	return matches
+}
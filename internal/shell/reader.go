@@ -0,0 +1,101 @@
+// Package shell (reader.go) reads the command lines of the shell: from a
+// terminal with line editing, history and Tab completion, or line by line
+// from any other input, so that the shell can also run a script.
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// CompleteFunc completes the word before position `pos` in `line`. It
+// returns the new line and position, and the candidates to show when the
+// word cannot be completed further.
+type CompleteFunc func(line string, pos int) (newLine string, newPos int, candidates []string)
+
+// LineReader reads the command lines of the shell.
+type LineReader interface {
+	// ReadLine returns the next line, showing `prompt` on a terminal, or
+	// io.EOF at the end of the input.
+	ReadLine(prompt string) (string, error)
+}
+
+// NewLineReader returns a LineReader for `in`. If `in` and `out` are a
+// terminal, lines are edited on the terminal, with the history of the
+// session on the arrow keys and `complete` on Tab.
+func NewLineReader(in io.Reader, out io.Writer, complete CompleteFunc) LineReader {
+	inFile, inOK := in.(*os.File)
+	outFile, outOK := out.(*os.File)
+	if inOK && outOK && term.IsTerminal(int(inFile.Fd())) && term.IsTerminal(int(outFile.Fd())) {
+		return newTerminalReader(inFile, outFile, complete)
+	}
+	return &plainReader{scanner: bufio.NewScanner(in)}
+}
+
+// plainReader reads lines without prompting, as from a script or a pipe.
+type plainReader struct {
+	scanner *bufio.Scanner
+}
+
+// ReadLine returns the next line of the input.
+func (r *plainReader) ReadLine(string) (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", fmt.Errorf("reading command: %w", err)
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// terminalReader edits lines on a terminal. The terminal is in raw mode only
+// while a line is read, so that commands run on a normal terminal and can
+// prompt, draw progress bars and be interrupted.
+type terminalReader struct {
+	fd       int
+	terminal *term.Terminal
+}
+
+// newTerminalReader returns a terminalReader reading from `in` and echoing
+// to `out`.
+func newTerminalReader(in, out *os.File, complete CompleteFunc) *terminalReader {
+	r := &terminalReader{
+		fd: int(in.Fd()),
+		terminal: term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{in, out}, ""),
+	}
+	r.terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' || complete == nil {
+			return "", 0, false
+		}
+		newLine, newPos, candidates := complete(line, pos)
+		if len(candidates) > 1 && newLine == line {
+			fmt.Fprintf(r.terminal, "%s\n", strings.Join(candidates, "  "))
+		}
+		return newLine, newPos, true
+	}
+	return r
+}
+
+// ReadLine reads a line on the terminal. Ctrl-D on an empty line, and
+// Ctrl-C, end the input.
+func (r *terminalReader) ReadLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(r.fd)
+	if err != nil {
+		return "", fmt.Errorf("setting up terminal: %w", err)
+	}
+	defer term.Restore(r.fd, state)
+
+	if width, height, err := term.GetSize(r.fd); err == nil {
+		r.terminal.SetSize(width, height)
+	}
+	r.terminal.SetPrompt(prompt)
+	return r.terminal.ReadLine()
+}
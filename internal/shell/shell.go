@@ -0,0 +1,118 @@
+// Package shell (shell.go) provides the building blocks of the interactive
+// shell ('onedrive-client shell'): splitting command lines into words,
+// resolving remote paths against a remote working directory, completing
+// remote and local names, and reading lines with history and completion.
+// The commands themselves are run by the cmd package.
+package shell
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// ErrUnterminatedQuote is returned for a command line with an unclosed quote.
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// Word is a word of a command line.
+type Word struct {
+	Text  string // The word without its quotes and escapes.
+	Start int    // Byte offset of the word in the line.
+}
+
+// Split splits a command line into words like a POSIX shell does, without
+// expansions: words are separated by blanks, and single quotes, double quotes
+// and backslashes keep blanks within a word.
+func Split(line string) ([]string, error) {
+	words, open := splitWords(line)
+	if open {
+		return nil, ErrUnterminatedQuote
+	}
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.Text
+	}
+	return texts, nil
+}
+
+// Words splits a command line that is being typed into words, like Split,
+// tolerating an unclosed quote at its end.
+func Words(line string) []Word {
+	words, _ := splitWords(line)
+	return words
+}
+
+// splitWords splits `line` into words, and reports whether a quote is still
+// open at its end, as while a word is being typed.
+func splitWords(line string) (words []Word, open bool) {
+	var current strings.Builder
+	start := -1
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' {
+				escaped = true
+			} else {
+				current.WriteRune(r)
+			}
+		case r == ' ' || r == '\t':
+			if start >= 0 {
+				words = append(words, Word{Text: current.String(), Start: start})
+				current.Reset()
+				start = -1
+			}
+			continue
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '\\':
+			escaped = true
+		default:
+			current.WriteRune(r)
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, Word{Text: current.String(), Start: start})
+	}
+	return words, quote != 0 || escaped
+}
+
+// Quote returns `word` with blanks, quotes and backslashes escaped, so that
+// Split reads it back as one word.
+func Quote(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		switch r {
+		case ' ', '\t', '\'', '"', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Resolve returns the remote path `p` relative to the remote working
+// directory `cwd`. Absolute paths and item references ("id:<itemId>") are
+// only cleaned.
+func Resolve(cwd, p string) string {
+	switch {
+	case strings.HasPrefix(p, "id:"):
+		return path.Clean(p)
+	case strings.HasPrefix(p, "/"):
+		return path.Clean(p)
+	}
+	resolved := path.Join(cwd, p)
+	if !strings.HasPrefix(resolved, "/") && !strings.HasPrefix(resolved, "id:") {
+		resolved = "/" + strings.TrimPrefix(resolved, ".")
+	}
+	return resolved
+}
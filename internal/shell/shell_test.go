@@ -0,0 +1,110 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"", []string{}},
+		{"  ls   -l  ", []string{"ls", "-l"}},
+		{`get "My Report.pdf" 'local copy.pdf'`, []string{"get", "My Report.pdf", "local copy.pdf"}},
+		{`cd My\ Documents`, []string{"cd", "My Documents"}},
+		{`put "say \"hi\".txt"`, []string{"put", `say "hi".txt`}},
+		{`rm a""b ''`, []string{"rm", "ab", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			words, err := Split(tt.line)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, words)
+		})
+	}
+
+	_, err := Split(`get "unterminated`)
+	assert.ErrorIs(t, err, ErrUnterminatedQuote)
+
+	words, err := Split("cd " + Quote(`it's "a" dir\name`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cd", `it's "a" dir\name`}, words, "Quote is read back as one word")
+}
+
+func TestWords(t *testing.T) {
+	assert.Equal(t, []Word{{"get", 0}, {"My Doc", 4}}, Words(`get "My Doc`), "an open quote is tolerated")
+	assert.Equal(t, []Word{{"ls", 2}}, Words("  ls "))
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		cwd, path, expected string
+	}{
+		{"/", "Documents", "/Documents"},
+		{"/Documents", "report.pdf", "/Documents/report.pdf"},
+		{"/Documents", "../Photos/", "/Photos"},
+		{"/Documents", "..", "/"},
+		{"/", "..", "/"},
+		{"/Documents", ".", "/Documents"},
+		{"/Documents", "/Photos/2024", "/Photos/2024"},
+		{"/Documents", "id:01ABC/Reports", "id:01ABC/Reports"},
+		{"id:01ABC", "Reports", "id:01ABC/Reports"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Resolve(tt.cwd, tt.path), "%s in %s", tt.path, tt.cwd)
+	}
+}
+
+func TestCompleter(t *testing.T) {
+	listed := map[string]int{}
+	c := NewCompleter(func(ctx context.Context, dir string) ([]string, error) {
+		listed[dir]++
+		switch dir {
+		case "/":
+			return []string{"Documents/", "Desktop/", "draft.txt", ".hidden"}, nil
+		case "/Documents":
+			return []string{"report.pdf", "Reports/"}, nil
+		}
+		return nil, errors.New("not found")
+	})
+	ctx := context.Background()
+
+	assert.Equal(t, []string{"Desktop/", "Documents/", "draft.txt"}, c.Complete(ctx, "/", "D"))
+	assert.Equal(t, []string{"Documents/"}, c.Complete(ctx, "/", "Do"))
+	assert.Equal(t, []string{"Documents/"}, c.Complete(ctx, "/", "doc"))
+	assert.Equal(t, []string{"Desktop/", "Documents/", "draft.txt"}, c.Complete(ctx, "/", ""), "hidden names need a dot")
+	assert.Equal(t, []string{"Desktop/"}, c.Complete(ctx, "/", "de"))
+	assert.Equal(t, []string{".hidden"}, c.Complete(ctx, "/", "."))
+	assert.Equal(t, []string{"Documents/Reports/", "Documents/report.pdf"}, c.Complete(ctx, "/", "Documents/r"), "case is ignored")
+	assert.Equal(t, []string{"../Documents/"}, c.Complete(ctx, "/Documents", "../Doc"))
+	assert.Empty(t, c.Complete(ctx, "/", "Missing/x"))
+	assert.Equal(t, 1, listed["/"], "listings are cached")
+
+	c.Invalidate()
+	c.Complete(ctx, "/", "D")
+	assert.Equal(t, 2, listed["/"], "Invalidate forgets the listings")
+}
+
+func TestCompleteLocal(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "photos"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "photo.jpg"), nil, 0o600))
+
+	assert.Equal(t, []string{filepath.ToSlash(dir) + "/photo.jpg", filepath.ToSlash(dir) + "/photos/"},
+		CompleteLocal(filepath.ToSlash(dir)+"/pho"))
+}
+
+func TestCommonPrefix(t *testing.T) {
+	assert.Equal(t, "Do", CommonPrefix([]string{"Documents/", "Downloads/"}))
+	assert.Equal(t, "report", CommonPrefix([]string{"report"}))
+	assert.Equal(t, "", CommonPrefix(nil))
+	assert.Equal(t, "caf", CommonPrefix([]string{"café", "cafè"}), "no partial characters")
+}
@@ -0,0 +1,45 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareTrees(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "photos"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "photos", "a.jpg"), []byte("hello"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes"), []byte("hi"), 0600))
+	local, err := scanLocal(root)
+	require.NoError(t, err)
+
+	remote := map[string]RemoteItem{
+		"photos":       {IsDir: true},
+		"photos/a.jpg": {Size: 5, Hash: "sha1:aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		"photos/b.jpg": {Size: 7},
+		"notes":        {IsDir: true},
+		"notes/todo":   {Size: 1},
+		"empty":        {IsDir: true},
+	}
+	result, err := compareTrees(root, local, remote)
+	require.NoError(t, err)
+	assert.Equal(t, []Difference{
+		{Type: DiffKind, Path: "notes"},
+		{Type: DiffOnlyRemote, Path: "notes/todo", RemoteSize: 1},
+		{Type: DiffOnlyRemote, Path: "photos/b.jpg", RemoteSize: 7},
+	}, result.Differences)
+	assert.Equal(t, 1, result.Compared)
+	assert.Equal(t, 1, result.Matched)
+	assert.Zero(t, result.SizeOnly)
+
+	remote["photos/a.jpg"] = RemoteItem{Size: 5, Hash: "sha1:0000000000000000000000000000000000000000"}
+	result, err = compareTrees(root, local, remote)
+	require.NoError(t, err)
+	assert.Contains(t, result.Differences, Difference{
+		Type: DiffHash, Path: "photos/a.jpg", LocalSize: 5, RemoteSize: 5, RemoteHash: "sha1:0000000000000000000000000000000000000000",
+	})
+}
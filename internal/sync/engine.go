@@ -0,0 +1,772 @@
+// Package sync implements two-way synchronisation between a local directory and
+// a OneDrive folder.
+//
+// Package sync (engine.go) contains the reconciliation engine. Remote changes
+// are discovered with delta queries (app.SDK.GetDelta), local changes with a
+// directory scan, and both are compared against a baseline recording the state
+// of every item after the previous successful sync. The resulting plan is
+// carried out with the regular SDK primitives: uploads (simple or resumable
+// upload sessions), DownloadFile, DeleteDriveItem and MoveDriveItem.
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/internal/upload"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// ActionType identifies what the engine did (or, in a dry run, would do) to
+// bring one path back in sync.
+type ActionType string
+
+const (
+	// ActionUpload copies a local file to OneDrive.
+	ActionUpload ActionType = "upload"
+	// ActionDownload copies a remote file to the local directory.
+	ActionDownload ActionType = "download"
+	// ActionCreateRemoteFolder creates a folder in OneDrive.
+	ActionCreateRemoteFolder ActionType = "mkdir-remote"
+	// ActionCreateLocalFolder creates a local directory.
+	ActionCreateLocalFolder ActionType = "mkdir-local"
+	// ActionDeleteRemote moves a remote item to the OneDrive recycle bin.
+	ActionDeleteRemote ActionType = "delete-remote"
+	// ActionDeleteLocal removes a local file or directory.
+	ActionDeleteLocal ActionType = "delete-local"
+	// ActionMoveRemote replays a local rename or move on OneDrive.
+	ActionMoveRemote ActionType = "move-remote"
+	// ActionMoveLocal replays a remote rename or move locally.
+	ActionMoveLocal ActionType = "move-local"
+	// ActionConflict marks a path changed on both sides. It is settled by the
+	// resolver according to Options.Conflicts; see Action.Resolution.
+	ActionConflict ActionType = "conflict"
+)
+
+// Action is a single step of a sync run.
+type Action struct {
+	Type ActionType
+	Path string // Path relative to the sync roots, slash-separated.
+	From string // Previous relative path, for moves only.
+	Err  error  // Set if the step failed.
+
+	Resolution Resolution // How a conflict was settled, for conflicts only.
+	Copy       string     // Relative path of the conflict copy made by keep-both.
+}
+
+// Options configures a sync run.
+type Options struct {
+	LocalRoot  string // Local directory to synchronise.
+	RemoteRoot string // OneDrive folder to synchronise, e.g. "/Documents".
+	DryRun     bool   // Plan only; do not change anything on either side.
+
+	Conflicts ConflictPolicy // How to resolve conflicts; defaults to PolicyKeepBoth.
+	Prompt    PromptFunc     // Asks the user about each conflict under PolicyInteractive.
+
+	// Sessions saves the progress of interrupted large uploads, so that the
+	// next run resumes them. Without it, they start over.
+	Sessions *session.Manager
+}
+
+// Result summarises a sync run.
+type Result struct {
+	Actions []Action
+	State   *State // Updated state to persist for the next run.
+	DryRun  bool
+}
+
+// Count returns the number of actions of the given type, excluding failures.
+func (r *Result) Count(t ActionType) int {
+	n := 0
+	for i := range r.Actions {
+		if r.Actions[i].Type == t && r.Actions[i].Err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Conflicts returns the conflict actions, each carrying its resolution.
+func (r *Result) Conflicts() []Action {
+	var conflicts []Action
+	for i := range r.Actions {
+		if r.Actions[i].Type == ActionConflict {
+			conflicts = append(conflicts, r.Actions[i])
+		}
+	}
+	return conflicts
+}
+
+// Failed returns the actions that returned an error.
+func (r *Result) Failed() []Action {
+	var failed []Action
+	for i := range r.Actions {
+		if r.Actions[i].Err != nil {
+			failed = append(failed, r.Actions[i])
+		}
+	}
+	return failed
+}
+
+// Engine reconciles a local directory with a OneDrive folder.
+type Engine struct {
+	sdk  app.SDK
+	opts Options
+}
+
+// NewEngine creates a sync engine operating through `sdk`.
+func NewEngine(sdk app.SDK, opts Options) *Engine {
+	return &Engine{sdk: sdk, opts: opts}
+}
+
+// Run performs one sync pass.
+//
+// `prev` is the state saved by the previous run, or a State carrying only its
+// Key for the first run. When it holds a delta link and remote index for the
+// same remote folder, only remote changes since that link are fetched;
+// otherwise the remote folder is enumerated in full. Without a baseline
+// nothing can be recognised as deleted, so the first run only merges the two
+// trees: files present on one side are copied to the other, and files present
+// on both with the same content are adopted as in sync. Content is compared by
+// hash when the API reports one, and by size otherwise.
+//
+// Conflicts are resolved last, once every other change has been applied, so
+// the conflict copies made by keep-both cannot collide with incoming files.
+//
+// Failures of individual actions are recorded in the Result and do not stop
+// the run; only failures to read either tree are returned as an error.
+func (e *Engine) Run(ctx context.Context, prev *State) (*Result, error) {
+	info, err := os.Stat(e.opts.LocalRoot)
+	if err != nil {
+		return nil, fmt.Errorf("accessing local directory '%s': %w", e.opts.LocalRoot, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("local path '%s' is not a directory", e.opts.LocalRoot)
+	}
+
+	root, err := e.remoteRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index, deltaLink, err := e.remoteChanges(ctx, root.ID, prev)
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := scanLocal(e.opts.LocalRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := prev.Baseline.clone()
+	result := &Result{DryRun: e.opts.DryRun}
+	s := &syncState{local: local, remote: index.Paths(), baseline: baseline, index: index}
+
+	for _, a := range planMoves(s) {
+		if !e.opts.DryRun {
+			a.Err = e.move(ctx, a)
+		}
+		result.Actions = append(result.Actions, a)
+		if a.Err == nil {
+			s.applyMove(a)
+		}
+	}
+
+	actions := plan(s)
+	for _, a := range actions {
+		switch {
+		case a.Type == ActionConflict:
+			a.Err = e.resolve(ctx, s, &a)
+		case !e.opts.DryRun:
+			a.Err = e.execute(ctx, s, a)
+		}
+		result.Actions = append(result.Actions, a)
+	}
+
+	result.State = &State{
+		Key:       prev.Key,
+		DeltaLink: deltaLink,
+		LastSync:  time.Now(),
+		Remote:    index,
+		Baseline:  s.baseline,
+	}
+	return result, nil
+}
+
+// remoteChanges brings the remote index up to date. It resumes from the delta
+// link in `prev` when that state belongs to the same remote root, and falls
+// back to a full enumeration otherwise or if the saved link is rejected (for
+// example because the server expired it).
+func (e *Engine) remoteChanges(ctx context.Context, rootID string, prev *State) (*RemoteIndex, string, error) {
+	if prev.DeltaLink != "" && prev.Remote != nil && prev.Remote.RootID == rootID {
+		index := prev.Remote.clone()
+		deltaLink, err := e.fetchDelta(ctx, index, onedrive.ExtractDeltaToken(prev.DeltaLink))
+		switch {
+		case err != nil:
+			log.Printf("Warning: resuming remote change tracking failed, re-scanning '%s': %v", e.opts.RemoteRoot, err)
+		case index.incomplete():
+			log.Printf("A folder was moved into '%s'; re-scanning it.", e.opts.RemoteRoot)
+		default:
+			return index, deltaLink, nil
+		}
+	}
+
+	index := NewRemoteIndex(rootID)
+	deltaLink, err := e.fetchDelta(ctx, index, "")
+	if err != nil {
+		return nil, "", err
+	}
+	return index, deltaLink, nil
+}
+
+// remoteRoot resolves the remote sync root, creating it if it does not exist.
+func (e *Engine) remoteRoot(ctx context.Context) (onedrive.DriveItem, error) {
+	root, err := e.sdk.GetDriveItemByPath(ctx, e.opts.RemoteRoot)
+	if err == nil {
+		if root.Folder == nil && root.ID != "" {
+			return root, fmt.Errorf("remote path '%s' is not a folder", e.opts.RemoteRoot)
+		}
+		return root, nil
+	}
+	if !errors.Is(err, onedrive.ErrResourceNotFound) || e.opts.DryRun {
+		return root, fmt.Errorf("getting remote folder '%s': %w", e.opts.RemoteRoot, err)
+	}
+
+	clean := cleanRemoteRoot(e.opts.RemoteRoot)
+	if path.Dir(clean) == "." {
+		// A missing item given by ID alone has no parent to recreate it in.
+		return root, fmt.Errorf("getting remote folder '%s': %w", e.opts.RemoteRoot, err)
+	}
+	root, err = e.sdk.CreateFolder(ctx, path.Dir(clean), path.Base(clean))
+	if err != nil {
+		return root, fmt.Errorf("creating remote folder '%s': %w", clean, err)
+	}
+	log.Printf("Created remote folder '%s'.", clean)
+	return root, nil
+}
+
+// fetchDelta pages through a delta query starting from `token` (empty for a
+// full enumeration), folding every page into `index`, and returns the final
+// delta link.
+func (e *Engine) fetchDelta(ctx context.Context, index *RemoteIndex, token string) (string, error) {
+	for {
+		delta, err := e.sdk.GetDelta(ctx, token)
+		if err != nil {
+			return "", fmt.Errorf("fetching remote changes: %w", err)
+		}
+		index.Apply(delta.Value)
+
+		if delta.NextLink == "" {
+			return delta.DeltaLink, nil
+		}
+		token = onedrive.ExtractDeltaToken(delta.NextLink)
+		if token == "" {
+			return "", fmt.Errorf("delta next link carries no token: %s", delta.NextLink)
+		}
+	}
+}
+
+// syncState is the working view of both sides during a run. It is updated as
+// actions complete so later planning steps see their effects.
+type syncState struct {
+	local    map[string]LocalEntry
+	remote   map[string]RemoteItem
+	baseline Baseline
+	index    *RemoteIndex // Kept in step with our own remote changes for the saved state.
+}
+
+// recordRemote stores the metadata of an item this run created or replaced
+// remotely. Our own changes are reported again by the next delta query, but
+// recording them now keeps the saved index correct even if that query is
+// never made. The parent and name are taken from the sync path, as upload
+// responses do not always carry a parent reference.
+func (s *syncState) recordRemote(rel string, item onedrive.DriveItem) RemoteItem {
+	r := newRemoteItem(item)
+	r.Name = path.Base(rel)
+	r.ParentID = s.parentID(rel)
+	if item.Folder != nil || s.local[rel].IsDir {
+		r.IsDir = true
+	}
+	s.remote[rel] = r
+	if s.index != nil && r.ID != "" {
+		s.index.Items[r.ID] = r
+	}
+	return r
+}
+
+// parentID returns the remote item ID of the folder containing `rel`.
+func (s *syncState) parentID(rel string) string {
+	if parent := path.Dir(rel); parent != "." {
+		return s.remote[parent].ID
+	}
+	if s.index != nil {
+		return s.index.RootID
+	}
+	return ""
+}
+
+// applyMove rekeys every map entry at or below `a.From` to `a.Path`, and
+// for remote moves updates the moved item in the remote index.
+func (s *syncState) applyMove(a Action) {
+	rekey(s.local, a.From, a.Path)
+	rekey(s.remote, a.From, a.Path)
+	rekey(s.baseline, a.From, a.Path)
+
+	if a.Type != ActionMoveRemote || s.index == nil {
+		return
+	}
+	if r, ok := s.remote[a.Path]; ok {
+		r.Name = path.Base(a.Path)
+		r.ParentID = s.parentID(a.Path)
+		s.remote[a.Path] = r
+		s.index.Items[r.ID] = r
+	}
+}
+
+// forgetRemote drops a deleted item and its descendants from the remote view.
+func (s *syncState) forgetRemote(rel string) {
+	for p := range s.remote {
+		if p == rel || isUnder(p, rel) {
+			if s.index != nil {
+				delete(s.index.Items, s.remote[p].ID)
+			}
+			delete(s.remote, p)
+		}
+	}
+}
+
+// rekey moves every entry of `m` at or below `from` to the same position below `to`.
+func rekey[V any](m map[string]V, from, to string) {
+	for p, v := range m {
+		if p == from || isUnder(p, from) {
+			delete(m, p)
+			m[to+strings.TrimPrefix(p, from)] = v
+		}
+	}
+}
+
+// planMoves detects renames and moves on either side.
+//
+// A remote move is recognised when a baseline item's ID now resolves to a
+// different remote path while the old local path is still present and the new
+// one is free. A local move is recognised when a baseline file has vanished
+// locally but is unchanged remotely, and exactly one new local file has the
+// same size and modification time, in a folder that already exists remotely.
+func planMoves(s *syncState) []Action {
+	var moves []Action
+
+	byID := make(map[string]string, len(s.remote))
+	for p, r := range s.remote {
+		byID[r.ID] = p
+	}
+
+	claimed := make(map[string]bool)
+	for _, p := range sortedKeys(s.baseline) {
+		b := s.baseline[p]
+		if b.ItemID == "" || claimed[p] || hasClaimedAncestor(claimed, p) {
+			continue
+		}
+		newPath, ok := byID[b.ItemID]
+		if !ok || newPath == p {
+			continue
+		}
+		if _, stillRemote := s.remote[p]; stillRemote {
+			continue
+		}
+		if _, present := s.local[p]; !present {
+			continue
+		}
+		if _, taken := s.local[newPath]; taken {
+			continue
+		}
+		moves = append(moves, Action{Type: ActionMoveLocal, From: p, Path: newPath})
+		claimed[p] = true
+	}
+
+	candidates := make(map[string][]string)
+	for p, l := range s.local {
+		if l.IsDir {
+			continue
+		}
+		if _, known := s.baseline[p]; known {
+			continue
+		}
+		if _, remote := s.remote[p]; remote {
+			continue
+		}
+		if parent := path.Dir(p); parent != "." {
+			// The destination folder must already exist remotely.
+			if _, ok := s.remote[parent]; !ok {
+				continue
+			}
+		}
+		key := fmt.Sprintf("%d/%d", l.Size, l.ModTime.UnixNano())
+		candidates[key] = append(candidates[key], p)
+	}
+
+	for _, p := range sortedKeys(s.baseline) {
+		b := s.baseline[p]
+		if b.IsDir || claimed[p] {
+			continue
+		}
+		if _, present := s.local[p]; present {
+			continue
+		}
+		r, ok := s.remote[p]
+		if !ok || !remoteUnchanged(r, b) {
+			continue
+		}
+		key := fmt.Sprintf("%d/%d", b.Size, b.ModTime.UnixNano())
+		if len(candidates[key]) != 1 {
+			continue
+		}
+		moves = append(moves, Action{Type: ActionMoveRemote, From: p, Path: candidates[key][0]})
+		delete(candidates, key)
+		claimed[p] = true
+	}
+
+	return moves
+}
+
+func hasClaimedAncestor(claimed map[string]bool, p string) bool {
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if claimed[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// plan compares each path's local entry, remote item and baseline and returns
+// the actions needed to reconcile them, ordered so that folders are created
+// before their contents and deleted after them.
+func plan(s *syncState) []Action {
+	paths := make(map[string]struct{})
+	for p := range s.local {
+		paths[p] = struct{}{}
+	}
+	for p := range s.remote {
+		paths[p] = struct{}{}
+	}
+	for p := range s.baseline {
+		paths[p] = struct{}{}
+	}
+
+	var creates, transfers, deletes, conflicts []Action
+	for _, p := range sortedKeys(paths) {
+		l, hasLocal := s.local[p]
+		r, hasRemote := s.remote[p]
+		b, hasBase := s.baseline[p]
+
+		switch {
+		case hasLocal && hasRemote:
+			if l.IsDir != r.IsDir {
+				conflicts = append(conflicts, Action{Type: ActionConflict, Path: p})
+				continue
+			}
+			if l.IsDir {
+				s.baseline[p] = newBaselineEntry(l, r)
+				continue
+			}
+			localChanged := !hasBase || !fileUnchanged(l, b)
+			remoteChanged := !hasBase || !remoteUnchanged(r, b)
+			switch {
+			case !hasBase && l.Size == r.Size && r.Hash == "":
+				// Without a hash, equal sizes are the best evidence of equal
+				// content. With one, the resolver compares contents instead.
+				s.baseline[p] = newBaselineEntry(l, r)
+			case localChanged && remoteChanged:
+				conflicts = append(conflicts, Action{Type: ActionConflict, Path: p})
+			case localChanged:
+				transfers = append(transfers, Action{Type: ActionUpload, Path: p})
+			case remoteChanged:
+				transfers = append(transfers, Action{Type: ActionDownload, Path: p})
+			}
+
+		case hasLocal:
+			if hasBase && (l.IsDir || fileUnchanged(l, b)) {
+				deletes = append(deletes, Action{Type: ActionDeleteLocal, Path: p})
+			} else if l.IsDir {
+				creates = append(creates, Action{Type: ActionCreateRemoteFolder, Path: p})
+			} else {
+				transfers = append(transfers, Action{Type: ActionUpload, Path: p})
+			}
+
+		case hasRemote:
+			if hasBase && (r.IsDir || remoteUnchanged(r, b)) {
+				deletes = append(deletes, Action{Type: ActionDeleteRemote, Path: p})
+			} else if r.IsDir {
+				creates = append(creates, Action{Type: ActionCreateLocalFolder, Path: p})
+			} else {
+				transfers = append(transfers, Action{Type: ActionDownload, Path: p})
+			}
+
+		default:
+			// Gone on both sides.
+			delete(s.baseline, p)
+		}
+	}
+
+	creates, deletes = keepNeededFolders(creates, transfers, conflicts, deletes)
+
+	actions := make([]Action, 0, len(creates)+len(transfers)+len(deletes)+len(conflicts))
+	actions = append(actions, creates...)
+	actions = append(actions, transfers...)
+	actions = append(actions, deletes...)
+	return append(actions, conflicts...)
+}
+
+// keepNeededFolders resolves deletions that would take other work with them.
+// A folder deleted on one side but holding new or changed content on the other
+// is recreated instead of deleted. Deletions nested inside another deleted
+// folder are dropped, as deleting the folder removes them. Remaining deletions
+// are ordered deepest first.
+func keepNeededFolders(creates, transfers, conflicts, deletes []Action) ([]Action, []Action) {
+	busy := make([]string, 0, len(creates)+len(transfers)+len(conflicts))
+	for _, group := range [][]Action{creates, transfers, conflicts} {
+		for i := range group {
+			busy = append(busy, group[i].Path)
+		}
+	}
+
+	var kept []Action
+	for _, d := range deletes {
+		needed := false
+		for _, p := range busy {
+			if isUnder(p, d.Path) {
+				needed = true
+				break
+			}
+		}
+		if !needed {
+			kept = append(kept, d)
+			continue
+		}
+		recreate := ActionCreateRemoteFolder
+		if d.Type == ActionDeleteRemote {
+			recreate = ActionCreateLocalFolder
+		}
+		creates = append(creates, Action{Type: recreate, Path: d.Path})
+	}
+	sort.SliceStable(creates, func(i, j int) bool { return creates[i].Path < creates[j].Path })
+
+	var pruned []Action
+	for _, d := range kept {
+		nested := false
+		for _, other := range kept {
+			if other.Type == d.Type && isUnder(d.Path, other.Path) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			pruned = append(pruned, d)
+		}
+	}
+	sort.SliceStable(pruned, func(i, j int) bool { return pruned[i].Path > pruned[j].Path })
+
+	return creates, pruned
+}
+
+// move carries out a move action detected by planMoves.
+func (e *Engine) move(ctx context.Context, a Action) error {
+	switch a.Type {
+	case ActionMoveLocal:
+		dst := localPath(e.opts.LocalRoot, a.Path)
+		if err := os.MkdirAll(filepath.Dir(dst), onedrive.PermSecureDir); err != nil {
+			return fmt.Errorf("creating local directory for '%s': %w", a.Path, err)
+		}
+		if err := os.Rename(localPath(e.opts.LocalRoot, a.From), dst); err != nil {
+			return fmt.Errorf("moving local '%s' to '%s': %w", a.From, a.Path, err)
+		}
+		return nil
+
+	case ActionMoveRemote:
+		src := remotePath(e.opts.RemoteRoot, a.From)
+		dstParent := path.Dir(remotePath(e.opts.RemoteRoot, a.Path))
+		current := src
+		if path.Dir(src) != dstParent {
+			if _, err := e.sdk.MoveDriveItem(ctx, src, dstParent); err != nil {
+				return fmt.Errorf("moving remote '%s' to '%s': %w", src, dstParent, err)
+			}
+			current = path.Join(dstParent, path.Base(src))
+		}
+		if newName := path.Base(a.Path); newName != path.Base(src) {
+			if _, err := e.sdk.UpdateDriveItem(ctx, current, newName); err != nil {
+				return fmt.Errorf("renaming remote '%s' to '%s': %w", current, newName, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unexpected move action type '%s'", a.Type)
+}
+
+// execute performs a single planned action and updates the baseline to match.
+func (e *Engine) execute(ctx context.Context, s *syncState, a Action) error {
+	local := localPath(e.opts.LocalRoot, a.Path)
+	remote := remotePath(e.opts.RemoteRoot, a.Path)
+
+	switch a.Type {
+	case ActionCreateRemoteFolder:
+		item, err := e.sdk.CreateFolder(ctx, path.Dir(remote), path.Base(remote))
+		if err != nil {
+			return fmt.Errorf("creating remote folder '%s': %w", remote, err)
+		}
+		s.baseline[a.Path] = newBaselineEntry(s.local[a.Path], s.recordRemote(a.Path, item))
+
+	case ActionCreateLocalFolder:
+		if err := os.MkdirAll(local, onedrive.PermSecureDir); err != nil {
+			return fmt.Errorf("creating local directory '%s': %w", local, err)
+		}
+		info, err := os.Stat(local)
+		if err != nil {
+			return fmt.Errorf("reading local directory '%s': %w", local, err)
+		}
+		s.baseline[a.Path] = newBaselineEntry(LocalEntry{IsDir: true, ModTime: info.ModTime()}, s.remote[a.Path])
+
+	case ActionUpload:
+		item, err := e.upload(ctx, local, remote)
+		if err != nil {
+			return err
+		}
+		if err := onedrive.VerifyFile(local, item); err != nil {
+			return fmt.Errorf("verifying upload of '%s': %w", local, err)
+		}
+		return e.recordLocal(s, a.Path, local, s.recordRemote(a.Path, item))
+
+	case ActionDownload:
+		r := s.remote[a.Path]
+		if err := e.download(ctx, remote, local, r); err != nil {
+			return err
+		}
+		return e.recordLocal(s, a.Path, local, r)
+
+	case ActionDeleteRemote:
+		if err := e.sdk.DeleteDriveItem(ctx, remote); err != nil {
+			return fmt.Errorf("deleting remote '%s': %w", remote, err)
+		}
+		s.forgetRemote(a.Path)
+		s.forget(a.Path)
+
+	case ActionDeleteLocal:
+		if err := os.RemoveAll(local); err != nil {
+			return fmt.Errorf("deleting local '%s': %w", local, err)
+		}
+		s.forget(a.Path)
+	}
+	return nil
+}
+
+// recordLocal stats a just-transferred file and stores its new baseline.
+func (e *Engine) recordLocal(s *syncState, rel, local string, r RemoteItem) error {
+	info, err := os.Stat(local)
+	if err != nil {
+		return fmt.Errorf("reading local file '%s' after transfer: %w", local, err)
+	}
+	s.baseline[rel] = newBaselineEntry(LocalEntry{Size: info.Size(), ModTime: info.ModTime()}, r)
+	return nil
+}
+
+// forget removes a path and everything below it from the baseline.
+func (s *syncState) forget(rel string) {
+	for p := range s.baseline {
+		if p == rel || isUnder(p, rel) {
+			delete(s.baseline, p)
+		}
+	}
+}
+
+// upload sends a local file to OneDrive, using a simple upload for small files
+// and a resumable upload session above onedrive.LargeFileThreshold, saved in
+// Options.Sessions. It returns the metadata of the uploaded item.
+func (e *Engine) upload(ctx context.Context, local, remote string) (onedrive.DriveItem, error) {
+	info, err := os.Stat(local)
+	if err != nil {
+		return onedrive.DriveItem{}, fmt.Errorf("reading local file '%s': %w", local, err)
+	}
+	if info.Size() <= onedrive.LargeFileThreshold {
+		item, err := e.sdk.UploadFile(ctx, local, remote)
+		if err != nil {
+			return item, fmt.Errorf("uploading '%s' to '%s': %w", local, remote, err)
+		}
+		return item, nil
+	}
+
+	// Large files share the resumable upload of 'items upload': pipelined,
+	// adaptive chunks that recover from gaps and resume on the next run.
+	return upload.Resumable(ctx, e.sdk, e.opts.Sessions, local, remote, nil)
+}
+
+// download fetches a remote file into a temporary file next to `local`, checks
+// it against the size and content hash reported for `r` and renames it into
+// place once verified, then applies the remote modification time.
+func (e *Engine) download(ctx context.Context, remote, local string, r RemoteItem) error {
+	if err := os.MkdirAll(filepath.Dir(local), onedrive.PermSecureDir); err != nil {
+		return fmt.Errorf("creating local directory for '%s': %w", local, err)
+	}
+	partial := local + partialSuffix
+	if err := e.sdk.DownloadFile(ctx, remote, partial); err != nil {
+		if removeErr := os.Remove(partial); removeErr != nil && !os.IsNotExist(removeErr) {
+			log.Printf("Warning: failed to remove partial download '%s': %v", partial, removeErr)
+		}
+		return fmt.Errorf("downloading '%s': %w", remote, err)
+	}
+	if err := verifyDownload(partial, r); err != nil {
+		if removeErr := os.Remove(partial); removeErr != nil {
+			log.Printf("Warning: failed to remove partial download '%s': %v", partial, removeErr)
+		}
+		return fmt.Errorf("verifying download of '%s': %w", remote, err)
+	}
+	if err := os.Rename(partial, local); err != nil {
+		return fmt.Errorf("moving downloaded file into place at '%s': %w", local, err)
+	}
+	if err := keepModTime(local, r.Modified); err != nil {
+		log.Printf("Warning: failed to set modification time on '%s': %v", local, err)
+	}
+	return nil
+}
+
+// verifyDownload checks a downloaded file against the size and, when the API
+// reported one, the content hash of the remote item it was fetched from.
+func verifyDownload(local string, r RemoteItem) error {
+	info, err := os.Stat(local)
+	if err != nil {
+		return fmt.Errorf("reading downloaded file '%s': %w", local, err)
+	}
+	if info.Size() != r.Size {
+		return fmt.Errorf("%w: downloaded %d bytes, remote item is %d bytes", onedrive.ErrContentMismatch, info.Size(), r.Size)
+	}
+	if r.Hash == "" {
+		return nil
+	}
+	same, err := sameContent(local, r.Hash)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return fmt.Errorf("%w: downloaded content does not match %s", onedrive.ErrContentMismatch, r.Hash)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a string-keyed map in lexical order, which
+// places every folder before its contents.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,261 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+func driveItem(id, parentID, name string, folder bool) onedrive.DriveItem {
+	item := onedrive.DriveItem{ID: id, Name: name, CTag: "c-" + id}
+	item.ParentReference.ID = parentID
+	if folder {
+		item.Folder = &onedrive.FolderFacet{}
+	}
+	return item
+}
+
+func TestRemoteIndexApply(t *testing.T) {
+	index := NewRemoteIndex("root")
+
+	// Child listed before its parent, plus an item outside the sync root.
+	index.Apply([]onedrive.DriveItem{
+		driveItem("root", "drive", "Work", true),
+		driveItem("f1", "d1", "a.txt", false),
+		driveItem("d1", "root", "docs", true),
+		driveItem("x1", "elsewhere", "other.txt", false),
+	})
+	assert.ElementsMatch(t, []string{"docs", "docs/a.txt"}, sortedKeys(index.Paths()))
+
+	// Renaming a folder moves its descendants without them being reported.
+	index.Apply([]onedrive.DriveItem{driveItem("d1", "root", "papers", true)})
+	assert.ElementsMatch(t, []string{"papers", "papers/a.txt"}, sortedKeys(index.Paths()))
+
+	// Moving a folder out of the root drops it and its descendants.
+	index.Apply([]onedrive.DriveItem{driveItem("d1", "elsewhere", "papers", true)})
+	assert.Empty(t, index.Paths())
+	assert.Empty(t, index.Items)
+}
+
+func TestPlan(t *testing.T) {
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	later := mtime.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		local    map[string]LocalEntry
+		remote   map[string]RemoteItem
+		baseline Baseline
+		expected []Action
+	}{
+		{
+			name:     "new local file is uploaded",
+			local:    map[string]LocalEntry{"a.txt": {Size: 3, ModTime: mtime}},
+			expected: []Action{{Type: ActionUpload, Path: "a.txt"}},
+		},
+		{
+			name:     "new remote folder and file are downloaded",
+			remote:   map[string]RemoteItem{"d": {ID: "1", IsDir: true}, "d/b.txt": {ID: "2", Size: 3}},
+			expected: []Action{{Type: ActionCreateLocalFolder, Path: "d"}, {Type: ActionDownload, Path: "d/b.txt"}},
+		},
+		{
+			name:     "file on both sides with equal size is adopted",
+			local:    map[string]LocalEntry{"a.txt": {Size: 3, ModTime: mtime}},
+			remote:   map[string]RemoteItem{"a.txt": {ID: "1", Size: 3, CTag: "c1"}},
+			expected: []Action{},
+		},
+		{
+			name:     "file on both sides with a hash is left to the resolver",
+			local:    map[string]LocalEntry{"a.txt": {Size: 3, ModTime: mtime}},
+			remote:   map[string]RemoteItem{"a.txt": {ID: "1", Size: 3, CTag: "c1", Hash: "sha1:abc"}},
+			expected: []Action{{Type: ActionConflict, Path: "a.txt"}},
+		},
+		{
+			name:     "remote cTag change with unchanged hash is ignored",
+			local:    map[string]LocalEntry{"a.txt": {Size: 3, ModTime: mtime}},
+			remote:   map[string]RemoteItem{"a.txt": {ID: "1", Size: 3, CTag: "c2", Hash: "sha1:abc"}},
+			baseline: Baseline{"a.txt": {ItemID: "1", Size: 3, ModTime: mtime, CTag: "c1", Hash: "sha1:abc"}},
+			expected: []Action{},
+		},
+		{
+			name:     "local edit is uploaded",
+			local:    map[string]LocalEntry{"a.txt": {Size: 4, ModTime: later}},
+			remote:   map[string]RemoteItem{"a.txt": {ID: "1", Size: 3, CTag: "c1"}},
+			baseline: Baseline{"a.txt": {ItemID: "1", Size: 3, ModTime: mtime, CTag: "c1"}},
+			expected: []Action{{Type: ActionUpload, Path: "a.txt"}},
+		},
+		{
+			name:     "remote edit is downloaded",
+			local:    map[string]LocalEntry{"a.txt": {Size: 3, ModTime: mtime}},
+			remote:   map[string]RemoteItem{"a.txt": {ID: "1", Size: 5, CTag: "c2"}},
+			baseline: Baseline{"a.txt": {ItemID: "1", Size: 3, ModTime: mtime, CTag: "c1"}},
+			expected: []Action{{Type: ActionDownload, Path: "a.txt"}},
+		},
+		{
+			name:     "edits on both sides conflict",
+			local:    map[string]LocalEntry{"a.txt": {Size: 4, ModTime: later}},
+			remote:   map[string]RemoteItem{"a.txt": {ID: "1", Size: 5, CTag: "c2"}},
+			baseline: Baseline{"a.txt": {ItemID: "1", Size: 3, ModTime: mtime, CTag: "c1"}},
+			expected: []Action{{Type: ActionConflict, Path: "a.txt"}},
+		},
+		{
+			name:     "remote deletion removes unchanged local file",
+			local:    map[string]LocalEntry{"a.txt": {Size: 3, ModTime: mtime}},
+			baseline: Baseline{"a.txt": {ItemID: "1", Size: 3, ModTime: mtime, CTag: "c1"}},
+			expected: []Action{{Type: ActionDeleteLocal, Path: "a.txt"}},
+		},
+		{
+			name:     "remote deletion of locally edited file re-uploads it",
+			local:    map[string]LocalEntry{"a.txt": {Size: 4, ModTime: later}},
+			baseline: Baseline{"a.txt": {ItemID: "1", Size: 3, ModTime: mtime, CTag: "c1"}},
+			expected: []Action{{Type: ActionUpload, Path: "a.txt"}},
+		},
+		{
+			name: "local folder deletion removes remote folder once",
+			remote: map[string]RemoteItem{
+				"d":       {ID: "1", IsDir: true},
+				"d/a.txt": {ID: "2", CTag: "c2"},
+			},
+			baseline: Baseline{
+				"d":       {ItemID: "1", IsDir: true},
+				"d/a.txt": {ItemID: "2", CTag: "c2"},
+			},
+			expected: []Action{{Type: ActionDeleteRemote, Path: "d"}},
+		},
+		{
+			name: "remote folder deletion keeps folder holding new local files",
+			local: map[string]LocalEntry{
+				"d":         {IsDir: true, ModTime: mtime},
+				"d/new.txt": {Size: 1, ModTime: later},
+			},
+			baseline: Baseline{"d": {ItemID: "1", IsDir: true}},
+			expected: []Action{{Type: ActionCreateRemoteFolder, Path: "d"}, {Type: ActionUpload, Path: "d/new.txt"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &syncState{local: tt.local, remote: tt.remote, baseline: tt.baseline}
+			if s.local == nil {
+				s.local = map[string]LocalEntry{}
+			}
+			if s.remote == nil {
+				s.remote = map[string]RemoteItem{}
+			}
+			if s.baseline == nil {
+				s.baseline = Baseline{}
+			}
+			assert.Equal(t, tt.expected, plan(s))
+		})
+	}
+}
+
+func TestPlanMoves(t *testing.T) {
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("remote rename is replayed locally", func(t *testing.T) {
+		s := &syncState{
+			local:    map[string]LocalEntry{"old.txt": {Size: 3, ModTime: mtime}},
+			remote:   map[string]RemoteItem{"new.txt": {ID: "1", Size: 3, CTag: "c1"}},
+			baseline: Baseline{"old.txt": {ItemID: "1", Size: 3, ModTime: mtime, CTag: "c1"}},
+		}
+		moves := planMoves(s)
+		assert.Equal(t, []Action{{Type: ActionMoveLocal, From: "old.txt", Path: "new.txt"}}, moves)
+
+		s.applyMove(moves[0])
+		assert.Empty(t, plan(s))
+	})
+
+	t.Run("local rename is replayed remotely", func(t *testing.T) {
+		s := &syncState{
+			local: map[string]LocalEntry{
+				"d":       {IsDir: true, ModTime: mtime},
+				"d/b.txt": {Size: 3, ModTime: mtime},
+			},
+			remote: map[string]RemoteItem{
+				"d":     {ID: "2", IsDir: true},
+				"a.txt": {ID: "1", Size: 3, CTag: "c1"},
+			},
+			baseline: Baseline{
+				"d":     {ItemID: "2", IsDir: true, ModTime: mtime},
+				"a.txt": {ItemID: "1", Size: 3, ModTime: mtime, CTag: "c1"},
+			},
+		}
+		moves := planMoves(s)
+		assert.Equal(t, []Action{{Type: ActionMoveRemote, From: "a.txt", Path: "d/b.txt"}}, moves)
+	})
+}
+
+func TestRemotePath(t *testing.T) {
+	assert.Equal(t, "/a/b.txt", remotePath("/", "a/b.txt"))
+	assert.Equal(t, "/Work/a/b.txt", remotePath("Work/", "a/b.txt"))
+	assert.Equal(t, "/Work/a/b.txt", remotePath("/Work", "a/b.txt"))
+	assert.Equal(t, "id:01ABC/a/b.txt", remotePath("id:01ABC/", "a/b.txt"))
+	assert.Equal(t, "id:01ABC/Work/a/b.txt", remotePath("id:01ABC/Work", "a/b.txt"))
+}
+
+// chunkSDK accepts resumable uploads, failing the first chunk at failAt or
+// later once. Methods it does not override panic through the nil embedded
+// interface.
+type chunkSDK struct {
+	app.SDK
+	failAt   int64
+	sessions int
+	received int64
+}
+
+func (c *chunkSDK) CreateUploadSession(ctx context.Context, remotePath string) (onedrive.UploadSession, error) {
+	c.sessions++
+	return onedrive.UploadSession{UploadURL: "https://upload.example/session"}, nil
+}
+
+func (c *chunkSDK) UploadChunk(ctx context.Context, uploadURL string, startByte, endByte, totalSize int64, chunkData io.Reader) (onedrive.UploadSession, error) {
+	if c.failAt > 0 && startByte >= c.failAt {
+		c.failAt = 0
+		return onedrive.UploadSession{}, errors.New("connection reset")
+	}
+	n, err := io.Copy(io.Discard, chunkData)
+	c.received += n
+	return onedrive.UploadSession{UploadURL: uploadURL}, err
+}
+
+func (c *chunkSDK) GetUploadSessionStatus(ctx context.Context, uploadURL string) (onedrive.UploadSession, error) {
+	return onedrive.UploadSession{}, errors.New("connection reset")
+}
+
+func (c *chunkSDK) GetDriveItemByPath(ctx context.Context, p string) (onedrive.DriveItem, error) {
+	return onedrive.DriveItem{ID: "big", Name: filepath.Base(p)}, nil
+}
+
+func TestEngineUploadResumesLargeFiles(t *testing.T) {
+	t.Setenv("ONEDRIVE_CONFIG_PATH", filepath.Join(t.TempDir(), "config.json"))
+	mgr, err := session.NewManager()
+	require.NoError(t, err)
+	local := filepath.Join(t.TempDir(), "big.bin")
+	size := int64(5*onedrive.DefaultChunkSize + 1) // Two chunks of a resumable upload.
+	require.NoError(t, os.WriteFile(local, make([]byte, size), 0600))
+
+	sdk := &chunkSDK{failAt: 1}
+	e := NewEngine(sdk, Options{Sessions: mgr})
+	_, err = e.upload(context.Background(), local, "/Work/big.bin")
+	require.Error(t, err)
+	sent := sdk.received
+
+	// The next run continues the saved session from where the first stopped.
+	item, err := e.upload(context.Background(), local, "/Work/big.bin")
+	require.NoError(t, err)
+	assert.Equal(t, "big", item.ID)
+	assert.Equal(t, 1, sdk.sessions)
+	assert.Equal(t, size, sdk.received)
+	assert.Positive(t, sent)
+}
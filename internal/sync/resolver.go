@@ -0,0 +1,266 @@
+// Package sync (resolver.go) resolves conflicts: files changed on both sides
+// since the last sync, or present on both sides with different content on the
+// first sync. A conflict whose two versions turn out to have the same content
+// is settled without a transfer; otherwise the configured ConflictPolicy
+// decides which version survives, or keeps both.
+package sync
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // SHA1 is required to compare with hashes reported by OneDrive.
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// Hash algorithm names used as prefixes of RemoteItem.Hash.
+const (
+	hashSHA1     = "sha1"
+	hashSHA256   = "sha256"
+	hashQuickXor = "quickxor"
+)
+
+// conflictTimeFormat is the timestamp format used in conflict copy names.
+const conflictTimeFormat = "2006-01-02 150405"
+
+// ConflictPolicy selects how conflicts are resolved.
+type ConflictPolicy string
+
+const (
+	// PolicyKeepBoth keeps the remote version under the original name and
+	// saves the local version next to it as a suffixed conflict copy, which
+	// is uploaded as well. Nothing is lost; this is the default.
+	PolicyKeepBoth ConflictPolicy = "keep-both"
+	// PolicyPreferLocal overwrites the remote version with the local one.
+	PolicyPreferLocal ConflictPolicy = "prefer-local"
+	// PolicyPreferRemote overwrites the local version with the remote one.
+	PolicyPreferRemote ConflictPolicy = "prefer-remote"
+	// PolicyPreferNewer keeps whichever version was modified last, falling
+	// back to keep-both when the modification times are equal or unknown.
+	PolicyPreferNewer ConflictPolicy = "prefer-newer"
+	// PolicyInteractive asks Options.Prompt how to resolve each conflict.
+	PolicyInteractive ConflictPolicy = "interactive"
+)
+
+// ConflictPolicies lists every supported policy, in the order shown in help text.
+func ConflictPolicies() []ConflictPolicy {
+	return []ConflictPolicy{PolicyKeepBoth, PolicyPreferLocal, PolicyPreferRemote, PolicyPreferNewer, PolicyInteractive}
+}
+
+// ParseConflictPolicy validates a policy name given on the command line.
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	names := make([]string, 0, len(ConflictPolicies()))
+	for _, p := range ConflictPolicies() {
+		if string(p) == name {
+			return p, nil
+		}
+		names = append(names, string(p))
+	}
+	return "", fmt.Errorf("unknown conflict policy '%s' (valid policies: %s)", name, strings.Join(names, ", "))
+}
+
+// Resolution records how a conflict was settled.
+type Resolution string
+
+const (
+	// ResolvedIdentical means both versions had the same content; only the
+	// baseline was updated.
+	ResolvedIdentical Resolution = "identical"
+	// ResolvedKeptLocal means the local version was uploaded over the remote one.
+	ResolvedKeptLocal Resolution = "kept local"
+	// ResolvedKeptRemote means the remote version was downloaded over the local one.
+	ResolvedKeptRemote Resolution = "kept remote"
+	// ResolvedKeptBoth means the local version was saved as a conflict copy
+	// (Action.Copy) and the remote version took its place.
+	ResolvedKeptBoth Resolution = "kept both"
+	// ResolvedSkipped means the conflict was left untouched, either by choice
+	// or because a file and a folder share the path. It is reported again on
+	// the next run.
+	ResolvedSkipped Resolution = "skipped"
+)
+
+// Conflict describes the two versions of a conflicting path, as passed to
+// Options.Prompt.
+type Conflict struct {
+	Path   string     // Path relative to the sync roots, slash-separated.
+	Local  LocalEntry // Local version.
+	Remote RemoteItem // Remote version.
+}
+
+// PromptFunc asks the user how to resolve a conflict. It returns
+// PolicyKeepBoth, PolicyPreferLocal, PolicyPreferRemote or PolicyPreferNewer
+// to apply that policy to this conflict, or "" to skip it.
+type PromptFunc func(c Conflict) (ConflictPolicy, error)
+
+// resolve settles the conflict described by `a`, recording the outcome in
+// a.Resolution (and a.Copy for keep-both). In a dry run nothing is changed and
+// interactive conflicts are left without a resolution, as no prompt is shown.
+func (e *Engine) resolve(ctx context.Context, s *syncState, a *Action) error {
+	l, r := s.local[a.Path], s.remote[a.Path]
+	local := localPath(e.opts.LocalRoot, a.Path)
+
+	if l.IsDir || r.IsDir {
+		a.Resolution = ResolvedSkipped
+		return nil
+	}
+
+	same, err := sameContent(local, r.Hash)
+	if err != nil {
+		return err
+	}
+	if same {
+		a.Resolution = ResolvedIdentical
+		if !e.opts.DryRun {
+			s.baseline[a.Path] = newBaselineEntry(l, r)
+		}
+		return nil
+	}
+
+	policy := e.opts.Conflicts
+	if policy == "" {
+		policy = PolicyKeepBoth
+	}
+	if policy == PolicyInteractive {
+		if e.opts.DryRun {
+			return nil
+		}
+		if e.opts.Prompt == nil {
+			return fmt.Errorf("interactive conflict resolution requested but no prompt is available")
+		}
+		policy, err = e.opts.Prompt(Conflict{Path: a.Path, Local: l, Remote: r})
+		if err != nil {
+			return fmt.Errorf("prompting for conflict on '%s': %w", a.Path, err)
+		}
+	}
+	if policy == PolicyPreferNewer {
+		policy = newerSide(l, r)
+	}
+
+	switch policy {
+	case PolicyPreferLocal:
+		a.Resolution = ResolvedKeptLocal
+		if e.opts.DryRun {
+			return nil
+		}
+		return e.execute(ctx, s, Action{Type: ActionUpload, Path: a.Path})
+
+	case PolicyPreferRemote:
+		a.Resolution = ResolvedKeptRemote
+		if e.opts.DryRun {
+			return nil
+		}
+		return e.execute(ctx, s, Action{Type: ActionDownload, Path: a.Path})
+
+	case PolicyKeepBoth:
+		a.Resolution = ResolvedKeptBoth
+		a.Copy = conflictCopyName(a.Path, time.Now(), func(p string) bool {
+			_, isLocal := s.local[p]
+			_, isRemote := s.remote[p]
+			return isLocal || isRemote
+		})
+		if e.opts.DryRun {
+			return nil
+		}
+		return e.keepBoth(ctx, s, a.Path, a.Copy)
+
+	case "":
+		a.Resolution = ResolvedSkipped
+		return nil
+	}
+	return fmt.Errorf("unsupported conflict resolution '%s' for '%s'", policy, a.Path)
+}
+
+// keepBoth renames the local version of `rel` to `copyRel`, downloads the
+// remote version in its place and uploads the renamed copy. If a step fails the
+// local version is already safe under its new name, and the next run carries on
+// from there.
+func (e *Engine) keepBoth(ctx context.Context, s *syncState, rel, copyRel string) error {
+	from := localPath(e.opts.LocalRoot, rel)
+	to := localPath(e.opts.LocalRoot, copyRel)
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("saving local version of '%s' as '%s': %w", rel, copyRel, err)
+	}
+	s.local[copyRel] = s.local[rel]
+	delete(s.local, rel)
+
+	if err := e.execute(ctx, s, Action{Type: ActionDownload, Path: rel}); err != nil {
+		return err
+	}
+	return e.execute(ctx, s, Action{Type: ActionUpload, Path: copyRel})
+}
+
+// newerSide picks the policy that keeps the most recently modified version,
+// or keep-both when that cannot be told.
+func newerSide(l LocalEntry, r RemoteItem) ConflictPolicy {
+	switch {
+	case r.Modified.IsZero() || l.ModTime.Equal(r.Modified):
+		return PolicyKeepBoth
+	case l.ModTime.After(r.Modified):
+		return PolicyPreferLocal
+	default:
+		return PolicyPreferRemote
+	}
+}
+
+// conflictCopyName returns the name the local version of `rel` is saved under
+// by keep-both, e.g. "docs/report (conflict 2024-05-01 120000).txt". A counter
+// is appended if `taken` reports the name as already in use.
+func conflictCopyName(rel string, now time.Time, taken func(string) bool) string {
+	dir, base := path.Split(rel)
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if stem == "" { // Dot files such as ".profile" have no stem.
+		stem, ext = base, ""
+	}
+
+	suffix := " (conflict " + now.Format(conflictTimeFormat)
+	candidate := dir + stem + suffix + ")" + ext
+	for n := 2; taken(candidate); n++ {
+		candidate = fmt.Sprintf("%s%s%s %d)%s", dir, stem, suffix, n, ext)
+	}
+	return candidate
+}
+
+// sameContent reports whether the local file at `local` has the content
+// described by `remoteHash`, a RemoteItem.Hash. It returns false when the
+// remote side carries no hash in a supported algorithm.
+func sameContent(local, remoteHash string) (bool, error) {
+	algorithm, want, ok := strings.Cut(remoteHash, ":")
+	if !ok {
+		return false, nil
+	}
+	var h hash.Hash
+	switch algorithm {
+	case hashSHA1:
+		h = sha1.New() //nolint:gosec // See import.
+	case hashSHA256:
+		h = sha256.New()
+	case hashQuickXor:
+		h = onedrive.NewQuickXorHash()
+	default:
+		return false, nil
+	}
+
+	file, err := os.Open(local)
+	if err != nil {
+		return false, fmt.Errorf("opening local file '%s' for hashing: %w", local, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close file: %v", closeErr)
+		}
+	}()
+	if _, err := io.Copy(h, file); err != nil {
+		return false, fmt.Errorf("hashing local file '%s': %w", local, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)) == want, nil
+}
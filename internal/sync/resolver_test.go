@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+func TestParseConflictPolicy(t *testing.T) {
+	for _, p := range ConflictPolicies() {
+		parsed, err := ParseConflictPolicy(string(p))
+		assert.NoError(t, err)
+		assert.Equal(t, p, parsed)
+	}
+
+	_, err := ParseConflictPolicy("prefer-mine")
+	assert.ErrorContains(t, err, "keep-both")
+}
+
+func TestConflictCopyName(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 15, 0, time.UTC)
+	none := func(string) bool { return false }
+
+	assert.Equal(t, "docs/report (conflict 2024-05-01 123015).txt", conflictCopyName("docs/report.txt", now, none))
+	assert.Equal(t, "Makefile (conflict 2024-05-01 123015)", conflictCopyName("Makefile", now, none))
+	assert.Equal(t, ".profile (conflict 2024-05-01 123015)", conflictCopyName(".profile", now, none))
+
+	taken := map[string]bool{"a (conflict 2024-05-01 123015).txt": true}
+	assert.Equal(t, "a (conflict 2024-05-01 123015 2).txt",
+		conflictCopyName("a.txt", now, func(p string) bool { return taken[p] }))
+}
+
+func TestNewerSide(t *testing.T) {
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, PolicyPreferLocal, newerSide(LocalEntry{ModTime: mtime.Add(time.Minute)}, RemoteItem{Modified: mtime}))
+	assert.Equal(t, PolicyPreferRemote, newerSide(LocalEntry{ModTime: mtime}, RemoteItem{Modified: mtime.Add(time.Minute)}))
+	assert.Equal(t, PolicyKeepBoth, newerSide(LocalEntry{ModTime: mtime}, RemoteItem{Modified: mtime}))
+	assert.Equal(t, PolicyKeepBoth, newerSide(LocalEntry{ModTime: mtime}, RemoteItem{}))
+}
+
+func TestSameContent(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(local, []byte("hello"), 0600))
+
+	tests := []struct {
+		name     string
+		hash     string
+		expected bool
+	}{
+		{"matching sha1", "sha1:aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", true},
+		{"matching sha256", "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", true},
+		{"matching quickxor", "quickxor:6828031bd8f00600000000000500000000000000", true},
+		{"different sha1", "sha1:0000000000000000000000000000000000000000", false},
+		{"no hash", "", false},
+		{"unsupported algorithm", "crc32:3610a686", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			same, err := sameContent(local, tt.hash)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, same)
+		})
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	item := onedrive.DriveItem{File: &onedrive.FileFacet{}}
+	assert.Empty(t, contentHash(item))
+
+	// QuickXorHash is reported in base64 and converted to hex.
+	item.File.Hashes = &onedrive.FileHashes{QuickXorHash: "aCgDG9jwBgAAAAAABQAAAAAAAAA="}
+	assert.Equal(t, "quickxor:6828031bd8f00600000000000500000000000000", contentHash(item))
+
+	item.File.Hashes.Sha1Hash = "AAF4C61DDCC5E8A2DABEDE0F3B482CD9AEA9434D"
+	assert.Equal(t, "sha1:aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", contentHash(item))
+
+	item.File.Hashes.Sha256Hash = "ABC"
+	assert.Equal(t, "sha256:abc", contentHash(item))
+}
+
+func TestVerifyDownload(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(local, []byte("hello"), 0600))
+
+	assert.NoError(t, verifyDownload(local, RemoteItem{Size: 5, Hash: "quickxor:6828031bd8f00600000000000500000000000000"}))
+	assert.NoError(t, verifyDownload(local, RemoteItem{Size: 5}))
+	assert.ErrorIs(t, verifyDownload(local, RemoteItem{Size: 6}), onedrive.ErrContentMismatch)
+	assert.ErrorIs(t, verifyDownload(local, RemoteItem{Size: 5, Hash: "sha1:0000000000000000000000000000000000000000"}), onedrive.ErrContentMismatch)
+}
+
+func TestRemoteUnchanged(t *testing.T) {
+	b := BaselineEntry{CTag: "c1", Hash: "sha1:abc"}
+	assert.True(t, remoteUnchanged(RemoteItem{CTag: "c1"}, b))
+	assert.True(t, remoteUnchanged(RemoteItem{CTag: "c2", Hash: "sha1:abc"}, b))
+	assert.False(t, remoteUnchanged(RemoteItem{CTag: "c2", Hash: "sha1:def"}, b))
+	assert.False(t, remoteUnchanged(RemoteItem{CTag: "c2"}, b))
+}
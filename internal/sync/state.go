@@ -0,0 +1,265 @@
+// Package sync (state.go) defines the persistent sync state and the Store that
+// keeps it on disk. A State records, for one synchronised folder, the delta link
+// of the last sync, the remote index built from delta responses, and the
+// baseline: the last synced state of every item, which lets the engine tell
+// which side changed an item and recognise deletions.
+//
+// States are stored as JSON files in a 'syncstate' directory next to the
+// 'sessions' directory managed by session.Manager. Writes go through a
+// temporary file and a rename so a crash never leaves a truncated state behind,
+// and a per-state flock lock prevents two processes from syncing the same
+// folder at once.
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// stateDirName is the name of the directory holding sync state files.
+const stateDirName = "syncstate"
+
+// BaselineEntry records the last synced state of one path on both sides.
+type BaselineEntry struct {
+	ItemID  string    `json:"itemId"`         // OneDrive item ID.
+	IsDir   bool      `json:"isDir"`          // True for folders.
+	Size    int64     `json:"size"`           // Local file size after the last sync.
+	ModTime time.Time `json:"modTime"`        // Local modification time after the last sync.
+	CTag    string    `json:"cTag,omitempty"` // Remote content tag after the last sync.
+	ETag    string    `json:"eTag,omitempty"` // Remote entity tag after the last sync.
+	Hash    string    `json:"hash,omitempty"` // Remote content hash after the last sync, if reported.
+}
+
+// Baseline maps slash-separated paths, relative to the sync roots, to their
+// last synced state.
+type Baseline map[string]BaselineEntry
+
+// newBaselineEntry combines the local and remote state of an in-sync path.
+func newBaselineEntry(l LocalEntry, r RemoteItem) BaselineEntry {
+	return BaselineEntry{
+		ItemID:  r.ID,
+		IsDir:   l.IsDir || r.IsDir,
+		Size:    l.Size,
+		ModTime: l.ModTime,
+		CTag:    r.CTag,
+		ETag:    r.ETag,
+		Hash:    r.Hash,
+	}
+}
+
+// clone returns a copy of the baseline that can be modified independently.
+func (b Baseline) clone() Baseline {
+	c := make(Baseline, len(b))
+	for k, v := range b {
+		c[k] = v
+	}
+	return c
+}
+
+// Key identifies a stored State: the drive and remote root folder it tracks,
+// plus the local directory for sync pairs. LocalRoot is empty for consumers
+// that only follow remote changes.
+type Key struct {
+	DriveID    string `json:"driveId"`
+	RemoteRoot string `json:"remoteRoot"`
+	LocalRoot  string `json:"localRoot,omitempty"`
+}
+
+// NewKey returns a Key with the remote root normalised to a leading "/" and
+// no trailing slash, so equivalent spellings map to the same state.
+func NewKey(driveID, remoteRoot, localRoot string) Key {
+	return Key{
+		DriveID:    driveID,
+		RemoteRoot: cleanRemoteRoot(remoteRoot),
+		LocalRoot:  localRoot,
+	}
+}
+
+// String returns a human-readable form of the key.
+func (k Key) String() string {
+	if k.LocalRoot == "" {
+		return fmt.Sprintf("%s (drive %s)", k.RemoteRoot, k.DriveID)
+	}
+	return fmt.Sprintf("%s <-> %s (drive %s)", k.LocalRoot, k.RemoteRoot, k.DriveID)
+}
+
+// State is the persisted sync state of one Key.
+type State struct {
+	Key
+	DeltaLink string       `json:"deltaLink,omitempty"` // Delta link to resume remote change tracking from.
+	LastSync  time.Time    `json:"lastSync"`            // When the state was last saved.
+	Remote    *RemoteIndex `json:"remote,omitempty"`    // Remote items below RemoteRoot, by ID.
+	Baseline  Baseline     `json:"baseline,omitempty"`  // Last synced state of each path.
+}
+
+// ItemCount returns the number of items tracked by the state: baseline
+// entries for sync pairs, or indexed remote items for remote-only states.
+func (st *State) ItemCount() int {
+	if st.LocalRoot == "" && st.Remote != nil {
+		return len(st.Remote.Items)
+	}
+	return len(st.Baseline)
+}
+
+// Store reads and writes State files.
+type Store struct {
+	configDir string // Base directory; states live in its 'syncstate' subdirectory.
+}
+
+// NewStore creates a Store in the same base directory session.Manager uses,
+// so sync state sits next to the 'sessions' directory.
+func NewStore() (*Store, error) {
+	mgr, err := session.NewManager()
+	if err != nil {
+		return nil, fmt.Errorf("locating config directory for sync state: %w", err)
+	}
+	return &Store{configDir: mgr.ConfigDir()}, nil
+}
+
+// NewStoreWithConfigDir creates a Store with a custom base directory.
+// This is primarily useful for testing.
+func NewStoreWithConfigDir(configDir string) *Store {
+	return &Store{configDir: configDir}
+}
+
+// Dir returns the directory state files are stored in.
+func (s *Store) Dir() string {
+	return filepath.Join(s.configDir, stateDirName)
+}
+
+// path returns the deterministic state file path for a key.
+func (s *Store) path(key Key) string {
+	hash := sha256.Sum256([]byte(key.DriveID + ":" + key.RemoteRoot + ":" + key.LocalRoot))
+	return filepath.Join(s.Dir(), hex.EncodeToString(hash[:])+".json")
+}
+
+// Lock takes an exclusive lock on the state for `key` and returns a function
+// that releases it. It fails immediately if another process holds the lock.
+// Callers must hold the lock while running a sync and calling Save or Delete.
+func (s *Store) Lock(key Key) (func(), error) {
+	if err := os.MkdirAll(s.Dir(), onedrive.PermSecureDir); err != nil {
+		return nil, fmt.Errorf("creating sync state directory '%s': %w", s.Dir(), err)
+	}
+
+	lockPath := s.path(key) + ".lock"
+	lock := flock.New(lockPath)
+	locked, err := lock.TryLock()
+	if err != nil {
+		return nil, fmt.Errorf("acquiring sync state lock '%s': %w", lockPath, err)
+	}
+	if !locked {
+		return nil, fmt.Errorf("could not acquire sync state lock for %s, another sync may be running", key)
+	}
+	return func() {
+		if unlockErr := lock.Unlock(); unlockErr != nil {
+			log.Printf("Warning: Failed to unlock sync state lock: %v", unlockErr)
+		}
+	}, nil
+}
+
+// Load returns the stored state for `key`, or (nil, nil) if there is none.
+// Reads do not need the lock, as Save replaces state files atomically.
+func (s *Store) Load(key Key) (*State, error) {
+	return s.readFile(s.path(key))
+}
+
+// Save writes `state` crash-safely: the data is written and synced to a
+// temporary file, which is then renamed over the previous state.
+func (s *Store) Save(state *State) error {
+	if err := os.MkdirAll(s.Dir(), onedrive.PermSecureDir); err != nil {
+		return fmt.Errorf("creating sync state directory '%s': %w", s.Dir(), err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling sync state for %s: %w", state.Key, err)
+	}
+
+	filePath := s.path(state.Key)
+	tmpPath := filePath + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, onedrive.PermSecureFile)
+	if err != nil {
+		return fmt.Errorf("creating temporary sync state file '%s': %w", tmpPath, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing temporary sync state file '%s': %w", tmpPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("syncing temporary sync state file '%s': %w", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary sync state file '%s': %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("renaming temporary sync state file '%s' to '%s': %w", tmpPath, filePath, err)
+	}
+	return nil
+}
+
+// Delete removes the stored state for `key`. Deleting a missing state is not an error.
+func (s *Store) Delete(key Key) error {
+	err := os.Remove(s.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("deleting sync state for %s: %w", key, err)
+	}
+	return nil
+}
+
+// List returns every stored state, ordered by remote root and local directory.
+func (s *Store) List() ([]*State, error) {
+	matches, err := filepath.Glob(filepath.Join(s.Dir(), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing sync state files: %w", err)
+	}
+
+	states := make([]*State, 0, len(matches))
+	for _, m := range matches {
+		state, err := s.readFile(m)
+		if err != nil {
+			return nil, err
+		}
+		if state != nil {
+			states = append(states, state)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].RemoteRoot != states[j].RemoteRoot {
+			return states[i].RemoteRoot < states[j].RemoteRoot
+		}
+		return states[i].LocalRoot < states[j].LocalRoot
+	})
+	return states, nil
+}
+
+// readFile loads a state file, returning (nil, nil) if it does not exist.
+func (s *Store) readFile(filePath string) (*State, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading sync state file '%s': %w", filePath, err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("unmarshaling sync state from '%s': %w", filePath, err)
+	}
+	if state.Baseline == nil {
+		state.Baseline = make(Baseline)
+	}
+	return &state, nil
+}
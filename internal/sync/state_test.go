@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreSaveLoadList(t *testing.T) {
+	store := NewStoreWithConfigDir(t.TempDir())
+	key := NewKey("drive1", "Work/", "/home/user/work")
+	assert.Equal(t, "/Work", key.RemoteRoot)
+
+	state, err := store.Load(key)
+	require.NoError(t, err)
+	assert.Nil(t, state, "missing state should load as nil")
+
+	saved := &State{
+		Key:       key,
+		DeltaLink: "https://graph.microsoft.com/v1.0/me/drive/root/delta?token=abc",
+		LastSync:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Remote:    NewRemoteIndex("root"),
+		Baseline:  Baseline{"a.txt": {ItemID: "1", Size: 3, CTag: "c1"}},
+	}
+	require.NoError(t, store.Save(saved))
+	assert.NoFileExists(t, store.path(key)+".tmp", "temporary file should be renamed away")
+
+	info, err := os.Stat(store.path(key))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := store.Load(NewKey("drive1", "/Work", "/home/user/work"))
+	require.NoError(t, err)
+	require.NotNil(t, loaded)
+	assert.Equal(t, saved.DeltaLink, loaded.DeltaLink)
+	assert.Equal(t, saved.Baseline, loaded.Baseline)
+	assert.Equal(t, 1, loaded.ItemCount())
+
+	other := &State{Key: NewKey("drive1", "/Photos", "")}
+	require.NoError(t, store.Save(other))
+	states, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	assert.Equal(t, "/Photos", states[0].RemoteRoot)
+	assert.Equal(t, "/Work", states[1].RemoteRoot)
+
+	require.NoError(t, store.Delete(key))
+	require.NoError(t, store.Delete(key), "deleting a missing state should succeed")
+	states, err = store.List()
+	require.NoError(t, err)
+	assert.Len(t, states, 1)
+}
+
+func TestStoreLock(t *testing.T) {
+	store := NewStoreWithConfigDir(t.TempDir())
+	key := NewKey("drive1", "/Work", "/home/user/work")
+
+	unlock, err := store.Lock(key)
+	require.NoError(t, err)
+
+	_, err = store.Lock(key)
+	assert.Error(t, err, "second lock on the same state should fail")
+
+	otherUnlock, err := store.Lock(NewKey("drive1", "/Other", "/home/user/other"))
+	require.NoError(t, err, "locks are per state")
+	otherUnlock()
+
+	unlock()
+	unlock, err = store.Lock(key)
+	require.NoError(t, err, "lock should be available after release")
+	unlock()
+}
+
+func TestStoreLoadCorrupted(t *testing.T) {
+	store := NewStoreWithConfigDir(t.TempDir())
+	key := NewKey("drive1", "/Work", "")
+	require.NoError(t, os.MkdirAll(store.Dir(), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(store.Dir(), filepath.Base(store.path(key))), []byte("{"), 0600))
+
+	_, err := store.Load(key)
+	assert.Error(t, err)
+}
@@ -0,0 +1,308 @@
+// Package sync (tree.go) builds the two views of a synchronised folder that the
+// engine reconciles: a snapshot of the local directory tree, and an index of the
+// remote folder assembled from Microsoft Graph delta responses.
+package sync
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// partialSuffix marks files that are still being downloaded. They are written
+// next to their final location and renamed into place only once complete, so
+// a crash mid-download never looks like a local modification. The local scan
+// ignores them.
+const partialSuffix = onedrive.PartialDownloadSuffix
+
+// LocalEntry describes a single file or directory found by the local scan.
+type LocalEntry struct {
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+}
+
+// scanLocal walks `root` and returns every file and directory beneath it, keyed
+// by its slash-separated path relative to `root`. The root itself is not
+// included. Symlinks and other special files are skipped, as OneDrive has no
+// representation for them.
+func scanLocal(root string) (map[string]LocalEntry, error) {
+	entries := make(map[string]LocalEntry)
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if p == root {
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), partialSuffix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("reading file info for '%s': %w", p, err)
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return fmt.Errorf("resolving '%s' relative to '%s': %w", p, root, err)
+		}
+
+		entry := LocalEntry{IsDir: d.IsDir(), ModTime: info.ModTime()}
+		if !entry.IsDir {
+			entry.Size = info.Size()
+		}
+		entries[filepath.ToSlash(rel)] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning local directory '%s': %w", root, err)
+	}
+	return entries, nil
+}
+
+// RemoteItem is the subset of DriveItem metadata the engine needs to track an
+// item inside the synchronised remote folder.
+type RemoteItem struct {
+	ID       string    `json:"id"`
+	ParentID string    `json:"parentId"`
+	Name     string    `json:"name"`
+	IsDir    bool      `json:"isDir"`
+	Size     int64     `json:"size"`
+	CTag     string    `json:"cTag,omitempty"`
+	ETag     string    `json:"eTag,omitempty"`
+	Hash     string    `json:"hash,omitempty"` // Content hash as "<algorithm>:<hex>", see contentHash.
+	Modified time.Time `json:"modified"`
+	Children int       `json:"children,omitempty"` // Folder child count reported by the API.
+}
+
+// newRemoteItem converts a DriveItem returned by the API into a RemoteItem.
+func newRemoteItem(item onedrive.DriveItem) RemoteItem {
+	children := 0
+	if item.Folder != nil {
+		children = item.Folder.ChildCount
+	}
+	return RemoteItem{
+		ID:       item.ID,
+		ParentID: item.ParentReference.ID,
+		Name:     item.Name,
+		IsDir:    item.Folder != nil,
+		Size:     item.Size,
+		CTag:     item.CTag,
+		ETag:     item.ETag,
+		Hash:     contentHash(item),
+		Modified: item.FileSystemInfo.LastModifiedDateTime,
+		Children: children,
+	}
+}
+
+// contentHash returns the strongest content hash the API reported for a file,
+// prefixed with its algorithm ("sha256:", "sha1:" or "quickxor:") and as
+// lower-case hex so it can be compared with hashes computed locally. It returns
+// "" when the item carries no usable hash, which is the norm for folders.
+func contentHash(item onedrive.DriveItem) string {
+	if item.File == nil || item.File.Hashes == nil {
+		return ""
+	}
+	switch {
+	case item.File.Hashes.Sha256Hash != "":
+		return hashSHA256 + ":" + strings.ToLower(item.File.Hashes.Sha256Hash)
+	case item.File.Hashes.Sha1Hash != "":
+		return hashSHA1 + ":" + strings.ToLower(item.File.Hashes.Sha1Hash)
+	case item.File.Hashes.QuickXorHash != "":
+		// Reported in base64, which is case-sensitive, so convert it to hex.
+		sum, err := base64.StdEncoding.DecodeString(item.File.Hashes.QuickXorHash)
+		if err == nil && len(sum) == onedrive.QuickXorHashSize {
+			return hashQuickXor + ":" + hex.EncodeToString(sum)
+		}
+	}
+	return ""
+}
+
+// RemoteIndex tracks every item below a remote root folder by ID.
+//
+// Delta responses do not reliably carry `parentReference.path` (renaming a
+// folder does not report its descendants), so items are tracked by ID and
+// parent ID, and paths are resolved on demand by walking up to the root.
+type RemoteIndex struct {
+	RootID string                `json:"rootId"`
+	Items  map[string]RemoteItem `json:"items"`
+}
+
+// NewRemoteIndex returns an empty index rooted at the folder with ID `rootID`.
+func NewRemoteIndex(rootID string) *RemoteIndex {
+	return &RemoteIndex{RootID: rootID, Items: make(map[string]RemoteItem)}
+}
+
+// clone returns a deep copy of the index.
+func (ri *RemoteIndex) clone() *RemoteIndex {
+	c := NewRemoteIndex(ri.RootID)
+	for id, item := range ri.Items {
+		c.Items[id] = item
+	}
+	return c
+}
+
+// Apply folds one page of delta results into the index.
+//
+// Deleted items are dropped. Other items are kept only if their parent is the
+// root or another indexed item; the rest of the drive is ignored. Items whose
+// parent appears later in the same page are resolved by repeated passes. An
+// indexed item whose new parent lies outside the root has been moved out of
+// the synchronised folder and is removed, along with any descendants left
+// orphaned by the change.
+func (ri *RemoteIndex) Apply(items []onedrive.DriveItem) {
+	pending := make([]onedrive.DriveItem, 0, len(items))
+	for i := range items {
+		item := &items[i]
+		if item.ID == ri.RootID {
+			continue
+		}
+		if item.Deleted != nil {
+			delete(ri.Items, item.ID)
+			continue
+		}
+		pending = append(pending, *item)
+	}
+
+	for len(pending) > 0 {
+		var unresolved []onedrive.DriveItem
+		for i := range pending {
+			parentID := pending[i].ParentReference.ID
+			if _, ok := ri.Items[parentID]; ok || parentID == ri.RootID {
+				ri.Items[pending[i].ID] = newRemoteItem(pending[i])
+				continue
+			}
+			unresolved = append(unresolved, pending[i])
+		}
+		if len(unresolved) == len(pending) {
+			// No progress: the remaining items live outside the root.
+			for i := range unresolved {
+				delete(ri.Items, unresolved[i].ID)
+			}
+			break
+		}
+		pending = unresolved
+	}
+
+	ri.prune()
+}
+
+// prune removes items whose ancestor chain no longer reaches the root.
+func (ri *RemoteIndex) prune() {
+	for id := range ri.Items {
+		if _, ok := ri.pathOf(id, 0); !ok {
+			delete(ri.Items, id)
+		}
+	}
+}
+
+// incomplete reports whether some indexed folder holds fewer items than the
+// API says it has. This happens when a folder is moved into the root from
+// elsewhere: delta reports the folder but not its existing descendants, so
+// the index must be rebuilt from a full enumeration.
+func (ri *RemoteIndex) incomplete() bool {
+	counts := make(map[string]int, len(ri.Items))
+	for _, item := range ri.Items {
+		counts[item.ParentID]++
+	}
+	for id, item := range ri.Items {
+		if item.IsDir && counts[id] < item.Children {
+			return true
+		}
+	}
+	return false
+}
+
+// pathOf resolves the slash-separated path of item `id` relative to the root.
+// `depth` guards against malformed parent chains.
+func (ri *RemoteIndex) pathOf(id string, depth int) (string, bool) {
+	item, ok := ri.Items[id]
+	if !ok || depth > len(ri.Items) {
+		return "", false
+	}
+	if item.ParentID == ri.RootID {
+		return item.Name, true
+	}
+	parent, ok := ri.pathOf(item.ParentID, depth+1)
+	if !ok {
+		return "", false
+	}
+	return parent + "/" + item.Name, true
+}
+
+// Paths returns every indexed item keyed by its path relative to the root.
+func (ri *RemoteIndex) Paths() map[string]RemoteItem {
+	paths := make(map[string]RemoteItem, len(ri.Items))
+	for id, item := range ri.Items {
+		if p, ok := ri.pathOf(id, 0); ok {
+			paths[p] = item
+		}
+	}
+	return paths
+}
+
+// localPath converts a relative sync path into a path under the local root.
+func localPath(root, rel string) string {
+	return filepath.Join(root, filepath.FromSlash(rel))
+}
+
+// cleanRemoteRoot normalises a remote root to a leading "/" and no trailing
+// slash. A root given as an item reference ("id:<itemId>[/<path>]") only loses
+// its trailing slash, as a leading one would turn it into an ordinary path.
+func cleanRemoteRoot(root string) string {
+	if _, _, ok := onedrive.SplitItemIDPath(root); ok {
+		return strings.TrimRight(root, "/")
+	}
+	return "/" + strings.Trim(root, "/")
+}
+
+// remotePath converts a relative sync path into a full OneDrive path under the
+// remote root, starting with "/" unless the root is an item reference.
+func remotePath(root, rel string) string {
+	root = cleanRemoteRoot(root)
+	if root == "/" {
+		return "/" + rel
+	}
+	return root + "/" + rel
+}
+
+// isUnder reports whether `p` is a strict descendant of the directory `dir`.
+func isUnder(p, dir string) bool {
+	return strings.HasPrefix(p, dir+"/")
+}
+
+// fileUnchanged reports whether a local file still matches its baseline.
+func fileUnchanged(l LocalEntry, b BaselineEntry) bool {
+	return l.Size == b.Size && l.ModTime.Equal(b.ModTime)
+}
+
+// remoteUnchanged reports whether a remote file still matches its baseline.
+// A different cTag normally means new content, but when both sides carry a
+// content hash and the hashes agree the content is known to be the same.
+func remoteUnchanged(r RemoteItem, b BaselineEntry) bool {
+	if r.CTag == b.CTag {
+		return true
+	}
+	return r.Hash != "" && r.Hash == b.Hash
+}
+
+// keepModTime applies a remote modification time to a freshly downloaded file
+// so local timestamps track the cloud copy.
+func keepModTime(p string, modified time.Time) error {
+	if modified.IsZero() {
+		return nil
+	}
+	return os.Chtimes(p, modified, modified)
+}
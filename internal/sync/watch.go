@@ -0,0 +1,417 @@
+// Package sync (watch.go) implements watch mode: a one-way push of local
+// changes to OneDrive as they happen. Filesystem events from fsnotify (inotify
+// on Linux) are collected until the directory has been quiet for a debounce
+// interval, so a burst of editor or build writes results in a single upload,
+// and are then replayed remotely with the same primitives the sync engine
+// uses: uploads (resumable in chunks for large files), CreateFolder,
+// MoveDriveItem/UpdateDriveItem and DeleteDriveItem.
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// DefaultDebounce is how long the local directory must be quiet before
+// pending changes are pushed.
+const DefaultDebounce = 2 * time.Second
+
+// maxDebounceFactor bounds how long a continuous stream of events can delay a
+// push, as a multiple of the debounce interval.
+const maxDebounceFactor = 10
+
+// watcher holds the state of a running Watch.
+type watcher struct {
+	e      *Engine
+	fsw    *fsnotify.Watcher
+	known  map[string]LocalEntry // Local entries believed to be mirrored remotely.
+	report func(Action)
+}
+
+// Watch mirrors local changes below Options.LocalRoot to Options.RemoteRoot
+// until `ctx` is cancelled, calling `report` for every action taken.
+//
+// Watch assumes the two folders are in step when it starts (run a sync first
+// if they may not be) and only pushes changes made afterwards. Remote changes
+// are not pulled. Events are batched until no event has arrived for
+// `debounce`, or for at most ten times that while events keep arriving. A
+// local rename or move is recognised when a path disappears and a new one with
+// the same size and modification time (or, for folders, the only new folder in
+// the batch) appears in the same batch; it becomes a server-side move instead
+// of an upload. In a dry run actions are reported but not performed.
+func (e *Engine) Watch(ctx context.Context, debounce time.Duration, report func(Action)) error {
+	info, err := os.Stat(e.opts.LocalRoot)
+	if err != nil {
+		return fmt.Errorf("accessing local directory '%s': %w", e.opts.LocalRoot, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("local path '%s' is not a directory", e.opts.LocalRoot)
+	}
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	if _, err := e.remoteRoot(ctx); err != nil {
+		return err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("starting filesystem watcher: %w", err)
+	}
+	defer func() {
+		if closeErr := fsw.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close filesystem watcher: %v", closeErr)
+		}
+	}()
+
+	known, err := scanLocal(e.opts.LocalRoot)
+	if err != nil {
+		return err
+	}
+	w := &watcher{e: e, fsw: fsw, known: known, report: report}
+	if err := w.addTree(""); err != nil {
+		return err
+	}
+
+	pending := make(map[string]struct{})
+	var first time.Time
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			rel, ok := w.relative(event)
+			if !ok {
+				continue
+			}
+			pending[rel] = struct{}{}
+
+			now := time.Now()
+			if first.IsZero() {
+				first = now
+			}
+			delay := debounce
+			if deadline := first.Add(maxDebounceFactor * debounce); now.Add(delay).After(deadline) {
+				delay = deadline.Sub(now)
+			}
+			timer.Reset(delay)
+
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				log.Printf("Warning: filesystem watcher: %v", err)
+				continue
+			}
+			// Events were lost; look at every path we know of or can find.
+			log.Printf("Warning: filesystem events were dropped, re-scanning '%s'.", e.opts.LocalRoot)
+			current, scanErr := scanLocal(e.opts.LocalRoot)
+			if scanErr != nil {
+				return scanErr
+			}
+			for p := range current {
+				pending[p] = struct{}{}
+			}
+			for p := range w.known {
+				pending[p] = struct{}{}
+			}
+			timer.Reset(0)
+
+		case <-timer.C:
+			w.flush(ctx, pending)
+			pending = make(map[string]struct{})
+			first = time.Time{}
+		}
+	}
+}
+
+// relative converts an event into a sync path, ignoring events that cannot
+// change what is mirrored: permission changes, the root itself and partial
+// downloads.
+func (w *watcher) relative(event fsnotify.Event) (string, bool) {
+	if event.Op == fsnotify.Chmod {
+		return "", false
+	}
+	rel, err := filepath.Rel(w.e.opts.LocalRoot, event.Name)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	if strings.HasSuffix(rel, partialSuffix) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// addTree watches the local directory `rel` and every directory below it.
+// fsnotify does not watch recursively, so each directory needs its own watch.
+func (w *watcher) addTree(rel string) error {
+	root := localPath(w.e.opts.LocalRoot, rel)
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p != root && errors.Is(err, fs.ErrNotExist) {
+				return nil // Removed while we were walking; its event is queued.
+			}
+			return fmt.Errorf("walking '%s' to watch it: %w", p, err)
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if err := w.fsw.Add(p); err != nil {
+			return fmt.Errorf("watching directory '%s': %w", p, err)
+		}
+		return nil
+	})
+}
+
+// removeTree drops the watches of `rel` and the known directories below it.
+// Errors are ignored: the watch may already be gone with the directory.
+func (w *watcher) removeTree(rel string) {
+	for p, l := range w.known {
+		if l.IsDir && (p == rel || isUnder(p, rel)) {
+			_ = w.fsw.Remove(localPath(w.e.opts.LocalRoot, p))
+		}
+	}
+}
+
+// flush pushes a batch of changed paths to OneDrive.
+//
+// It works in four steps, each in path order so parents come first: renames
+// are matched and replayed as moves, and other new folders are created; new
+// and moved folders are expanded, since their contents may predate the watch
+// on them; new or changed entries are created or uploaded; and known paths
+// that are still missing are deleted, outermost first. A failed move falls
+// back to deleting the old path and uploading the new one.
+func (w *watcher) flush(ctx context.Context, paths map[string]struct{}) {
+	current := make(map[string]LocalEntry, len(paths))
+	var vanished []string
+	for p := range paths {
+		entry, ok := w.stat(p)
+		if ok {
+			current[p] = entry
+		} else if _, wasKnown := w.known[p]; wasKnown {
+			vanished = append(vanished, p)
+		}
+	}
+	sort.Strings(vanished)
+
+	var expand []string
+	for _, p := range sortedKeys(current) {
+		l := current[p]
+		if _, ok := w.known[p]; ok {
+			continue
+		}
+		if from := w.matchRename(l, vanished, current); from != "" {
+			vanished = removeString(vanished, from)
+			if w.do(ctx, Action{Type: ActionMoveRemote, From: from, Path: p}) {
+				w.removeTree(from)
+				rekey(w.known, from, p)
+				if l.IsDir {
+					expand = append(expand, p)
+				}
+				continue
+			}
+			vanished = append(vanished, from)
+			sort.Strings(vanished)
+		}
+		if l.IsDir && w.do(ctx, Action{Type: ActionCreateRemoteFolder, Path: p}) {
+			w.known[p] = l
+			expand = append(expand, p)
+		}
+	}
+
+	for _, dir := range expand {
+		if err := w.addTree(dir); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		entries, err := scanLocal(localPath(w.e.opts.LocalRoot, dir))
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		for p, entry := range entries {
+			if _, ok := current[dir+"/"+p]; !ok {
+				current[dir+"/"+p] = entry
+			}
+		}
+	}
+
+	for _, p := range sortedKeys(current) {
+		l := current[p]
+		k, wasKnown := w.known[p]
+		switch {
+		case l.IsDir && !wasKnown:
+			if w.do(ctx, Action{Type: ActionCreateRemoteFolder, Path: p}) {
+				w.known[p] = l
+			}
+		case l.IsDir:
+			w.known[p] = l
+		case !wasKnown || k.IsDir || !fileUnchanged(l, BaselineEntry{Size: k.Size, ModTime: k.ModTime}):
+			// A performed upload records the verified file itself.
+			if w.do(ctx, Action{Type: ActionUpload, Path: p}) && w.e.opts.DryRun {
+				w.known[p] = l
+			}
+		}
+	}
+
+	var deleted []string
+	for _, p := range vanished {
+		if hasAncestorIn(deleted, p) {
+			continue
+		}
+		if w.do(ctx, Action{Type: ActionDeleteRemote, Path: p}) {
+			w.removeTree(p)
+			for k := range w.known {
+				if k == p || isUnder(k, p) {
+					delete(w.known, k)
+				}
+			}
+			deleted = append(deleted, p)
+		}
+	}
+}
+
+// stat returns the current local entry for `rel`, or false if it is gone or
+// is not something OneDrive can hold.
+func (w *watcher) stat(rel string) (LocalEntry, bool) {
+	info, err := os.Lstat(localPath(w.e.opts.LocalRoot, rel))
+	if err != nil || (!info.IsDir() && !info.Mode().IsRegular()) {
+		return LocalEntry{}, false
+	}
+	entry := LocalEntry{IsDir: info.IsDir(), ModTime: info.ModTime()}
+	if !entry.IsDir {
+		entry.Size = info.Size()
+	}
+	return entry, true
+}
+
+// matchRename returns the vanished path that `l` was most likely renamed
+// from, or "" if there is no unambiguous match. Files match on size and
+// modification time, which a rename preserves. Folders match only when the
+// batch holds exactly one vanished and one new folder.
+func (w *watcher) matchRename(l LocalEntry, vanished []string, current map[string]LocalEntry) string {
+	var candidates []string
+	for _, v := range vanished {
+		k := w.known[v]
+		if k.IsDir != l.IsDir {
+			continue
+		}
+		if !l.IsDir && (k.Size != l.Size || !k.ModTime.Equal(l.ModTime)) {
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+	if len(candidates) != 1 {
+		return ""
+	}
+	if l.IsDir {
+		newDirs := 0
+		for p, c := range current {
+			if _, ok := w.known[p]; c.IsDir && !ok {
+				newDirs++
+			}
+		}
+		if newDirs != 1 {
+			return ""
+		}
+	}
+	return candidates[0]
+}
+
+// do performs a single action, reports it and returns whether it succeeded.
+func (w *watcher) do(ctx context.Context, a Action) bool {
+	if !w.e.opts.DryRun {
+		a.Err = w.push(ctx, a)
+	}
+	if w.report != nil {
+		w.report(a)
+	}
+	return a.Err == nil
+}
+
+// push carries out an action on the remote side. Creating a folder that
+// already exists and deleting an item that is already gone both succeed.
+// Uploads are verified against the returned item before they are recorded as
+// mirrored, as in Engine.execute.
+func (w *watcher) push(ctx context.Context, a Action) error {
+	local := localPath(w.e.opts.LocalRoot, a.Path)
+	remote := remotePath(w.e.opts.RemoteRoot, a.Path)
+
+	switch a.Type {
+	case ActionMoveRemote:
+		return w.e.move(ctx, a)
+
+	case ActionCreateRemoteFolder:
+		existing, err := w.e.sdk.GetDriveItemByPath(ctx, remote)
+		if err == nil && existing.Folder != nil {
+			return nil
+		}
+		if _, err := w.e.sdk.CreateFolder(ctx, path.Dir(remote), path.Base(remote)); err != nil {
+			return fmt.Errorf("creating remote folder '%s': %w", remote, err)
+		}
+		return nil
+
+	case ActionUpload:
+		item, err := w.e.upload(ctx, local, remote)
+		if err != nil {
+			return err
+		}
+		if err := onedrive.VerifyFile(local, item); err != nil {
+			return fmt.Errorf("verifying upload of '%s': %w", local, err)
+		}
+		info, err := os.Stat(local)
+		if err != nil {
+			return fmt.Errorf("reading local file '%s' after transfer: %w", local, err)
+		}
+		w.known[a.Path] = LocalEntry{Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+
+	case ActionDeleteRemote:
+		err := w.e.sdk.DeleteDriveItem(ctx, remote)
+		if err != nil && !errors.Is(err, onedrive.ErrResourceNotFound) {
+			return fmt.Errorf("deleting remote '%s': %w", remote, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unexpected watch action type '%s'", a.Type)
+}
+
+// hasAncestorIn reports whether one of `dirs` contains `p`.
+func hasAncestorIn(dirs []string, p string) bool {
+	for _, d := range dirs {
+		if isUnder(p, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeString returns `list` without the first occurrence of `s`.
+func removeString(list []string, s string) []string {
+	for i := range list {
+		if list[i] == s {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
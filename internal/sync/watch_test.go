@@ -0,0 +1,191 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// recordingSDK records the remote operations made by the watcher. Methods it
+// does not override panic through the nil embedded interface.
+type recordingSDK struct {
+	app.SDK
+	mu    gosync.Mutex
+	calls []string
+}
+
+func (r *recordingSDK) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recordingSDK) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func (r *recordingSDK) GetDriveItemByPath(ctx context.Context, p string) (onedrive.DriveItem, error) {
+	if p == "/Work" {
+		return onedrive.DriveItem{ID: "root", Folder: &onedrive.FolderFacet{}}, nil
+	}
+	return onedrive.DriveItem{}, onedrive.ErrResourceNotFound
+}
+
+func (r *recordingSDK) CreateFolder(ctx context.Context, parentPath, name string) (onedrive.DriveItem, error) {
+	r.record("mkdir " + parentPath + " " + name)
+	return onedrive.DriveItem{ID: name, Folder: &onedrive.FolderFacet{}}, nil
+}
+
+func (r *recordingSDK) UploadFile(ctx context.Context, localPath, remotePath string) (onedrive.DriveItem, error) {
+	r.record("upload " + remotePath)
+	return onedrive.DriveItem{ID: remotePath}, nil
+}
+
+func (r *recordingSDK) MoveDriveItem(ctx context.Context, sourcePath, destinationParentPath string) (onedrive.DriveItem, error) {
+	r.record("move " + sourcePath + " " + destinationParentPath)
+	return onedrive.DriveItem{}, nil
+}
+
+func (r *recordingSDK) UpdateDriveItem(ctx context.Context, p, newName string) (onedrive.DriveItem, error) {
+	r.record("rename " + p + " " + newName)
+	return onedrive.DriveItem{}, nil
+}
+
+func (r *recordingSDK) DeleteDriveItem(ctx context.Context, p string) error {
+	r.record("delete " + p)
+	return nil
+}
+
+func TestWatcherFlush(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, rel), []byte(content), 0600))
+	}
+	write("a.txt", "hello")
+
+	fsw, err := fsnotify.NewWatcher()
+	require.NoError(t, err)
+	defer fsw.Close()
+
+	sdk := &recordingSDK{}
+	known, err := scanLocal(root)
+	require.NoError(t, err)
+	w := &watcher{
+		e:     NewEngine(sdk, Options{LocalRoot: root, RemoteRoot: "/Work"}),
+		fsw:   fsw,
+		known: known,
+	}
+	flush := func(paths ...string) []string {
+		before := len(sdk.Calls())
+		batch := make(map[string]struct{})
+		for _, p := range paths {
+			batch[p] = struct{}{}
+		}
+		w.flush(context.Background(), batch)
+		return sdk.Calls()[before:]
+	}
+
+	// A rename in place becomes a server-side rename.
+	require.NoError(t, os.Rename(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")))
+	assert.Equal(t, []string{"rename /Work/a.txt b.txt"}, flush("a.txt", "b.txt"))
+
+	// A move into a new folder creates the folder first.
+	require.NoError(t, os.Mkdir(filepath.Join(root, "d"), 0700))
+	require.NoError(t, os.Rename(filepath.Join(root, "b.txt"), filepath.Join(root, "d", "b.txt")))
+	assert.Equal(t, []string{"mkdir /Work d", "move /Work/b.txt /Work/d"}, flush("b.txt", "d", "d/b.txt"))
+
+	// Renaming a folder moves it without touching its contents.
+	require.NoError(t, os.Rename(filepath.Join(root, "d"), filepath.Join(root, "e")))
+	assert.Equal(t, []string{"rename /Work/d e"}, flush("d", "e"))
+	assert.Contains(t, w.known, "e/b.txt")
+
+	// New files are uploaded and deleted ones removed.
+	write("c.txt", "new")
+	require.NoError(t, os.Remove(filepath.Join(root, "e", "b.txt")))
+	assert.Equal(t, []string{"upload /Work/c.txt", "delete /Work/e/b.txt"}, flush("c.txt", "e/b.txt"))
+
+	// A file that came and went within one batch is never pushed.
+	assert.Empty(t, flush("tmp.swp"))
+
+	// Deleting a folder deletes it once, not each of its contents.
+	write(filepath.Join("e", "x.txt"), "x")
+	flush("e/x.txt")
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "e")))
+	assert.Equal(t, []string{"delete /Work/e"}, flush("e", "e/x.txt"))
+}
+
+// corruptingSDK is a recordingSDK whose uploads report a different size.
+type corruptingSDK struct {
+	recordingSDK
+}
+
+func (c *corruptingSDK) UploadFile(ctx context.Context, localPath, remotePath string) (onedrive.DriveItem, error) {
+	c.record("upload " + remotePath)
+	return onedrive.DriveItem{ID: remotePath, Name: path.Base(remotePath), Size: 1, File: &onedrive.FileFacet{}}, nil
+}
+
+func TestWatcherVerifiesUploads(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0600))
+
+	var reported []Action
+	w := &watcher{
+		e:      NewEngine(&corruptingSDK{}, Options{LocalRoot: root, RemoteRoot: "/Work"}),
+		known:  make(map[string]LocalEntry),
+		report: func(a Action) { reported = append(reported, a) },
+	}
+	w.flush(context.Background(), map[string]struct{}{"a.txt": {}})
+
+	require.Len(t, reported, 1)
+	assert.ErrorIs(t, reported[0].Err, onedrive.ErrContentMismatch)
+	assert.NotContains(t, w.known, "a.txt", "a corrupted upload is not recorded as mirrored")
+}
+
+func TestWatchDebouncesWrites(t *testing.T) {
+	root := t.TempDir()
+	sdk := &recordingSDK{}
+	engine := NewEngine(sdk, Options{LocalRoot: root, RemoteRoot: "/Work"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	var reported []Action
+	var mu gosync.Mutex
+	go func() {
+		done <- engine.Watch(ctx, 200*time.Millisecond, func(a Action) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, a)
+		})
+	}()
+
+	// Give the watcher time to register before changing anything.
+	time.Sleep(100 * time.Millisecond)
+	target := filepath.Join(root, "artifact.bin")
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(target, []byte{byte(i)}, 0600))
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	assert.Eventually(t, func() bool { return len(sdk.Calls()) > 0 }, 5*time.Second, 20*time.Millisecond)
+	time.Sleep(400 * time.Millisecond) // No further uploads should follow.
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.Equal(t, []string{"upload /Work/artifact.bin"}, sdk.Calls())
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, reported, 1)
+	assert.Equal(t, ActionUpload, reported[0].Type)
+}
@@ -0,0 +1,421 @@
+// Package tui (browser.go) holds the state of the file browser: the listed
+// folder, the cursor and marked items, the details of the selected item, and
+// the prompts for batch operations, which are handed to the Queue.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/shell"
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// Options configure a Browser.
+type Options struct {
+	DownloadDir    string      // Local folder that downloads are saved in.
+	Connections    int         // Parallel connections per downloaded file.
+	DirPermissions os.FileMode // Permissions of local folders created for downloads.
+}
+
+// promptKind is what the input at the bottom of the screen is for.
+type promptKind int
+
+const (
+	promptNone    promptKind = iota
+	promptMove               // Destination folder of a move.
+	promptConfirm            // Yes or no.
+)
+
+// Browser is a full-screen browser of a drive.
+type Browser struct {
+	sdk   app.SDK
+	opts  Options
+	queue *Queue
+
+	cwd        string
+	items      []onedrive.DriveItem
+	cursor     int
+	offset     int                           // Index of the first item shown.
+	marked     map[string]onedrive.DriveItem // Marked items by remote path.
+	thumbnails map[string]string             // Thumbnail summaries by item ID.
+	status     string
+	listRows   int // Rows of the list at the last Draw, for paging.
+
+	prompt    promptKind
+	question  string
+	input     []rune
+	onConfirm func()
+	done      bool // Quitting was confirmed.
+}
+
+// NewBrowser returns a Browser of the drive of `sdk`. Call Open to list the
+// first folder.
+func NewBrowser(sdk app.SDK, opts Options) *Browser {
+	if opts.DownloadDir == "" {
+		opts.DownloadDir = "."
+	}
+	return &Browser{
+		sdk:        sdk,
+		opts:       opts,
+		queue:      NewQueue(sdk, opts.Connections, opts.DirPermissions),
+		cwd:        "/",
+		marked:     make(map[string]onedrive.DriveItem),
+		thumbnails: make(map[string]string),
+		listRows:   1,
+	}
+}
+
+// Queue returns the queue of the browser's operations.
+func (b *Browser) Queue() *Queue {
+	return b.queue
+}
+
+// Cwd returns the remote folder being browsed.
+func (b *Browser) Cwd() string {
+	return b.cwd
+}
+
+// Open lists the remote folder `dir` and puts the cursor on its first item.
+func (b *Browser) Open(ctx context.Context, dir string) error {
+	if err := b.load(ctx, dir); err != nil {
+		return err
+	}
+	b.cursor, b.offset = 0, 0
+	return nil
+}
+
+// Refresh lists the current folder again if a queued deletion or move has
+// changed the drive, keeping the cursor where it was.
+func (b *Browser) Refresh(ctx context.Context) {
+	if !b.queue.TakeRemoteChange() {
+		return
+	}
+	if err := b.load(ctx, b.cwd); err != nil {
+		b.status = err.Error()
+	}
+}
+
+// load lists `dir`, with folders first.
+func (b *Browser) load(ctx context.Context, dir string) error {
+	children, err := b.sdk.GetDriveItemChildrenByPath(ctx, dir)
+	if err != nil {
+		return fmt.Errorf("listing '%s': %w", dir, err)
+	}
+	items := children.Value
+	sort.SliceStable(items, func(i, j int) bool {
+		if (items[i].Folder != nil) != (items[j].Folder != nil) {
+			return items[i].Folder != nil
+		}
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	b.cwd, b.items = dir, items
+	b.cursor = min(b.cursor, max(len(items)-1, 0))
+	return nil
+}
+
+// current returns the item under the cursor.
+func (b *Browser) current() (onedrive.DriveItem, bool) {
+	if b.cursor < 0 || b.cursor >= len(b.items) {
+		return onedrive.DriveItem{}, false
+	}
+	return b.items[b.cursor], true
+}
+
+// itemPath returns the remote path of `item` in the current folder.
+func (b *Browser) itemPath(item onedrive.DriveItem) string {
+	return path.Join(b.cwd, item.Name)
+}
+
+// selection returns the remote paths and items of the marked items, sorted
+// by path, or of the item under the cursor if none is marked.
+func (b *Browser) selection() ([]string, []onedrive.DriveItem) {
+	if len(b.marked) == 0 {
+		if item, ok := b.current(); ok {
+			return []string{b.itemPath(item)}, []onedrive.DriveItem{item}
+		}
+		return nil, nil
+	}
+	paths := make([]string, 0, len(b.marked))
+	for p := range b.marked {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	items := make([]onedrive.DriveItem, len(paths))
+	for i, p := range paths {
+		items[i] = b.marked[p]
+	}
+	return paths, items
+}
+
+// HandleKey reacts to a key press. It reports whether the browser should
+// quit.
+func (b *Browser) HandleKey(ctx context.Context, ev Event) bool {
+	if b.prompt != promptNone {
+		b.handlePromptKey(ev)
+		return b.done
+	}
+	b.status = ""
+
+	switch {
+	case ev.Key == KeyUp || ev.Rune == 'k':
+		b.moveCursor(-1)
+	case ev.Key == KeyDown || ev.Rune == 'j':
+		b.moveCursor(1)
+	case ev.Key == KeyPageUp:
+		b.moveCursor(-b.listRows)
+	case ev.Key == KeyPageDown:
+		b.moveCursor(b.listRows)
+	case ev.Key == KeyHome:
+		b.moveCursor(-len(b.items))
+	case ev.Key == KeyEnd:
+		b.moveCursor(len(b.items))
+	case ev.Key == KeyEnter || ev.Key == KeyRight || ev.Rune == 'l':
+		b.enter(ctx)
+	case ev.Key == KeyLeft || ev.Key == KeyBackspace || ev.Rune == 'h':
+		b.parent(ctx)
+	case ev.Rune == ' ':
+		b.toggleMark()
+	case ev.Rune == 'a':
+		b.toggleMarkAll()
+	case ev.Rune == 't':
+		b.loadThumbnails(ctx)
+	case ev.Rune == 'r':
+		b.queue.TakeRemoteChange()
+		if err := b.load(ctx, b.cwd); err != nil {
+			b.status = err.Error()
+		}
+	case ev.Rune == 'd':
+		b.download()
+	case ev.Rune == 'x':
+		b.askDelete()
+	case ev.Rune == 'm':
+		if paths, _ := b.selection(); len(paths) > 0 {
+			b.prompt, b.question, b.input = promptMove, fmt.Sprintf("Move %s to: ", b.describeSelection()), nil
+		}
+	case ev.Rune == 'q' || ev.Key == KeyCtrlC:
+		return b.quit()
+	}
+	return false
+}
+
+// handlePromptKey edits the input of a prompt.
+func (b *Browser) handlePromptKey(ev Event) {
+	if b.prompt == promptConfirm {
+		confirmed := ev.Rune == 'y' || ev.Rune == 'Y'
+		onConfirm := b.onConfirm
+		b.prompt, b.onConfirm = promptNone, nil
+		if confirmed && onConfirm != nil {
+			onConfirm()
+		}
+		return
+	}
+
+	switch ev.Key {
+	case KeyEscape, KeyCtrlC:
+		b.prompt = promptNone
+	case KeyBackspace:
+		if len(b.input) > 0 {
+			b.input = b.input[:len(b.input)-1]
+		}
+	case KeyEnter:
+		b.prompt = promptNone
+		if destination := strings.TrimSpace(string(b.input)); destination != "" {
+			b.move(shell.Resolve(b.cwd, destination))
+		}
+	case KeyRune:
+		b.input = append(b.input, ev.Rune)
+	}
+}
+
+// moveCursor moves the cursor by `delta` items.
+func (b *Browser) moveCursor(delta int) {
+	b.cursor = max(min(b.cursor+delta, len(b.items)-1), 0)
+}
+
+// enter opens the folder under the cursor.
+func (b *Browser) enter(ctx context.Context) {
+	item, ok := b.current()
+	if !ok || item.Folder == nil {
+		return
+	}
+	if err := b.Open(ctx, b.itemPath(item)); err != nil {
+		b.status = err.Error()
+	}
+}
+
+// parent opens the parent folder, with the cursor on the folder left.
+func (b *Browser) parent(ctx context.Context) {
+	if b.cwd == "/" {
+		return
+	}
+	left := path.Base(b.cwd)
+	if err := b.Open(ctx, path.Dir(b.cwd)); err != nil {
+		b.status = err.Error()
+		return
+	}
+	for i, item := range b.items {
+		if item.Name == left {
+			b.cursor = i
+		}
+	}
+}
+
+// toggleMark marks or unmarks the item under the cursor and moves down.
+func (b *Browser) toggleMark() {
+	item, ok := b.current()
+	if !ok {
+		return
+	}
+	p := b.itemPath(item)
+	if _, marked := b.marked[p]; marked {
+		delete(b.marked, p)
+	} else {
+		b.marked[p] = item
+	}
+	b.moveCursor(1)
+}
+
+// toggleMarkAll marks every item of the folder, or unmarks them if all are
+// marked.
+func (b *Browser) toggleMarkAll() {
+	all := true
+	for _, item := range b.items {
+		if _, marked := b.marked[b.itemPath(item)]; !marked {
+			all = false
+		}
+	}
+	for _, item := range b.items {
+		if all {
+			delete(b.marked, b.itemPath(item))
+		} else {
+			b.marked[b.itemPath(item)] = item
+		}
+	}
+}
+
+// loadThumbnails fetches the thumbnail sizes of the item under the cursor
+// for the details panel.
+func (b *Browser) loadThumbnails(ctx context.Context) {
+	item, ok := b.current()
+	if !ok || item.Folder != nil {
+		return
+	}
+	thumbnails, err := b.sdk.GetThumbnails(ctx, b.itemPath(item))
+	if err != nil {
+		b.status = fmt.Sprintf("getting thumbnails: %v", err)
+		return
+	}
+	b.thumbnails[item.ID] = summarizeThumbnails(thumbnails)
+}
+
+// download queues the download of the selected files into the download
+// folder. Folders are skipped.
+func (b *Browser) download() {
+	queued, skipped := 0, 0
+	paths, items := b.selection()
+	for i, item := range items {
+		if item.Folder != nil {
+			skipped++
+			continue
+		}
+		if err := onedrive.ValidateFileName(item.Name); err != nil {
+			b.status = err.Error()
+			continue
+		}
+		b.queue.Add(Job{Kind: JobDownload, Name: item.Name, Path: paths[i],
+			Target: filepath.Join(b.opts.DownloadDir, item.Name), Total: item.Size})
+		queued++
+	}
+	b.marked = make(map[string]onedrive.DriveItem)
+	if b.status == "" {
+		b.status = fmt.Sprintf("Queued %d download(s) to %s.", queued, b.opts.DownloadDir)
+		if skipped > 0 {
+			b.status += fmt.Sprintf(" Skipped %d folder(s); use 'items download -r' for folders.", skipped)
+		}
+	}
+}
+
+// askDelete asks to confirm the deletion of the selection.
+func (b *Browser) askDelete() {
+	paths, items := b.selection()
+	if len(items) == 0 {
+		return
+	}
+	b.prompt, b.question = promptConfirm, fmt.Sprintf("Delete %s? (y/n) ", b.describeSelection())
+	b.onConfirm = func() {
+		for i, item := range items {
+			b.queue.Add(Job{Kind: JobDelete, Name: item.Name, Path: paths[i]})
+		}
+		b.marked = make(map[string]onedrive.DriveItem)
+		b.status = fmt.Sprintf("Queued %d deletion(s).", len(items))
+	}
+}
+
+// move queues the move of the selection into the remote folder
+// `destination`.
+func (b *Browser) move(destination string) {
+	paths, items := b.selection()
+	for i, item := range items {
+		b.queue.Add(Job{Kind: JobMove, Name: item.Name, Path: paths[i], Target: destination})
+	}
+	b.marked = make(map[string]onedrive.DriveItem)
+	b.status = fmt.Sprintf("Queued %d move(s) to %s.", len(items), destination)
+}
+
+// quit reports whether to quit now, or asks first while jobs are pending.
+func (b *Browser) quit() bool {
+	for _, job := range b.queue.Jobs() {
+		if job.State == JobQueued || job.State == JobRunning {
+			b.prompt, b.question = promptConfirm, "Transfers are still running. Quit and cancel them? (y/n) "
+			b.onConfirm = func() { b.done = true }
+			return false
+		}
+	}
+	return true
+}
+
+// describeSelection describes the selection for a prompt.
+func (b *Browser) describeSelection() string {
+	_, items := b.selection()
+	if len(items) == 1 {
+		return "'" + items[0].Name + "'"
+	}
+	return fmt.Sprintf("%d items", len(items))
+}
+
+// summarizeThumbnails lists the thumbnail sizes of an item.
+func summarizeThumbnails(list onedrive.ThumbnailSetList) string {
+	if len(list.Value) == 0 {
+		return "none"
+	}
+	var sizes []string
+	set := list.Value[0]
+	for _, t := range []struct {
+		name      string
+		thumbnail *onedrive.Thumbnail
+	}{{"small", set.Small}, {"medium", set.Medium}, {"large", set.Large}, {"source", set.Source}} {
+		if t.thumbnail != nil {
+			sizes = append(sizes, fmt.Sprintf("%s %dx%d", t.name, t.thumbnail.Width, t.thumbnail.Height))
+		}
+	}
+	if len(sizes) == 0 {
+		return "none"
+	}
+	return strings.Join(sizes, ", ")
+}
+
+// formatSize returns the size of `item` for the list.
+func formatSize(item onedrive.DriveItem) string {
+	if item.Folder != nil {
+		return fmt.Sprintf("%d items", item.Folder.ChildCount)
+	}
+	return ui.FormatBytes(item.Size)
+}
@@ -0,0 +1,274 @@
+// Package tui (draw.go) lays out the browser on a Screen: a header, the list
+// of items with a details panel beside it on wide screens, the transfer queue
+// and a status line.
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tonimelisma/onedrive-client/internal/ui"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+const (
+	// minDetailsWidth is the screen width from which the details panel is shown.
+	minDetailsWidth = 80
+	// maxQueueRows is the number of jobs the transfer panel shows.
+	maxQueueRows = 4
+	// dateLayout is the layout of dates in the list and the details panel.
+	dateLayout = "2006-01-02 15:04"
+	// helpLine lists the keys when there is no status to show.
+	helpLine = "Enter open  ← back  Space mark  a all  d download  x delete  m move  t thumbnails  r refresh  q quit"
+)
+
+// Draw draws the browser on `s`.
+func (b *Browser) Draw(s *Screen) {
+	s.Clear()
+	if s.Width <= 0 || s.Height <= 0 {
+		return
+	}
+	jobs := b.queue.Jobs()
+	queueRows := 1 + min(len(jobs), maxQueueRows)
+
+	b.drawHeader(s)
+	top, bottom := 1, s.Height-1-queueRows // The rows of the list.
+	b.listRows = max(bottom-top, 1)
+
+	listWidth := s.Width
+	if s.Width >= minDetailsWidth {
+		listWidth = s.Width * 3 / 5
+		for y := top; y < bottom; y++ {
+			s.Print(listWidth, y, "│", StyleDim)
+		}
+		b.drawDetails(s, listWidth+2, top, bottom)
+	}
+	b.drawList(s, listWidth, top, bottom)
+	drawQueue(s, jobs, bottom)
+	b.drawStatus(s, s.Height-1)
+}
+
+// drawHeader draws the folder and the counts of items on the first row.
+func (b *Browser) drawHeader(s *Screen) {
+	s.Fill(0, 0, StyleReverse)
+	x := s.Print(0, 0, " OneDrive ", StyleReverse|StyleBold)
+	s.Print(x, 0, b.cwd, StyleReverse)
+	counts := fmt.Sprintf("%d items", len(b.items))
+	if len(b.marked) > 0 {
+		counts += fmt.Sprintf(", %d marked", len(b.marked))
+	}
+	s.Print(s.Width-len(counts)-1, 0, counts, StyleReverse)
+}
+
+// drawList draws the items of the folder in the rows from `top` to before
+// `bottom`, scrolled so that the cursor is visible.
+func (b *Browser) drawList(s *Screen, width, top, bottom int) {
+	rows := bottom - top
+	if rows <= 0 {
+		return
+	}
+	if len(b.items) == 0 {
+		s.Print(2, top, "(empty folder)", StyleDim)
+		return
+	}
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	} else if b.cursor >= b.offset+rows {
+		b.offset = b.cursor - rows + 1
+	}
+
+	// Columns: mark, name, size and, if there is room, the modification date.
+	sizeWidth, dateWidth := 10, len(dateLayout)
+	nameWidth := width - 2 - 1 - sizeWidth - 1
+	showDate := nameWidth-dateWidth-1 >= 12
+	if showDate {
+		nameWidth -= dateWidth + 1
+	}
+
+	for i := b.offset; i < len(b.items) && i-b.offset < rows; i++ {
+		item, y := b.items[i], top+i-b.offset
+		style := Style(0)
+		if i == b.cursor {
+			style = StyleReverse
+		}
+		mark := "  "
+		if _, marked := b.marked[b.itemPath(item)]; marked {
+			mark = "* "
+		}
+		name := item.Name
+		nameStyle := style
+		if item.Folder != nil {
+			name += "/"
+			nameStyle |= StyleBold
+		}
+		s.Print(0, y, strings.Repeat(" ", width), style)
+		x := s.Print(0, y, mark, style)
+		s.Print(x, y, truncate(name, nameWidth), nameStyle)
+		x += nameWidth + 1
+		x = s.Print(x, y, fmt.Sprintf("%*s", sizeWidth, formatSize(item)), style)
+		if showDate && !item.LastModifiedDateTime.IsZero() {
+			s.Print(x+1, y, item.LastModifiedDateTime.Local().Format(dateLayout), style)
+		}
+	}
+}
+
+// drawDetails draws the metadata of the item under the cursor from column
+// `x`, in the rows from `top` to before `bottom`.
+func (b *Browser) drawDetails(s *Screen, x, top, bottom int) {
+	item, ok := b.current()
+	if !ok {
+		return
+	}
+	width := s.Width - x
+	lines := itemDetails(item, b.itemPath(item))
+	if item.Folder == nil {
+		thumbnails, ok := b.thumbnails[item.ID]
+		if !ok {
+			thumbnails = "press t to load"
+		}
+		lines = append(lines, detail{"Thumbnails", thumbnails})
+	}
+
+	y := top
+	s.Print(x, y, truncate(item.Name, width), StyleBold)
+	for _, line := range lines {
+		y++
+		if y >= bottom {
+			return
+		}
+		labelEnd := s.Print(x, y, line.label+":", StyleDim)
+		// Long values continue on the following rows.
+		value := []rune(line.value)
+		valueWidth := width - (labelEnd - x) - 1
+		for valueWidth > 0 && y < bottom {
+			n := min(len(value), valueWidth)
+			s.Print(labelEnd+1, y, string(value[:n]), 0)
+			value = value[n:]
+			if len(value) == 0 {
+				break
+			}
+			y++
+		}
+	}
+}
+
+// detail is a line of the details panel.
+type detail struct {
+	label, value string
+}
+
+// itemDetails returns the metadata of `item` for the details panel.
+func itemDetails(item onedrive.DriveItem, remotePath string) []detail {
+	details := []detail{{"Path", remotePath}}
+	switch {
+	case item.Folder != nil:
+		details = append(details, detail{"Type", fmt.Sprintf("Folder, %d items", item.Folder.ChildCount)})
+	case item.File != nil && item.File.MimeType != "":
+		details = append(details, detail{"Type", "File, " + item.File.MimeType})
+	default:
+		details = append(details, detail{"Type", "File"})
+	}
+	details = append(details, detail{"Size", fmt.Sprintf("%s (%d bytes)", ui.FormatBytes(item.Size), item.Size)})
+	if !item.LastModifiedDateTime.IsZero() {
+		modified := item.LastModifiedDateTime.Local().Format(dateLayout)
+		if by := item.LastModifiedBy.User; by != nil && by.DisplayName != "" {
+			modified += " by " + by.DisplayName
+		}
+		details = append(details, detail{"Modified", modified})
+	}
+	if !item.CreatedDateTime.IsZero() {
+		details = append(details, detail{"Created", item.CreatedDateTime.Local().Format(dateLayout)})
+	}
+	if item.Image != nil {
+		details = append(details, detail{"Image", fmt.Sprintf("%dx%d", item.Image.Width, item.Image.Height)})
+	}
+	if item.ID != "" {
+		details = append(details, detail{"ID", item.ID})
+	}
+	return details
+}
+
+// drawQueue draws the transfer panel from row `y`: a title with the counts
+// of jobs, then running and queued jobs before the latest finished ones.
+func drawQueue(s *Screen, jobs []Job, y int) {
+	counts := map[JobState]int{}
+	for _, job := range jobs {
+		counts[job.State]++
+	}
+	s.Fill(0, y, StyleReverse)
+	title := " Transfers: none"
+	if len(jobs) > 0 {
+		title = fmt.Sprintf(" Transfers: %d running, %d queued, %d done, %d failed",
+			counts[JobRunning], counts[JobQueued], counts[JobDone], counts[JobFailed])
+	}
+	s.Print(0, y, title, StyleReverse)
+
+	var shown []Job
+	for _, state := range []JobState{JobRunning, JobQueued} {
+		for _, job := range jobs {
+			if job.State == state {
+				shown = append(shown, job)
+			}
+		}
+	}
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if jobs[i].State == JobDone || jobs[i].State == JobFailed {
+			shown = append(shown, jobs[i])
+		}
+	}
+	for i := 0; i < len(shown) && i < maxQueueRows; i++ {
+		s.Print(0, y+1+i, truncate(describeJob(shown[i]), s.Width), 0)
+	}
+}
+
+// describeJob returns the line of the transfer panel for `job`.
+func describeJob(job Job) string {
+	var action string
+	switch job.Kind {
+	case JobDownload:
+		action = "download " + job.Name
+	case JobDelete:
+		action = "delete " + job.Name
+	case JobMove:
+		action = "move " + job.Name + " to " + job.Target
+	}
+	switch job.State {
+	case JobQueued:
+		return "  … " + action + " (queued)"
+	case JobRunning:
+		if job.Kind == JobDownload && job.Total > 0 {
+			return fmt.Sprintf("  ↓ %s  %d%%  %s / %s", action, job.Completed*100/job.Total,
+				ui.FormatBytes(job.Completed), ui.FormatBytes(job.Total))
+		}
+		return "  ↻ " + action
+	case JobFailed:
+		return "  ✗ " + action + ": " + job.Err.Error()
+	}
+	return "  ✓ " + action
+}
+
+// drawStatus draws the prompt, the status message or the key help on row `y`.
+func (b *Browser) drawStatus(s *Screen, y int) {
+	switch {
+	case b.prompt != promptNone:
+		x := s.Print(0, y, b.question, StyleBold)
+		x = s.Print(x, y, string(b.input), 0)
+		s.Print(x, y, " ", StyleReverse) // The cursor.
+	case b.status != "":
+		s.Print(0, y, truncate(b.status, s.Width), 0)
+	default:
+		s.Print(0, y, truncate(helpLine, s.Width), StyleDim)
+	}
+}
+
+// truncate shortens `text` to `width` characters, marking the cut with "…".
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if width <= 0 {
+		return ""
+	}
+	if len(runes) <= width {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:width-1])) + "…"
+}
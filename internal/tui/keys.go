@@ -0,0 +1,124 @@
+// Package tui (keys.go) decodes the bytes a terminal sends for key presses
+// into key events.
+package tui
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"unicode/utf8"
+)
+
+// Key identifies a key press.
+type Key int
+
+// The keys the browser reacts to. KeyRune is any printable character.
+const (
+	KeyRune Key = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyEnter
+	KeyBackspace
+	KeyEscape
+	KeyTab
+	KeyCtrlC
+)
+
+// Event is a key press.
+type Event struct {
+	Key  Key
+	Rune rune // The character, for KeyRune.
+}
+
+// escapeSequences maps the escape sequences of special keys, without their
+// leading ESC, to keys.
+var escapeSequences = map[string]Key{
+	"[A": KeyUp, "[B": KeyDown, "[C": KeyRight, "[D": KeyLeft,
+	"OA": KeyUp, "OB": KeyDown, "OC": KeyRight, "OD": KeyLeft,
+	"[H": KeyHome, "[F": KeyEnd, "OH": KeyHome, "OF": KeyEnd,
+	"[1~": KeyHome, "[4~": KeyEnd, "[7~": KeyHome, "[8~": KeyEnd,
+	"[5~": KeyPageUp, "[6~": KeyPageDown,
+}
+
+// DecodeKeys returns the key events in `data`.
+func DecodeKeys(data []byte) []Event {
+	var events []Event
+	for len(data) > 0 {
+		event, n := decodeKey(data)
+		data = data[n:]
+		if n > 0 && (event.Key != KeyRune || event.Rune != 0) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// decodeKey decodes the first key of `data`, returning it and the number of
+// bytes it took. Unknown sequences are skipped as a zero Event.
+func decodeKey(data []byte) (Event, int) {
+	switch b := data[0]; b {
+	case 0x1b:
+		if len(data) == 1 {
+			return Event{Key: KeyEscape}, 1
+		}
+		// A CSI or SS3 sequence ends with a byte in the range '@' to '~'.
+		if data[1] == '[' || data[1] == 'O' {
+			for i := 2; i < len(data); i++ {
+				if data[i] >= '@' && data[i] <= '~' {
+					if key, ok := escapeSequences[string(data[1:i+1])]; ok {
+						return Event{Key: key}, i + 1
+					}
+					return Event{}, i + 1
+				}
+			}
+			return Event{}, len(data)
+		}
+		return Event{Key: KeyEscape}, 1
+	case '\r', '\n':
+		return Event{Key: KeyEnter}, 1
+	case 0x7f, 0x08:
+		return Event{Key: KeyBackspace}, 1
+	case '\t':
+		return Event{Key: KeyTab}, 1
+	case 0x03:
+		return Event{Key: KeyCtrlC}, 1
+	default:
+		if b < ' ' {
+			return Event{}, 1
+		}
+		r, n := utf8.DecodeRune(data)
+		return Event{Key: KeyRune, Rune: r}, n
+	}
+}
+
+// ReadEvents sends the key events read from `in` to the returned channel,
+// which is closed at the end of the input. Events are dropped once `ctx` is
+// done.
+func ReadEvents(ctx context.Context, in io.Reader) <-chan Event {
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		reader := bufio.NewReader(in)
+		buf := make([]byte, 256)
+		for {
+			n, err := reader.Read(buf)
+			for _, event := range DecodeKeys(buf[:n]) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,202 @@
+// Package tui (queue.go) runs the downloads, deletions and moves started in
+// the browser one after another in the background, and keeps their state and
+// progress for the transfer panel.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// JobKind is the operation of a Job.
+type JobKind int
+
+// The operations of the queue.
+const (
+	JobDownload JobKind = iota
+	JobDelete
+	JobMove
+)
+
+// JobState is the progress of a Job.
+type JobState int
+
+// The states of a Job, in order.
+const (
+	JobQueued JobState = iota
+	JobRunning
+	JobDone
+	JobFailed
+)
+
+// Job is an operation of the queue.
+type Job struct {
+	Kind      JobKind
+	Name      string // Name of the item.
+	Path      string // Remote path of the item.
+	Target    string // Local file of a download, or destination folder of a move.
+	State     JobState
+	Completed int64 // Bytes downloaded.
+	Total     int64 // Size of a download.
+	Err       error
+}
+
+// Queue runs Jobs one at a time.
+type Queue struct {
+	sdk            app.SDK
+	connections    int
+	dirPermissions os.FileMode
+
+	mu            sync.Mutex
+	jobs          []*Job
+	remoteChanged bool // A deletion or move finished since TakeRemoteChange.
+
+	wake    chan struct{}
+	changed chan struct{}
+	pending sync.WaitGroup
+}
+
+// NewQueue returns a Queue that downloads with `connections` parallel
+// connections per file, creating local folders with `dirPermissions`.
+func NewQueue(sdk app.SDK, connections int, dirPermissions os.FileMode) *Queue {
+	return &Queue{
+		sdk:            sdk,
+		connections:    max(connections, 1),
+		dirPermissions: dirPermissions,
+		wake:           make(chan struct{}, 1),
+		changed:        make(chan struct{}, 1),
+	}
+}
+
+// Start runs the jobs of the queue in the background until `ctx` is done.
+func (q *Queue) Start(ctx context.Context) {
+	go func() {
+		for {
+			job := q.next()
+			if job == nil {
+				select {
+				case <-q.wake:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+			q.run(ctx, job)
+		}
+	}()
+}
+
+// Add queues `job`.
+func (q *Queue) Add(job Job) {
+	job.State = JobQueued
+	q.pending.Add(1)
+	q.mu.Lock()
+	q.jobs = append(q.jobs, &job)
+	q.mu.Unlock()
+	q.signal(q.wake)
+	q.signal(q.changed)
+}
+
+// Wait blocks until every queued job has finished.
+func (q *Queue) Wait() {
+	q.pending.Wait()
+}
+
+// Jobs returns a copy of the jobs, in the order they were added.
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, len(q.jobs))
+	for i, job := range q.jobs {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+// Changed returns a channel that receives a value after jobs were added or
+// made progress.
+func (q *Queue) Changed() <-chan struct{} {
+	return q.changed
+}
+
+// TakeRemoteChange reports whether a deletion or move has finished since the
+// last call, so that the listing should be reloaded.
+func (q *Queue) TakeRemoteChange() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	changed := q.remoteChanged
+	q.remoteChanged = false
+	return changed
+}
+
+// next marks the first queued job as running and returns it, or nil.
+func (q *Queue) next() *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, job := range q.jobs {
+		if job.State == JobQueued {
+			job.State = JobRunning
+			return job
+		}
+	}
+	return nil
+}
+
+// run performs `job` and records its outcome.
+func (q *Queue) run(ctx context.Context, job *Job) {
+	defer q.pending.Done()
+	q.signal(q.changed)
+
+	var err error
+	switch job.Kind {
+	case JobDownload:
+		err = q.download(ctx, job)
+	case JobDelete:
+		err = q.sdk.DeleteDriveItem(ctx, job.Path)
+	case JobMove:
+		_, err = q.sdk.MoveDriveItem(ctx, job.Path, job.Target)
+	}
+
+	q.mu.Lock()
+	if err != nil {
+		job.State, job.Err = JobFailed, err
+	} else {
+		job.State = JobDone
+	}
+	if job.Kind != JobDownload {
+		q.remoteChanged = true
+	}
+	q.mu.Unlock()
+	q.signal(q.changed)
+}
+
+// download downloads the file of `job`, recording its progress. Existing
+// local files are not overwritten.
+func (q *Queue) download(ctx context.Context, job *Job) error {
+	if err := onedrive.ValidateDownloadPath(job.Target, false, q.dirPermissions); err != nil {
+		return err
+	}
+	err := q.sdk.DownloadFileParallel(ctx, job.Path, job.Target, q.connections, func(completed, total int64) {
+		q.mu.Lock()
+		job.Completed, job.Total = completed, total
+		q.mu.Unlock()
+		q.signal(q.changed)
+	})
+	if err != nil {
+		return fmt.Errorf("downloading '%s': %w", job.Path, err)
+	}
+	return nil
+}
+
+// signal sends to `ch` unless a value is already waiting there.
+func (q *Queue) signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
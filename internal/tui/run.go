@@ -0,0 +1,61 @@
+// Package tui (run.go) runs the browser on a terminal: it reads key presses,
+// starts the queue and redraws the screen after every change.
+package tui
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+const (
+	// enterScreen switches to the alternate screen and hides the cursor.
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	// leaveScreen shows the cursor and restores the normal screen.
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	// redrawInterval is how often the screen is redrawn without changes, to
+	// pick up a resized terminal.
+	redrawInterval = 500 * time.Millisecond
+)
+
+// Run shows the browser on the terminal written to by `out` until the user
+// quits, `in` ends or `ctx` is done. Key presses are read from `in`, which
+// should be in raw mode. `size` returns the columns and rows of the terminal.
+// Open must have been called first.
+func (b *Browser) Run(ctx context.Context, in io.Reader, out io.Writer, size func() (int, int)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	b.queue.Start(ctx)
+	events := ReadEvents(ctx, in)
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+
+	if _, err := io.WriteString(out, enterScreen); err != nil {
+		return err
+	}
+	defer io.WriteString(out, leaveScreen) //nolint:errcheck // Nothing to do if restoring fails.
+
+	screen := NewScreen(size())
+	for {
+		if width, height := size(); width != screen.Width || height != screen.Height {
+			screen.Resize(width, height)
+		}
+		b.Draw(screen)
+		if err := screen.Render(out); err != nil {
+			return err
+		}
+
+		select {
+		case ev, ok := <-events:
+			if !ok || b.HandleKey(ctx, ev) {
+				return nil
+			}
+		case <-b.queue.Changed():
+			b.Refresh(ctx)
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
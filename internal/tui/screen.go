@@ -0,0 +1,160 @@
+// Package tui (screen.go) implements the full-screen file browser of
+// 'onedrive-client browse'. The browser draws into a Screen, a virtual
+// terminal of styled cells, which is then written to the real terminal with
+// ANSI escape sequences. Tests inspect the Screen instead of a terminal.
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Style is the text style of a cell.
+type Style uint8
+
+// Styles that can be combined.
+const (
+	StyleBold Style = 1 << iota
+	StyleReverse
+	StyleDim
+)
+
+// cell is a character of the Screen.
+type cell struct {
+	r     rune
+	style Style
+}
+
+// Screen is a virtual terminal of Width columns and Height rows.
+type Screen struct {
+	Width, Height int
+	cells         [][]cell
+}
+
+// NewScreen returns a blank Screen of `width` columns and `height` rows.
+func NewScreen(width, height int) *Screen {
+	s := &Screen{}
+	s.Resize(width, height)
+	return s
+}
+
+// Resize changes the size of the Screen and clears it.
+func (s *Screen) Resize(width, height int) {
+	s.Width, s.Height = max(width, 0), max(height, 0)
+	s.cells = make([][]cell, s.Height)
+	for y := range s.cells {
+		s.cells[y] = make([]cell, s.Width)
+	}
+	s.Clear()
+}
+
+// Clear blanks every cell.
+func (s *Screen) Clear() {
+	for y := range s.cells {
+		for x := range s.cells[y] {
+			s.cells[y][x] = cell{r: ' '}
+		}
+	}
+}
+
+// Print writes `text` at column `x` of row `y` in `style`, cut at the right
+// edge of the Screen. It returns the column after the text.
+func (s *Screen) Print(x, y int, text string, style Style) int {
+	if y < 0 || y >= s.Height {
+		return x
+	}
+	for _, r := range text {
+		if x >= s.Width {
+			break
+		}
+		if r < ' ' {
+			r = '?' // Control characters would garble the terminal.
+		}
+		if x >= 0 {
+			s.cells[y][x] = cell{r: r, style: style}
+		}
+		x++
+	}
+	return x
+}
+
+// Fill sets the cells from column `x` to the right edge of row `y` to blanks
+// in `style`.
+func (s *Screen) Fill(x, y int, style Style) {
+	if y < 0 || y >= s.Height {
+		return
+	}
+	for ; x < s.Width; x++ {
+		if x >= 0 {
+			s.cells[y][x] = cell{r: ' ', style: style}
+		}
+	}
+}
+
+// Row returns the text of row `y`, without trailing blanks.
+func (s *Screen) Row(y int) string {
+	if y < 0 || y >= s.Height {
+		return ""
+	}
+	var b strings.Builder
+	for _, c := range s.cells[y] {
+		b.WriteRune(c.r)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// StyleAt returns the style of the cell at column `x` of row `y`.
+func (s *Screen) StyleAt(x, y int) Style {
+	if y < 0 || y >= s.Height || x < 0 || x >= s.Width {
+		return 0
+	}
+	return s.cells[y][x].style
+}
+
+// String returns the text of all rows, one per line.
+func (s *Screen) String() string {
+	rows := make([]string, s.Height)
+	for y := range rows {
+		rows[y] = s.Row(y)
+	}
+	return strings.Join(rows, "\n")
+}
+
+// Render writes the Screen to a terminal as ANSI escape sequences, redrawing
+// every row.
+func (s *Screen) Render(w io.Writer) error {
+	var b strings.Builder
+	for y, row := range s.cells {
+		fmt.Fprintf(&b, "\x1b[%d;1H\x1b[0m", y+1)
+		if y == len(s.cells)-1 && len(row) > 0 {
+			row = row[:len(row)-1] // Writing the last cell would scroll some terminals.
+		}
+		current := Style(0)
+		for _, c := range row {
+			if c.style != current {
+				b.WriteString(sgr(c.style))
+				current = c.style
+			}
+			b.WriteRune(c.r)
+		}
+	}
+	b.WriteString("\x1b[0m")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// sgr returns the escape sequence selecting `style`.
+func sgr(style Style) string {
+	codes := []string{"0"}
+	if style&StyleBold != 0 {
+		codes = append(codes, "1")
+	}
+	if style&StyleDim != 0 {
+		codes = append(codes, "2")
+	}
+	if style&StyleReverse != 0 {
+		codes = append(codes, "7")
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScreenPrint(t *testing.T) {
+	s := NewScreen(10, 3)
+	end := s.Print(2, 1, "hello", StyleBold)
+	assert.Equal(t, 7, end)
+	assert.Equal(t, "  hello", s.Row(1))
+	assert.Equal(t, StyleBold, s.StyleAt(2, 1))
+	assert.Equal(t, Style(0), s.StyleAt(1, 1))
+
+	// Text is cut at the right edge and control characters are replaced.
+	s.Print(6, 0, "wide\ttext", 0)
+	assert.Equal(t, "      wide", s.Row(0))
+	s.Print(0, 2, "a\x1bb", 0)
+	assert.Equal(t, "a?b", s.Row(2))
+
+	// Rows outside the screen are ignored.
+	assert.Equal(t, 3, s.Print(3, 5, "x", 0))
+	assert.Equal(t, "", s.Row(5))
+
+	s.Fill(8, 1, StyleReverse)
+	assert.Equal(t, StyleReverse, s.StyleAt(9, 1))
+	s.Clear()
+	assert.Equal(t, "\n\n", s.String())
+}
+
+func TestScreenRender(t *testing.T) {
+	s := NewScreen(4, 2)
+	s.Print(0, 0, "ab", StyleReverse)
+	s.Print(0, 1, "cdef", 0)
+
+	var out bytes.Buffer
+	require.NoError(t, s.Render(&out))
+	rendered := out.String()
+	assert.Contains(t, rendered, "\x1b[1;1H\x1b[0m\x1b[0;7mab\x1b[0m  ")
+	// The last cell of the screen is not written.
+	assert.Contains(t, rendered, "\x1b[2;1H\x1b[0mcde\x1b[0m")
+	assert.NotContains(t, rendered, "cdef")
+}
+
+func TestDecodeKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Event
+	}{
+		{"arrows", "\x1b[A\x1b[B\x1bOC\x1b[D", []Event{{Key: KeyUp}, {Key: KeyDown}, {Key: KeyRight}, {Key: KeyLeft}}},
+		{"paging", "\x1b[5~\x1b[6~\x1b[H\x1b[4~", []Event{{Key: KeyPageUp}, {Key: KeyPageDown}, {Key: KeyHome}, {Key: KeyEnd}}},
+		{"runes", "aé ", []Event{{Key: KeyRune, Rune: 'a'}, {Key: KeyRune, Rune: 'é'}, {Key: KeyRune, Rune: ' '}}},
+		{"control keys", "\r\x7f\t\x03", []Event{{Key: KeyEnter}, {Key: KeyBackspace}, {Key: KeyTab}, {Key: KeyCtrlC}}},
+		{"escape", "\x1b", []Event{{Key: KeyEscape}}},
+		{"unknown sequences are skipped", "\x1b[15~x\x01", []Event{{Key: KeyRune, Rune: 'x'}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DecodeKeys([]byte(tt.data)))
+		})
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	var events []Event
+	for ev := range ReadEvents(context.Background(), strings.NewReader("j\x1b[B")) {
+		events = append(events, ev)
+	}
+	assert.Equal(t, []Event{{Key: KeyRune, Rune: 'j'}, {Key: KeyDown}}, events)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "a long…", truncate("a long name", 7))
+	assert.Equal(t, "", truncate("text", 0))
+}
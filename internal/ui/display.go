@@ -6,6 +6,7 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"strings"
 	"time"
@@ -14,6 +15,24 @@ import (
 	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
 )
 
+// Default CSV columns of the results, see Result.Columns.
+var (
+	itemColumns       = []string{"id", "name", "size", "lastModifiedDateTime", "file.mimeType", "folder.childCount"}
+	searchColumns     = []string{"id", "name", "size", "lastModifiedDateTime", "parentReference.path"}
+	deltaColumns      = []string{"id", "name", "size", "lastModifiedDateTime", "parentReference.path", "deleted.state"}
+	driveColumns      = []string{"id", "name", "driveType", "quota.used", "quota.total", "owner.user.displayName"}
+	quotaColumns      = []string{"total", "used", "remaining", "state"}
+	userColumns       = []string{"id", "displayName", "userPrincipalName"}
+	linkColumns       = []string{"id", "roles", "link.type", "link.scope", "link.webUrl", "expirationDateTime"}
+	versionColumns    = []string{"id", "size", "lastModifiedDateTime", "lastModifiedBy.user.displayName"}
+	activityColumns   = []string{"id", "times.recordedTime", "actor.user.displayName", "driveItem.name"}
+	thumbnailColumns  = []string{"id", "small.url", "medium.url", "large.url"}
+	previewColumns    = []string{"getUrl", "postUrl", "postParameters"}
+	permissionColumns = []string{"id", "roles", "link.type", "link.scope", "link.webUrl", "grantedToV2.user.displayName", "expirationDateTime"}
+	copyColumns       = []string{"status", "percentageComplete", "statusDescription", "resourceId", "error.code"}
+	uploadColumns     = []string{"uploadUrl", "expirationDateTime", "nextExpectedRanges"}
+)
+
 // StdLogger implements the onedrive.Logger interface using the standard log package.
 // This allows the SDK to output debug messages through the application's logger
 // when debug mode is enabled.
@@ -97,16 +116,20 @@ func PrintError(err error) {
 
 // DisplayItems prints a table of DriveItem resources, showing name, size, and type.
 // It indicates whether each item is a file or a folder.
-// The title parameter allows customizing the header message.
-func DisplayItems(items onedrive.DriveItemList) {
+func DisplayItems(items onedrive.DriveItemList) error {
+	return render(Result{Data: items, Columns: itemColumns, Table: func(w io.Writer) { printItems(w, items) }})
+}
+
+// printItems writes the table of DisplayItems.
+func printItems(w io.Writer, items onedrive.DriveItemList) {
 	if len(items.Value) == 0 {
-		fmt.Println("No items found in the specified location.")
+		fmt.Fprintln(w, "No items found in the specified location.")
 		return
 	}
 
-	fmt.Printf("Items in the specified location (%d item(s) found)\n\n", len(items.Value))
-	fmt.Printf("%-60.60s %12s %-10s %s\n", "Name", "Size", "Type", "Last Modified")
-	fmt.Println(strings.Repeat("-", onedrive.StandardSeparatorLength))
+	fmt.Fprintf(w, "Items in the specified location (%d item(s) found)\n\n", len(items.Value))
+	fmt.Fprintf(w, "%-60.60s %12s %-10s %s\n", "Name", "Size", "Type", "Last Modified")
+	fmt.Fprintln(w, strings.Repeat("-", onedrive.StandardSeparatorLength))
 	for i := range items.Value {
 		item := &items.Value[i] // Use pointer to avoid copying large struct
 		itemType := "File"
@@ -121,20 +144,25 @@ func DisplayItems(items onedrive.DriveItemList) {
 
 		lastModified := item.LastModifiedDateTime.Local().Format(onedrive.StandardTimeFormat)
 
-		fmt.Printf("%-60.60s %12s %-10s %s\n", name, formatBytes(item.Size), itemType, lastModified)
+		fmt.Fprintf(w, "%-60.60s %12s %-10s %s\n", name, FormatBytes(item.Size), itemType, lastModified)
 	}
 }
 
 // DisplayDrives displays a list of drives with their names, types, and quota information.
-func DisplayDrives(drives onedrive.DriveList) {
+func DisplayDrives(drives onedrive.DriveList) error {
+	return render(Result{Data: drives, Columns: driveColumns, Table: func(w io.Writer) { printDrives(w, drives) }})
+}
+
+// printDrives writes the table of DisplayDrives.
+func printDrives(w io.Writer, drives onedrive.DriveList) {
 	if len(drives.Value) == 0 {
-		fmt.Println("No drives found for this account.")
+		fmt.Fprintln(w, "No drives found for this account.")
 		return
 	}
 
-	fmt.Printf("Drives for this account (%d drive(s) found)\n\n", len(drives.Value))
-	fmt.Printf("%-40.40s %-15s %10s %10s %s\n", "Name", "Type", "Used", "Total", "Owner")
-	fmt.Println(strings.Repeat("-", onedrive.StandardSeparatorLength))
+	fmt.Fprintf(w, "Drives for this account (%d drive(s) found)\n\n", len(drives.Value))
+	fmt.Fprintf(w, "%-40.40s %-15s %10s %10s %s\n", "Name", "Type", "Used", "Total", "Owner")
+	fmt.Fprintln(w, strings.Repeat("-", onedrive.StandardSeparatorLength))
 	for i := range drives.Value {
 		drive := &drives.Value[i] // Use pointer to avoid copying large struct
 		owner := "N/A"
@@ -142,18 +170,18 @@ func DisplayDrives(drives onedrive.DriveList) {
 			owner = drive.Owner.User.DisplayName
 		}
 
-		fmt.Printf("%-40.40s %-15s %10s %10s %s\n",
+		fmt.Fprintf(w, "%-40.40s %-15s %10s %10s %s\n",
 			drive.Name,
 			drive.DriveType,
-			formatBytes(drive.Quota.Used),
-			formatBytes(drive.Quota.Total),
+			FormatBytes(drive.Quota.Used),
+			FormatBytes(drive.Quota.Total),
 			owner)
 	}
 }
 
-// formatBytes converts a size in bytes (int64) to a human-readable string
+// FormatBytes converts a size in bytes (int64) to a human-readable string
 // using IEC units (KiB, MiB, GiB, etc.).
-func formatBytes(b int64) string {
+func FormatBytes(b int64) string {
 	const unit = onedrive.DefaultBufferSize // Use constant for consistency
 	if b < unit {
 		return fmt.Sprintf("%d B", b)
@@ -168,41 +196,53 @@ func formatBytes(b int64) string {
 }
 
 // DisplayQuota prints detailed information about a drive's storage quota.
-func DisplayQuota(drive onedrive.Drive) {
-	fmt.Println("Drive Quota Information:")
-	fmt.Printf("  Total Space: %s\n", formatBytes(drive.Quota.Total))
-	fmt.Printf("  Used Space:  %s\n", formatBytes(drive.Quota.Used))
-	fmt.Printf("  Free Space:  %s\n", formatBytes(drive.Quota.Remaining))
-	fmt.Printf("  Quota State: %s\n", drive.Quota.State) // e.g., "normal", "nearing", "critical"
+func DisplayQuota(drive onedrive.Drive) error {
+	return render(Result{Data: drive.Quota, Columns: quotaColumns, Table: func(w io.Writer) { printQuota(w, drive) }})
+}
+
+// printQuota writes the summary of DisplayQuota.
+func printQuota(w io.Writer, drive onedrive.Drive) {
+	fmt.Fprintln(w, "Drive Quota Information:")
+	fmt.Fprintf(w, "  Total Space: %s\n", FormatBytes(drive.Quota.Total))
+	fmt.Fprintf(w, "  Used Space:  %s\n", FormatBytes(drive.Quota.Used))
+	fmt.Fprintf(w, "  Free Space:  %s\n", FormatBytes(drive.Quota.Remaining))
+	fmt.Fprintf(w, "  Quota State: %s\n", drive.Quota.State) // e.g., "normal", "nearing", "critical"
 }
 
 // DisplayUser prints information about the authenticated user.
-func DisplayUser(user onedrive.User) {
-	fmt.Printf("Logged in as: %s (User Principal Name: %s, ID: %s)\n", user.DisplayName, user.UserPrincipalName, user.ID)
+func DisplayUser(user onedrive.User) error {
+	return render(Result{Data: user, Columns: userColumns, Table: func(w io.Writer) {
+		fmt.Fprintf(w, "Logged in as: %s (User Principal Name: %s, ID: %s)\n", user.DisplayName, user.UserPrincipalName, user.ID)
+	}})
 }
 
 // DisplayDriveItem prints detailed metadata for a single DriveItem.
-func DisplayDriveItem(item onedrive.DriveItem) {
-	fmt.Println("Item Metadata:")
-	fmt.Printf("  Name:             %s\n", item.Name)
-	fmt.Printf("  ID:               %s\n", item.ID)
-	fmt.Printf("  Size:             %s (%d bytes)\n", formatBytes(item.Size), item.Size)
-	fmt.Printf("  Created:          %s\n", item.CreatedDateTime.Local().Format(time.RFC1123)) // Format for readability
-	fmt.Printf("  Last Modified:    %s\n", item.LastModifiedDateTime.Local().Format(time.RFC1123))
+func DisplayDriveItem(item onedrive.DriveItem) error {
+	return render(Result{Data: item, Columns: itemColumns, Table: func(w io.Writer) { printDriveItem(w, item) }})
+}
+
+// printDriveItem writes the metadata of DisplayDriveItem.
+func printDriveItem(w io.Writer, item onedrive.DriveItem) {
+	fmt.Fprintln(w, "Item Metadata:")
+	fmt.Fprintf(w, "  Name:             %s\n", item.Name)
+	fmt.Fprintf(w, "  ID:               %s\n", item.ID)
+	fmt.Fprintf(w, "  Size:             %s (%d bytes)\n", FormatBytes(item.Size), item.Size)
+	fmt.Fprintf(w, "  Created:          %s\n", item.CreatedDateTime.Local().Format(time.RFC1123)) // Format for readability
+	fmt.Fprintf(w, "  Last Modified:    %s\n", item.LastModifiedDateTime.Local().Format(time.RFC1123))
 	if item.WebURL != "" {
-		fmt.Printf("  Web URL:          %s\n", item.WebURL)
+		fmt.Fprintf(w, "  Web URL:          %s\n", item.WebURL)
 	}
 
 	if item.Folder != nil {
-		fmt.Printf("  Type:             Folder\n")
-		fmt.Printf("  Child Count:      %d\n", item.Folder.ChildCount)
+		fmt.Fprintf(w, "  Type:             Folder\n")
+		fmt.Fprintf(w, "  Child Count:      %d\n", item.Folder.ChildCount)
 	} else if item.File != nil { // Check for File facet
-		fmt.Printf("  Type:             File\n")
+		fmt.Fprintf(w, "  Type:             File\n")
 		if item.File.MimeType != "" {
-			fmt.Printf("  MIME Type:        %s\n", item.File.MimeType)
+			fmt.Fprintf(w, "  MIME Type:        %s\n", item.File.MimeType)
 		}
 	} else {
-		fmt.Printf("  Type:             Unknown/Other\n")
+		fmt.Fprintf(w, "  Type:             Unknown/Other\n")
 	}
 	// Add more fields as needed, e.g., item.CreatedBy.User.DisplayName
 }
@@ -228,15 +268,21 @@ func NewSpinner() *progressbar.ProgressBar {
 }
 
 // DisplaySearchResults displays search results with highlighting or context.
-func DisplaySearchResults(items onedrive.DriveItemList) {
+// In structured output, the link to the next page is the list's "@odata.nextLink" field.
+func DisplaySearchResults(items onedrive.DriveItemList) error {
+	return render(Result{Data: items, Columns: searchColumns, Table: func(w io.Writer) { printSearchResults(w, items) }})
+}
+
+// printSearchResults writes the table of DisplaySearchResults.
+func printSearchResults(w io.Writer, items onedrive.DriveItemList) {
 	if len(items.Value) == 0 {
-		fmt.Println("No items matched your search criteria.")
+		fmt.Fprintln(w, "No items matched your search criteria.")
 		return
 	}
 
-	fmt.Printf("Search results (%d item(s) found)\n\n", len(items.Value))
-	fmt.Printf("%-60.60s %12s %-10s %-20s %s\n", "Name", "Size", "Type", "Last Modified", "Path")
-	fmt.Println(strings.Repeat("-", onedrive.ExtraLongSeparatorLength))
+	fmt.Fprintf(w, "Search results (%d item(s) found)\n\n", len(items.Value))
+	fmt.Fprintf(w, "%-60.60s %12s %-10s %-20s %s\n", "Name", "Size", "Type", "Last Modified", "Path")
+	fmt.Fprintln(w, strings.Repeat("-", onedrive.ExtraLongSeparatorLength))
 	for i := range items.Value {
 		item := &items.Value[i] // Use pointer to avoid copying large struct
 		itemType := "File"
@@ -256,20 +302,25 @@ func DisplaySearchResults(items onedrive.DriveItemList) {
 
 		lastModified := item.LastModifiedDateTime.Local().Format(onedrive.StandardTimeFormat)
 
-		fmt.Printf("%-60.60s %12s %-10s %-20s %s\n", name, formatBytes(item.Size), itemType, lastModified, path)
+		fmt.Fprintf(w, "%-60.60s %12s %-10s %-20s %s\n", name, FormatBytes(item.Size), itemType, lastModified, path)
 	}
 }
 
 // DisplaySharedItems displays items that have been shared with the user.
-func DisplaySharedItems(items onedrive.DriveItemList) {
+func DisplaySharedItems(items onedrive.DriveItemList) error {
+	return render(Result{Data: items, Columns: itemColumns, Table: func(w io.Writer) { printSharedItems(w, items) }})
+}
+
+// printSharedItems writes the table of DisplaySharedItems.
+func printSharedItems(w io.Writer, items onedrive.DriveItemList) {
 	if len(items.Value) == 0 {
-		fmt.Println("No shared items found for this account.")
+		fmt.Fprintln(w, "No shared items found for this account.")
 		return
 	}
 
-	fmt.Printf("Shared items for this account (%d item(s) found)\n\n", len(items.Value))
-	fmt.Printf("%-50.50s %12s %-10s %-20s %s\n", "Name", "Size", "Type", "Shared/Created Date", "Shared By/Owner")
-	fmt.Println(strings.Repeat("-", onedrive.ExtraLongLineLength))
+	fmt.Fprintf(w, "Shared items for this account (%d item(s) found)\n\n", len(items.Value))
+	fmt.Fprintf(w, "%-50.50s %12s %-10s %-20s %s\n", "Name", "Size", "Type", "Shared/Created Date", "Shared By/Owner")
+	fmt.Fprintln(w, strings.Repeat("-", onedrive.ExtraLongLineLength))
 	for i := range items.Value {
 		item := &items.Value[i] // Use pointer to avoid copying large struct
 		itemType := "File"
@@ -289,20 +340,25 @@ func DisplaySharedItems(items onedrive.DriveItemList) {
 
 		sharedDate := item.CreatedDateTime.Local().Format(onedrive.StandardTimeFormat)
 
-		fmt.Printf("%-50.50s %12s %-10s %-20s %s\n", name, formatBytes(item.Size), itemType, sharedDate, owner)
+		fmt.Fprintf(w, "%-50.50s %12s %-10s %-20s %s\n", name, FormatBytes(item.Size), itemType, sharedDate, owner)
 	}
 }
 
 // DisplayRecentItems displays recently accessed items with timestamps.
-func DisplayRecentItems(items onedrive.DriveItemList) {
+func DisplayRecentItems(items onedrive.DriveItemList) error {
+	return render(Result{Data: items, Columns: itemColumns, Table: func(w io.Writer) { printRecentItems(w, items) }})
+}
+
+// printRecentItems writes the table of DisplayRecentItems.
+func printRecentItems(w io.Writer, items onedrive.DriveItemList) {
 	if len(items.Value) == 0 {
-		fmt.Println("No recent items found for this account.")
+		fmt.Fprintln(w, "No recent items found for this account.")
 		return
 	}
 
-	fmt.Printf("Recent items for this account (%d item(s) found)\n\n", len(items.Value))
-	fmt.Printf("%-60.60s %12s %-10s %s\n", "Name", "Size", "Type", "Last Modified/Accessed")
-	fmt.Println(strings.Repeat("-", onedrive.ExtraLongSeparatorLength))
+	fmt.Fprintf(w, "Recent items for this account (%d item(s) found)\n\n", len(items.Value))
+	fmt.Fprintf(w, "%-60.60s %12s %-10s %s\n", "Name", "Size", "Type", "Last Modified/Accessed")
+	fmt.Fprintln(w, strings.Repeat("-", onedrive.ExtraLongSeparatorLength))
 	for i := range items.Value {
 		item := &items.Value[i] // Use pointer to avoid copying large struct
 		itemType := "File"
@@ -317,60 +373,72 @@ func DisplayRecentItems(items onedrive.DriveItemList) {
 
 		accessTime := item.LastModifiedDateTime.Local().Format(onedrive.StandardTimeFormat)
 
-		fmt.Printf("%-60.60s %12s %-10s %s\n", name, formatBytes(item.Size), itemType, accessTime)
+		fmt.Fprintf(w, "%-60.60s %12s %-10s %s\n", name, FormatBytes(item.Size), itemType, accessTime)
 	}
 }
 
 // DisplaySpecialFolder prints detailed information about a requested special folder.
-func DisplaySpecialFolder(item onedrive.DriveItem, folderName string) {
-	fmt.Printf("Details for Special Folder: '%s'\n", folderName)
-	// Use DisplayDriveItem for consistent detailed output.
-	DisplayDriveItem(item)
+func DisplaySpecialFolder(item onedrive.DriveItem, folderName string) error {
+	return render(Result{Data: item, Columns: itemColumns, Table: func(w io.Writer) {
+		fmt.Fprintf(w, "Details for Special Folder: '%s'\n", folderName)
+		// Use printDriveItem for consistent detailed output.
+		printDriveItem(w, item)
+	}})
 }
 
 // DisplaySharingLink prints information about a newly created sharing link.
-func DisplaySharingLink(link onedrive.SharingLink) {
-	fmt.Println("Sharing Link Created Successfully!")
-	fmt.Printf("  Link ID:          %s\n", link.ID) // This is the permission ID for the link.
-	fmt.Printf("  Type:             %s\n", link.Link.Type)
-	fmt.Printf("  Scope:            %s\n", link.Link.Scope)
+func DisplaySharingLink(link onedrive.SharingLink) error {
+	return render(Result{Data: link, Columns: linkColumns, Table: func(w io.Writer) { printSharingLink(w, link) }})
+}
+
+// printSharingLink writes the details of DisplaySharingLink.
+func printSharingLink(w io.Writer, link onedrive.SharingLink) {
+	fmt.Fprintln(w, "Sharing Link Created Successfully!")
+	fmt.Fprintf(w, "  Link ID:          %s\n", link.ID) // This is the permission ID for the link.
+	fmt.Fprintf(w, "  Type:             %s\n", link.Link.Type)
+	fmt.Fprintf(w, "  Scope:            %s\n", link.Link.Scope)
 	if len(link.Roles) > 0 {
-		fmt.Printf("  Effective Roles:  %s\n", strings.Join(link.Roles, ", "))
+		fmt.Fprintf(w, "  Effective Roles:  %s\n", strings.Join(link.Roles, ", "))
 	}
-	fmt.Printf("  Share URL:        %s\n", link.Link.WebUrl)
+	fmt.Fprintf(w, "  Share URL:        %s\n", link.Link.WebUrl)
 
 	if link.HasPassword {
-		fmt.Printf("  Password:         Protected (password not displayed)\n")
+		fmt.Fprintf(w, "  Password:         Protected (password not displayed)\n")
 	}
 
 	if link.ExpirationDateTime != "" {
 		expTime, err := time.Parse(time.RFC3339Nano, link.ExpirationDateTime)
 		if err == nil {
-			fmt.Printf("  Expires:          %s\n", expTime.Local().Format(time.RFC1123))
+			fmt.Fprintf(w, "  Expires:          %s\n", expTime.Local().Format(time.RFC1123))
 		} else {
-			fmt.Printf("  Expires:          %s (raw value)\n", link.ExpirationDateTime)
+			fmt.Fprintf(w, "  Expires:          %s (raw value)\n", link.ExpirationDateTime)
 		}
 	}
 
 	if link.Link.WebHtml != "" { // For 'embed' type links.
-		fmt.Printf("  Embed HTML:       %s\n", link.Link.WebHtml)
+		fmt.Fprintf(w, "  Embed HTML:       %s\n", link.Link.WebHtml)
 	}
 
 	if link.Link.Application != nil && link.Link.Application.DisplayName != "" {
-		fmt.Printf("  Creating App:     %s (ID: %s)\n", link.Link.Application.DisplayName, link.Link.Application.Id)
+		fmt.Fprintf(w, "  Creating App:     %s (ID: %s)\n", link.Link.Application.DisplayName, link.Link.Application.Id)
 	}
 }
 
 // DisplayDeltaItems displays items from a delta response, indicating what has changed.
-func DisplayDeltaItems(delta onedrive.DeltaResponse) {
+func DisplayDeltaItems(delta onedrive.DeltaResponse) error {
+	return render(Result{Data: delta, Columns: deltaColumns, Table: func(w io.Writer) { printDeltaItems(w, delta) }})
+}
+
+// printDeltaItems writes the table of DisplayDeltaItems.
+func printDeltaItems(w io.Writer, delta onedrive.DeltaResponse) {
 	if len(delta.Value) == 0 {
-		fmt.Println("No changes found since last sync.")
+		fmt.Fprintln(w, "No changes found since last sync.")
 		return
 	}
 
-	fmt.Printf("Delta changes (%d item(s) found)\n\n", len(delta.Value))
-	fmt.Printf("%-60.60s %12s %-10s %-20s %s\n", "Name", "Size", "Type", "Last Modified", "Status")
-	fmt.Println(strings.Repeat("-", onedrive.ExtraLongSeparatorLength))
+	fmt.Fprintf(w, "Delta changes (%d item(s) found)\n\n", len(delta.Value))
+	fmt.Fprintf(w, "%-60.60s %12s %-10s %-20s %s\n", "Name", "Size", "Type", "Last Modified", "Status")
+	fmt.Fprintln(w, strings.Repeat("-", onedrive.ExtraLongSeparatorLength))
 	for i := range delta.Value {
 		item := &delta.Value[i] // Use pointer to avoid copying large struct
 		itemType := "File"
@@ -390,46 +458,129 @@ func DisplayDeltaItems(delta onedrive.DeltaResponse) {
 
 		lastModified := item.LastModifiedDateTime.Local().Format(onedrive.StandardTimeFormat)
 
-		fmt.Printf("%-60.60s %12s %-10s %-20s %s\n", name, formatBytes(item.Size), itemType, lastModified, status)
+		fmt.Fprintf(w, "%-60.60s %12s %-10s %-20s %s\n", name, FormatBytes(item.Size), itemType, lastModified, status)
 	}
 
 	if delta.DeltaLink != "" {
-		fmt.Printf("\nDelta link for next sync: %s\n", delta.DeltaLink)
+		fmt.Fprintf(w, "\nDelta link for next sync: %s\n", delta.DeltaLink)
 	}
 	if delta.NextLink != "" {
-		fmt.Printf("Next page available: %s\n", delta.NextLink)
+		fmt.Fprintf(w, "Next page available: %s\n", delta.NextLink)
+	}
+}
+
+// DisplayDeltaChange prints a single delta change on one line, as 'drives delta --watch'
+// streams changes while they arrive. The line holds the time the change was seen, its
+// status, type, size, item ID and, when the API reports it, the parent path. In
+// structured output every change is rendered on its own, as one record of a stream.
+func DisplayDeltaChange(item onedrive.DriveItem) error {
+	return render(Result{Data: item, Columns: deltaColumns, Table: func(w io.Writer) { printDeltaChange(w, item) }})
+}
+
+// printDeltaChange writes the line of DisplayDeltaChange.
+func printDeltaChange(w io.Writer, item onedrive.DriveItem) {
+	itemType := "File"
+	if item.Folder != nil {
+		itemType = "Folder"
+	}
+	status := "Modified"
+	if item.Deleted != nil {
+		status = "Deleted"
+	}
+
+	name := item.Name
+	if item.ParentReference.Path != "" {
+		name = item.ParentReference.Path + "/" + item.Name
+	}
+	fmt.Fprintf(w, "%s  %-8s %-6s %10s  %s  %s\n", time.Now().Format(time.DateTime),
+		status, itemType, FormatBytes(item.Size), item.ID, name)
+}
+
+// DisplayCopyStatus prints the status of an asynchronous copy operation,
+// as reported by the monitor URL `monitorURL`.
+func DisplayCopyStatus(status onedrive.CopyOperationStatus, monitorURL string) error {
+	return render(Result{Data: status, Columns: copyColumns, Table: func(w io.Writer) { printCopyStatus(w, status, monitorURL) }})
+}
+
+// printCopyStatus writes the details of DisplayCopyStatus.
+func printCopyStatus(w io.Writer, status onedrive.CopyOperationStatus, monitorURL string) {
+	fmt.Fprintf(w, "Copy Operation Status (from %s):\n", monitorURL)
+	fmt.Fprintf(w, "  Status:             %s\n", status.Status)
+	fmt.Fprintf(w, "  Description:        %s\n", status.StatusDescription)
+	if status.PercentageComplete > 0 || status.Status == "inProgress" { // Show progress if available or in progress
+		fmt.Fprintf(w, "  Progress:           %d%%\n", status.PercentageComplete)
+	}
+	if status.ResourceID != "" {
+		fmt.Fprintf(w, "  New Item ID:        %s\n", status.ResourceID)
+	} else if status.ResourceLocation != "" {
+		fmt.Fprintf(w, "  New Item Location:  %s (Note: This field is deprecated by Graph API)\n", status.ResourceLocation)
+	}
+	if status.Error != nil {
+		fmt.Fprintf(w, "  Error Code:         %s\n", status.Error.Code)
+		fmt.Fprintf(w, "  Error Message:      %s\n", status.Error.Message)
+	}
+}
+
+// DisplayUploadSession prints the status of a resumable upload session.
+func DisplayUploadSession(status onedrive.UploadSession) error {
+	return render(Result{Data: status, Columns: uploadColumns, Table: func(w io.Writer) { printUploadSession(w, status) }})
+}
+
+// printUploadSession writes the details of DisplayUploadSession.
+func printUploadSession(w io.Writer, status onedrive.UploadSession) {
+	fmt.Fprintln(w, "Upload Session Status:")
+	fmt.Fprintf(w, "  Upload URL:          %s\n", status.UploadURL)
+	fmt.Fprintf(w, "  Expiration DateTime: %s\n", status.ExpirationDateTime)
+	if len(status.NextExpectedRanges) > 0 {
+		fmt.Fprintln(w, "  Next Expected Ranges (start-end, inclusive):")
+		for _, r := range status.NextExpectedRanges {
+			fmt.Fprintf(w, "    %s\n", r)
+		}
+	} else {
+		// If NextExpectedRanges is empty, it usually means the upload is complete or the session is invalid/expired.
+		fmt.Fprintln(w, "  Status: Upload appears to be complete, or session is no longer active for new ranges.")
 	}
 }
 
 // DisplayDrive prints detailed information about a specific OneDrive Drive resource.
-func DisplayDrive(drive onedrive.Drive) {
-	fmt.Println("Drive Information:")
-	fmt.Printf("  Name:        %s\n", drive.Name)
-	fmt.Printf("  ID:          %s\n", drive.ID)
-	fmt.Printf("  Drive Type:  %s\n", drive.DriveType)
+func DisplayDrive(drive onedrive.Drive) error {
+	return render(Result{Data: drive, Columns: driveColumns, Table: func(w io.Writer) { printDrive(w, drive) }})
+}
+
+// printDrive writes the details of DisplayDrive.
+func printDrive(w io.Writer, drive onedrive.Drive) {
+	fmt.Fprintln(w, "Drive Information:")
+	fmt.Fprintf(w, "  Name:        %s\n", drive.Name)
+	fmt.Fprintf(w, "  ID:          %s\n", drive.ID)
+	fmt.Fprintf(w, "  Drive Type:  %s\n", drive.DriveType)
 
 	if drive.Owner.User != nil && drive.Owner.User.DisplayName != "" {
-		fmt.Printf("  Owner:       %s (ID: %s)\n", drive.Owner.User.DisplayName, drive.Owner.User.ID)
+		fmt.Fprintf(w, "  Owner:       %s (ID: %s)\n", drive.Owner.User.DisplayName, drive.Owner.User.ID)
 	}
 
-	fmt.Println("  Storage Quota:")
-	DisplayQuota(drive) // Re-use DisplayQuota for consistent formatting.
+	fmt.Fprintln(w, "  Storage Quota:")
+	printQuota(w, drive) // Re-use printQuota for consistent formatting.
 }
 
 // DisplayFileVersions displays file version history with formatting.
-func DisplayFileVersions(versions onedrive.DriveItemVersionList, filePath string) {
+func DisplayFileVersions(versions onedrive.DriveItemVersionList, filePath string) error {
+	return render(Result{Data: versions, Columns: versionColumns, Table: func(w io.Writer) { printFileVersions(w, versions, filePath) }})
+}
+
+// printFileVersions writes the table of DisplayFileVersions.
+func printFileVersions(w io.Writer, versions onedrive.DriveItemVersionList, filePath string) {
 	if len(versions.Value) == 0 {
-		fmt.Printf("No versions found for file: %s\n", filePath)
+		fmt.Fprintf(w, "No versions found for file: %s\n", filePath)
 		return
 	}
 
-	fmt.Printf("Version history for file: %s (%d version(s) found)\n\n", filePath, len(versions.Value))
-	fmt.Printf("%-10s %-40.40s %12s %s\n", "Index", "Version ID", "Size", "Last Modified")
-	fmt.Println(strings.Repeat("-", onedrive.LongSeparatorLength)) // Use constant
+	fmt.Fprintf(w, "Version history for file: %s (%d version(s) found)\n\n", filePath, len(versions.Value))
+	fmt.Fprintf(w, "%-10s %-40.40s %12s %s\n", "Index", "Version ID", "Size", "Last Modified")
+	fmt.Fprintln(w, strings.Repeat("-", onedrive.LongSeparatorLength)) // Use constant
 	for i, version := range versions.Value {
 		size := "N/A"
 		if version.Size > 0 {
-			size = formatBytes(version.Size)
+			size = FormatBytes(version.Size)
 		}
 		lastModified := version.LastModifiedDateTime.Local().Format(onedrive.FullTimeFormat) // Use constant
 
@@ -438,20 +589,26 @@ func DisplayFileVersions(versions onedrive.DriveItemVersionList, filePath string
 			versionID = versionID[:onedrive.MaxVersionIDLength] + onedrive.EllipsisMarker
 		}
 
-		fmt.Printf("%-10d %-40.40s %12s %s\n", i+1, versionID, size, lastModified)
+		fmt.Fprintf(w, "%-10d %-40.40s %12s %s\n", i+1, versionID, size, lastModified)
 	}
 }
 
 // DisplayActivities displays a list of activities with timestamps and actors.
-func DisplayActivities(activities onedrive.ActivityList) {
+// In structured output, the link to the next page is the list's "@odata.nextLink" field.
+func DisplayActivities(activities onedrive.ActivityList) error {
+	return render(Result{Data: activities, Columns: activityColumns, Table: func(w io.Writer) { printActivities(w, activities) }})
+}
+
+// printActivities writes the table of DisplayActivities.
+func printActivities(w io.Writer, activities onedrive.ActivityList) {
 	if len(activities.Value) == 0 {
-		fmt.Println("No activities found.")
+		fmt.Fprintln(w, "No activities found.")
 		return
 	}
 
-	fmt.Printf("Activities (%d found)\n\n", len(activities.Value))
-	fmt.Printf("%-20s %-18s %-30s %s\n", "Time", "Actor", "Action", "Item Name")
-	fmt.Println(strings.Repeat("-", onedrive.MediumSeparatorLength))
+	fmt.Fprintf(w, "Activities (%d found)\n\n", len(activities.Value))
+	fmt.Fprintf(w, "%-20s %-18s %-30s %s\n", "Time", "Actor", "Action", "Item Name")
+	fmt.Fprintln(w, strings.Repeat("-", onedrive.MediumSeparatorLength))
 	for i := range activities.Value {
 		activity := &activities.Value[i] // Use pointer to avoid copying large struct
 		actorName := "Unknown"
@@ -497,226 +654,248 @@ func DisplayActivities(activities onedrive.ActivityList) {
 
 		timeFormatted := activity.Times.RecordedTime.Local().Format(onedrive.StandardTimeFormat)
 
-		fmt.Printf("%-20s %-18s %-30s %s\n", timeFormatted, actorName, actionType, itemName)
+		fmt.Fprintf(w, "%-20s %-18s %-30s %s\n", timeFormatted, actorName, actionType, itemName)
 	}
 }
 
 // DisplayThumbnails displays thumbnail information for a file.
-func DisplayThumbnails(thumbnails onedrive.ThumbnailSetList, remotePath string) {
+func DisplayThumbnails(thumbnails onedrive.ThumbnailSetList, remotePath string) error {
+	return render(Result{Data: thumbnails, Columns: thumbnailColumns, Table: func(w io.Writer) { printThumbnails(w, thumbnails, remotePath) }})
+}
+
+// printThumbnails writes the details of DisplayThumbnails.
+func printThumbnails(w io.Writer, thumbnails onedrive.ThumbnailSetList, remotePath string) {
 	if len(thumbnails.Value) == 0 {
-		fmt.Printf("No thumbnails found for: %s\n", remotePath)
+		fmt.Fprintf(w, "No thumbnails found for: %s\n", remotePath)
 		return
 	}
 
-	fmt.Printf("Thumbnails for: %s (%d thumbnail set(s) found)\n\n", remotePath, len(thumbnails.Value))
+	fmt.Fprintf(w, "Thumbnails for: %s (%d thumbnail set(s) found)\n\n", remotePath, len(thumbnails.Value))
 	for i, thumbSet := range thumbnails.Value {
-		fmt.Printf("Thumbnail Set %d (ID: %s):\n", i+1, thumbSet.ID)
+		fmt.Fprintf(w, "Thumbnail Set %d (ID: %s):\n", i+1, thumbSet.ID)
 		if thumbSet.Small != nil {
-			fmt.Printf("  Small:  %4dx%-4d URL: %s\n", thumbSet.Small.Width, thumbSet.Small.Height, thumbSet.Small.URL)
+			fmt.Fprintf(w, "  Small:  %4dx%-4d URL: %s\n", thumbSet.Small.Width, thumbSet.Small.Height, thumbSet.Small.URL)
 		}
 		if thumbSet.Medium != nil {
-			fmt.Printf("  Medium: %4dx%-4d URL: %s\n", thumbSet.Medium.Width, thumbSet.Medium.Height, thumbSet.Medium.URL)
+			fmt.Fprintf(w, "  Medium: %4dx%-4d URL: %s\n", thumbSet.Medium.Width, thumbSet.Medium.Height, thumbSet.Medium.URL)
 		}
 		if thumbSet.Large != nil {
-			fmt.Printf("  Large:  %4dx%-4d URL: %s\n", thumbSet.Large.Width, thumbSet.Large.Height, thumbSet.Large.URL)
+			fmt.Fprintf(w, "  Large:  %4dx%-4d URL: %s\n", thumbSet.Large.Width, thumbSet.Large.Height, thumbSet.Large.URL)
 		}
 		if thumbSet.Source != nil { // Source usually refers to the original image dimensions for some APIs
-			fmt.Printf("  Source: %4dx%-4d URL: %s\n", thumbSet.Source.Width, thumbSet.Source.Height, thumbSet.Source.URL)
+			fmt.Fprintf(w, "  Source: %4dx%-4d URL: %s\n", thumbSet.Source.Width, thumbSet.Source.Height, thumbSet.Source.URL)
 		}
-		fmt.Println() // Separator between thumbnail sets if multiple exist (rare for one item).
+		fmt.Fprintln(w) // Separator between thumbnail sets if multiple exist (rare for one item).
 	}
 }
 
 // DisplayPreview displays preview information (embed URLs) for a file.
-func DisplayPreview(preview onedrive.PreviewResponse, remotePath string) {
-	fmt.Printf("Preview information for: %s\n", remotePath)
+func DisplayPreview(preview onedrive.PreviewResponse, remotePath string) error {
+	return render(Result{Data: preview, Columns: previewColumns, Table: func(w io.Writer) { printPreview(w, preview, remotePath) }})
+}
+
+// printPreview writes the details of DisplayPreview.
+func printPreview(w io.Writer, preview onedrive.PreviewResponse, remotePath string) {
+	fmt.Fprintf(w, "Preview information for: %s\n", remotePath)
 
 	if preview.GetURL != "" {
-		fmt.Printf("  GET URL (for direct content):        %s\n", preview.GetURL)
+		fmt.Fprintf(w, "  GET URL (for direct content):        %s\n", preview.GetURL)
 	}
 	if preview.PostURL != "" {
-		fmt.Printf("  POST URL (for embedding with params): %s\n", preview.PostURL)
+		fmt.Fprintf(w, "  POST URL (for embedding with params): %s\n", preview.PostURL)
 	}
 	if preview.PostParameters != "" {
-		fmt.Printf("  POST Parameters (form-encoded):     %s\n", preview.PostParameters)
+		fmt.Fprintf(w, "  POST Parameters (form-encoded):     %s\n", preview.PostParameters)
 	}
 
 	if preview.GetURL == "" && preview.PostURL == "" {
-		fmt.Println("  No specific preview URLs available for this file type or item.")
-		fmt.Println("  The item might be viewable directly via its WebURL if it's a common format.")
+		fmt.Fprintln(w, "  No specific preview URLs available for this file type or item.")
+		fmt.Fprintln(w, "  The item might be viewable directly via its WebURL if it's a common format.")
 	}
 }
 
 // DisplayInviteResponse displays the result of inviting users to access an item.
-func DisplayInviteResponse(response onedrive.InviteResponse, remotePath string) {
-	fmt.Printf("Invitation results for: %s\n", remotePath)
+func DisplayInviteResponse(response onedrive.InviteResponse, remotePath string) error {
+	return render(Result{Data: response, Columns: permissionColumns, Table: func(w io.Writer) { printInviteResponse(w, response, remotePath) }})
+}
+
+// printInviteResponse writes the details of DisplayInviteResponse.
+func printInviteResponse(w io.Writer, response onedrive.InviteResponse, remotePath string) {
+	fmt.Fprintf(w, "Invitation results for: %s\n", remotePath)
 
 	if len(response.Value) == 0 {
 		// This might mean the invitation was sent but no new permissions were immediately created,
 		// or the API doesn't return permissions in this specific call for some reason.
-		fmt.Println("Invitation processed. No new explicit permissions returned in this response (check item permissions separately if needed).")
+		fmt.Fprintln(w, "Invitation processed. No new explicit permissions returned in this response (check item permissions separately if needed).")
 		return
 	}
 
-	fmt.Printf("Successfully created/updated %d permission(s) through invitation:\n\n", len(response.Value))
+	fmt.Fprintf(w, "Successfully created/updated %d permission(s) through invitation:\n\n", len(response.Value))
 	for i, permission := range response.Value {
-		fmt.Printf("Permission %d:\n", i+1)
-		displayPermissionDetails(permission) // Use the helper for detailed output.
-		fmt.Println()
+		fmt.Fprintf(w, "Permission %d:\n", i+1)
+		displayPermissionDetails(w, permission) // Use the helper for detailed output.
+		fmt.Fprintln(w)
 	}
 }
 
 // DisplayPermissions displays a list of permissions for a DriveItem with details.
-func DisplayPermissions(permissions onedrive.PermissionList) {
+func DisplayPermissions(permissions onedrive.PermissionList) error {
+	return render(Result{Data: permissions, Columns: permissionColumns, Table: func(w io.Writer) { printPermissions(w, permissions) }})
+}
+
+// printPermissions writes the details of DisplayPermissions.
+func printPermissions(w io.Writer, permissions onedrive.PermissionList) {
 	if len(permissions.Value) == 0 {
-		fmt.Println("No permissions found for this item.")
+		fmt.Fprintln(w, "No permissions found for this item.")
 		return
 	}
 
-	fmt.Printf("Permissions for this item (%d found)\n\n", len(permissions.Value))
+	fmt.Fprintf(w, "Permissions for this item (%d found)\n\n", len(permissions.Value))
 	for i := range permissions.Value {
 		permission := &permissions.Value[i] // Use pointer to avoid copying large struct
-		displayPermissionDetails(*permission)
-		fmt.Println(strings.Repeat("-", onedrive.StandardSeparatorLength))
+		displayPermissionDetails(w, *permission)
+		fmt.Fprintln(w, strings.Repeat("-", onedrive.StandardSeparatorLength))
 	}
 }
 
 // DisplaySinglePermission displays detailed information about a single permission.
-func DisplaySinglePermission(permission onedrive.Permission, remotePath, permissionID string) {
-	fmt.Printf("Detailed permission information for item: %s\n", remotePath)
-	fmt.Printf("Permission ID: %s\n\n", permissionID) // Use the passed permissionID for consistency
-	displayPermissionDetails(permission)
+func DisplaySinglePermission(permission onedrive.Permission, remotePath, permissionID string) error {
+	return render(Result{Data: permission, Columns: permissionColumns, Table: func(w io.Writer) {
+		fmt.Fprintf(w, "Detailed permission information for item: %s\n", remotePath)
+		fmt.Fprintf(w, "Permission ID: %s\n\n", permissionID) // Use the passed permissionID for consistency
+		displayPermissionDetails(w, permission)
+	}})
 }
 
 // displayPermissionDetails is an unexported helper function to print the details of a single Permission object.
 // This promotes consistency in how permission details are displayed.
-func displayPermissionDetails(permission onedrive.Permission) {
-	displayBasicPermissionInfo(permission)
-	displayPermissionLink(permission)
-	displayGrantedToInfo(permission)
-	displayInheritedFromInfo(permission)
-	displayInvitationInfo(permission)
+func displayPermissionDetails(w io.Writer, permission onedrive.Permission) {
+	displayBasicPermissionInfo(w, permission)
+	displayPermissionLink(w, permission)
+	displayGrantedToInfo(w, permission)
+	displayInheritedFromInfo(w, permission)
+	displayInvitationInfo(w, permission)
 }
 
 // displayBasicPermissionInfo displays basic permission information
-func displayBasicPermissionInfo(permission onedrive.Permission) {
-	fmt.Printf("  ID:                 %s\n", permission.ID)
-	fmt.Printf("  Roles:              %s\n", strings.Join(permission.Roles, ", "))
+func displayBasicPermissionInfo(w io.Writer, permission onedrive.Permission) {
+	fmt.Fprintf(w, "  ID:                 %s\n", permission.ID)
+	fmt.Fprintf(w, "  Roles:              %s\n", strings.Join(permission.Roles, ", "))
 
 	if permission.ShareID != "" {
-		fmt.Printf("  Share ID:           %s\n", permission.ShareID)
+		fmt.Fprintf(w, "  Share ID:           %s\n", permission.ShareID)
 	}
 
-	displayExpirationTime(permission.ExpirationDateTime)
+	displayExpirationTime(w, permission.ExpirationDateTime)
 
 	if permission.HasPassword {
-		fmt.Printf("  Password Protected: Yes\n")
+		fmt.Fprintf(w, "  Password Protected: Yes\n")
 	}
 }
 
 // displayExpirationTime formats and displays permission expiration time
-func displayExpirationTime(expirationDateTime string) {
+func displayExpirationTime(w io.Writer, expirationDateTime string) {
 	if expirationDateTime == "" {
 		return
 	}
 
 	expTime, err := time.Parse(time.RFC3339Nano, expirationDateTime)
 	if err == nil {
-		fmt.Printf("  Expires:            %s\n", expTime.Local().Format(time.RFC1123))
+		fmt.Fprintf(w, "  Expires:            %s\n", expTime.Local().Format(time.RFC1123))
 	} else {
-		fmt.Printf("  Expires (raw):      %s\n", expirationDateTime)
+		fmt.Fprintf(w, "  Expires (raw):      %s\n", expirationDateTime)
 	}
 }
 
 // displayPermissionLink displays sharing link information if present
-func displayPermissionLink(permission onedrive.Permission) {
+func displayPermissionLink(w io.Writer, permission onedrive.Permission) {
 	if permission.Link == nil {
 		return
 	}
 
-	fmt.Printf("  Link Details:\n")
-	fmt.Printf("    Type:             %s\n", permission.Link.Type)
-	fmt.Printf("    Scope:            %s\n", permission.Link.Scope)
-	fmt.Printf("    Web URL:          %s\n", permission.Link.WebURL)
+	fmt.Fprintf(w, "  Link Details:\n")
+	fmt.Fprintf(w, "    Type:             %s\n", permission.Link.Type)
+	fmt.Fprintf(w, "    Scope:            %s\n", permission.Link.Scope)
+	fmt.Fprintf(w, "    Web URL:          %s\n", permission.Link.WebURL)
 
 	if permission.Link.WebHTML != "" {
-		fmt.Printf("    Embed HTML:       %s\n", permission.Link.WebHTML)
+		fmt.Fprintf(w, "    Embed HTML:       %s\n", permission.Link.WebHTML)
 	}
 	if permission.Link.PreventsDownload {
-		fmt.Printf("    Prevents Download:Yes\n")
+		fmt.Fprintf(w, "    Prevents Download:Yes\n")
 	}
 	if permission.Link.Application != nil && permission.Link.Application.DisplayName != "" {
-		fmt.Printf("    Creating App:     %s (ID: %s)\n", permission.Link.Application.DisplayName, permission.Link.Application.ID)
+		fmt.Fprintf(w, "    Creating App:     %s (ID: %s)\n", permission.Link.Application.DisplayName, permission.Link.Application.ID)
 	}
 }
 
 // displayGrantedToInfo displays information about who the permission is granted to
-func displayGrantedToInfo(permission onedrive.Permission) {
+func displayGrantedToInfo(w io.Writer, permission onedrive.Permission) {
 	if len(permission.GrantedToIdentitiesV2) > 0 {
-		displayGrantedToIdentities(permission.GrantedToIdentitiesV2)
+		displayGrantedToIdentities(w, permission.GrantedToIdentitiesV2)
 	} else if permission.GrantedToV2 != nil {
-		displayGrantedToV2(permission.GrantedToV2)
+		displayGrantedToV2(w, permission.GrantedToV2)
 	}
 }
 
 // displayGrantedToIdentities displays multiple granted identities
-func displayGrantedToIdentities(identities []struct {
+func displayGrantedToIdentities(w io.Writer, identities []struct {
 	User     *onedrive.Identity `json:"user,omitempty"`
 	SiteUser *onedrive.Identity `json:"siteUser,omitempty"`
 }) {
-	fmt.Printf("  Granted To Identities (%d):\n", len(identities))
+	fmt.Fprintf(w, "  Granted To Identities (%d):\n", len(identities))
 	for i, identity := range identities {
-		fmt.Printf("    %d. ", i+1)
-		displayIdentityInfo(identity.User, identity.SiteUser)
+		fmt.Fprintf(w, "    %d. ", i+1)
+		displayIdentityInfo(w, identity.User, identity.SiteUser)
 	}
 }
 
 // displayGrantedToV2 displays single granted identity (V2 format)
-func displayGrantedToV2(grantedTo *struct {
+func displayGrantedToV2(w io.Writer, grantedTo *struct {
 	User     *onedrive.Identity `json:"user,omitempty"`
 	SiteUser *onedrive.Identity `json:"siteUser,omitempty"`
 }) {
-	fmt.Printf("  Granted To (V2):\n")
-	displayIdentityInfo(grantedTo.User, grantedTo.SiteUser)
+	fmt.Fprintf(w, "  Granted To (V2):\n")
+	displayIdentityInfo(w, grantedTo.User, grantedTo.SiteUser)
 }
 
 // displayIdentityInfo displays user or site user identity information
-func displayIdentityInfo(user, siteUser *onedrive.Identity) {
+func displayIdentityInfo(w io.Writer, user, siteUser *onedrive.Identity) {
 	if user != nil {
-		fmt.Printf("User: %s", user.DisplayName)
-		fmt.Printf(" [ID: %s]\n", user.ID)
+		fmt.Fprintf(w, "User: %s", user.DisplayName)
+		fmt.Fprintf(w, " [ID: %s]\n", user.ID)
 	} else if siteUser != nil {
-		fmt.Printf("Site User: %s", siteUser.DisplayName)
-		fmt.Printf(" [ID: %s]\n", siteUser.ID)
+		fmt.Fprintf(w, "Site User: %s", siteUser.DisplayName)
+		fmt.Fprintf(w, " [ID: %s]\n", siteUser.ID)
 	} else {
-		fmt.Println("Unknown identity type")
+		fmt.Fprintln(w, "Unknown identity type")
 	}
 }
 
 // displayInheritedFromInfo displays inheritance information if present
-func displayInheritedFromInfo(permission onedrive.Permission) {
+func displayInheritedFromInfo(w io.Writer, permission onedrive.Permission) {
 	if permission.InheritedFrom == nil {
 		return
 	}
 
-	fmt.Printf("  Inherited From (Item ID: %s):\n", permission.InheritedFrom.ID)
+	fmt.Fprintf(w, "  Inherited From (Item ID: %s):\n", permission.InheritedFrom.ID)
 	if permission.InheritedFrom.DriveID != "" {
-		fmt.Printf("    Drive ID: %s\n", permission.InheritedFrom.DriveID)
+		fmt.Fprintf(w, "    Drive ID: %s\n", permission.InheritedFrom.DriveID)
 	}
 	if permission.InheritedFrom.Path != "" {
-		fmt.Printf("    Path:     %s\n", permission.InheritedFrom.Path)
+		fmt.Fprintf(w, "    Path:     %s\n", permission.InheritedFrom.Path)
 	}
 }
 
 // displayInvitationInfo displays invitation details if present
-func displayInvitationInfo(permission onedrive.Permission) {
+func displayInvitationInfo(w io.Writer, permission onedrive.Permission) {
 	if permission.Invitation == nil {
 		return
 	}
 
-	fmt.Printf("  Invitation Details:\n")
+	fmt.Fprintf(w, "  Invitation Details:\n")
 	if permission.Invitation.Email != "" {
-		fmt.Printf("    Invited Email: %s\n", permission.Invitation.Email)
+		fmt.Fprintf(w, "    Invited Email: %s\n", permission.Invitation.Email)
 	}
-	fmt.Printf("    Sign-in Required: %t\n", permission.Invitation.SignInRequired)
+	fmt.Fprintf(w, "    Sign-in Required: %t\n", permission.Invitation.SignInRequired)
 }
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"io"
 	"testing"
 	"time"
 
@@ -36,7 +37,7 @@ func TestDisplayBasicPermissionInfo(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			assert.NotPanics(t, func() {
-				displayBasicPermissionInfo(tt.permission)
+				displayBasicPermissionInfo(io.Discard, tt.permission)
 			})
 		})
 	}
@@ -64,7 +65,7 @@ func TestDisplayExpirationTime(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			assert.NotPanics(t, func() {
-				displayExpirationTime(tt.expirationTime)
+				displayExpirationTime(io.Discard, tt.expirationTime)
 			})
 		})
 	}
@@ -109,7 +110,7 @@ func TestDisplayPermissionLink(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			assert.NotPanics(t, func() {
-				displayPermissionLink(tt.permission)
+				displayPermissionLink(io.Discard, tt.permission)
 			})
 		})
 	}
@@ -148,7 +149,7 @@ func TestDisplayPermissionDetailsRefactored(t *testing.T) {
 
 	// Test that the function doesn't panic with a complete permission object
 	assert.NotPanics(t, func() {
-		displayPermissionDetails(permission)
+		displayPermissionDetails(io.Discard, permission)
 	})
 }
 
@@ -161,7 +162,7 @@ func TestDisplayPermissionDetailsEmptyFields(t *testing.T) {
 
 	// Test that the function doesn't panic with minimal data
 	assert.NotPanics(t, func() {
-		displayPermissionDetails(permission)
+		displayPermissionDetails(io.Discard, permission)
 	})
 }
 
@@ -329,7 +330,7 @@ func TestPermissionDisplayComponents(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Test that each permission type can be displayed without panicking
 			assert.NotPanics(t, func() {
-				displayPermissionDetails(tt.permission)
+				displayPermissionDetails(io.Discard, tt.permission)
 			})
 		})
 	}
@@ -139,7 +139,7 @@ func TestDisplayDrives(t *testing.T) {
 }
 
 func TestFormatBytes(t *testing.T) {
-	// Test the formatBytes helper function
+	// Test the FormatBytes helper function
 	tests := []struct {
 		input    int64
 		expected string
@@ -154,7 +154,34 @@ func TestFormatBytes(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		result := formatBytes(test.input)
-		assert.Equal(t, test.expected, result, "formatBytes(%d) should return %s, got %s", test.input, test.expected, result)
+		result := FormatBytes(test.input)
+		assert.Equal(t, test.expected, result, "FormatBytes(%d) should return %s, got %s", test.input, test.expected, result)
 	}
 }
+
+func TestDisplayDeltaChange(t *testing.T) {
+	item := onedrive.DriveItem{ID: "item-1", Name: "report.docx", Size: 2048}
+	item.ParentReference.Path = "/drive/root:/Documents"
+	deleted := onedrive.DriveItem{ID: "item-2", Name: "old", Folder: &onedrive.FolderFacet{}, Deleted: &onedrive.DeletedFacet{}}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	DisplayDeltaChange(item)
+	DisplayDeltaChange(deleted)
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	buf := make([]byte, 1024)
+	n, _ := r.Read(buf)
+	lines := strings.Split(strings.TrimSpace(string(buf[:n])), "\n")
+
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "Modified")
+	assert.Contains(t, lines[0], "item-1")
+	assert.Contains(t, lines[0], "/drive/root:/Documents/report.docx")
+	assert.Contains(t, lines[1], "Deleted")
+	assert.Contains(t, lines[1], "Folder")
+}
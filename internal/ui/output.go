@@ -0,0 +1,381 @@
+// Package ui (output.go) renders command results in the format selected with
+// the global --output flag: the human-readable tables of display.go (the
+// default), JSON or YAML of the raw SDK models, CSV with selectable columns,
+// or a Go text/template executed against the SDK models. Every Display
+// function hands its result to the current Renderer instead of printing it
+// directly.
+package ui
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FlagOutput is the name of the global flag selecting the output format.
+	FlagOutput = "output"
+	// FlagColumns is the name of the global flag selecting the CSV columns.
+	FlagColumns = "columns"
+	// FlagTemplate is the name of the global flag holding the Go template.
+	FlagTemplate = "template"
+)
+
+// Output formats accepted by --output.
+const (
+	FormatTable    = "table"    // Human-readable tables and summaries.
+	FormatJSON     = "json"     // Indented JSON of the SDK models.
+	FormatYAML     = "yaml"     // YAML of the SDK models, with the JSON field names.
+	FormatCSV      = "csv"      // One CSV row per record, with the columns of --columns.
+	FormatTemplate = "template" // The Go text/template of --template, executed against the SDK models.
+)
+
+// Formats returns the output formats accepted by --output.
+func Formats() []string {
+	return []string{FormatTable, FormatJSON, FormatYAML, FormatCSV, FormatTemplate}
+}
+
+// Result is a command result: the SDK model it is built from, and how to
+// present it to people.
+type Result struct {
+	// Data is the SDK model. It is encoded as JSON or YAML, passed to the
+	// template, and split into CSV records: the elements of its "value" list
+	// for list models, or the model itself otherwise.
+	Data any
+	// Columns are the default CSV columns, as JSON field names of a record.
+	// Nested fields are separated by dots, as in "file.mimeType".
+	Columns []string
+	// Table writes the human-readable form of the result.
+	Table func(w io.Writer)
+}
+
+// Renderer writes command results in one output format.
+type Renderer interface {
+	Render(w io.Writer, result Result) error
+}
+
+// renderer is the Renderer of the running command, set by SetRenderer.
+var renderer Renderer = tableRenderer{}
+
+// SetRenderer makes the Display functions render results with `r`.
+func SetRenderer(r Renderer) {
+	renderer = r
+}
+
+// Structured reports whether results are rendered for programs rather than
+// people, that is, in any format but FormatTable.
+func Structured() bool {
+	_, isTable := renderer.(tableRenderer)
+	return !isTable
+}
+
+// Render writes `result` to `w` in the output format of the running command.
+// Commands whose results have no Display function use it directly.
+func Render(w io.Writer, result Result) error {
+	return renderer.Render(w, result)
+}
+
+// render writes `result` to standard output. Standard output is looked up on
+// every call so that it can be redirected.
+func render(result Result) error {
+	return Render(os.Stdout, result)
+}
+
+// NewRenderer returns the Renderer of `format`. `columns` overrides the
+// default columns of FormatCSV, and `tmpl` is the template of FormatTemplate.
+func NewRenderer(format string, columns []string, tmpl string) (Renderer, error) {
+	if len(columns) > 0 && format != FormatCSV {
+		return nil, fmt.Errorf("%w: '--%s' requires '--%s %s'", onedrive.ErrInvalidRequest, FlagColumns, FlagOutput, FormatCSV)
+	}
+	if tmpl != "" && format != FormatTemplate {
+		return nil, fmt.Errorf("%w: '--%s' requires '--%s %s'", onedrive.ErrInvalidRequest, FlagTemplate, FlagOutput, FormatTemplate)
+	}
+
+	switch format {
+	case "", FormatTable:
+		return tableRenderer{}, nil
+	case FormatJSON:
+		return jsonRenderer{}, nil
+	case FormatYAML:
+		return yamlRenderer{}, nil
+	case FormatCSV:
+		return &csvRenderer{columns: columns}, nil
+	case FormatTemplate:
+		if tmpl == "" {
+			return nil, fmt.Errorf("%w: '--%s %s' requires '--%s'", onedrive.ErrInvalidRequest, FlagOutput, FormatTemplate, FlagTemplate)
+		}
+		parsed, err := template.New(FlagTemplate).Funcs(templateFuncs).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parsing output template: %w", onedrive.ErrInvalidRequest, err)
+		}
+		return templateRenderer{tmpl: parsed}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown output format '%s' (valid formats: %s)",
+			onedrive.ErrInvalidRequest, format, strings.Join(Formats(), ", "))
+	}
+}
+
+// AddOutputFlags adds the global output flags to `cmd`, for all its
+// subcommands:
+//
+//	--output <format>:    table (default), json, yaml, csv or template.
+//	--columns <fields>:   Comma-separated JSON fields to print with --output csv.
+//	--template <text>:    Go template to execute with --output template.
+func AddOutputFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringP(FlagOutput, "o", FormatTable, "Output format: "+strings.Join(Formats(), ", "))
+	cmd.PersistentFlags().StringSlice(FlagColumns, nil, "Comma-separated JSON fields to print with '--output csv' (e.g. id,name,size,file.mimeType)")
+	cmd.PersistentFlags().String(FlagTemplate, "", "Go template to execute against the result with '--output template' (e.g. '{{range .Value}}{{.Name}}{{\"\\n\"}}{{end}}')")
+}
+
+// ParseOutputFlags returns the Renderer selected by the flags added with
+// AddOutputFlags.
+func ParseOutputFlags(cmd *cobra.Command) (Renderer, error) {
+	format, err := cmd.Flags().GetString(FlagOutput)
+	if err != nil {
+		return nil, fmt.Errorf("parsing '--%s' flag: %w", FlagOutput, err)
+	}
+	columns, err := cmd.Flags().GetStringSlice(FlagColumns)
+	if err != nil {
+		return nil, fmt.Errorf("parsing '--%s' flag: %w", FlagColumns, err)
+	}
+	tmpl, err := cmd.Flags().GetString(FlagTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing '--%s' flag: %w", FlagTemplate, err)
+	}
+	return NewRenderer(format, columns, tmpl)
+}
+
+// templateFuncs are the functions available to output templates in addition
+// to the text/template builtins.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"bytes": FormatBytes,
+}
+
+// tableRenderer writes the human-readable form of results.
+type tableRenderer struct{}
+
+// Render writes the table of `result`.
+func (tableRenderer) Render(w io.Writer, result Result) error {
+	result.Table(w)
+	return nil
+}
+
+// jsonRenderer writes results as indented JSON.
+type jsonRenderer struct{}
+
+// Render writes the SDK model of `result` as indented JSON.
+func (jsonRenderer) Render(w io.Writer, result Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result.Data); err != nil {
+		return fmt.Errorf("encoding result as JSON: %w", err)
+	}
+	return nil
+}
+
+// yamlRenderer writes results as YAML documents.
+type yamlRenderer struct{}
+
+// Render writes the SDK model of `result` as a YAML document. The model goes
+// through its JSON encoding, so the field names and their order are the same
+// as with FormatJSON.
+func (yamlRenderer) Render(w io.Writer, result Result) error {
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		return fmt.Errorf("encoding result as JSON: %w", err)
+	}
+	// JSON is valid YAML, so decoding it into a node keeps the field order.
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("converting result to YAML: %w", err)
+	}
+	clearYAMLStyle(&node)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return fmt.Errorf("encoding result as YAML: %w", err)
+	}
+	return encoder.Close()
+}
+
+// clearYAMLStyle resets the flow and quoting styles that `node` and its
+// children got from the JSON they were decoded from, so that they are
+// encoded in block style.
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
+
+// csvRenderer writes the records of results as CSV rows. The header row is
+// written once, before the first row, so that streamed results form a
+// single table.
+type csvRenderer struct {
+	columns       []string // Columns selected with --columns; empty for the defaults of each result.
+	headerWritten bool
+}
+
+// Render writes one CSV row per record of `result`.
+func (r *csvRenderer) Render(w io.Writer, result Result) error {
+	records, err := csvRecords(result.Data)
+	if err != nil {
+		return err
+	}
+	columns := r.columns
+	if len(columns) == 0 {
+		columns = result.Columns
+	}
+	if len(columns) == 0 && len(records) > 0 {
+		columns = scalarFields(records[0])
+	}
+
+	writer := csv.NewWriter(w)
+	if !r.headerWritten {
+		if err := writer.Write(columns); err != nil {
+			return fmt.Errorf("writing CSV header: %w", err)
+		}
+		r.headerWritten = true
+	}
+	for _, record := range records {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = csvValue(lookupField(record, column))
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("writing CSV row: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
+	}
+	return nil
+}
+
+// csvRecords returns the records of the SDK model `data` as decoded JSON
+// objects: the elements of the "value" list of a list model, none for a nil
+// model, or the model itself.
+func csvRecords(data any) ([]any, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding result as JSON: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber() // Keep sizes and IDs exact.
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("decoding result: %w", err)
+	}
+
+	switch value := decoded.(type) {
+	case nil:
+		return nil, nil // No result, such as a missing item.
+	case []any:
+		return value, nil
+	case map[string]any:
+		if list, ok := value["value"].([]any); ok {
+			return list, nil
+		}
+	}
+	return []any{decoded}, nil
+}
+
+// scalarFields returns the sorted names of the fields of `record` that hold
+// a single value, the columns used when neither --columns nor the result
+// name any.
+func scalarFields(record any) []string {
+	object, ok := record.(map[string]any)
+	if !ok {
+		return nil
+	}
+	var fields []string
+	for name, value := range object {
+		switch value.(type) {
+		case map[string]any, []any:
+		default:
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// lookupField returns the field of `record` at the dot-separated `path`, or
+// nil if there is none.
+func lookupField(record any, path string) any {
+	value := record
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// csvValue formats a decoded JSON value as a CSV cell. Lists of single values
+// are joined with semicolons; objects and other lists are written as JSON.
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, element := range v {
+			switch element.(type) {
+			case map[string]any, []any:
+				data, _ := json.Marshal(v)
+				return string(data)
+			}
+			parts = append(parts, csvValue(element))
+		}
+		return strings.Join(parts, ";")
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// templateRenderer executes a Go template against results.
+type templateRenderer struct {
+	tmpl *template.Template
+}
+
+// Render executes the template with the SDK model of `result` as its data.
+// Raw JSON, as returned for endpoints without a model, is decoded first so
+// that the template can address its fields by their JSON names.
+func (r templateRenderer) Render(w io.Writer, result Result) error {
+	data := result.Data
+	if raw, ok := data.(json.RawMessage); ok {
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return fmt.Errorf("decoding result for output template: %w", err)
+		}
+	}
+	if err := r.tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("executing output template: %w", err)
+	}
+	return nil
+}
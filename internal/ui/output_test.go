@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// useRenderer makes the Display functions render with the renderer of
+// `format` for the rest of the test.
+func useRenderer(t *testing.T, format string, columns []string, tmpl string) {
+	t.Helper()
+	r, err := NewRenderer(format, columns, tmpl)
+	require.NoError(t, err)
+	SetRenderer(r)
+	t.Cleanup(func() { SetRenderer(tableRenderer{}) })
+}
+
+// captureStdout returns what `f` writes to standard output.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	oldStdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	f()
+	w.Close()
+	os.Stdout = oldStdout
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func testItemList() onedrive.DriveItemList {
+	file := onedrive.DriveItem{ID: "id-1", Name: "report.pdf", Size: 2048,
+		LastModifiedDateTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		File:                 &onedrive.FileFacet{MimeType: "application/pdf"}}
+	folder := onedrive.DriveItem{ID: "id-2", Name: "Photos, 2024", Folder: &onedrive.FolderFacet{ChildCount: 3}}
+	return onedrive.DriveItemList{Value: []onedrive.DriveItem{file, folder}, NextLink: "https://graph.example/next"}
+}
+
+func TestNewRenderer(t *testing.T) {
+	for _, format := range Formats() {
+		tmpl := ""
+		if format == FormatTemplate {
+			tmpl = "{{.Name}}"
+		}
+		_, err := NewRenderer(format, nil, tmpl)
+		assert.NoError(t, err, format)
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		columns []string
+		tmpl    string
+	}{
+		{"unknown format", "xml", nil, ""},
+		{"columns without csv", FormatJSON, []string{"id"}, ""},
+		{"template without template format", FormatYAML, nil, "{{.}}"},
+		{"template format without template", FormatTemplate, nil, ""},
+		{"unparsable template", FormatTemplate, nil, "{{.Name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRenderer(tt.format, tt.columns, tt.tmpl)
+			assert.True(t, errors.Is(err, onedrive.ErrInvalidRequest), "got %v", err)
+		})
+	}
+}
+
+func TestDisplayItemsJSON(t *testing.T) {
+	useRenderer(t, FormatJSON, nil, "")
+	assert.True(t, Structured())
+
+	out := captureStdout(t, func() {
+		require.NoError(t, DisplayItems(testItemList()))
+	})
+
+	var decoded onedrive.DriveItemList
+	require.NoError(t, json.Unmarshal([]byte(out), &decoded), out)
+	assert.Equal(t, testItemList(), decoded)
+	assert.NotContains(t, out, "Items in the specified location")
+}
+
+func TestDisplayItemsYAML(t *testing.T) {
+	useRenderer(t, FormatYAML, nil, "")
+
+	out := captureStdout(t, func() {
+		require.NoError(t, DisplayDriveItem(onedrive.DriveItem{ID: "123", Name: "true", Size: 7}))
+	})
+
+	// Field names and order follow the JSON encoding, and strings that would
+	// read as other types stay strings.
+	assert.Contains(t, out, "createdDateTime: \"0001-01-01T00:00:00Z\"\n")
+	assert.Contains(t, out, "id: \"123\"\n")
+	assert.Contains(t, out, "name: \"true\"\n")
+	assert.Contains(t, out, "size: 7\n")
+	assert.Less(t, strings.Index(out, "id:"), strings.Index(out, "name:"))
+}
+
+func TestDisplayItemsCSV(t *testing.T) {
+	t.Run("default columns", func(t *testing.T) {
+		useRenderer(t, FormatCSV, nil, "")
+		out := captureStdout(t, func() {
+			require.NoError(t, DisplayItems(testItemList()))
+		})
+		assert.Equal(t, "id,name,size,lastModifiedDateTime,file.mimeType,folder.childCount\n"+
+			"id-1,report.pdf,2048,2024-05-01T12:00:00Z,application/pdf,\n"+
+			"id-2,\"Photos, 2024\",0,0001-01-01T00:00:00Z,,3\n", out)
+	})
+
+	t.Run("selected columns", func(t *testing.T) {
+		useRenderer(t, FormatCSV, []string{"name", "missing", "folder"}, "")
+		out := captureStdout(t, func() {
+			require.NoError(t, DisplayItems(testItemList()))
+		})
+		assert.Equal(t, "name,missing,folder\nreport.pdf,,\n\"Photos, 2024\",,\"{\"\"childCount\"\":3}\"\n", out)
+	})
+
+	t.Run("lists and streams", func(t *testing.T) {
+		useRenderer(t, FormatCSV, []string{"id", "roles"}, "")
+		out := captureStdout(t, func() {
+			require.NoError(t, DisplaySinglePermission(onedrive.Permission{ID: "p1", Roles: []string{"read", "write"}}, "/a", "p1"))
+			require.NoError(t, DisplaySinglePermission(onedrive.Permission{ID: "p2", Roles: []string{"owner"}}, "/a", "p2"))
+		})
+		assert.Equal(t, "id,roles\np1,read;write\np2,owner\n", out, "the header is written once")
+	})
+}
+
+func TestDisplayItemsTemplate(t *testing.T) {
+	useRenderer(t, FormatTemplate, nil, `{{range .Value}}{{.Name}} {{bytes .Size}}{{"\n"}}{{end}}next={{.NextLink}}`)
+
+	out := captureStdout(t, func() {
+		require.NoError(t, DisplayItems(testItemList()))
+	})
+	assert.Equal(t, "report.pdf 2.0 KiB\nPhotos, 2024 0 B\nnext=https://graph.example/next", out)
+
+	useRenderer(t, FormatTemplate, nil, "{{.NoSuchField}}")
+	captureStdout(t, func() {
+		assert.Error(t, DisplayItems(testItemList()))
+	})
+}
+
+func TestHandleNextPageInfoStructured(t *testing.T) {
+	out := captureStdout(t, func() { HandleNextPageInfo("https://graph.example/next", false) })
+	assert.Contains(t, out, "--next \"https://graph.example/next\"")
+
+	useRenderer(t, FormatJSON, nil, "")
+	out = captureStdout(t, func() { HandleNextPageInfo("https://graph.example/next", false) })
+	assert.Empty(t, out, "structured output carries the link in the list instead")
+}
@@ -72,8 +72,13 @@ func ParsePagingFlags(cmd *cobra.Command) (onedrive.Paging, error) {
 // `nextLink` is the @odata.nextLink URL from the API response.
 // `fetchAll` indicates if the --all flag was active for the current command.
 //
-// This provides a user-friendly way to continue pagination.
+// This provides a user-friendly way to continue pagination. Structured output
+// carries the link in the list's "@odata.nextLink" field instead, so nothing
+// is printed then.
 func HandleNextPageInfo(nextLink string, fetchAll bool) {
+	if Structured() {
+		return
+	}
 	// Only show next page info if there is a nextLink and the user wasn't trying to fetch all pages.
 	// If fetchAll was true, the SDK's collectAllPages should have already retrieved everything.
 	if nextLink != "" && !fetchAll {
@@ -0,0 +1,200 @@
+// Package upload (pipeline.go) implements the pipelined chunk uploader used by
+// resumable uploads. OneDrive requires the fragments of an upload session to
+// arrive in order, so chunks are read ahead of the network and sent back to
+// back rather than as concurrent requests. The chunk size adapts to the
+// measured throughput, and gaps reported by the service through
+// NextExpectedRanges are recovered by resuming from the first byte it still
+// expects.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// Chunk sizes must be multiples of 320 KiB. The service rejects fragments of
+// 60 MiB or more; the upper bound stays well below that to limit the memory
+// held by chunks that have been read ahead.
+const (
+	uploadChunkUnit       = 320 * 1024
+	minUploadChunkSize    = uploadChunkUnit
+	maxUploadChunkSize    = 100 * uploadChunkUnit // 31.25 MiB
+	initialUploadChunk    = 5 * onedrive.DefaultChunkSize
+	uploadPipelineDepth   = 3                      // Chunks read ahead of the one being sent.
+	targetChunkDuration   = 4 * time.Second        // Chunk size aims for one chunk per interval.
+	maxChunkUploadRetries = 3                      // Consecutive failures before giving up.
+	chunkRetryDelay       = 500 * time.Millisecond // Base delay between retries, multiplied by the attempt.
+)
+
+// errRangeMismatch reports that the service expects a different byte next than
+// the uploader was about to send.
+var errRangeMismatch = errors.New("upload session expects a different range")
+
+// chunkUploader sends a file through a resumable upload session.
+type chunkUploader struct {
+	sdk       app.SDK
+	session   onedrive.UploadSession
+	file      io.ReaderAt
+	total     int64
+	chunkSize atomic.Int64 // Read by the read-ahead goroutine, adapted by the sender.
+
+	// progress, if set, is called with the number of bytes the service has accepted.
+	progress func(completed int64)
+}
+
+// newChunkUploader returns an uploader for a file of `total` bytes read from `file`.
+func newChunkUploader(sdk app.SDK, session onedrive.UploadSession, file io.ReaderAt, total int64) *chunkUploader {
+	u := &chunkUploader{sdk: sdk, session: session, file: file, total: total}
+	u.chunkSize.Store(initialUploadChunk)
+	return u
+}
+
+// pendingChunk is a chunk read ahead of the network.
+type pendingChunk struct {
+	start int64
+	data  []byte
+	err   error
+}
+
+// upload sends the file from `offset` to the end, returning the number of bytes
+// the service has accepted. After a failed chunk it asks the service which range
+// it expects next and resumes from there, up to maxChunkUploadRetries times in a
+// row. The returned offset is where a later resumption should start.
+func (u *chunkUploader) upload(ctx context.Context, offset int64) (int64, error) {
+	failures := 0
+	for offset < u.total {
+		next, err := u.stream(ctx, offset)
+		if next > offset {
+			failures = 0
+		}
+		offset = next
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return offset, ctx.Err()
+		}
+		failures++
+		if failures > maxChunkUploadRetries {
+			return offset, err
+		}
+		if errors.Is(err, errRangeMismatch) {
+			continue // offset already points at the byte the service expects.
+		}
+
+		select {
+		case <-ctx.Done():
+			return offset, ctx.Err()
+		case <-time.After(time.Duration(failures) * chunkRetryDelay):
+		}
+		status, statusErr := u.sdk.GetUploadSessionStatus(ctx, u.session.UploadURL)
+		if statusErr != nil {
+			return offset, fmt.Errorf("%w (and getting upload session status failed: %v)", err, statusErr)
+		}
+		resume, ok := firstExpectedByte(status.NextExpectedRanges)
+		if !ok {
+			return offset, fmt.Errorf("%w (upload session reports no expected ranges)", err)
+		}
+		offset = resume
+	}
+	return offset, nil
+}
+
+// stream reads chunks ahead from `offset` and sends them in order until the end
+// of the file or the first error. It returns the offset up to which the service
+// has accepted data; on errRangeMismatch, the offset the service expects instead.
+func (u *chunkUploader) stream(ctx context.Context, offset int64) (int64, error) {
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks := make(chan pendingChunk, uploadPipelineDepth)
+	go u.readAhead(readCtx, offset, chunks)
+
+	for c := range chunks {
+		if c.err != nil {
+			return offset, c.err
+		}
+		end := c.start + int64(len(c.data)) - 1
+		began := time.Now()
+		result, err := u.sdk.UploadChunk(ctx, u.session.UploadURL, c.start, end, u.total, bytes.NewReader(c.data))
+		if err != nil {
+			return offset, fmt.Errorf("uploading chunk (bytes %d-%d): %w", c.start, end, err)
+		}
+		offset = end + 1
+		u.adapt(int64(len(c.data)), time.Since(began))
+		if result.ExpirationDateTime != "" {
+			u.session.ExpirationDateTime = result.ExpirationDateTime
+		}
+		if u.progress != nil {
+			u.progress(offset)
+		}
+		if offset < u.total {
+			if expected, ok := firstExpectedByte(result.NextExpectedRanges); ok && expected != offset {
+				return expected, errRangeMismatch
+			}
+		}
+	}
+	return offset, nil
+}
+
+// readAhead reads consecutive chunks starting at `offset` into `chunks`, using
+// the current adaptive chunk size for each, and closes the channel when done.
+func (u *chunkUploader) readAhead(ctx context.Context, offset int64, chunks chan<- pendingChunk) {
+	defer close(chunks)
+	for offset < u.total {
+		size := u.chunkSize.Load()
+		if remaining := u.total - offset; size > remaining {
+			size = remaining
+		}
+		c := pendingChunk{start: offset, data: make([]byte, size)}
+		if _, err := u.file.ReadAt(c.data, offset); err != nil && !errors.Is(err, io.EOF) {
+			c.err = fmt.Errorf("reading bytes %d-%d: %w", offset, offset+size-1, err)
+		}
+		select {
+		case chunks <- c:
+		case <-ctx.Done():
+			return
+		}
+		if c.err != nil {
+			return
+		}
+		offset += size
+	}
+}
+
+// adapt sizes the next chunks so that each takes about targetChunkDuration at
+// the throughput just measured. The size changes by at most a factor of two per
+// chunk and stays a multiple of 320 KiB within the allowed bounds.
+func (u *chunkUploader) adapt(sent int64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	current := u.chunkSize.Load()
+	ideal := int64(float64(sent) / elapsed.Seconds() * targetChunkDuration.Seconds())
+	ideal = max(current/2, min(ideal, current*2))
+	ideal -= ideal % uploadChunkUnit
+	u.chunkSize.Store(max(minUploadChunkSize, min(ideal, maxUploadChunkSize)))
+}
+
+// firstExpectedByte parses the start of the first range in NextExpectedRanges,
+// which the service reports as "start-end" or "start-".
+func firstExpectedByte(ranges []string) (int64, bool) {
+	if len(ranges) == 0 {
+		return 0, false
+	}
+	start, _, _ := strings.Cut(ranges[0], "-")
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,220 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// sessionSDK is the SDK of the upload tests. Methods without a function set
+// panic through the nil embedded interface.
+type sessionSDK struct {
+	app.SDK
+	createSession func(ctx context.Context, remotePath string) (onedrive.UploadSession, error)
+	uploadChunk   func(ctx context.Context, uploadURL string, startByte, endByte, totalSize int64, chunkData io.Reader) (onedrive.UploadSession, error)
+	status        func(ctx context.Context, uploadURL string) (onedrive.UploadSession, error)
+	cancelled     []string
+}
+
+func (s *sessionSDK) CreateUploadSession(ctx context.Context, remotePath string) (onedrive.UploadSession, error) {
+	return s.createSession(ctx, remotePath)
+}
+
+func (s *sessionSDK) UploadChunk(ctx context.Context, uploadURL string, startByte, endByte, totalSize int64, chunkData io.Reader) (onedrive.UploadSession, error) {
+	return s.uploadChunk(ctx, uploadURL, startByte, endByte, totalSize, chunkData)
+}
+
+func (s *sessionSDK) GetUploadSessionStatus(ctx context.Context, uploadURL string) (onedrive.UploadSession, error) {
+	return s.status(ctx, uploadURL)
+}
+
+func (s *sessionSDK) CancelUploadSession(ctx context.Context, uploadURL string) error {
+	s.cancelled = append(s.cancelled, uploadURL)
+	return nil
+}
+
+func (s *sessionSDK) GetDriveItemByPath(ctx context.Context, path string) (onedrive.DriveItem, error) {
+	return onedrive.DriveItem{Name: path}, nil
+}
+
+// sessionServer emulates the receiving end of an upload session. It stores the
+// bytes of every chunk at its offset, and hook, if set, can fail a chunk or
+// override the NextExpectedRanges reported after it.
+type sessionServer struct {
+	received []byte
+	starts   []int64
+	hook     func(start int64) ([]string, error)
+}
+
+func (s *sessionServer) sdk() *sessionSDK {
+	return &sessionSDK{
+		uploadChunk: func(ctx context.Context, uploadURL string, startByte, endByte, totalSize int64, chunkData io.Reader) (onedrive.UploadSession, error) {
+			var expected []string
+			if s.hook != nil {
+				var err error
+				if expected, err = s.hook(startByte); err != nil {
+					return onedrive.UploadSession{}, err
+				}
+			}
+			data, err := io.ReadAll(chunkData)
+			if err != nil {
+				return onedrive.UploadSession{}, err
+			}
+			if int64(len(data)) != endByte-startByte+1 {
+				return onedrive.UploadSession{}, fmt.Errorf("chunk %d-%d carries %d bytes", startByte, endByte, len(data))
+			}
+			s.starts = append(s.starts, startByte)
+			copy(s.received[startByte:], data)
+			if expected == nil && endByte+1 < totalSize {
+				expected = []string{fmt.Sprintf("%d-", endByte+1)}
+			}
+			return onedrive.UploadSession{UploadURL: uploadURL, NextExpectedRanges: expected}, nil
+		},
+	}
+}
+
+func TestChunkUploaderUpload(t *testing.T) {
+	content := make([]byte, initialUploadChunk+5*uploadChunkUnit+123)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	session := onedrive.UploadSession{UploadURL: "https://upload.example/session"}
+
+	t.Run("sends the whole file in order", func(t *testing.T) {
+		server := &sessionServer{received: make([]byte, len(content))}
+		u := newChunkUploader(server.sdk(), session, bytes.NewReader(content), int64(len(content)))
+		u.chunkSize.Store(uploadChunkUnit)
+		var reported []int64
+		u.progress = func(completed int64) { reported = append(reported, completed) }
+
+		offset, err := u.upload(context.Background(), 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), offset)
+		assert.Equal(t, content, server.received)
+		assert.IsIncreasing(t, server.starts)
+		assert.Equal(t, int64(len(content)), reported[len(reported)-1])
+	})
+
+	t.Run("resumes from the gap reported in NextExpectedRanges", func(t *testing.T) {
+		server := &sessionServer{received: make([]byte, len(content))}
+		gapped := false
+		server.hook = func(start int64) ([]string, error) {
+			if len(server.starts) == 2 && !gapped {
+				// The service lost the second chunk and asks for it again.
+				gapped = true
+				return []string{fmt.Sprintf("%d-%d", server.starts[1], len(content)-1)}, nil
+			}
+			return nil, nil
+		}
+		u := newChunkUploader(server.sdk(), session, bytes.NewReader(content), int64(len(content)))
+		u.chunkSize.Store(uploadChunkUnit)
+
+		offset, err := u.upload(context.Background(), 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), offset)
+		assert.Equal(t, content, server.received)
+		require.Greater(t, len(server.starts), 3)
+		assert.Equal(t, server.starts[1], server.starts[3])
+	})
+
+	t.Run("recovers from a failed chunk through the session status", func(t *testing.T) {
+		server := &sessionServer{received: make([]byte, len(content))}
+		failed := false
+		server.hook = func(start int64) ([]string, error) {
+			if start > 0 && !failed {
+				failed = true
+				return nil, errors.New("connection reset")
+			}
+			return nil, nil
+		}
+		sdk := server.sdk()
+		statusCalls := 0
+		sdk.status = func(ctx context.Context, uploadURL string) (onedrive.UploadSession, error) {
+			statusCalls++
+			return onedrive.UploadSession{NextExpectedRanges: []string{fmt.Sprintf("%d-", initialUploadChunk)}}, nil
+		}
+		u := newChunkUploader(sdk, session, bytes.NewReader(content), int64(len(content)))
+
+		offset, err := u.upload(context.Background(), 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), offset)
+		assert.Equal(t, 1, statusCalls)
+		assert.Equal(t, content, server.received)
+	})
+
+	t.Run("stops retrying when the context is cancelled", func(t *testing.T) {
+		server := &sessionServer{received: make([]byte, len(content))}
+		server.hook = func(start int64) ([]string, error) {
+			if start > 0 {
+				return nil, errors.New("connection reset")
+			}
+			return nil, nil
+		}
+		sdk := server.sdk()
+		sdk.status = func(ctx context.Context, uploadURL string) (onedrive.UploadSession, error) {
+			return onedrive.UploadSession{NextExpectedRanges: []string{fmt.Sprintf("%d-", initialUploadChunk)}}, nil
+		}
+		u := newChunkUploader(sdk, session, bytes.NewReader(content), int64(len(content)))
+
+		ctx, cancel := context.WithTimeout(context.Background(), chunkRetryDelay/2)
+		defer cancel()
+		offset, err := u.upload(ctx, 0)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, int64(initialUploadChunk), offset)
+	})
+}
+
+func TestChunkUploaderAdapt(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  int64
+		sent     int64
+		elapsed  time.Duration
+		expected int64
+	}{
+		{"fast link doubles the chunk", initialUploadChunk, initialUploadChunk, 100 * time.Millisecond, 2 * initialUploadChunk},
+		{"slow link halves the chunk", 10 * uploadChunkUnit, 10 * uploadChunkUnit, time.Minute, 5 * uploadChunkUnit},
+		{"steady link keeps the chunk", initialUploadChunk, initialUploadChunk, targetChunkDuration, initialUploadChunk},
+		{"never exceeds the maximum", maxUploadChunkSize, maxUploadChunkSize, time.Millisecond, maxUploadChunkSize},
+		{"never drops below the minimum", minUploadChunkSize, minUploadChunkSize, time.Hour, minUploadChunkSize},
+		{"ignores unmeasurable chunks", initialUploadChunk, initialUploadChunk, 0, initialUploadChunk},
+		{"rounds down to 320 KiB", 10 * uploadChunkUnit, 10 * uploadChunkUnit, 3 * time.Second, 13 * uploadChunkUnit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newChunkUploader(&sessionSDK{}, onedrive.UploadSession{}, bytes.NewReader(nil), 0)
+			u.chunkSize.Store(tt.current)
+			u.adapt(tt.sent, tt.elapsed)
+			got := u.chunkSize.Load()
+			assert.Equal(t, tt.expected, got)
+			assert.Zero(t, got%uploadChunkUnit)
+		})
+	}
+}
+
+func TestFirstExpectedByte(t *testing.T) {
+	tests := []struct {
+		ranges   []string
+		expected int64
+		ok       bool
+	}{
+		{[]string{"12345-"}, 12345, true},
+		{[]string{"0-99", "200-299"}, 0, true},
+		{[]string{"x-"}, 0, false},
+		{nil, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := firstExpectedByte(tt.ranges)
+		assert.Equal(t, tt.ok, ok, "ranges %v", tt.ranges)
+		assert.Equal(t, tt.expected, got, "ranges %v", tt.ranges)
+	}
+}
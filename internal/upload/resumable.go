@@ -0,0 +1,115 @@
+// Package upload (resumable.go) sends a local file to OneDrive through a
+// resumable upload session. It is shared by 'items upload', folder uploads and
+// the sync engine, so that all of them pipeline and adapt their chunks, recover
+// from gaps, and resume an interrupted upload on the next attempt.
+package upload
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/tonimelisma/onedrive-client/internal/app"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+// Resumable uploads the file at localPath to remotePath in chunks through a
+// resumable upload session and returns the metadata of the uploaded item.
+//
+// With a session Manager, the upload resumes from the progress saved in `mgr`
+// by an earlier attempt, and on failure or cancellation the progress is saved
+// so that the next attempt picks up from there. Without one (`mgr` is nil),
+// a failed upload session is cancelled. `progress`, if set, is called with the
+// number of bytes the service has accepted, starting with the resumed offset.
+//
+// The item is not verified against the local file; callers do so with
+// onedrive.VerifyFile.
+func Resumable(ctx context.Context, sdk app.SDK, mgr *session.Manager, localPath, remotePath string, progress func(completed int64)) (onedrive.DriveItem, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return onedrive.DriveItem{}, fmt.Errorf("opening local file '%s' for chunked upload: %w", localPath, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close file: %v", closeErr)
+		}
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return onedrive.DriveItem{}, fmt.Errorf("getting file info for '%s': %w", localPath, err)
+	}
+
+	uploadSession, offset, err := openSession(ctx, sdk, mgr, localPath, remotePath)
+	if err != nil {
+		return onedrive.DriveItem{}, err
+	}
+
+	uploader := newChunkUploader(sdk, uploadSession, file, info.Size())
+	uploader.progress = progress
+	if progress != nil {
+		progress(offset)
+	}
+	if offset, err = uploader.upload(ctx, offset); err != nil {
+		if mgr != nil {
+			saveState(mgr, localPath, remotePath, uploader.session, offset)
+		} else if cancelErr := sdk.CancelUploadSession(context.WithoutCancel(ctx), uploader.session.UploadURL); cancelErr != nil {
+			log.Printf("Warning: failed to cancel upload session for '%s': %v", remotePath, cancelErr)
+		}
+		return onedrive.DriveItem{}, fmt.Errorf("uploading '%s' from byte %d: %w", localPath, offset, err)
+	}
+
+	if mgr != nil {
+		if err := mgr.Delete(localPath, remotePath); err != nil {
+			log.Printf("Warning: failed to delete session file for completed upload '%s': %v", localPath, err)
+		}
+	}
+	// The final chunk response is not decoded as a DriveItem, so fetch the
+	// metadata of the completed upload explicitly.
+	item, err := sdk.GetDriveItemByPath(ctx, remotePath)
+	if err != nil {
+		return item, fmt.Errorf("getting metadata of uploaded '%s': %w", remotePath, err)
+	}
+	return item, nil
+}
+
+// openSession returns the upload session saved in `mgr` for the file and the
+// offset to resume from, or a new session starting at byte 0.
+func openSession(ctx context.Context, sdk app.SDK, mgr *session.Manager, localPath, remotePath string) (onedrive.UploadSession, int64, error) {
+	if mgr != nil {
+		state, err := mgr.Load(localPath, remotePath)
+		if err != nil {
+			return onedrive.UploadSession{}, 0, fmt.Errorf("loading upload session state for '%s': %w", localPath, err)
+		}
+		if state != nil && state.UploadURL != "" {
+			log.Printf("Resuming upload of '%s' to '%s' from %d bytes.", localPath, remotePath, state.CompletedBytes)
+			return onedrive.UploadSession{
+				UploadURL:          state.UploadURL,
+				ExpirationDateTime: state.ExpirationDateTime.Format(time.RFC3339),
+			}, state.CompletedBytes, nil
+		}
+	}
+	uploadSession, err := sdk.CreateUploadSession(ctx, remotePath)
+	if err != nil {
+		return onedrive.UploadSession{}, 0, fmt.Errorf("creating upload session for '%s': %w", remotePath, err)
+	}
+	return uploadSession, 0, nil
+}
+
+// saveState records the progress of a resumable upload so that it can be
+// resumed later. Failures are only logged, as the upload error matters more.
+func saveState(mgr *session.Manager, localPath, remotePath string, uploadSession onedrive.UploadSession, completed int64) {
+	expirationTime, _ := time.Parse(time.RFC3339, uploadSession.ExpirationDateTime)
+	state := &session.State{
+		UploadURL:          uploadSession.UploadURL,
+		ExpirationDateTime: expirationTime,
+		LocalPath:          localPath,
+		RemotePath:         remotePath,
+		CompletedBytes:     completed,
+	}
+	if err := mgr.Save(state); err != nil {
+		log.Printf("Error saving session state for '%s': %v", localPath, err)
+	}
+}
@@ -0,0 +1,82 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/session"
+	"github.com/tonimelisma/onedrive-client/pkg/onedrive"
+)
+
+func TestResumable(t *testing.T) {
+	content := make([]byte, 2*initialUploadChunk+123)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	localPath := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, os.WriteFile(localPath, content, 0600))
+
+	// interruptingSDK returns the SDK of a session server that cancels `ctx`
+	// when the second chunk arrives, counting the sessions created.
+	interruptingSDK := func(server *sessionServer, cancel context.CancelFunc, sessions *int) *sessionSDK {
+		server.hook = func(start int64) ([]string, error) {
+			if start > 0 && cancel != nil {
+				cancel()
+				return nil, errors.New("connection reset")
+			}
+			return nil, nil
+		}
+		sdk := server.sdk()
+		sdk.createSession = func(ctx context.Context, remotePath string) (onedrive.UploadSession, error) {
+			*sessions++
+			return onedrive.UploadSession{UploadURL: "https://upload.example/session"}, nil
+		}
+		return sdk
+	}
+
+	t.Run("saves the progress and resumes from it", func(t *testing.T) {
+		t.Setenv("ONEDRIVE_CONFIG_PATH", filepath.Join(t.TempDir(), "config.json"))
+		mgr, err := session.NewManager()
+		require.NoError(t, err)
+		server := &sessionServer{received: make([]byte, len(content))}
+		sessions := 0
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_, err = Resumable(ctx, interruptingSDK(server, cancel, &sessions), mgr, localPath, "/disk.img", nil)
+		require.ErrorIs(t, err, context.Canceled)
+		state, err := mgr.Load(localPath, "/disk.img")
+		require.NoError(t, err)
+		require.NotNil(t, state)
+		assert.Equal(t, int64(initialUploadChunk), state.CompletedBytes)
+
+		var reported []int64
+		item, err := Resumable(context.Background(), interruptingSDK(server, nil, &sessions), mgr, localPath, "/disk.img",
+			func(completed int64) { reported = append(reported, completed) })
+		require.NoError(t, err)
+		assert.Equal(t, "/disk.img", item.Name)
+		assert.Equal(t, 1, sessions, "the saved session is resumed")
+		assert.Equal(t, int64(initialUploadChunk), reported[0], "progress starts at the resumed offset")
+		assert.Equal(t, content, server.received)
+		state, err = mgr.Load(localPath, "/disk.img")
+		require.NoError(t, err)
+		assert.Nil(t, state, "the saved progress is removed once complete")
+	})
+
+	t.Run("cancels the session without a manager", func(t *testing.T) {
+		server := &sessionServer{received: make([]byte, len(content))}
+		sessions := 0
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sdk := interruptingSDK(server, cancel, &sessions)
+
+		_, err := Resumable(ctx, sdk, nil, localPath, "/disk.img", nil)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, []string{"https://upload.example/session"}, sdk.cancelled)
+	})
+}
@@ -32,13 +32,13 @@ func (c *Client) GetItemActivities(ctx context.Context, remotePath string, pagin
 
 	// First, resolve the remotePath to a DriveItem to get its ID.
 	// The activities endpoint requires an item ID.
-	item, err := c.GetDriveItemByPath(ctx, remotePath)
+	itemID, err := c.resolveItemID(ctx, remotePath)
 	if err != nil {
 		return activities, "", fmt.Errorf("getting item ID for path '%s' to fetch activities: %w", remotePath, err)
 	}
 
 	// Construct the base URL for item activities.
-	baseURL := customRootURL + "me/drive/items/" + item.ID + "/activities"
+	baseURL := c.itemURL(itemID) + "/activities"
 	var queryParams []string
 	if paging.Top > 0 {
 		queryParams = append(queryParams, fmt.Sprintf("$top=%d", paging.Top))
@@ -0,0 +1,141 @@
+// Package onedrive (api.go) sends requests to arbitrary Microsoft Graph
+// endpoints, for those the SDK does not wrap. The requests go through the
+// same authenticated, token-refreshing client as every other SDK call, with
+// its retries and its mapping of error responses to the sentinel errors.
+package onedrive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// graphVersions are the path segments of the Microsoft Graph API versions. A
+// RawRequest path starting with one of them is resolved against the Graph
+// host rather than the client's versioned Graph root.
+var graphVersions = []string{"v1.0", "beta"}
+
+// RawRequest is a request to an arbitrary Microsoft Graph endpoint.
+type RawRequest struct {
+	Method string        // HTTP method, such as "GET" or "PATCH"; defaults to "GET".
+	Path   string        // Path below the Graph root, such as "/me/drive/recent" or "/beta/me/drive"; or an absolute URL on the Graph host, such as an @odata.nextLink.
+	Header http.Header   // Extra request headers, such as "ConsistencyLevel".
+	Body   io.ReadSeeker // Request body, or nil. It is sent as JSON unless Header sets a Content-Type.
+}
+
+// RawResponse is the response to a RawRequest.
+type RawResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Raw sends `req` to Microsoft Graph and returns the response. Error
+// responses are mapped to the sentinel errors as for every other call.
+//
+// Example:
+//
+//	res, err := client.Raw(ctx, onedrive.RawRequest{Path: "/me/drive/root/children"})
+//	if err != nil { log.Fatal(err) }
+//	fmt.Println(string(res.Body))
+func (c *Client) Raw(ctx context.Context, req RawRequest) (RawResponse, error) {
+	method, requestURL, header, err := c.rawRequest(req)
+	if err != nil {
+		return RawResponse{}, err
+	}
+	res, err := c.apiCallWithHeader(ctx, method, requestURL, header, req.Body)
+	if err != nil {
+		return RawResponse{}, err
+	}
+	defer closeBodySafely(res.Body, c.logger, "raw API response")
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return RawResponse{}, fmt.Errorf("%w: reading response from %s: %w", ErrNetworkFailed, requestURL, err)
+	}
+	return RawResponse{StatusCode: res.StatusCode, Header: res.Header, Body: body}, nil
+}
+
+// RawAllPages sends the GET request `req` to a Microsoft Graph list endpoint
+// and follows every @odata.nextLink. It returns the items of all pages as a
+// single JSON document of the form {"value": [...]}.
+func (c *Client) RawAllPages(ctx context.Context, req RawRequest) (json.RawMessage, error) {
+	method, requestURL, header, err := c.rawRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if method != http.MethodGet {
+		return nil, fmt.Errorf("%w: only GET requests can be paginated, not %s", ErrInvalidRequest, method)
+	}
+	items, _, err := c.collectPages(ctx, requestURL, header, Paging{FetchAll: true})
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+	data, err := json.Marshal(struct {
+		Value []json.RawMessage `json:"value"`
+	}{items})
+	if err != nil {
+		return nil, fmt.Errorf("encoding collected pages: %w", err)
+	}
+	return data, nil
+}
+
+// rawRequest validates `req` and returns its method, absolute URL and
+// headers.
+func (c *Client) rawRequest(req RawRequest) (string, string, http.Header, error) {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	requestURL, err := c.rawURL(req.Path)
+	if err != nil {
+		return "", "", nil, err
+	}
+	header := req.Header.Clone()
+	if req.Body != nil && header.Get("Content-Type") == "" {
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	return method, requestURL, header, nil
+}
+
+// rawURL resolves the path of a RawRequest to an absolute URL. Absolute URLs
+// must point at the Graph host, as the client sends its access token with
+// every request.
+func (c *Client) rawURL(path string) (string, error) {
+	graph, err := url.Parse(c.endpoints.GraphURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid Microsoft Graph URL '%s': %w", ErrInvalidRequest, c.endpoints.GraphURL, err)
+	}
+
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		target, err := url.Parse(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid URL '%s': %w", ErrInvalidRequest, path, err)
+		}
+		if target.Scheme != graph.Scheme || target.Host != graph.Host {
+			return "", fmt.Errorf("%w: URL '%s' is not on the Microsoft Graph host %s", ErrInvalidRequest, path, graph.Host)
+		}
+		return path, nil
+	}
+
+	relative := strings.TrimPrefix(path, "/")
+	if relative == "" {
+		return "", fmt.Errorf("%w: an API path is required", ErrInvalidRequest)
+	}
+	for _, version := range graphVersions {
+		if relative == version || strings.HasPrefix(relative, version+"/") {
+			return graph.Scheme + "://" + graph.Host + "/" + relative, nil
+		}
+	}
+	return strings.TrimSuffix(c.endpoints.GraphURL, "/") + "/" + relative, nil
+}
@@ -0,0 +1,114 @@
+package onedrive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/logger"
+)
+
+// newRawTestClient returns a client whose Graph root is /v1.0/ on `server`.
+func newRawTestClient(server *httptest.Server) *Client {
+	client := NewClient(context.Background(), &Token{AccessToken: "test-token"}, "test-client-id", nil, &logger.NoopLogger{}, WithEndpoints(Endpoints{GraphURL: server.URL + "/v1.0/"}))
+	client.httpClient = &http.Client{}
+	return client
+}
+
+func TestRawURL(t *testing.T) {
+	client := NewClient(context.Background(), &Token{AccessToken: "test-token"}, "test-client-id", nil, &logger.NoopLogger{})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/me/drive", "https://graph.microsoft.com/v1.0/me/drive"},
+		{"me/drive/root/children?$top=5", "https://graph.microsoft.com/v1.0/me/drive/root/children?$top=5"},
+		{"/beta/me/drive", "https://graph.microsoft.com/beta/me/drive"},
+		{"/v1.0/me", "https://graph.microsoft.com/v1.0/me"},
+		{"https://graph.microsoft.com/v1.0/me/drive/root/children?$skiptoken=abc", "https://graph.microsoft.com/v1.0/me/drive/root/children?$skiptoken=abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := client.rawURL(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	for _, path := range []string{"", "/", "https://example.com/v1.0/me", "http://graph.microsoft.com/v1.0/me"} {
+		_, err := client.rawURL(path)
+		assert.ErrorIs(t, err, ErrInvalidRequest, path)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.0/me/drive/items/123":
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, "replace", r.Header.Get("If-Match"))
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"name":"new.txt"}`, string(body))
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"123","name":"new.txt"}`))
+		case "/beta/me/drive":
+			w.Write([]byte(`{"id":"beta-drive"}`))
+		case "/v1.0/me/drive/items/gone":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"itemNotFound"}}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	client := newRawTestClient(server)
+	ctx := context.Background()
+
+	res, err := client.Raw(ctx, RawRequest{Method: "patch", Path: "/me/drive/items/123",
+		Header: http.Header{"If-Match": {"replace"}}, Body: strings.NewReader(`{"name":"new.txt"}`)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"id":"123","name":"new.txt"}`, string(res.Body))
+
+	res, err = client.Raw(ctx, RawRequest{Path: "/beta/me/drive"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"beta-drive"}`, string(res.Body))
+
+	res, err = client.Raw(ctx, RawRequest{Method: http.MethodDelete, Path: "/me/drive/items/old"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Empty(t, res.Body)
+
+	_, err = client.Raw(ctx, RawRequest{Path: "/me/drive/items/gone"})
+	assert.ErrorIs(t, err, ErrResourceNotFound)
+}
+
+func TestRawAllPages(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "eventual", r.Header.Get("ConsistencyLevel"))
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`{"value":[{"id":"c"}]}`))
+			return
+		}
+		fmt.Fprintf(w, `{"value":[{"id":"a"},{"id":"b"}],"@odata.nextLink":"%s/v1.0/me/drive/root/children?page=2"}`, server.URL)
+	}))
+	defer server.Close()
+	client := newRawTestClient(server)
+
+	data, err := client.RawAllPages(context.Background(), RawRequest{Path: "/me/drive/root/children",
+		Header: http.Header{"ConsistencyLevel": {"eventual"}}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"value":[{"id":"a"},{"id":"b"},{"id":"c"}]}`, string(data))
+
+	_, err = client.RawAllPages(context.Background(), RawRequest{Method: http.MethodPost, Path: "/me/drive/root/children"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
@@ -17,27 +17,9 @@ import (
 	"strings"
 	"time"
 
-	cv "github.com/nirasan/go-oauth-pkce-code-verifier"
 	"golang.org/x/oauth2"
 )
 
-// SetCustomEndpoints allows overriding the default OAuth endpoints.
-// This is primarily used for testing purposes, enabling tests to target
-// mock OAuth servers instead of Microsoft's live endpoints.
-// It modifies global variables `customAuthURL`, `customTokenURL`, and `customDeviceURL`.
-func SetCustomEndpoints(authURL, tokenURL, deviceURL string) {
-	customAuthURL = authURL
-	customTokenURL = tokenURL
-	customDeviceURL = deviceURL
-}
-
-// SetCustomGraphEndpoint allows overriding the default Microsoft Graph API root endpoint.
-// Similar to SetCustomEndpoints, this is useful for testing against a mock Graph API server.
-// It modifies the global variable `customRootURL`.
-func SetCustomGraphEndpoint(graphURL string) {
-	customRootURL = graphURL
-}
-
 // OAuthConfig is an alias for oauth2.Config, tailored for OneDrive.
 // It represents the configuration for an OAuth2 client.
 type OAuthConfig oauth2.Config
@@ -146,7 +128,7 @@ func apiCallWithDebug(method, url, contentType string, body io.Reader, debug boo
 //
 // Example:
 //
-//	ctx, oauthCfg := onedrive.GetOauth2Config("YOUR_CLIENT_ID")
+//	ctx, oauthCfg := onedrive.GetOauth2Config(onedrive.AuthConfig{ClientID: "YOUR_CLIENT_ID"})
 //	authURL, verifier, err := onedrive.StartAuthentication(ctx, oauthCfg)
 //	if err != nil { log.Fatal(err) }
 //	// Store verifier securely (e.g., in a session)
@@ -160,24 +142,10 @@ func StartAuthentication(
 		return "", "", fmt.Errorf("context must not be nil for StartAuthentication")
 	}
 
-	var codeVerifierObj *cv.CodeVerifier
-	codeVerifierObj, err = cv.CreateCodeVerifier() // Generate a new PKCE code verifier.
-	if err != nil {
-		return "", "", fmt.Errorf("could not create PKCE code verifier: %w", err)
-	}
-	codeVerifier = codeVerifierObj.String()
-	codeChallenge := codeVerifierObj.CodeChallengeS256() // Generate S256 challenge from verifier.
-
-	// Parameters for PKCE.
-	pkceParams := []oauth2.AuthCodeOption{
-		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
-		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
-	}
-
-	// Construct the authorization URL. "state" can be used to prevent CSRF but is less critical
-	// in some PKCE flows if the redirect URI is strictly controlled.
-	authURL = (*oauth2.Config)(oauthConfig).AuthCodeURL("state-does-not-matter", pkceParams...)
-	return authURL, codeVerifier, nil
+	// "state" can be used to prevent CSRF but is less critical in some PKCE flows
+	// if the redirect URI is strictly controlled. Callers that receive the
+	// redirect themselves should use StartAuthenticationWithState instead.
+	return StartAuthenticationWithState(ctx, oauthConfig, "state-does-not-matter")
 }
 
 // CompleteAuthentication exchanges an authorization code (obtained after user consent
@@ -194,7 +162,7 @@ func StartAuthentication(
 //	// After user is redirected back from authURL with an authorization code:
 //	code := "THE_AUTHORIZATION_CODE_FROM_REDIRECT"
 //	verifier := "THE_STORED_CODE_VERIFIER" // From StartAuthentication
-//	ctx, oauthCfg := onedrive.GetOauth2Config("YOUR_CLIENT_ID")
+//	ctx, oauthCfg := onedrive.GetOauth2Config(onedrive.AuthConfig{ClientID: "YOUR_CLIENT_ID"})
 //	token, err := onedrive.CompleteAuthentication(ctx, oauthCfg, code, verifier)
 //	if err != nil { log.Fatal(err) }
 //	// Use token to create an authenticated onedrive.Client
@@ -231,22 +199,23 @@ func CompleteAuthentication(
 }
 
 // GetOauth2Config returns a basic OAuth2 configuration for OneDrive.
-// It uses the provided clientID and the SDK's predefined scopes and endpoints
-// (which can be customized for testing via SetCustomEndpoints).
+// It uses the client ID, scopes and endpoints of `auth`, defaulting the
+// endpoints to DefaultEndpoints and the scopes to DefaultScopes.
 //
 // Example:
 //
-//	ctx, oauthCfg := onedrive.GetOauth2Config("YOUR_CLIENT_ID")
+//	ctx, oauthCfg := onedrive.GetOauth2Config(onedrive.AuthConfig{ClientID: "YOUR_CLIENT_ID"})
 //	// Use oauthCfg with StartAuthentication and CompleteAuthentication
-func GetOauth2Config(clientID string) (context.Context, *OAuthConfig) {
+func GetOauth2Config(auth AuthConfig) (context.Context, *OAuthConfig) {
 	// A background context is generally suitable for configuration setup.
 	ctx := context.Background()
+	auth = auth.withDefaults()
 	conf := &oauth2.Config{
-		ClientID: clientID,
-		Scopes:   oAuthScopes, // Uses the global oAuthScopes.
+		ClientID: auth.ClientID,
+		Scopes:   append([]string(nil), auth.Scopes...),
 		Endpoint: oauth2.Endpoint{
-			AuthURL:  customAuthURL,  // Uses customizable endpoint.
-			TokenURL: customTokenURL, // Uses customizable endpoint.
+			AuthURL:  auth.Endpoints.AuthURL,
+			TokenURL: auth.Endpoints.TokenURL,
 		},
 	}
 	return ctx, (*OAuthConfig)(conf)
@@ -260,20 +229,22 @@ func GetOauth2Config(clientID string) (context.Context, *OAuthConfig) {
 //
 // Example:
 //
-//	resp, err := onedrive.InitiateDeviceCodeFlow("YOUR_CLIENT_ID", true) // true for debug
+//	resp, err := onedrive.InitiateDeviceCodeFlow(onedrive.AuthConfig{ClientID: "YOUR_CLIENT_ID"}, true) // true for debug
 //	if err != nil { log.Fatal(err) }
 //	fmt.Println(resp.Message) // E.g., "To sign in, use a web browser to open the page https://microsoft.com/devicelogin and enter the code XXXXXXXX to authenticate."
 //	// Store resp.DeviceCode and poll using VerifyDeviceCode
-func InitiateDeviceCodeFlow(clientID string, debug bool) (*DeviceCodeResponse, error) {
+func InitiateDeviceCodeFlow(auth AuthConfig, debug bool) (*DeviceCodeResponse, error) {
+	auth = auth.withDefaults()
 	// Parameters for the device code request.
 	data := url.Values{}
-	data.Set("client_id", clientID)
-	data.Set("scope", strings.Join(oAuthScopes, " ")) // Scopes are space-separated.
+	data.Set("client_id", auth.ClientID)
+	data.Set("scope", strings.Join(auth.Scopes, " ")) // Scopes are space-separated.
 
 	// Make the unauthenticated call to the device code endpoint.
-	res, err := apiCallWithDebug("POST", customDeviceURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()), debug)
+	deviceURL := auth.Endpoints.DeviceURL
+	res, err := apiCallWithDebug("POST", deviceURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()), debug)
 	if err != nil {
-		return nil, fmt.Errorf("requesting device code from %s: %w", customDeviceURL, err)
+		return nil, fmt.Errorf("requesting device code from %s: %w", deviceURL, err)
 	}
 	defer func() {
 		if err := res.Body.Close(); err != nil {
@@ -305,7 +276,7 @@ func InitiateDeviceCodeFlow(clientID string, debug bool) (*DeviceCodeResponse, e
 //
 //	// After InitiateDeviceCodeFlow, assuming 'deviceCode' is from its response:
 //	// Loop with appropriate polling interval (e.g., resp.Interval from InitiateDeviceCodeFlow)
-//	token, err := onedrive.VerifyDeviceCode("YOUR_CLIENT_ID", deviceCode, true)
+//	token, err := onedrive.VerifyDeviceCode(onedrive.AuthConfig{ClientID: "YOUR_CLIENT_ID"}, deviceCode, true)
 //	if err != nil {
 //	    if errors.Is(err, onedrive.ErrAuthorizationPending) {
 //	        // Continue polling
@@ -317,18 +288,20 @@ func InitiateDeviceCodeFlow(clientID string, debug bool) (*DeviceCodeResponse, e
 //	    // Token acquired successfully
 //	    // Use token to create an authenticated onedrive.Client
 //	}
-func VerifyDeviceCode(clientID string, deviceCode string, debug bool) (*Token, error) {
+func VerifyDeviceCode(auth AuthConfig, deviceCode string, debug bool) (*Token, error) {
+	auth = auth.withDefaults()
 	// Parameters for the token request in the device code flow.
 	data := url.Values{}
 	data.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
-	data.Set("client_id", clientID)
+	data.Set("client_id", auth.ClientID)
 	data.Set("device_code", deviceCode)
 
 	// Poll the token endpoint.
-	res, err := apiCallWithDebug("POST", customTokenURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()), debug)
+	tokenURL := auth.Endpoints.TokenURL
+	res, err := apiCallWithDebug("POST", tokenURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()), debug)
 	if err != nil {
 		// apiCallWithDebug already maps "authorization_pending", etc., to sentinel errors.
-		return nil, fmt.Errorf("polling token endpoint %s: %w", customTokenURL, err)
+		return nil, fmt.Errorf("polling token endpoint %s: %w", tokenURL, err)
 	}
 	defer func() {
 		if err := res.Body.Close(); err != nil {
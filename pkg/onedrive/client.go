@@ -14,6 +14,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -31,28 +32,6 @@ type Logger = logger.Logger
 // It is used if no other logger is provided to the Client.
 type DefaultLogger = logger.NoopLogger
 
-// oAuthScopes defines the necessary OAuth2 permissions (scopes) required by the SDK
-// to access OneDrive resources on behalf of the user.
-var oAuthScopes = []string{"offline_access", "files.readwrite.all", "user.read", "email", "openid", "profile"}
-
-// Constants for Microsoft Graph API and OAuth2 endpoints.
-// These are the standard production URLs.
-const (
-	oAuthAuthURL   = "https://login.microsoftonline.com/common/oauth2/v2.0/authorize"  // Standard OAuth2 authorization endpoint.
-	oAuthTokenURL  = "https://login.microsoftonline.com/common/oauth2/v2.0/token"      // Standard OAuth2 token endpoint.
-	oAuthDeviceURL = "https://login.microsoftonline.com/common/oauth2/v2.0/devicecode" // Endpoint for OAuth2 Device Code Flow.
-	rootUrl        = "https://graph.microsoft.com/v1.0/"                               // Base URL for Microsoft Graph API v1.0.
-)
-
-// Variables that allow overriding the default API and OAuth endpoints.
-// This is primarily useful for testing against mock servers or different environments.
-var (
-	customAuthURL   = oAuthAuthURL   // Can be overridden by SetCustomEndpoints.
-	customTokenURL  = oAuthTokenURL  // Can be overridden by SetCustomEndpoints.
-	customDeviceURL = oAuthDeviceURL // Can be overridden by SetCustomEndpoints.
-	customRootURL   = rootUrl        // Can be overridden by SetCustomGraphEndpoint.
-)
-
 // Token represents an OAuth2 Token and is the canonical representation
 // used by the SDK. It embeds oauth2.Token and can be used directly
 // with the golang.org/x/oauth2 package.
@@ -105,6 +84,58 @@ type Client struct {
 	onNewToken func(*Token) error // Callback invoked when a token is refreshed.
 	logger     Logger             // Logger for debugging SDK operations.
 	httpConfig HTTPConfig         // HTTP configuration for non-authenticated clients
+	driveID    string             // Drive addressed by drive-scoped calls; empty for the user's default drive.
+	tokens     oauth2.TokenSource // Source of the access tokens sent with each request.
+	endpoints  Endpoints          // Sign-in and Microsoft Graph endpoints of this client.
+	fileMode   os.FileMode        // Permissions of the files DownloadFileParallel creates.
+}
+
+// clientOptions holds the settings that ClientOption values change.
+type clientOptions struct {
+	endpoints Endpoints
+	scopes    []string
+	fileMode  os.FileMode
+}
+
+// ClientOption changes a setting of a Client when it is created.
+type ClientOption func(*clientOptions)
+
+// WithEndpoints makes the client sign in and call Microsoft Graph at
+// `endpoints`, such as those of a national cloud or tenant (see
+// Cloud.Endpoints) or of a test server. Empty fields keep their defaults.
+func WithEndpoints(endpoints Endpoints) ClientOption {
+	return func(o *clientOptions) {
+		o.endpoints = endpoints.withDefaults()
+	}
+}
+
+// WithScopes makes the client request `scopes` when it refreshes its token.
+// A nil or empty slice keeps DefaultScopes.
+func WithScopes(scopes []string) ClientOption {
+	return func(o *clientOptions) {
+		if len(scopes) > 0 {
+			o.scopes = append([]string(nil), scopes...)
+		}
+	}
+}
+
+// WithFilePermissions makes DownloadFileParallel create files with `perm`
+// (before the umask) instead of 0666. Zero keeps the default.
+func WithFilePermissions(perm os.FileMode) ClientOption {
+	return func(o *clientOptions) {
+		if perm != 0 {
+			o.fileMode = perm
+		}
+	}
+}
+
+// newClientOptions returns the default settings with `opts` applied.
+func newClientOptions(opts []ClientOption) clientOptions {
+	o := clientOptions{endpoints: DefaultEndpoints(), scopes: DefaultScopes(), fileMode: 0o666}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
 }
 
 // SetLogger allows users of the SDK to set their own logger implementation.
@@ -143,50 +174,83 @@ func (c *Client) SetLogger(l Logger) {
 //
 //	client := onedrive.NewClient(context.Background(), initialToken, clientID, onTokenRefresh, myLogger)
 //	// Now use the client to make API calls, e.g., client.GetMe(context.Background())
-func NewClient(ctx context.Context, initialToken *Token, clientID string, onNewToken func(*Token) error, logger Logger) *Client {
-	return NewClientWithConfig(ctx, initialToken, clientID, onNewToken, logger, DefaultHTTPConfig())
+func NewClient(ctx context.Context, initialToken *Token, clientID string, onNewToken func(*Token) error, logger Logger, opts ...ClientOption) *Client {
+	return NewClientWithConfig(ctx, initialToken, clientID, onNewToken, logger, DefaultHTTPConfig(), opts...)
 }
 
 // NewClientWithConfig creates a new OneDrive client with custom HTTP configuration.
 // This allows fine-tuning of HTTP timeouts, retry behavior, and other client settings.
-func NewClientWithConfig(ctx context.Context, initialToken *Token, clientID string, onNewToken func(*Token) error, logger Logger, httpConfig HTTPConfig) *Client {
+// Options select the endpoints and scopes of the client; by default it talks to
+// the global Microsoft cloud.
+//
+// Example:
+//
+//	endpoints := onedrive.CloudUSGov.Endpoints("contoso.onmicrosoft.us")
+//	client := onedrive.NewClientWithConfig(ctx, token, clientID, onTokenRefresh, nil,
+//	    onedrive.DefaultHTTPConfig(), onedrive.WithEndpoints(endpoints))
+func NewClientWithConfig(ctx context.Context, initialToken *Token, clientID string, onNewToken func(*Token) error, logger Logger, httpConfig HTTPConfig, opts ...ClientOption) *Client {
 	// The oauth2.Config is used here primarily to configure the TokenSource for refresh operations.
 	// It does not initiate a new token acquisition flow itself.
-	config := &oauth2.Config{
-		ClientID: clientID,
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  customAuthURL,  // Uses customizable endpoint.
-			TokenURL: customTokenURL, // Uses customizable endpoint.
-		},
-		Scopes: oAuthScopes,
-	}
+	options := newClientOptions(opts)
+	_, config := GetOauth2Config(AuthConfig{ClientID: clientID, Endpoints: options.endpoints, Scopes: options.scopes})
 
 	// persistingTokenSource wraps the standard oauth2.TokenSource.
 	// It intercepts token refreshes to trigger the onNewToken callback.
 	persistingSource := &persistingTokenSource{
-		source:     config.TokenSource(ctx, (*oauth2.Token)(initialToken)),
+		source:     (*oauth2.Config)(config).TokenSource(ctx, (*oauth2.Token)(initialToken)),
 		onNewToken: onNewToken,
 		lastToken:  (*oauth2.Token)(initialToken), // Store initial token for comparison.
 	}
 
+	client := NewClientWithTokenSource(ctx, persistingSource, logger, httpConfig, opts...)
+	client.onNewToken = onNewToken
+	return client
+}
+
+// NewClientWithTokenSource creates a new OneDrive client that authenticates
+// with tokens from `source`, such as the app-only tokens of
+// NewClientCredentialsTokenSource. The source is responsible for refreshing
+// tokens; nothing is persisted by the client, and WithScopes has no effect.
+func NewClientWithTokenSource(ctx context.Context, source oauth2.TokenSource, logger Logger, httpConfig HTTPConfig, opts ...ClientOption) *Client {
 	if logger == nil {
 		logger = DefaultLogger{} // Use no-op logger if none provided.
 	}
 
 	// Create the base oauth2 client with authentication
-	baseOAuth2Client := oauth2.NewClient(ctx, persistingSource)
+	baseOAuth2Client := oauth2.NewClient(ctx, source)
 
 	// Apply our HTTP configuration while preserving the OAuth2 transport
 	configuredClient := NewConfiguredHTTPClientWithTransport(httpConfig, baseOAuth2Client.Transport)
 
+	options := newClientOptions(opts)
 	return &Client{
 		httpClient: configuredClient,
-		onNewToken: onNewToken,
 		logger:     logger,
 		httpConfig: httpConfig,
+		tokens:     source,
+		endpoints:  options.endpoints,
+		fileMode:   options.fileMode,
 	}
 }
 
+// Endpoints returns the sign-in and Microsoft Graph endpoints of the client.
+func (c *Client) Endpoints() Endpoints {
+	return c.endpoints
+}
+
+// AccessToken returns a valid access token, refreshing it first if it has
+// expired, for use by other tools that call Microsoft Graph directly.
+func (c *Client) AccessToken(ctx context.Context) (*Token, error) {
+	if c.tokens == nil {
+		return nil, fmt.Errorf("%w: client has no token source", ErrReauthRequired)
+	}
+	token, err := c.tokens.Token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReauthRequired, err)
+	}
+	return (*Token)(token), nil
+}
+
 // persistingTokenSource is an internal struct that wraps an oauth2.TokenSource.
 // Its purpose is to detect when a token has been refreshed by the underlying
 // source and then invoke the `onNewToken` callback to allow the application
@@ -228,8 +292,62 @@ func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
 	return newToken, nil
 }
 
+// WithDrive returns a copy of the client whose drive-scoped operations address
+// the drive with ID `driveID` instead of the user's default drive. This makes
+// SharePoint document libraries, group drives and drives shared with the user
+// accessible through the same API. The copy shares the underlying HTTP client,
+// and thus the token and its refresh callback, with the original. An empty
+// `driveID` selects the default drive again.
+//
+// Example:
+//
+//	drives, _ := client.GetDrives(ctx)
+//	library := client.WithDrive(drives.Value[1].ID)
+//	items, err := library.GetDriveItemChildrenByPath(ctx, "/Shared Documents")
+func (c *Client) WithDrive(driveID string) *Client {
+	bound := *c
+	bound.driveID = driveID
+	return &bound
+}
+
+// DriveID returns the ID of the drive the client is bound to, or "" if it
+// addresses the user's default drive.
+func (c *Client) DriveID() string {
+	return c.driveID
+}
+
+// driveURL returns the base URL of the drive the client addresses:
+// "me/drive" for the default drive, or "drives/{drive-id}" once bound with
+// WithDrive.
+func (c *Client) driveURL() string {
+	if c.driveID == "" {
+		return c.endpoints.GraphURL + "me/drive"
+	}
+	return c.endpoints.GraphURL + "drives/" + url.PathEscape(c.driveID)
+}
+
+// itemURL returns the URL of the item with ID `itemID` in the client's drive.
+func (c *Client) itemURL(itemID string) string {
+	return c.driveURL() + "/items/" + url.PathEscape(itemID)
+}
+
+// pathURL is the drive-aware counterpart of BuildPathURL, addressing `path`
+// in the drive the client is bound to. `path` may also be an item reference
+// (see ItemIDPath), which is addressed relative to the referenced item.
+func (c *Client) pathURL(path string) string {
+	if itemID, rest, ok := SplitItemIDPath(path); ok {
+		if rest == "" {
+			return c.itemURL(itemID)
+		}
+		return c.itemURL(itemID) + ":/" + rest
+	}
+	return buildPathURL(c.driveURL(), path)
+}
+
 // BuildPathURL constructs the full Microsoft Graph API URL for a given item path
-// within the user's default OneDrive (me/drive).
+// within the user's default OneDrive (me/drive) in the global Microsoft cloud.
+// Clients build their URLs against their own endpoints and, once bound to
+// another drive with WithDrive, against that drive instead.
 // It handles encoding and correct formatting for root and nested paths.
 //
 // Example:
@@ -238,16 +356,21 @@ func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
 //	BuildPathURL("/") -> "https://graph.microsoft.com/v1.0/me/drive/root"
 //	BuildPathURL("/Documents/MyFile.docx") -> "https://graph.microsoft.com/v1.0/me/drive/root:/Documents/MyFile.docx"
 func BuildPathURL(path string) string {
-	// For the root of the drive, the path is simply "me/drive/root".
+	return buildPathURL(DefaultEndpoints().GraphURL+"me/drive", path)
+}
+
+// buildPathURL constructs the URL of `path` below the drive at `driveURL`.
+func buildPathURL(driveURL, path string) string {
+	// For the root of the drive, the path is simply "<drive>/root".
 	if path == "" || path == "/" {
-		return customRootURL + "me/drive/root"
+		return driveURL + "/root"
 	}
 	// For other paths, they are relative to the root and require URI encoding.
-	// The format is "me/drive/root:/<encoded_path>".
+	// The format is "<drive>/root:/<encoded_path>".
 	encodedPath := strings.TrimPrefix(path, "/") // Ensure no leading slash before encoding.
 	// Note: url.PathEscape is not used here as Graph API expects certain characters like ':' to be unescaped in this segment.
 	// The path itself should be correctly formed by the caller if it contains special characters needing specific encoding.
-	return customRootURL + "me/drive/root:/" + encodedPath
+	return driveURL + "/root:/" + encodedPath
 }
 
 // GetMe retrieves the profile of the currently signed-in user.
@@ -264,7 +387,7 @@ func (c *Client) GetMe(ctx context.Context) (User, error) {
 	c.logger.Debug("GetMe called")
 	var user User
 
-	url := customRootURL + "me" // Endpoint for the current user's profile.
+	url := c.endpoints.GraphURL + "me" // Endpoint for the current user's profile.
 	res, err := c.apiCall(ctx, "GET", url, "", nil)
 	if err != nil {
 		return user, err // Error from apiCall (network, auth, or API error).
@@ -279,6 +402,7 @@ func (c *Client) GetMe(ctx context.Context) (User, error) {
 }
 
 // GetSharedWithMe retrieves a list of drive items that have been shared with the current user.
+// It is scoped to the signed-in user and ignores the drive selected with WithDrive.
 // This includes items shared by others that appear in the user's "Shared with me" view in OneDrive.
 //
 // Example:
@@ -295,7 +419,7 @@ func (c *Client) GetSharedWithMe(ctx context.Context) (DriveItemList, error) {
 	var items DriveItemList
 
 	// Endpoint for items shared with the current user.
-	url := customRootURL + "me/drive/sharedWithMe"
+	url := c.endpoints.GraphURL + "me/drive/sharedWithMe"
 	res, err := c.apiCall(ctx, "GET", url, "", nil)
 	if err != nil {
 		return items, err
@@ -310,6 +434,7 @@ func (c *Client) GetSharedWithMe(ctx context.Context) (DriveItemList, error) {
 }
 
 // GetRecentItems retrieves a list of drive items that have been recently accessed by the current user.
+// It is scoped to the signed-in user and ignores the drive selected with WithDrive.
 // This corresponds to the "Recent" view in OneDrive.
 //
 // Example:
@@ -326,7 +451,7 @@ func (c *Client) GetRecentItems(ctx context.Context) (DriveItemList, error) {
 	var items DriveItemList
 
 	// Endpoint for recently accessed items.
-	url := customRootURL + "me/drive/recent"
+	url := c.endpoints.GraphURL + "me/drive/recent"
 	res, err := c.apiCall(ctx, "GET", url, "", nil)
 	if err != nil {
 		return items, err
@@ -341,7 +466,8 @@ func (c *Client) GetRecentItems(ctx context.Context) (DriveItemList, error) {
 }
 
 // GetSpecialFolder retrieves a specific "special" folder for the user, such as Documents, Photos, etc.
-// Special folders are well-known folders that OneDrive provisions for users.
+// Special folders are well-known folders that OneDrive provisions for users, so they are
+// always looked up in the user's own drive, regardless of WithDrive.
 // Valid folder names include: "documents", "photos", "cameraroll", "approot", "music", "desktop", "downloads", "videos".
 //
 // Example:
@@ -356,7 +482,7 @@ func (c *Client) GetSpecialFolder(ctx context.Context, folderName string) (Drive
 	var item DriveItem
 
 	// Endpoint for special folders, requires URL path escaping for the folder name.
-	url := customRootURL + "me/drive/special/" + url.PathEscape(folderName)
+	url := c.endpoints.GraphURL + "me/drive/special/" + url.PathEscape(folderName)
 
 	err := c.makeAPICallAndDecode(ctx, "GET", url, "", nil, &item, fmt.Sprintf("special folder '%s'", folderName))
 	if err != nil {
@@ -383,21 +509,21 @@ func (c *Client) GetSpecialFolder(ctx context.Context, folderName string) (Drive
 //
 // Example (subsequent call):
 //
-//	deltaResponse, err = client.GetDelta(context.Background(), extractTokenFromDeltaLink(nextToken))
+//	deltaResponse, err = client.GetDelta(context.Background(), ExtractDeltaToken(nextToken))
 //	// Process changed items...
 //	nextToken = deltaResponse.DeltaLink
 func (c *Client) GetDelta(ctx context.Context, deltaToken string) (DeltaResponse, error) {
 	c.logger.Debugf("GetDelta called with token: %s", deltaToken)
 	var deltaResponse DeltaResponse
 
-	url := customRootURL + "me/drive/root/delta"
+	deltaURL := c.driveURL() + "/root/delta"
 	if deltaToken != "" {
 		// The deltaToken is the opaque token part of the @odata.deltaLink.
-		// It should not be the full deltaLink URL.
-		url += "?token=" + deltaToken
+		// It should not be the full deltaLink URL (see ExtractDeltaToken).
+		deltaURL += "?token=" + url.QueryEscape(deltaToken)
 	}
 
-	res, err := c.apiCall(ctx, "GET", url, "", nil)
+	res, err := c.apiCall(ctx, "GET", deltaURL, "", nil)
 	if err != nil {
 		return deltaResponse, err
 	}
@@ -410,6 +536,26 @@ func (c *Client) GetDelta(ctx context.Context, deltaToken string) (DeltaResponse
 	return deltaResponse, nil
 }
 
+// ExtractDeltaToken returns the opaque `token` query parameter from an
+// @odata.deltaLink or @odata.nextLink URL returned by a delta query, in the form
+// expected by GetDelta. If the link cannot be parsed or carries no token,
+// an empty string is returned, which GetDelta treats as a fresh enumeration.
+//
+// Example:
+//
+//	token := ExtractDeltaToken(deltaResponse.NextLink)
+//	nextPage, err := client.GetDelta(context.Background(), token)
+func ExtractDeltaToken(link string) string {
+	if link == "" {
+		return ""
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return parsed.Query().Get("token")
+}
+
 // GetFileVersions retrieves all versions of a specific file by its path.
 // This allows access to the version history of a file if versioning is enabled on the drive.
 // Note: This first resolves the file path to an item ID.
@@ -427,15 +573,20 @@ func (c *Client) GetFileVersions(ctx context.Context, filePath string) (DriveIte
 
 	// First, get the DriveItem to resolve its ID from the path.
 	// This is necessary because the versions endpoint requires an item ID.
-	item, err := c.GetDriveItemByPath(ctx, filePath)
-	if err != nil {
-		return versions, fmt.Errorf("getting file item ID for versions: %w", err)
-	}
-	if item.Folder != nil {
-		return versions, fmt.Errorf("cannot get versions for a folder: %s", filePath)
+	// An item reference already carries the ID, and the API rejects folders itself.
+	itemID, ok := bareItemID(filePath)
+	if !ok {
+		item, err := c.GetDriveItemByPath(ctx, filePath)
+		if err != nil {
+			return versions, fmt.Errorf("getting file item ID for versions: %w", err)
+		}
+		if item.Folder != nil {
+			return versions, fmt.Errorf("cannot get versions for a folder: %s", filePath)
+		}
+		itemID = item.ID
 	}
 
-	url := customRootURL + "me/drive/items/" + item.ID + "/versions"
+	url := c.itemURL(itemID) + "/versions"
 	res, err := c.apiCall(ctx, "GET", url, "", nil)
 	if err != nil {
 		return versions, err
@@ -457,6 +608,12 @@ func (c *Client) GetFileVersions(ctx context.Context, filePath string) (DriveIte
 // Using json.RawMessage helps in deferring the unmarshaling of individual items, which can be
 // memory-efficient if the caller processes items one by one or needs different parts of the JSON.
 func (c *Client) collectAllPages(ctx context.Context, initialURL string, paging Paging) ([]json.RawMessage, string, error) {
+	return c.collectPages(ctx, initialURL, nil, paging)
+}
+
+// collectPages is collectAllPages with extra request headers `header`, which
+// are sent with every page request.
+func (c *Client) collectPages(ctx context.Context, initialURL string, header http.Header, paging Paging) ([]json.RawMessage, string, error) {
 	var allItems []json.RawMessage
 	currentURL := initialURL
 
@@ -477,7 +634,7 @@ func (c *Client) collectAllPages(ctx context.Context, initialURL string, paging
 			currentURL += fmt.Sprintf("%s$top=%d", separator, paging.Top)
 		}
 
-		res, err := c.apiCall(ctx, "GET", currentURL, "", nil)
+		res, err := c.apiCallWithHeader(ctx, "GET", currentURL, header, nil)
 		if err != nil {
 			return allItems, "", err
 		}
@@ -520,6 +677,15 @@ func (c *Client) collectAllPages(ctx context.Context, initialURL string, paging
 //
 // This function is fundamental to the SDK's operation.
 func (c *Client) apiCall(ctx context.Context, method, url, contentType string, body io.ReadSeeker) (*http.Response, error) {
+	var header http.Header
+	if contentType != "" {
+		header = http.Header{"Content-Type": {contentType}}
+	}
+	return c.apiCallWithHeader(ctx, method, url, header, body)
+}
+
+// apiCallWithHeader is apiCall with arbitrary request headers `header`.
+func (c *Client) apiCallWithHeader(ctx context.Context, method, url string, header http.Header, body io.ReadSeeker) (*http.Response, error) {
 	maxRetries := c.httpConfig.RetryAttempts
 	retryDelay := c.httpConfig.RetryDelay
 	maxRetryDelay := c.httpConfig.MaxRetryDelay
@@ -535,9 +701,9 @@ func (c *Client) apiCall(ctx context.Context, method, url, contentType string, b
 			return nil, fmt.Errorf("creating request: %w", err)
 		}
 
-		// Set content type if provided
-		if contentType != "" {
-			req.Header.Set("Content-Type", contentType)
+		// Set the headers, such as the content type, if provided
+		for name, values := range header {
+			req.Header[http.CanonicalHeaderKey(name)] = values
 		}
 
 		c.logger.Debug("Request created, sending request...")
@@ -646,6 +812,8 @@ func (c *Client) handleNonRetryableStatus(res *http.Response, url string) error
 		return fmt.Errorf("%w: received %d Not Found from %s", ErrResourceNotFound, StatusNotFound, url)
 	case StatusConflict:
 		return fmt.Errorf("%w: received %d Conflict from %s", ErrConflict, StatusConflict, url)
+	case StatusGone:
+		return fmt.Errorf("%w: received %d Gone from %s", ErrSyncStateExpired, StatusGone, url)
 	case StatusPayloadTooLarge:
 		return fmt.Errorf("%w: received %d Payload Too Large from %s", ErrQuotaExceeded, StatusPayloadTooLarge, url)
 	case StatusInsufficientStorage:
@@ -669,6 +837,8 @@ func getStatusDescription(statusCode int) string {
 		return "not found"
 	case StatusConflict:
 		return "conflict"
+	case StatusGone:
+		return "gone"
 	case StatusPayloadTooLarge:
 		return "payload too large"
 	case StatusInsufficientStorage:
@@ -699,4 +869,6 @@ var (
 	ErrDecodingFailed        = errors.New("response decoding failed")                 // JSON/response decoding failed.
 	ErrNetworkFailed         = errors.New("network operation failed")                 // Network-level failure.
 	ErrOperationFailed       = errors.New("operation failed")                         // General operation failure.
+	ErrSyncStateExpired      = errors.New("delta token expired, resync required")     // The server no longer accepts a delta token.
+	ErrDownloadURLExpired    = errors.New("download URL expired")                     // A pre-authenticated download URL is no longer valid.
 )
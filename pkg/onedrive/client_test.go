@@ -8,11 +8,15 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tonimelisma/onedrive-client/internal/logger"
 )
 
@@ -321,16 +325,12 @@ func TestErrorSentinelWrapping(t *testing.T) {
 
 			ctx := context.Background()
 			token := &Token{AccessToken: "test-token"}
-			client := NewClient(ctx, token, "test-client-id", nil, &logger.NoopLogger{})
+			// Point the client's Graph endpoint at our test server
+			client := NewClient(ctx, token, "test-client-id", nil, &logger.NoopLogger{}, WithEndpoints(Endpoints{GraphURL: server.URL + "/"}))
 
 			// Override the httpClient to use our test server
 			client.httpClient = &http.Client{}
 
-			// Replace the root URL to point to our test server
-			originalRootURL := customRootURL
-			customRootURL = server.URL + "/"
-			defer func() { customRootURL = originalRootURL }()
-
 			_, err := client.GetMe(ctx)
 			assert.Error(t, err)
 			assert.True(t, errors.Is(err, tt.expectedSentinel),
@@ -356,6 +356,8 @@ func TestAllErrorSentinels(t *testing.T) {
 		ErrDecodingFailed,
 		ErrNetworkFailed,
 		ErrOperationFailed,
+		ErrSyncStateExpired,
+		ErrDownloadURLExpired,
 	}
 
 	for _, sentinel := range sentinels {
@@ -638,6 +640,7 @@ func TestErrorHandlingRefactoring(t *testing.T) {
 		{"Forbidden", 403, ErrAccessDenied},
 		{"Not Found", 404, ErrResourceNotFound},
 		{"Conflict", 409, ErrConflict},
+		{"Gone", 410, ErrSyncStateExpired},
 		{"Too Many Requests", 429, ErrRetryLater},
 		{"Quota Exceeded", 507, ErrQuotaExceeded},
 		{"Bad Request", 400, ErrInvalidRequest},
@@ -663,3 +666,240 @@ func TestErrorHandlingRefactoring(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractDeltaToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     string
+		expected string
+	}{
+		{
+			name:     "delta link",
+			link:     "https://graph.microsoft.com/v1.0/me/drive/root/delta?token=aTE09NjM4",
+			expected: "aTE09NjM4",
+		},
+		{
+			name:     "next link with encoded token",
+			link:     "https://graph.microsoft.com/v1.0/me/drive/root/delta?token=abc%3D%3D&$top=200",
+			expected: "abc==",
+		},
+		{name: "no token", link: "https://graph.microsoft.com/v1.0/me/drive/root/delta", expected: ""},
+		{name: "empty", link: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExtractDeltaToken(tt.link))
+		})
+	}
+}
+
+func TestDownloadFileChunk(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/expired":
+			w.WriteHeader(http.StatusForbidden)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("boom"))
+		default:
+			assert.Equal(t, "bytes=2-4", r.Header.Get("Range"))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("llo"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(context.Background(), &Token{AccessToken: "test-token"}, "test-client-id", nil, &logger.NoopLogger{})
+	client.httpClient = &http.Client{}
+
+	body, err := client.DownloadFileChunk(context.Background(), server.URL+"/file", 2, 4)
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	assert.Equal(t, "llo", string(data))
+
+	_, err = client.DownloadFileChunk(context.Background(), server.URL+"/expired", 0, 1)
+	assert.ErrorIs(t, err, ErrDownloadURLExpired)
+
+	_, err = client.DownloadFileChunk(context.Background(), server.URL+"/broken", 0, 1)
+	assert.ErrorContains(t, err, "unexpected status code 500")
+	assert.ErrorContains(t, err, "boom")
+}
+
+func TestDownloadFileParallel(t *testing.T) {
+	content := make([]byte, ParallelDownloadPartSize*2+ParallelDownloadPartSize/2)
+	for i := range content {
+		content[i] = byte(i % 253)
+	}
+	h := NewQuickXorHash()
+	h.Write(content)
+	quickXor := EncodeQuickXorHash(h.Sum(nil))
+
+	var mu sync.Mutex
+	urls := 0
+	failedOnce := false
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/me/drive/root:/big.bin":
+			urls++
+			fmt.Fprintf(w, `{"name":"big.bin","size":%d,"cTag":"c1","file":{"hashes":{"quickXorHash":"%s"}},"@microsoft.graph.downloadUrl":"%s/content/%d"}`,
+				len(content), quickXor, server.URL, urls)
+		case r.URL.Path == "/content/1":
+			w.WriteHeader(http.StatusForbidden) // The first URL has already expired.
+		case strings.HasPrefix(r.URL.Path, "/content/"):
+			var start, end int
+			_, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			require.NoError(t, err)
+			if start == ParallelDownloadPartSize && !failedOnce {
+				failedOnce = true
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[start : end+1])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(context.Background(), &Token{AccessToken: "test-token"}, "test-client-id", nil, &logger.NoopLogger{},
+		WithEndpoints(Endpoints{GraphURL: server.URL + "/"}), WithFilePermissions(0o600))
+	client.httpClient = &http.Client{}
+
+	local := filepath.Join(t.TempDir(), "big.bin")
+	var last int64
+	err := client.DownloadFileParallel(context.Background(), "/big.bin", local, 3, func(completed, total int64) {
+		assert.GreaterOrEqual(t, completed, last)
+		assert.Equal(t, int64(len(content)), total)
+		last = completed
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), last)
+	assert.True(t, failedOnce)
+	assert.Equal(t, 2, urls, "the expired URL should be refreshed exactly once")
+
+	got, err := os.ReadFile(local)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(content, got), "downloaded content differs")
+	assert.NoFileExists(t, local+PartialDownloadSuffix)
+	info, err := os.Stat(local)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the file has the configured permissions")
+}
+
+func TestDownloadFileParallelKeepsExistingFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnRange := false
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/me/drive/root:/big.bin" {
+			fmt.Fprintf(w, `{"name":"big.bin","size":4,"file":{"hashes":{"quickXorHash":"AAAAAAAAAAAAAAAAAAAAAAAAAAA="}},"@microsoft.graph.downloadUrl":"%s/content"}`, server.URL)
+			return
+		}
+		if cancelOnRange {
+			cancel()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("abcd")) // Does not match the hash.
+	}))
+	defer server.Close()
+
+	client := NewClient(context.Background(), &Token{AccessToken: "test-token"}, "test-client-id", nil, &logger.NoopLogger{}, WithEndpoints(Endpoints{GraphURL: server.URL + "/"}))
+	client.httpClient = &http.Client{}
+
+	local := filepath.Join(t.TempDir(), "big.bin")
+	require.NoError(t, os.WriteFile(local, []byte("keep me"), 0o600))
+
+	err := client.DownloadFileParallel(ctx, "/big.bin", local, 2, nil)
+	require.ErrorIs(t, err, ErrContentMismatch)
+	got, err := os.ReadFile(local)
+	require.NoError(t, err)
+	assert.Equal(t, "keep me", string(got))
+	assert.NoFileExists(t, local+PartialDownloadSuffix)
+
+	// A download cancelled midway leaves the file untouched as well.
+	cancelOnRange = true
+	require.ErrorIs(t, client.DownloadFileParallel(ctx, "/big.bin", local, 2, nil), context.Canceled)
+	got, err = os.ReadFile(local)
+	require.NoError(t, err)
+	assert.Equal(t, "keep me", string(got))
+	assert.NoFileExists(t, local+PartialDownloadSuffix)
+}
+
+func TestGetDriveItemChildrenByPathPages(t *testing.T) {
+	var requested []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RequestURI())
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skiptoken") == "" {
+			fmt.Fprintf(w, `{"value":[{"name":"a.txt"},{"name":"b.txt"}],"@odata.nextLink":"%s/me/drive/root:/Big:/children?$skiptoken=page2"}`, server.URL)
+			return
+		}
+		w.Write([]byte(`{"value":[{"name":"c.txt"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(context.Background(), &Token{AccessToken: "test-token"}, "test-client-id", nil, &logger.NoopLogger{}, WithEndpoints(Endpoints{GraphURL: server.URL + "/"}))
+	client.httpClient = &http.Client{}
+
+	children, err := client.GetDriveItemChildrenByPath(context.Background(), "/Big")
+	require.NoError(t, err)
+	var names []string
+	for _, child := range children.Value {
+		names = append(names, child.Name)
+	}
+	assert.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, names)
+	assert.Equal(t, []string{"/me/drive/root:/Big:/children", "/me/drive/root:/Big:/children?$skiptoken=page2"}, requested)
+}
+
+func TestWithDrive(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"item1","name":"Report.docx","value":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(context.Background(), &Token{AccessToken: "test-token"}, "test-client-id", nil, &logger.NoopLogger{}, WithEndpoints(Endpoints{GraphURL: server.URL + "/"}))
+	client.httpClient = &http.Client{}
+	library := client.WithDrive("b!abc-DEF_1")
+
+	assert.Equal(t, "", client.DriveID())
+	assert.Equal(t, "b!abc-DEF_1", library.DriveID())
+	assert.Equal(t, "", library.WithDrive("").DriveID())
+
+	ctx := context.Background()
+	_, err := library.GetDriveItemByPath(ctx, "/Shared Documents/Report.docx")
+	require.NoError(t, err)
+	_, err = library.GetDriveItemChildrenByPath(ctx, "/")
+	require.NoError(t, err)
+	_, err = library.GetDefaultDrive(ctx)
+	require.NoError(t, err)
+	_, err = library.GetDelta(ctx, "")
+	require.NoError(t, err)
+	_, err = library.GetFileVersions(ctx, "/Report.docx")
+	require.NoError(t, err)
+	_, err = client.GetDriveItemByPath(ctx, "/Report.docx")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"GET /drives/b!abc-DEF_1/root:/Shared Documents/Report.docx",
+		"GET /drives/b!abc-DEF_1/root/children",
+		"GET /drives/b!abc-DEF_1",
+		"GET /drives/b!abc-DEF_1/root/delta",
+		"GET /drives/b!abc-DEF_1/root:/Report.docx",
+		"GET /drives/b!abc-DEF_1/items/item1/versions",
+		"GET /me/drive/root:/Report.docx",
+	}, requested)
+}
@@ -0,0 +1,157 @@
+// Package onedrive (cloud.go) describes the Microsoft national clouds and the
+// OAuth2 scopes the SDK can request. Each cloud has its own sign-in
+// (authority) host and Microsoft Graph host; together with a tenant they
+// determine the Endpoints a client talks to. Endpoints and scopes belong to
+// each Client (see WithEndpoints) and each sign-in (see AuthConfig), so
+// clients for different clouds and tenants can be used side by side.
+package onedrive
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultTenant is the tenant used when none is configured: it accepts both
+// personal Microsoft accounts and work or school accounts.
+const DefaultTenant = "common"
+
+// Cloud identifies a Microsoft cloud by its sign-in and Microsoft Graph hosts.
+type Cloud struct {
+	Name     string // Short name, as used in configuration files.
+	LoginURL string // Base URL of the Microsoft identity platform, without a trailing slash.
+	GraphURL string // Base URL of Microsoft Graph, without a trailing slash.
+}
+
+// The Microsoft clouds known to the SDK.
+var (
+	CloudGlobal   = Cloud{Name: "global", LoginURL: "https://login.microsoftonline.com", GraphURL: "https://graph.microsoft.com"}
+	CloudUSGov    = Cloud{Name: "usgov", LoginURL: "https://login.microsoftonline.us", GraphURL: "https://graph.microsoft.us"}
+	CloudUSGovDoD = Cloud{Name: "usgov-dod", LoginURL: "https://login.microsoftonline.us", GraphURL: "https://dod-graph.microsoft.us"}
+	CloudChina    = Cloud{Name: "china", LoginURL: "https://login.chinacloudapi.cn", GraphURL: "https://microsoftgraph.chinacloudapi.cn"}
+)
+
+// clouds indexes the known clouds by name.
+var clouds = map[string]Cloud{
+	CloudGlobal.Name:   CloudGlobal,
+	CloudUSGov.Name:    CloudUSGov,
+	CloudUSGovDoD.Name: CloudUSGovDoD,
+	CloudChina.Name:    CloudChina,
+}
+
+// LookupCloud returns the cloud called `name`. An empty name selects CloudGlobal.
+func LookupCloud(name string) (Cloud, error) {
+	if name == "" {
+		return CloudGlobal, nil
+	}
+	cloud, ok := clouds[strings.ToLower(name)]
+	if !ok {
+		return Cloud{}, fmt.Errorf("%w: unknown cloud '%s' (known clouds: %s)", ErrInvalidRequest, name, strings.Join(CloudNames(), ", "))
+	}
+	return cloud, nil
+}
+
+// CloudNames returns the names of the known clouds, sorted.
+func CloudNames() []string {
+	names := make([]string, 0, len(clouds))
+	for name := range clouds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Endpoints holds the URLs of the OAuth2 and Microsoft Graph endpoints.
+type Endpoints struct {
+	AuthURL   string `json:"auth_url,omitempty"`   // OAuth2 authorization endpoint.
+	TokenURL  string `json:"token_url,omitempty"`  // OAuth2 token endpoint.
+	DeviceURL string `json:"device_url,omitempty"` // OAuth2 device authorization endpoint.
+	GraphURL  string `json:"graph_url,omitempty"`  // Microsoft Graph API root, with a trailing slash.
+}
+
+// Endpoints returns the endpoints of the cloud for `tenant`, which is
+// "common", "organizations", "consumers", a tenant ID or a verified domain.
+// An empty tenant selects DefaultTenant.
+func (c Cloud) Endpoints(tenant string) Endpoints {
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	authority := c.LoginURL + "/" + url.PathEscape(tenant) + "/oauth2/v2.0/"
+	return Endpoints{
+		AuthURL:   authority + "authorize",
+		TokenURL:  authority + "token",
+		DeviceURL: authority + "devicecode",
+		GraphURL:  c.GraphURL + "/v1.0/",
+	}
+}
+
+// DefaultEndpoints returns the endpoints of CloudGlobal for DefaultTenant.
+func DefaultEndpoints() Endpoints {
+	return CloudGlobal.Endpoints(DefaultTenant)
+}
+
+// Override returns the endpoints with the non-empty fields of `override`
+// replacing their counterparts.
+func (e Endpoints) Override(override Endpoints) Endpoints {
+	if override.AuthURL != "" {
+		e.AuthURL = override.AuthURL
+	}
+	if override.TokenURL != "" {
+		e.TokenURL = override.TokenURL
+	}
+	if override.DeviceURL != "" {
+		e.DeviceURL = override.DeviceURL
+	}
+	if override.GraphURL != "" {
+		e.GraphURL = override.GraphURL
+	}
+	return e
+}
+
+// withDefaults returns the endpoints with empty fields taken from
+// DefaultEndpoints.
+func (e Endpoints) withDefaults() Endpoints {
+	return DefaultEndpoints().Override(e)
+}
+
+// AuthConfig identifies the application that signs in, the endpoints it signs
+// in at and the scopes it requests. It is passed to the sign-in functions,
+// such as InitiateDeviceCodeFlow and GetOauth2Config. Empty Endpoints fields
+// select DefaultEndpoints and empty Scopes select DefaultScopes.
+type AuthConfig struct {
+	ClientID  string    // Application (client) ID of the app registration.
+	Endpoints Endpoints // Sign-in endpoints; only the OAuth2 URLs are used.
+	Scopes    []string  // Scopes to request.
+}
+
+// withDefaults returns the configuration with empty settings defaulted.
+func (a AuthConfig) withDefaults() AuthConfig {
+	a.Endpoints = a.Endpoints.withDefaults()
+	if len(a.Scopes) == 0 {
+		a.Scopes = DefaultScopes()
+	}
+	return a
+}
+
+// DefaultScopes returns the scopes requested by default: read and write
+// access to all files the user can access, and the user's profile.
+func DefaultScopes() []string {
+	return []string{"offline_access", "files.readwrite.all", "user.read", "email", "openid", "profile"}
+}
+
+// ReadOnlyScopes returns the scopes of read-only mode, which grant read
+// access to files only. Write operations fail with ErrAccessDenied.
+func ReadOnlyScopes() []string {
+	return []string{"offline_access", "files.read.all", "user.read", "email", "openid", "profile"}
+}
+
+// appOnlyScope returns the scope requested by the Client Credentials Grant:
+// all application permissions granted to the app registration for the
+// Microsoft Graph instance at `graphURL`.
+func appOnlyScope(graphURL string) string {
+	if u, err := url.Parse(graphURL); err == nil && u.Host != "" {
+		return u.Scheme + "://" + u.Host + "/.default"
+	}
+	return CloudGlobal.GraphURL + "/.default"
+}
@@ -0,0 +1,90 @@
+package onedrive
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCloud(t *testing.T) {
+	cloud, err := LookupCloud("")
+	require.NoError(t, err)
+	assert.Equal(t, CloudGlobal, cloud)
+
+	cloud, err = LookupCloud("USGov")
+	require.NoError(t, err)
+	assert.Equal(t, CloudUSGov, cloud)
+
+	_, err = LookupCloud("moon")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, []string{"china", "global", "usgov", "usgov-dod"}, CloudNames())
+}
+
+func TestCloudEndpoints(t *testing.T) {
+	assert.Equal(t, Endpoints{
+		AuthURL:   "https://login.microsoftonline.com/common/oauth2/v2.0/authorize",
+		TokenURL:  "https://login.microsoftonline.com/common/oauth2/v2.0/token",
+		DeviceURL: "https://login.microsoftonline.com/common/oauth2/v2.0/devicecode",
+		GraphURL:  "https://graph.microsoft.com/v1.0/",
+	}, DefaultEndpoints())
+
+	china := CloudChina.Endpoints("contoso.partner.onmschina.cn")
+	assert.Equal(t, "https://login.chinacloudapi.cn/contoso.partner.onmschina.cn/oauth2/v2.0/token", china.TokenURL)
+	assert.Equal(t, "https://microsoftgraph.chinacloudapi.cn/v1.0/", china.GraphURL)
+
+	dod := CloudUSGovDoD.Endpoints("organizations")
+	assert.Equal(t, "https://login.microsoftonline.us/organizations/oauth2/v2.0/devicecode", dod.DeviceURL)
+	assert.Equal(t, "https://dod-graph.microsoft.us/v1.0/", dod.GraphURL)
+}
+
+func TestAuthConfigDefaults(t *testing.T) {
+	_, config := GetOauth2Config(AuthConfig{ClientID: "client"})
+	assert.Equal(t, DefaultScopes(), config.Scopes)
+	assert.Equal(t, DefaultEndpoints().TokenURL, config.Endpoint.TokenURL)
+
+	_, config = GetOauth2Config(AuthConfig{
+		ClientID:  "client",
+		Endpoints: CloudChina.Endpoints("organizations"),
+		Scopes:    ReadOnlyScopes(),
+	})
+	assert.Equal(t, ReadOnlyScopes(), config.Scopes)
+	assert.Equal(t, "https://login.chinacloudapi.cn/organizations/oauth2/v2.0/authorize", config.Endpoint.AuthURL)
+}
+
+func TestEndpointsOverride(t *testing.T) {
+	endpoints := CloudUSGov.Endpoints("").Override(Endpoints{GraphURL: "http://localhost:8080/"})
+	assert.Equal(t, "https://login.microsoftonline.us/common/oauth2/v2.0/token", endpoints.TokenURL)
+	assert.Equal(t, "http://localhost:8080/", endpoints.GraphURL)
+}
+
+func TestClientEndpointsAreIndependent(t *testing.T) {
+	var global, china []string
+	globalServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		global = append(global, r.URL.Path)
+		w.Write([]byte(`{"id":"a"}`))
+	}))
+	defer globalServer.Close()
+	chinaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		china = append(china, r.URL.Path)
+		w.Write([]byte(`{"id":"b"}`))
+	}))
+	defer chinaServer.Close()
+
+	ctx := context.Background()
+	token := &Token{AccessToken: "test-token"}
+	first := NewClient(ctx, token, "client", nil, nil, WithEndpoints(Endpoints{GraphURL: globalServer.URL + "/"}))
+	second := NewClient(ctx, token, "client", nil, nil, WithEndpoints(Endpoints{GraphURL: chinaServer.URL + "/v1.0/"}))
+	first.httpClient, second.httpClient = &http.Client{}, &http.Client{}
+
+	_, err := first.GetMe(ctx)
+	require.NoError(t, err)
+	_, err = second.GetMe(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/me"}, global)
+	assert.Equal(t, []string{"/v1.0/me"}, china)
+	assert.Equal(t, chinaServer.URL+"/v1.0/", second.WithDrive("d").Endpoints().GraphURL)
+}
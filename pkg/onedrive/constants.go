@@ -14,6 +14,7 @@ const (
 	StatusForbidden           = 403
 	StatusNotFound            = 404
 	StatusConflict            = 409
+	StatusGone                = 410
 	StatusPayloadTooLarge     = 413
 	StatusTooManyRequests     = 429
 	StatusServiceUnavailable  = 503
@@ -97,6 +98,13 @@ const (
 	DefaultProgressInterval = 100 * time.Millisecond // Progress update frequency
 )
 
+// Parallel Download Constants
+const (
+	ParallelDownloadPartSize   = 8 * 1024 * 1024     // 8MB byte range fetched per request
+	ParallelDownloadMaxRetries = 3                   // Attempts per byte range before giving up
+	PartialDownloadSuffix      = ".onedrive-partial" // Suffix of files still being downloaded, next to their final path
+)
+
 // Time Format Constants
 const (
 	StandardTimeFormat = "2006-01-02 15:04"
@@ -22,6 +22,7 @@ func TestHTTPStatusConstants(t *testing.T) {
 		{"StatusForbidden", StatusForbidden, 403},
 		{"StatusNotFound", StatusNotFound, 404},
 		{"StatusConflict", StatusConflict, 409},
+		{"StatusGone", StatusGone, 410},
 		{"StatusPayloadTooLarge", StatusPayloadTooLarge, 413},
 		{"StatusTooManyRequests", StatusTooManyRequests, 429},
 		{"StatusServiceUnavailable", StatusServiceUnavailable, 503},
@@ -193,6 +194,8 @@ func TestBufferAndChunkConstants(t *testing.T) {
 		{"DefaultBufferSize", DefaultBufferSize, 1024},
 		{"MinFileSize", MinFileSize, 1024},
 		{"LargeFileThreshold", LargeFileThreshold, 4 * 1024 * 1024},
+		{"ParallelDownloadPartSize", ParallelDownloadPartSize, 8 * 1024 * 1024},
+		{"ParallelDownloadMaxRetries", ParallelDownloadMaxRetries, 3},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,218 @@
+// Package onedrive (credentials.go) provides non-interactive authentication
+// for headless use, such as CI pipelines: exchanging a refresh token obtained
+// elsewhere, and the OAuth2 Client Credentials Grant for app-only access with
+// a client secret or certificate. App-only tokens act as the application
+// rather than a user, so they can only address drives by ID (see WithDrive).
+package onedrive
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // The x5t header is defined as the certificate's SHA1 thumbprint.
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// clientAssertionType identifies a JWT client assertion in a token request.
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// clientAssertionLifetime is how long a certificate-signed client assertion is
+// valid. A fresh assertion is signed for every token request.
+const clientAssertionLifetime = 5 * time.Minute
+
+// ClientCredentials configures the OAuth2 Client Credentials Grant. Exactly
+// one of ClientSecret or Certificate (with its PrivateKey) must be set.
+type ClientCredentials struct {
+	TenantID     string            // Directory (tenant) ID or domain of the app registration.
+	ClientID     string            // Application (client) ID of the app registration.
+	ClientSecret string            // Client secret, if authenticating with a secret.
+	Certificate  *x509.Certificate // Certificate registered with the app, if authenticating with a certificate.
+	PrivateKey   *rsa.PrivateKey   // Private key of Certificate.
+	Endpoints    Endpoints         // Endpoints of the cloud to sign in to; empty fields select DefaultEndpoints.
+}
+
+// ParseCertificatePEM parses PEM data holding a certificate and its RSA
+// private key (PKCS#1 or PKCS#8), as exported for Microsoft Entra app
+// registrations.
+func ParseCertificatePEM(data []byte) (*x509.Certificate, *rsa.PrivateKey, error) {
+	var cert *x509.Certificate
+	var key *rsa.PrivateKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			if cert != nil {
+				continue // The first certificate is the app's; the rest form its chain.
+			}
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing certificate: %w", err)
+			}
+			cert = parsed
+		case "RSA PRIVATE KEY":
+			parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing RSA private key: %w", err)
+			}
+			key = parsed
+		case "PRIVATE KEY":
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing private key: %w", err)
+			}
+			rsaKey, ok := parsed.(*rsa.PrivateKey)
+			if !ok {
+				return nil, nil, errors.New("private key is not an RSA key")
+			}
+			key = rsaKey
+		}
+	}
+	if cert == nil || key == nil {
+		return nil, nil, fmt.Errorf("%w: PEM data must contain a certificate and its private key", ErrInvalidRequest)
+	}
+	return cert, key, nil
+}
+
+// NewClientCredentialsTokenSource returns a token source that acquires
+// app-only tokens with the Client Credentials Grant, caching each token until
+// it expires. Pass it to NewClientWithTokenSource.
+//
+// Example:
+//
+//	source, err := onedrive.NewClientCredentialsTokenSource(ctx, onedrive.ClientCredentials{
+//	    TenantID: "contoso.onmicrosoft.com", ClientID: "app-id", ClientSecret: secret,
+//	})
+//	if err != nil { log.Fatal(err) }
+//	client := onedrive.NewClientWithTokenSource(ctx, source, nil, onedrive.DefaultHTTPConfig()).WithDrive(driveID)
+//
+// For a national cloud, set Endpoints and pass the same endpoints to the
+// client with WithEndpoints.
+func NewClientCredentialsTokenSource(ctx context.Context, creds ClientCredentials) (oauth2.TokenSource, error) {
+	if creds.TenantID == "" || creds.ClientID == "" {
+		return nil, fmt.Errorf("%w: client credentials require a tenant ID and a client ID", ErrInvalidRequest)
+	}
+	if (creds.ClientSecret == "") == (creds.Certificate == nil) {
+		return nil, fmt.Errorf("%w: client credentials require either a client secret or a certificate", ErrInvalidRequest)
+	}
+	if creds.Certificate != nil && creds.PrivateKey == nil {
+		return nil, fmt.Errorf("%w: client certificate has no private key", ErrInvalidRequest)
+	}
+	return oauth2.ReuseTokenSource(nil, &clientCredentialsSource{ctx: ctx, creds: creds}), nil
+}
+
+// clientCredentialsSource requests a new app-only token on every call. It
+// builds the request afresh each time so that certificate-signed assertions
+// are never stale.
+type clientCredentialsSource struct {
+	ctx   context.Context
+	creds ClientCredentials
+}
+
+// Token requests an app-only token from the tenant's token endpoint.
+func (s *clientCredentialsSource) Token() (*oauth2.Token, error) {
+	endpoints := s.creds.Endpoints.withDefaults()
+	config := &clientcredentials.Config{
+		ClientID:     s.creds.ClientID,
+		ClientSecret: s.creds.ClientSecret,
+		TokenURL:     tenantTokenURL(endpoints.TokenURL, s.creds.TenantID),
+		Scopes:       []string{appOnlyScope(endpoints.GraphURL)},
+		AuthStyle:    oauth2.AuthStyleInParams,
+	}
+	if s.creds.Certificate != nil {
+		assertion, err := signClientAssertion(s.creds, config.TokenURL)
+		if err != nil {
+			return nil, err
+		}
+		config.EndpointParams = url.Values{
+			"client_assertion_type": {clientAssertionType},
+			"client_assertion":      {assertion},
+		}
+	}
+	token, err := config.Token(s.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: acquiring app-only token: %w", ErrReauthRequired, err)
+	}
+	return token, nil
+}
+
+// tenantTokenURL returns the token endpoint `tokenURL` for `tenantID`. The
+// Client Credentials Grant is not available on the multi-tenant "common",
+// "organizations" and "consumers" endpoints, so those are replaced.
+func tenantTokenURL(tokenURL, tenantID string) string {
+	for _, multiTenant := range []string{DefaultTenant, "organizations", "consumers"} {
+		segment := "/" + multiTenant + "/"
+		if strings.Contains(tokenURL, segment) {
+			return strings.Replace(tokenURL, segment, "/"+url.PathEscape(tenantID)+"/", 1)
+		}
+	}
+	return tokenURL
+}
+
+// signClientAssertion returns a JWT, signed with the client certificate's
+// private key, that authenticates the application to the token endpoint
+// `audience`.
+func signClientAssertion(creds ClientCredentials, audience string) (string, error) {
+	thumbprint := sha1.Sum(creds.Certificate.Raw) //nolint:gosec // See import.
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"x5t": base64.RawURLEncoding.EncodeToString(thumbprint[:]),
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding client assertion header: %w", err)
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("generating client assertion ID: %w", err)
+	}
+	now := time.Now()
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": audience,
+		"iss": creds.ClientID,
+		"sub": creds.ClientID,
+		"jti": base64.RawURLEncoding.EncodeToString(jti),
+		"nbf": now.Unix(),
+		"exp": now.Add(clientAssertionLifetime).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding client assertion claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, creds.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing client assertion: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// RefreshAccessToken exchanges `refreshToken` for a new token of the
+// application `auth.ClientID`, as the SDK does automatically when an access
+// token expires. It lets a refresh token obtained elsewhere bootstrap a session.
+func RefreshAccessToken(ctx context.Context, auth AuthConfig, refreshToken string) (*Token, error) {
+	_, config := GetOauth2Config(auth)
+	token, err := (*oauth2.Config)(config).TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: refreshing access token: %w", ErrReauthRequired, err)
+	}
+	return (*Token)(token), nil
+}
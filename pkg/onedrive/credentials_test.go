@@ -0,0 +1,132 @@
+package onedrive
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenServer starts a token endpoint that records the last form it
+// received and answers with `accessToken`, and returns endpoints using it.
+func newTokenServer(t *testing.T, accessToken string) (Endpoints, *url.Values) {
+	t.Helper()
+	form := &url.Values{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		*form = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": accessToken, "token_type": "Bearer", "expires_in": 3600})
+	}))
+	t.Cleanup(server.Close)
+	return Endpoints{TokenURL: server.URL + "/common/oauth2/v2.0/token"}, form
+}
+
+// newTestCertificatePEM returns a self-signed certificate and its key as PEM.
+func newTestCertificatePEM(t *testing.T) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "onedrive-client test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})...,
+	)
+}
+
+func TestClientCredentialsWithSecret(t *testing.T) {
+	endpoints, form := newTokenServer(t, "app-token")
+
+	source, err := NewClientCredentialsTokenSource(context.Background(), ClientCredentials{
+		TenantID: "contoso", ClientID: "app-id", ClientSecret: "s3cret", Endpoints: endpoints,
+	})
+	require.NoError(t, err)
+	client := NewClientWithTokenSource(context.Background(), source, nil, DefaultHTTPConfig())
+
+	token, err := client.AccessToken(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "app-token", token.AccessToken)
+	assert.Equal(t, "client_credentials", form.Get("grant_type"))
+	assert.Equal(t, "app-id", form.Get("client_id"))
+	assert.Equal(t, "s3cret", form.Get("client_secret"))
+	assert.Equal(t, "https://graph.microsoft.com/.default", form.Get("scope"))
+}
+
+func TestClientCredentialsWithCertificate(t *testing.T) {
+	endpoints, form := newTokenServer(t, "app-token")
+
+	cert, key, err := ParseCertificatePEM(newTestCertificatePEM(t))
+	require.NoError(t, err)
+	source, err := NewClientCredentialsTokenSource(context.Background(), ClientCredentials{
+		TenantID: "contoso", ClientID: "app-id", Certificate: cert, PrivateKey: key, Endpoints: endpoints,
+	})
+	require.NoError(t, err)
+	token, err := source.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "app-token", token.AccessToken)
+
+	assert.Empty(t, form.Get("client_secret"))
+	assert.Equal(t, clientAssertionType, form.Get("client_assertion_type"))
+	parts := strings.Split(form.Get("client_assertion"), ".")
+	require.Len(t, parts, 3)
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal(claimsJSON, &claims))
+	assert.Equal(t, "app-id", claims["iss"])
+	assert.Equal(t, "app-id", claims["sub"])
+	// The tenant replaces "common" in the token endpoint.
+	assert.Equal(t, strings.Replace(endpoints.TokenURL, "/common/", "/contoso/", 1), claims["aud"])
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+}
+
+func TestNewClientCredentialsTokenSourceValidation(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewClientCredentialsTokenSource(ctx, ClientCredentials{ClientID: "app-id", ClientSecret: "s"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = NewClientCredentialsTokenSource(ctx, ClientCredentials{TenantID: "t", ClientID: "app-id"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	_, _, err = ParseCertificatePEM([]byte("not pem"))
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestRefreshAccessToken(t *testing.T) {
+	endpoints, form := newTokenServer(t, "fresh-token")
+
+	token, err := RefreshAccessToken(context.Background(), AuthConfig{ClientID: "test-client-id", Endpoints: endpoints}, "my-refresh-token")
+	require.NoError(t, err)
+	assert.Equal(t, "fresh-token", token.AccessToken)
+	assert.Equal(t, "refresh_token", form.Get("grant_type"))
+	assert.Equal(t, "my-refresh-token", form.Get("refresh_token"))
+}
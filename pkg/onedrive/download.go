@@ -5,12 +5,15 @@ package onedrive
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
+	"time"
 )
 
 // DownloadFile downloads a file from the specified `remotePath` in OneDrive to the given `localPath`.
@@ -32,7 +35,7 @@ import (
 //	fmt.Println("File downloaded successfully.")
 func (c *Client) DownloadFile(ctx context.Context, remotePath, localPath string) error {
 	c.logger.Debugf("DownloadFile called for remotePath: '%s', localPath: '%s'", remotePath, localPath)
-	contentURL := BuildPathURL(remotePath) + ":/content"
+	contentURL := c.actionURL(remotePath, "content")
 
 	// Create a new HTTP client that does *not* automatically follow redirects.
 	// The standard oauth2 client transport follows redirects by default. We need to
@@ -198,14 +201,256 @@ func (c *Client) DownloadFileChunk(ctx context.Context, downloadURL string, star
 
 	// For a successful range request, the server should respond with HTTP 206 Partial Content.
 	if res.StatusCode != http.StatusPartialContent {
-		closeBodySafely(res.Body, c.logger, "download file chunk error")
 		errorBody, _ := io.ReadAll(res.Body)
+		closeBodySafely(res.Body, c.logger, "download file chunk error")
+		// Pre-authenticated download URLs are short-lived; once expired they are
+		// rejected and a fresh URL must be fetched from the item metadata.
+		switch res.StatusCode {
+		case StatusUnauthorized, StatusForbidden, StatusGone:
+			return nil, fmt.Errorf("%w: received %d for chunk download (range %d-%d)", ErrDownloadURLExpired, res.StatusCode, startByte, endByte)
+		}
 		return nil, fmt.Errorf("unexpected status code %d for chunk download from '%s' (range %d-%d): %s", res.StatusCode, downloadURL, startByte, endByte, string(errorBody))
 	}
 
 	return res.Body, nil // Caller is responsible for closing the body.
 }
 
+// ProgressFunc reports the progress of a transfer as the number of bytes
+// completed so far out of the total size.
+type ProgressFunc func(completed, total int64)
+
+// DownloadFileParallel downloads the file at `remotePath` to `localPath` using up to
+// `connections` concurrent ranged requests. The file is split by its DriveItem.Size into
+// ParallelDownloadPartSize ranges, which are written into a preallocated partial file
+// next to `localPath` (named with PartialDownloadSuffix) and created with the
+// client's file permissions (see WithFilePermissions).
+// A failed range is retried on its own (up to ParallelDownloadMaxRetries attempts, resuming
+// from the last byte received), and an expired download URL is refreshed from the item
+// metadata. `progress`, if not nil, is called serially as data arrives.
+// The assembled file is checked with VerifyFile and only then renamed to `localPath`.
+// On any failure, including ErrContentMismatch, the partial file is removed and an
+// existing file at `localPath` is left untouched.
+//
+// Example:
+//
+//	err := client.DownloadFileParallel(context.Background(), "/Videos/Talk.mp4", "./Talk.mp4", 8, nil)
+//	if err != nil { log.Fatal(err) }
+func (c *Client) DownloadFileParallel(ctx context.Context, remotePath, localPath string, connections int, progress ProgressFunc) (err error) {
+	c.logger.Debugf("DownloadFileParallel called for remotePath: '%s', localPath: '%s', connections: %d", remotePath, localPath, connections)
+	if connections < 1 {
+		connections = 1
+	}
+
+	item, err := c.GetDriveItemByPath(ctx, remotePath)
+	if err != nil {
+		return fmt.Errorf("getting item metadata for '%s' to download: %w", remotePath, err)
+	}
+	if item.Folder != nil {
+		return fmt.Errorf("%w: '%s' is a folder", ErrInvalidRequest, remotePath)
+	}
+	if item.DownloadURL == "" {
+		return fmt.Errorf("item '%s' has no @microsoft.graph.downloadUrl in its metadata", remotePath)
+	}
+
+	partialPath := localPath + PartialDownloadSuffix
+	file, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, c.fileMode)
+	if err != nil {
+		return fmt.Errorf("creating local file '%s': %w", partialPath, err)
+	}
+	defer func() {
+		if file != nil {
+			if closeErr := file.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("closing local file '%s': %w", partialPath, closeErr)
+			}
+		}
+		if err != nil {
+			if removeErr := os.Remove(partialPath); removeErr != nil && !os.IsNotExist(removeErr) {
+				log.Printf("Warning: failed to remove incomplete download '%s': %v", partialPath, removeErr)
+			}
+		}
+	}()
+	// Preallocate the file so that every range can be written at its own offset.
+	if err := file.Truncate(item.Size); err != nil {
+		return fmt.Errorf("preallocating local file '%s': %w", partialPath, err)
+	}
+
+	d := &parallelDownload{
+		client:     c,
+		remotePath: remotePath,
+		url:        item.DownloadURL,
+		cTag:       item.CTag,
+		file:       file,
+		total:      item.Size,
+		progress:   progress,
+	}
+	if err := d.run(ctx, connections); err != nil {
+		return err
+	}
+	closeErr := file.Close()
+	file = nil
+	if closeErr != nil {
+		return fmt.Errorf("closing local file '%s': %w", partialPath, closeErr)
+	}
+	if err := VerifyFile(partialPath, item); err != nil {
+		return fmt.Errorf("verifying download of '%s': %w", remotePath, err)
+	}
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return fmt.Errorf("moving completed download '%s' to '%s': %w", partialPath, localPath, err)
+	}
+	return nil
+}
+
+// parallelDownload holds the state shared by the workers of DownloadFileParallel.
+type parallelDownload struct {
+	client     *Client
+	remotePath string
+	cTag       string
+	file       *os.File
+	total      int64
+	progress   ProgressFunc
+
+	urlMu sync.Mutex // Guards url.
+	url   string
+
+	mu        sync.Mutex // Guards completed and serializes progress calls.
+	completed int64
+}
+
+// run fetches all ranges with the given number of workers and returns the first error.
+func (d *parallelDownload) run(ctx context.Context, connections int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ranges := make(chan [2]int64)
+	errs := make(chan error, connections)
+	var wg sync.WaitGroup
+	for i := 0; i < connections; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range ranges {
+				if err := d.fetchRange(ctx, r[0], r[1]); err != nil {
+					errs <- err
+					cancel() // Stop the other workers; the download has failed.
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for start := int64(0); start < d.total; start += ParallelDownloadPartSize {
+		end := start + ParallelDownloadPartSize - 1
+		if end >= d.total {
+			end = d.total - 1
+		}
+		select {
+		case ranges <- [2]int64{start, end}:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ranges)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// fetchRange downloads the inclusive byte range start-end, retrying from the
+// last byte received when a request fails.
+func (d *parallelDownload) fetchRange(ctx context.Context, start, end int64) error {
+	var lastErr error
+	for attempt := 0; attempt < ParallelDownloadMaxRetries; attempt++ {
+		if attempt > 0 {
+			d.client.logger.Debugf("Retrying range %d-%d of '%s' (attempt %d): %v", start, end, d.remotePath, attempt+1, lastErr)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * DefaultRetryDelay):
+			}
+		}
+
+		d.urlMu.Lock()
+		downloadURL := d.url
+		d.urlMu.Unlock()
+
+		n, err := d.copyRange(ctx, downloadURL, start, end)
+		start += n
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		lastErr = err
+		if errors.Is(err, ErrDownloadURLExpired) {
+			if refreshErr := d.refreshURL(ctx, downloadURL); refreshErr != nil {
+				return refreshErr
+			}
+		}
+	}
+	return fmt.Errorf("downloading bytes %d-%d of '%s' after %d attempts: %w", start, end, d.remotePath, ParallelDownloadMaxRetries, lastErr)
+}
+
+// copyRange issues one ranged request and writes the response at its offset,
+// returning the number of bytes written.
+func (d *parallelDownload) copyRange(ctx context.Context, downloadURL string, start, end int64) (int64, error) {
+	body, err := d.client.DownloadFileChunk(ctx, downloadURL, start, end)
+	if err != nil {
+		return 0, err
+	}
+	defer closeBodySafely(body, d.client.logger, "parallel download range")
+
+	w := &progressWriter{d: d, w: io.NewOffsetWriter(d.file, start)}
+	want := end - start + 1
+	n, err := io.Copy(w, io.LimitReader(body, want))
+	if err == nil && n < want {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// refreshURL replaces an expired download URL with a fresh one from the item
+// metadata, unless another worker already did. It fails if the remote content
+// changed, since the ranges already written would no longer match.
+func (d *parallelDownload) refreshURL(ctx context.Context, expired string) error {
+	d.urlMu.Lock()
+	defer d.urlMu.Unlock()
+	if d.url != expired {
+		return nil
+	}
+	item, err := d.client.GetDriveItemByPath(ctx, d.remotePath)
+	if err != nil {
+		return fmt.Errorf("refreshing download URL for '%s': %w", d.remotePath, err)
+	}
+	if item.CTag != d.cTag {
+		return fmt.Errorf("%w: '%s' changed during the download", ErrConflict, d.remotePath)
+	}
+	d.url = item.DownloadURL
+	return nil
+}
+
+// progressWriter counts bytes written for a parallelDownload and reports them.
+type progressWriter struct {
+	d *parallelDownload
+	w io.Writer
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.d.mu.Lock()
+	p.d.completed += int64(n)
+	if p.d.progress != nil {
+		p.d.progress(p.d.completed, p.d.total)
+	}
+	p.d.mu.Unlock()
+	return n, err
+}
+
 // DownloadFileAsFormat downloads a file from OneDrive and converts it to a specified format
 // (e.g., from ".docx" to ".pdf"). The converted file is saved to `localPath`.
 // Not all file types and format conversions are supported by the Graph API.
@@ -219,7 +464,7 @@ func (c *Client) DownloadFileChunk(ctx context.Context, downloadURL string, star
 func (c *Client) DownloadFileAsFormat(ctx context.Context, remotePath, localPath, format string) error {
 	c.logger.Debugf("DownloadFileAsFormat called for remotePath: '%s', localPath: '%s', format: '%s'", remotePath, localPath, format)
 	// Construct the URL for format conversion: /content?format={format}
-	contentURL := BuildPathURL(remotePath) + ":/content?format=" + url.QueryEscape(format)
+	contentURL := c.actionURL(remotePath, "content") + "?format=" + url.QueryEscape(format)
 
 	// Use a client that doesn't follow redirects to capture the pre-authenticated download URL.
 	noRedirectClient := &http.Client{
@@ -27,7 +27,7 @@ func (c *Client) GetDrives(ctx context.Context) (DriveList, error) {
 	var drives DriveList
 
 	// Endpoint to list all drives accessible by the user.
-	url := customRootURL + "me/drives"
+	url := c.endpoints.GraphURL + "me/drives"
 	res, err := c.apiCall(ctx, "GET", url, "", nil)
 	if err != nil {
 		return drives, err
@@ -43,6 +43,7 @@ func (c *Client) GetDrives(ctx context.Context) (DriveList, error) {
 
 // GetDefaultDrive retrieves information about the user's default OneDrive drive.
 // This is typically the user's personal OneDrive or their primary OneDrive for Business drive.
+// A client bound to another drive with WithDrive retrieves that drive instead.
 // The returned Drive object includes details like quota information.
 //
 // Example:
@@ -54,8 +55,8 @@ func (c *Client) GetDefaultDrive(ctx context.Context) (Drive, error) {
 	c.logger.Debug("GetDefaultDrive called")
 	var drive Drive
 
-	// Endpoint for the user's default drive.
-	url := customRootURL + "me/drive"
+	// Endpoint for the user's default drive, or the drive the client is bound to.
+	url := c.driveURL()
 	res, err := c.apiCall(ctx, "GET", url, "", nil)
 	if err != nil {
 		return drive, err
@@ -84,7 +85,7 @@ func (c *Client) GetDriveByID(ctx context.Context, driveID string) (Drive, error
 	var drive Drive
 
 	// Endpoint to get a drive by its ID. The driveID needs to be URL-escaped.
-	url := customRootURL + "drives/" + url.PathEscape(driveID)
+	url := c.endpoints.GraphURL + "drives/" + url.PathEscape(driveID)
 
 	err := c.makeAPICallAndDecode(ctx, "GET", url, "", nil, &drive, fmt.Sprintf("drive info for ID '%s'", driveID))
 	if err != nil {
@@ -94,7 +95,8 @@ func (c *Client) GetDriveByID(ctx context.Context, driveID string) (Drive, error
 	return drive, nil
 }
 
-// GetDriveActivities retrieves a list of activities that have occurred on the user's default drive.
+// GetDriveActivities retrieves a list of activities that have occurred on the user's default drive,
+// or on the drive the client is bound to with WithDrive.
 // Activities can include file creation, deletion, edits, sharing, etc.
 // This method supports pagination via the `paging` parameter.
 //
@@ -115,7 +117,7 @@ func (c *Client) GetDriveActivities(ctx context.Context, paging Paging) (Activit
 	var activities ActivityList
 
 	// Base URL for drive activities.
-	baseURL := customRootURL + "me/drive/activities"
+	baseURL := c.driveURL() + "/activities"
 	var queryParams []string
 	if paging.Top > 0 {
 		queryParams = append(queryParams, fmt.Sprintf("$top=%d", paging.Top))
@@ -152,7 +154,7 @@ func (c *Client) GetDriveActivities(ctx context.Context, paging Paging) (Activit
 }
 
 // GetRootDriveItems retrieves a list of drive items (files and folders) in the root
-// of the user's default OneDrive drive.
+// of the user's default OneDrive drive, or of the drive the client is bound to.
 // This is equivalent to calling GetDriveItemChildrenByPath with path "/".
 //
 // Example:
@@ -166,8 +168,8 @@ func (c *Client) GetRootDriveItems(ctx context.Context) (DriveItemList, error) {
 	c.logger.Debug("GetRootDriveItems called")
 	var items DriveItemList
 
-	// Endpoint for children of the default drive's root.
-	url := customRootURL + "me/drive/root/children"
+	// Endpoint for children of the drive's root.
+	url := c.driveURL() + "/root/children"
 	res, err := c.apiCall(ctx, "GET", url, "", nil)
 	if err != nil {
 		return items, err
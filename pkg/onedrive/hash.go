@@ -0,0 +1,188 @@
+// Package onedrive (hash.go) provides content hashing for integrity checks.
+// This includes QuickXorHash, the hash OneDrive reports for every file on both
+// Personal and Business accounts, and verification of local files against the
+// size and hashes the service reported for an item.
+package onedrive
+
+import (
+	"crypto/sha1" //nolint:gosec // SHA1 is required to compare with hashes reported by OneDrive.
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// ErrContentMismatch reports that a local file does not match the size or
+// content hash the service reported for the corresponding item.
+var ErrContentMismatch = errors.New("content does not match the remote item")
+
+// QuickXorHash parameters: a 160-bit register into which every input byte is
+// XORed, shifted 11 bits further than the previous one.
+const (
+	QuickXorHashSize = 20 // Size of a QuickXorHash checksum in bytes.
+
+	quickXorWidth = 160
+	quickXorShift = 11
+)
+
+// quickXorHash implements hash.Hash for Microsoft's QuickXorHash.
+type quickXorHash struct {
+	data   [3]uint64 // The 160-bit register; only the low 32 bits of the last cell are used.
+	length int64     // Number of bytes written so far.
+	shift  int       // Bit position at which the next byte is XORed in.
+}
+
+// NewQuickXorHash returns a hash.Hash computing the QuickXorHash of the data
+// written to it. The service reports this hash base64-encoded as
+// `file.hashes.quickXorHash`; see EncodeQuickXorHash.
+func NewQuickXorHash() hash.Hash {
+	return &quickXorHash{}
+}
+
+// Write XORs p into the register. It never returns an error.
+func (q *quickXorHash) Write(p []byte) (int, error) {
+	cell := q.shift / 64
+	offset := q.shift % 64
+	// Bytes quickXorWidth apart land on the same bit position, so each of the
+	// first quickXorWidth positions is visited once and the bytes that map to
+	// it are XORed together before being shifted into place.
+	iterations := min(len(p), quickXorWidth)
+	for i := 0; i < iterations; i++ {
+		lastCell := cell == len(q.data)-1
+		cellBits := 64
+		if lastCell {
+			cellBits = quickXorWidth % 64
+		}
+
+		var xored byte
+		for j := i; j < len(p); j += quickXorWidth {
+			xored ^= p[j]
+		}
+		if offset <= cellBits-8 {
+			q.data[cell] ^= uint64(xored) << offset
+		} else {
+			// The byte straddles two cells, wrapping around after the last one.
+			next := cell + 1
+			if lastCell {
+				next = 0
+			}
+			q.data[cell] ^= uint64(xored) << offset
+			q.data[next] ^= uint64(xored) >> (cellBits - offset)
+		}
+
+		offset += quickXorShift
+		if offset >= cellBits { // The shift is smaller than a cell, so at most one step.
+			offset -= cellBits
+			cell++
+			if lastCell {
+				cell = 0
+			}
+		}
+	}
+	q.shift = (q.shift + quickXorShift*(len(p)%quickXorWidth)) % quickXorWidth
+	q.length += int64(len(p))
+	return len(p), nil
+}
+
+// Sum appends the checksum to b without changing the hash state.
+func (q *quickXorHash) Sum(b []byte) []byte {
+	var sum [QuickXorHashSize]byte
+	binary.LittleEndian.PutUint64(sum[0:8], q.data[0])
+	binary.LittleEndian.PutUint64(sum[8:16], q.data[1])
+	binary.LittleEndian.PutUint32(sum[16:20], uint32(q.data[2]))
+	// The length is XORed into the last 8 bytes.
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(q.length))
+	for i, v := range length {
+		sum[QuickXorHashSize-8+i] ^= v
+	}
+	return append(b, sum[:]...)
+}
+
+// Reset restores the initial state.
+func (q *quickXorHash) Reset() { *q = quickXorHash{} }
+
+// Size returns QuickXorHashSize.
+func (q *quickXorHash) Size() int { return QuickXorHashSize }
+
+// BlockSize returns the preferred write size; any size is accepted.
+func (q *quickXorHash) BlockSize() int { return 64 }
+
+// EncodeQuickXorHash encodes a QuickXorHash checksum the way the service
+// reports it in `file.hashes.quickXorHash`.
+func EncodeQuickXorHash(sum []byte) string {
+	return base64.StdEncoding.EncodeToString(sum)
+}
+
+// VerifyFile checks the local file at localPath against the metadata the
+// service reported for `item`: its size, and the strongest content hash
+// available (SHA256, SHA1, then QuickXorHash). It returns an error wrapping
+// ErrContentMismatch when either differs. Items without a file facet carry
+// neither and are not checked; a file facet without hashes is checked by size.
+func VerifyFile(localPath string, item DriveItem) error {
+	if item.File == nil {
+		return nil
+	}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("opening local file '%s' for verification: %w", localPath, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close file: %v", closeErr)
+		}
+	}()
+
+	algorithm, h, want := verificationHash(item.File.Hashes)
+	var size int64
+	if h != nil {
+		size, err = io.Copy(h, file)
+	} else {
+		size, err = io.Copy(io.Discard, file)
+	}
+	if err != nil {
+		return fmt.Errorf("reading local file '%s' for verification: %w", localPath, err)
+	}
+
+	if size != item.Size {
+		return fmt.Errorf("%w: '%s' is %d bytes, remote item '%s' is %d bytes",
+			ErrContentMismatch, localPath, size, item.Name, item.Size)
+	}
+	if h == nil {
+		return nil
+	}
+	if got := h.Sum(nil); !strings.EqualFold(hex.EncodeToString(got), want) {
+		return fmt.Errorf("%w: %s of '%s' is %s, remote item '%s' reports %s",
+			ErrContentMismatch, algorithm, localPath, hex.EncodeToString(got), item.Name, want)
+	}
+	return nil
+}
+
+// verificationHash picks the strongest hash in `hashes` and returns its name, a
+// hash.Hash to compute it and the expected value in hex. h is nil when there is
+// no usable hash.
+func verificationHash(hashes *FileHashes) (algorithm string, h hash.Hash, want string) {
+	if hashes == nil {
+		return "", nil, ""
+	}
+	switch {
+	case hashes.Sha256Hash != "":
+		return "SHA256", sha256.New(), hashes.Sha256Hash
+	case hashes.Sha1Hash != "":
+		return "SHA1", sha1.New(), hashes.Sha1Hash //nolint:gosec // See import.
+	case hashes.QuickXorHash != "":
+		sum, err := base64.StdEncoding.DecodeString(hashes.QuickXorHash)
+		if err != nil || len(sum) != QuickXorHashSize {
+			return "", nil, ""
+		}
+		return "QuickXorHash", NewQuickXorHash(), hex.EncodeToString(sum)
+	}
+	return "", nil, ""
+}
@@ -0,0 +1,100 @@
+package onedrive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuickXorHash(t *testing.T) {
+	counting := make([]byte, 1000)
+	for i := range counting {
+		counting[i] = byte(i % 251)
+	}
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{"Empty input", nil, "AAAAAAAAAAAAAAAAAAAAAAAAAAA="},
+		{"Single byte", []byte("J"), "SgAAAAAAAAAAAAAAAQAAAAAAAAA="},
+		{"Sentence", []byte("The quick brown fox jumps over the lazy dog"), "bMSlbysmxJL6S75XwfMcQZOpcr4="},
+		{"Longer than the register", counting, "KbphcpColXb1/3Wm950vUzeX1es="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewQuickXorHash()
+			h.Write(tt.input)
+			if got := EncodeQuickXorHash(h.Sum(nil)); got != tt.expected {
+				t.Errorf("QuickXorHash = %s, want %s", got, tt.expected)
+			}
+			if h.Size() != QuickXorHashSize {
+				t.Errorf("Size() = %d, want %d", h.Size(), QuickXorHashSize)
+			}
+		})
+	}
+
+	t.Run("Incremental writes", func(t *testing.T) {
+		whole := NewQuickXorHash()
+		whole.Write(counting)
+		want := EncodeQuickXorHash(whole.Sum(nil))
+
+		// Split points that do and do not line up with the 160-byte period.
+		for _, sizes := range [][]int{{1, 999}, {160, 840}, {7, 333, 160, 500}, {999, 1}} {
+			h := NewQuickXorHash()
+			offset := 0
+			for _, n := range sizes {
+				h.Write(counting[offset : offset+n])
+				offset += n
+			}
+			if got := EncodeQuickXorHash(h.Sum(nil)); got != want {
+				t.Errorf("writes of %v: QuickXorHash = %s, want %s", sizes, got, want)
+			}
+		}
+
+		whole.Reset()
+		if got := EncodeQuickXorHash(whole.Sum(nil)); got != "AAAAAAAAAAAAAAAAAAAAAAAAAAA=" {
+			t.Errorf("after Reset: QuickXorHash = %s, want the empty hash", got)
+		}
+	})
+}
+
+func TestVerifyFile(t *testing.T) {
+	content := []byte("The quick brown fox jumps over the lazy dog")
+	localPath := filepath.Join(t.TempDir(), "fox.txt")
+	if err := os.WriteFile(localPath, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	item := func(size int64, hashes *FileHashes) DriveItem {
+		return DriveItem{Name: "fox.txt", Size: size, File: &FileFacet{Hashes: hashes}}
+	}
+	size := int64(len(content))
+
+	tests := []struct {
+		name     string
+		item     DriveItem
+		mismatch bool
+	}{
+		{"Matching QuickXorHash", item(size, &FileHashes{QuickXorHash: "bMSlbysmxJL6S75XwfMcQZOpcr4="}), false},
+		{"Matching SHA1 in upper case", item(size, &FileHashes{Sha1Hash: "2FD4E1C67A2D28FCED849EE1BB76E7391B93EB12"}), false},
+		{"Matching SHA256", item(size, &FileHashes{Sha256Hash: "d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592"}), false},
+		{"Size only", item(size, nil), false},
+		{"Not a file", DriveItem{Name: "folder", Folder: &FolderFacet{}}, false},
+		{"Truncated file", item(size+1, &FileHashes{QuickXorHash: "bMSlbysmxJL6S75XwfMcQZOpcr4="}), true},
+		{"Different QuickXorHash", item(size, &FileHashes{QuickXorHash: "AAAAAAAAAAAAAAAAAAAAAAAAAAA="}), true},
+		{"SHA256 takes precedence", item(size, &FileHashes{Sha256Hash: "00", QuickXorHash: "bMSlbysmxJL6S75XwfMcQZOpcr4="}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyFile(localPath, tt.item)
+			if tt.mismatch {
+				if !errors.Is(err, ErrContentMismatch) {
+					t.Errorf("VerifyFile() error = %v, want ErrContentMismatch", err)
+				}
+			} else if err != nil {
+				t.Errorf("VerifyFile() unexpected error = %v", err)
+			}
+		})
+	}
+}
@@ -10,9 +10,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
-	"strings"
 )
 
 // GetDriveItemByPath retrieves the metadata for a single drive item (file or folder)
@@ -28,7 +26,7 @@ func (c *Client) GetDriveItemByPath(ctx context.Context, path string) (DriveItem
 	var item DriveItem
 
 	// BuildPathURL handles correct formatting for root or nested paths.
-	url := BuildPathURL(path)
+	url := c.pathURL(path)
 	res, err := c.apiCall(ctx, "GET", url, "", nil)
 	if err != nil {
 		return item, err
@@ -45,6 +43,8 @@ func (c *Client) GetDriveItemByPath(ctx context.Context, path string) (DriveItem
 // GetDriveItemChildrenByPath retrieves a list of drive items (children) within a
 // specific folder, identified by its path.
 // If path is "" or "/", it lists children of the drive's root folder.
+// Large folders are returned by Graph in pages; every `@odata.nextLink` is
+// followed, so the list holds all children.
 //
 // Example:
 //
@@ -57,24 +57,21 @@ func (c *Client) GetDriveItemChildrenByPath(ctx context.Context, path string) (D
 	c.logger.Debug("GetDriveItemChildrenByPath called for path: ", path)
 	var items DriveItemList
 
-	var url string
-	// Determine the correct endpoint based on whether the path is root or a subfolder.
-	if path == "" || path == "/" {
-		// To list children of the root, target "me/drive/root/children".
-		url = customRootURL + "me/drive/root/children"
-	} else {
-		// For subfolders, append ":/children" to the folder's path URL.
-		url = BuildPathURL(path) + ":/children"
-	}
+	// The root, a subfolder path and an item reference each have their own
+	// form of the "children" endpoint; actionURL picks the right one.
+	url := c.actionURL(path, "children")
 
-	res, err := c.apiCall(ctx, "GET", url, "", nil)
+	rawItems, _, err := c.collectAllPages(ctx, url, Paging{FetchAll: true})
 	if err != nil {
-		return items, err
+		return items, fmt.Errorf("listing children for path '%s': %w", path, err)
 	}
-	defer closeBodySafely(res.Body, c.logger, "get drive item children")
-
-	if err := json.NewDecoder(res.Body).Decode(&items); err != nil {
-		return items, fmt.Errorf("%w: decoding children for path '%s': %w", ErrDecodingFailed, path, err)
+	items.Value = make([]DriveItem, 0, len(rawItems))
+	for _, rawItem := range rawItems {
+		var item DriveItem
+		if err := json.Unmarshal(rawItem, &item); err != nil {
+			return items, fmt.Errorf("%w: decoding children for path '%s': %w", ErrDecodingFailed, path, err)
+		}
+		items.Value = append(items.Value, item)
 	}
 
 	return items, nil
@@ -110,15 +107,8 @@ func (c *Client) CreateFolder(ctx context.Context, parentPath string, folderName
 		return item, fmt.Errorf("marshaling create folder request for '%s': %w", folderName, err)
 	}
 
-	var url string
-	// Determine the target URL for creating a child item.
-	if parentPath == "" || parentPath == "/" {
-		// If parent is root, target "me/drive/root/children".
-		url = customRootURL + "me/drive/root/children"
-	} else {
-		// If parent is a subfolder, target "<parent_path_url>:/children".
-		url = BuildPathURL(parentPath) + ":/children"
-	}
+	// Determine the target URL for creating a child item in the root, a subfolder or a referenced item.
+	url := c.actionURL(parentPath, "children")
 
 	res, err := c.apiCall(ctx, "POST", url, "application/json", bytes.NewReader(data))
 	if err != nil {
@@ -159,7 +149,7 @@ func (c *Client) UploadFile(ctx context.Context, localPath, remotePath string) (
 	}()
 
 	// The target URL for content upload is "<item_path_url>:/content".
-	url := BuildPathURL(remotePath) + ":/content"
+	url := c.actionURL(remotePath, "content")
 	// Content-Type for raw file upload.
 	res, err := c.apiCall(ctx, "PUT", url, "application/octet-stream", file)
 	if err != nil {
@@ -184,7 +174,7 @@ func (c *Client) UploadFile(ctx context.Context, localPath, remotePath string) (
 //	fmt.Println("File moved to recycle bin.")
 func (c *Client) DeleteDriveItem(ctx context.Context, path string) error {
 	c.logger.Debug("DeleteDriveItem called for path: ", path)
-	url := BuildPathURL(path) // URL of the item to delete.
+	url := c.pathURL(path) // URL of the item to delete.
 	res, err := c.apiCall(ctx, "DELETE", url, "", nil)
 	if err != nil {
 		return err
@@ -216,27 +206,23 @@ func (c *Client) DeleteDriveItem(ctx context.Context, path string) error {
 func (c *Client) CopyDriveItem(ctx context.Context, sourcePath, destinationParentPath, newName string) (string, error) {
 	c.logger.Debugf("CopyDriveItem called for source: '%s', destParent: '%s', newName: '%s'", sourcePath, destinationParentPath, newName)
 	// First, get the item ID of the source item.
-	item, err := c.GetDriveItemByPath(ctx, sourcePath)
+	itemID, err := c.resolveItemID(ctx, sourcePath)
 	if err != nil {
 		return "", fmt.Errorf("getting source item '%s' for copy: %w", sourcePath, err)
 	}
+	// The copy always stays within the client's drive.
+	parent, err := c.parentReference(ctx, destinationParentPath)
+	if err != nil {
+		return "", err
+	}
 
 	// Prepare the request body for the copy operation.
 	// It requires a ParentReference pointing to the destination folder.
 	copyRequest := struct {
-		ParentReference struct {
-			Path string `json:"path"` // Graph API path for the parent, e.g., "/drive/root:/Documents"
-		} `json:"parentReference"`
-		Name string `json:"name,omitempty"` // Optional new name for the copy.
+		ParentReference itemParentReference `json:"parentReference"`
+		Name            string              `json:"name,omitempty"` // Optional new name for the copy.
 	}{
-		ParentReference: struct {
-			Path string `json:"path"`
-		}{
-			// The ParentReference path needs to be in the format "/drive/root:<path_to_parent_folder_from_root>"
-			// or by providing driveId and itemId if copying across drives.
-			// Assuming same drive for now.
-			Path: fmt.Sprintf("/drive/root:%s", strings.TrimSuffix(destinationParentPath, "/")),
-		},
+		ParentReference: parent,
 	}
 
 	if newName != "" {
@@ -249,7 +235,7 @@ func (c *Client) CopyDriveItem(ctx context.Context, sourcePath, destinationParen
 	}
 
 	// The copy endpoint is on the source item: "/items/{source-item-id}/copy".
-	url := customRootURL + "me/drive/items/" + url.PathEscape(item.ID) + "/copy"
+	url := c.itemURL(itemID) + "/copy"
 	res, err := c.apiCall(ctx, "POST", url, "application/json", bytes.NewReader(bodyBytes))
 	if err != nil {
 		return "", err
@@ -327,24 +313,21 @@ func (c *Client) MoveDriveItem(ctx context.Context, sourcePath, destinationParen
 	c.logger.Debugf("MoveDriveItem called for source: '%s', destParent: '%s'", sourcePath, destinationParentPath)
 	var item DriveItem
 	// Get the ID of the source item.
-	srcItem, err := c.GetDriveItemByPath(ctx, sourcePath)
+	srcItemID, err := c.resolveItemID(ctx, sourcePath)
 	if err != nil {
 		return item, fmt.Errorf("getting source item '%s' for move: %w", sourcePath, err)
 	}
+	parent, err := c.parentReference(ctx, destinationParentPath)
+	if err != nil {
+		return item, err
+	}
 
 	// Prepare the request body for the PATCH operation to update the parentReference.
 	moveRequest := struct {
-		ParentReference struct {
-			Path string `json:"path"` // Graph API path for the new parent.
-		} `json:"parentReference"`
+		ParentReference itemParentReference `json:"parentReference"` // The new parent.
 		// To also rename during move, add: Name string `json:"name,omitempty"`
 	}{
-		ParentReference: struct {
-			Path string `json:"path"`
-		}{
-			// Path format: "/drive/root:<path_to_new_parent_from_root>"
-			Path: fmt.Sprintf("/drive/root:%s", strings.TrimSuffix(destinationParentPath, "/")),
-		},
+		ParentReference: parent,
 	}
 
 	bodyBytes, err := json.Marshal(moveRequest)
@@ -353,7 +336,7 @@ func (c *Client) MoveDriveItem(ctx context.Context, sourcePath, destinationParen
 	}
 
 	// The PATCH request is made to the source item's URL.
-	url := customRootURL + "me/drive/items/" + url.PathEscape(srcItem.ID)
+	url := c.itemURL(srcItemID)
 	res, err := c.apiCall(ctx, "PATCH", url, "application/json", bytes.NewReader(bodyBytes))
 	if err != nil {
 		return item, err
@@ -379,7 +362,7 @@ func (c *Client) UpdateDriveItem(ctx context.Context, path, newName string) (Dri
 	c.logger.Debugf("UpdateDriveItem called for path: '%s', newName: '%s'", path, newName)
 	var item DriveItem
 	// Get the ID of the source item.
-	srcItem, err := c.GetDriveItemByPath(ctx, path)
+	srcItemID, err := c.resolveItemID(ctx, path)
 	if err != nil {
 		return item, fmt.Errorf("getting item '%s' for rename: %w", path, err)
 	}
@@ -397,7 +380,7 @@ func (c *Client) UpdateDriveItem(ctx context.Context, path, newName string) (Dri
 	}
 
 	// The PATCH request is made to the item's URL.
-	url := customRootURL + "me/drive/items/" + url.PathEscape(srcItem.ID)
+	url := c.itemURL(srcItemID)
 	res, err := c.apiCall(ctx, "PATCH", url, "application/json", bytes.NewReader(bodyBytes))
 	if err != nil {
 		return item, err
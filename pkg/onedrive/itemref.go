@@ -0,0 +1,164 @@
+// Package onedrive (itemref.go) lets items be addressed by their ID instead of
+// their path. Every method that takes a remote path also accepts an item
+// reference of the form "id:<itemId>", optionally followed by a path relative
+// to that item, e.g. "id:01ABC/Reports/2024.xlsx". IDs stay valid when an item
+// is renamed or moved, and addressing an item by ID saves the round trip that
+// path-based methods spend resolving a path to an ID.
+package onedrive
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ItemIDPrefix marks a remote path as an item reference rather than a path
+// from the drive root. Paths starting with "/" are never item references, so
+// an item whose name begins with "id:" can still be addressed as "/id:...".
+const ItemIDPrefix = "id:"
+
+// ItemIDPath returns the item reference for the item with ID `itemID`, which
+// can be passed to any method that takes a remote path.
+//
+// Example:
+//
+//	item, err := client.GetDriveItemByPath(ctx, onedrive.ItemIDPath("01ABCDEF"))
+//	report, err := client.GetDriveItemByPath(ctx, onedrive.ItemIDPath("01ABCDEF")+"/Reports/2024.xlsx")
+func ItemIDPath(itemID string) string {
+	return ItemIDPrefix + itemID
+}
+
+// SplitItemIDPath splits an item reference "id:<itemId>[/<relative path>]"
+// into the item ID and the path relative to it, without a leading slash. It
+// returns ok == false if `path` is an ordinary path from the drive root or
+// carries an empty ID.
+//
+// Example:
+//
+//	SplitItemIDPath("id:01ABC")             -> "01ABC", "", true
+//	SplitItemIDPath("id:01ABC/Reports/a.md") -> "01ABC", "Reports/a.md", true
+//	SplitItemIDPath("/Documents")           -> "", "", false
+func SplitItemIDPath(path string) (itemID, rest string, ok bool) {
+	if !strings.HasPrefix(path, ItemIDPrefix) {
+		return "", "", false
+	}
+	itemID, rest, _ = strings.Cut(strings.TrimPrefix(path, ItemIDPrefix), "/")
+	if itemID == "" {
+		return "", "", false
+	}
+	return itemID, strings.Trim(rest, "/"), true
+}
+
+// bareItemID returns the ID of an item reference that names the item itself,
+// with no relative path after it.
+func bareItemID(path string) (string, bool) {
+	itemID, rest, ok := SplitItemIDPath(path)
+	if !ok || rest != "" {
+		return "", false
+	}
+	return itemID, true
+}
+
+// actionURL returns the URL of `action` (such as "children" or "content") on
+// the item at `path`, which may be a path from the drive root or an item
+// reference. Path-addressed items take the action after a colon, ID-addressed
+// ones as a plain URL segment.
+func (c *Client) actionURL(path, action string) string {
+	if path == "" || path == "/" {
+		return c.driveURL() + "/root/" + action
+	}
+	if itemID, ok := bareItemID(path); ok {
+		return c.itemURL(itemID) + "/" + action
+	}
+	return c.pathURL(path) + ":/" + action
+}
+
+// resolveItemID returns the ID of the item at `path`. Item references that
+// name the item itself are returned without an API call; anything else is
+// looked up with GetDriveItemByPath.
+func (c *Client) resolveItemID(ctx context.Context, path string) (string, error) {
+	if itemID, ok := bareItemID(path); ok {
+		return itemID, nil
+	}
+	item, err := c.GetDriveItemByPath(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return item.ID, nil
+}
+
+// itemParentReference identifies the destination folder of a copy or move.
+type itemParentReference struct {
+	DriveID string `json:"driveId,omitempty"` // Set when the client is bound to a drive other than the default.
+	ID      string `json:"id,omitempty"`      // Set when the destination is given as an item reference.
+	Path    string `json:"path,omitempty"`    // Graph API path for the parent, e.g., "/drive/root:/Documents"
+}
+
+// parentReference builds the reference to the destination folder at
+// `parentPath`. Folders given by path are referenced by path, in the format
+// "/drive/root:<path_to_parent_folder_from_root>"; item references are
+// resolved to an ID, which costs a lookup only if they carry a relative path.
+func (c *Client) parentReference(ctx context.Context, parentPath string) (itemParentReference, error) {
+	ref := itemParentReference{DriveID: c.driveID}
+	if _, _, ok := SplitItemIDPath(parentPath); !ok {
+		ref.Path = fmt.Sprintf("/drive/root:%s", strings.TrimSuffix(parentPath, "/"))
+		return ref, nil
+	}
+	parentID, err := c.resolveItemID(ctx, parentPath)
+	if err != nil {
+		return ref, fmt.Errorf("getting destination folder '%s': %w", parentPath, err)
+	}
+	ref.ID = parentID
+	return ref, nil
+}
+
+// GetDriveItemByID retrieves the metadata for the drive item with ID `itemID`.
+// It is equivalent to GetDriveItemByPath with ItemIDPath(itemID).
+func (c *Client) GetDriveItemByID(ctx context.Context, itemID string) (DriveItem, error) {
+	return c.GetDriveItemByPath(ctx, ItemIDPath(itemID))
+}
+
+// GetDriveItemChildrenByID lists the children of the folder with ID `itemID`.
+func (c *Client) GetDriveItemChildrenByID(ctx context.Context, itemID string) (DriveItemList, error) {
+	return c.GetDriveItemChildrenByPath(ctx, ItemIDPath(itemID))
+}
+
+// DeleteDriveItemByID deletes the drive item with ID `itemID`, moving it to the recycle bin.
+func (c *Client) DeleteDriveItemByID(ctx context.Context, itemID string) error {
+	return c.DeleteDriveItem(ctx, ItemIDPath(itemID))
+}
+
+// MoveDriveItemByID moves the drive item with ID `itemID` into the folder with
+// ID `destinationParentID`, without resolving either path first.
+func (c *Client) MoveDriveItemByID(ctx context.Context, itemID, destinationParentID string) (DriveItem, error) {
+	return c.MoveDriveItem(ctx, ItemIDPath(itemID), ItemIDPath(destinationParentID))
+}
+
+// CopyDriveItemByID asynchronously copies the drive item with ID `itemID` into
+// the folder with ID `destinationParentID`. `newName` is optional. It returns
+// the monitor URL for MonitorCopyOperation.
+func (c *Client) CopyDriveItemByID(ctx context.Context, itemID, destinationParentID, newName string) (string, error) {
+	return c.CopyDriveItem(ctx, ItemIDPath(itemID), ItemIDPath(destinationParentID), newName)
+}
+
+// ListPermissionsByID lists the permissions on the drive item with ID `itemID`.
+func (c *Client) ListPermissionsByID(ctx context.Context, itemID string) (PermissionList, error) {
+	return c.ListPermissions(ctx, ItemIDPath(itemID))
+}
+
+// GetFileVersionsByID lists the versions of the file with ID `itemID`.
+func (c *Client) GetFileVersionsByID(ctx context.Context, itemID string) (DriveItemVersionList, error) {
+	return c.GetFileVersions(ctx, ItemIDPath(itemID))
+}
+
+// GetThumbnailsByID retrieves the thumbnail sets of the drive item with ID `itemID`.
+func (c *Client) GetThumbnailsByID(ctx context.Context, itemID string) (ThumbnailSetList, error) {
+	return c.GetThumbnails(ctx, ItemIDPath(itemID))
+}
+
+// CreateUploadSessionByID creates an upload session for the file `fileName`
+// inside the folder with ID `parentID`. To replace the content of an existing
+// file by ID, pass ItemIDPath(fileID) to CreateUploadSession instead.
+func (c *Client) CreateUploadSessionByID(ctx context.Context, parentID, fileName string) (UploadSession, error) {
+	return c.CreateUploadSession(ctx, ItemIDPath(parentID)+"/"+fileName)
+}
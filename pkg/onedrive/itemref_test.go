@@ -0,0 +1,102 @@
+package onedrive
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tonimelisma/onedrive-client/internal/logger"
+)
+
+func TestSplitItemIDPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		itemID string
+		rest   string
+		ok     bool
+	}{
+		{"id:01ABC", "01ABC", "", true},
+		{"id:01ABC/", "01ABC", "", true},
+		{"id:01ABC/Reports/2024.xlsx", "01ABC", "Reports/2024.xlsx", true},
+		{"id:", "", "", false},
+		{"id:/Reports", "", "", false},
+		{"/id:01ABC", "", "", false},
+		{"/Documents", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			itemID, rest, ok := SplitItemIDPath(tt.path)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.itemID, itemID)
+			assert.Equal(t, tt.rest, rest)
+		})
+	}
+	assert.Equal(t, "id:01ABC", ItemIDPath("01ABC"))
+}
+
+func TestItemIDAddressing(t *testing.T) {
+	var requested, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.Method+" "+r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		if len(body) > 0 {
+			bodies = append(bodies, string(body))
+		}
+		if r.URL.Path == "/me/drive/items/src/copy" {
+			w.Header().Set("Location", "https://example.com/monitor")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"resolved","name":"Reports","value":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(context.Background(), &Token{AccessToken: "test-token"}, "test-client-id", nil, &logger.NoopLogger{}, WithEndpoints(Endpoints{GraphURL: server.URL + "/"}))
+	client.httpClient = &http.Client{}
+	ctx := context.Background()
+
+	_, err := client.GetDriveItemByID(ctx, "file1")
+	require.NoError(t, err)
+	_, err = client.GetDriveItemByPath(ctx, "id:folder1/Reports/2024.xlsx")
+	require.NoError(t, err)
+	_, err = client.GetDriveItemChildrenByID(ctx, "folder1")
+	require.NoError(t, err)
+	_, err = client.CreateUploadSessionByID(ctx, "folder1", "big.bin")
+	require.NoError(t, err)
+	_, err = client.ListPermissionsByID(ctx, "file1")
+	require.NoError(t, err)
+	_, err = client.GetFileVersionsByID(ctx, "file1")
+	require.NoError(t, err)
+	_, err = client.GetThumbnailsByID(ctx, "file1")
+	require.NoError(t, err)
+	require.NoError(t, client.DeleteDriveItemByID(ctx, "file1"))
+	_, err = client.MoveDriveItemByID(ctx, "file1", "folder2")
+	require.NoError(t, err)
+	// A destination with a relative path is resolved to its ID first.
+	_, err = client.CopyDriveItem(ctx, "id:src", "id:folder2/Reports", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"GET /me/drive/items/file1",
+		"GET /me/drive/items/folder1:/Reports/2024.xlsx",
+		"GET /me/drive/items/folder1/children",
+		"POST /me/drive/items/folder1:/big.bin:/createUploadSession",
+		"GET /me/drive/items/file1/permissions",
+		"GET /me/drive/items/file1/versions",
+		"GET /me/drive/items/file1/thumbnails",
+		"DELETE /me/drive/items/file1",
+		"PATCH /me/drive/items/file1",
+		"GET /me/drive/items/folder2:/Reports",
+		"POST /me/drive/items/src/copy",
+	}, requested)
+	assert.Equal(t, []string{
+		`{"parentReference":{"id":"folder2"}}`,
+		`{"parentReference":{"id":"resolved"}}`,
+	}, bodies)
+}
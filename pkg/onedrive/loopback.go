@@ -0,0 +1,215 @@
+// Package onedrive (loopback.go) completes the Authorization Code Grant with
+// PKCE on the user's own machine. A LoopbackRedirect listens on a temporary
+// localhost port that serves as the OAuth redirect URI: the user signs in
+// with their browser, Microsoft redirects the browser to the listener, and the
+// listener hands the authorization code back to the caller. Unlike the Device
+// Code Flow, this flow is permitted by most conditional access policies.
+package onedrive
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	cv "github.com/nirasan/go-oauth-pkce-code-verifier"
+	"golang.org/x/oauth2"
+)
+
+// ErrStateMismatch describes an authorization redirect whose state parameter
+// does not match the one sent with the authorization request, which indicates
+// a forged or stale redirect. LoopbackRedirect answers such redirects with 400
+// and keeps waiting for the genuine one.
+var ErrStateMismatch = errors.New("authorization state mismatch")
+
+// loopbackReadHeaderTimeout bounds how long the loopback listener waits for a
+// request's headers, so a stray connection cannot hold it open.
+const loopbackReadHeaderTimeout = 10 * time.Second
+
+// loopbackResponsePage is shown in the browser once the redirect is handled.
+const loopbackResponsePage = `<!DOCTYPE html>
+<html><head><title>onedrive-client</title></head>
+<body><h3>%s</h3><p>You can close this window and return to the terminal.</p></body></html>
+`
+
+// NewAuthState returns a random value for the OAuth state parameter, which
+// ties an authorization redirect to the request that caused it.
+func NewAuthState() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating authorization state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// StartAuthenticationWithState is StartAuthentication with a caller-chosen
+// state parameter, which the authorization redirect must carry back; use
+// NewAuthState to generate one. `oauthConfig.RedirectURL` must be set to the
+// URL the redirect is received on, such as LoopbackRedirect.RedirectURL.
+func StartAuthenticationWithState(
+	ctx context.Context,
+	oauthConfig *OAuthConfig,
+	state string,
+) (authURL string, codeVerifier string, err error) {
+	if ctx == nil {
+		return "", "", fmt.Errorf("context must not be nil for StartAuthenticationWithState")
+	}
+
+	codeVerifierObj, err := cv.CreateCodeVerifier()
+	if err != nil {
+		return "", "", fmt.Errorf("could not create PKCE code verifier: %w", err)
+	}
+	authURL = (*oauth2.Config)(oauthConfig).AuthCodeURL(state,
+		oauth2.SetAuthURLParam("code_challenge", codeVerifierObj.CodeChallengeS256()),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+	return authURL, codeVerifierObj.String(), nil
+}
+
+// loopbackResult carries the outcome of the authorization redirect from the
+// HTTP handler to Wait.
+type loopbackResult struct {
+	code string
+	err  error
+}
+
+// LoopbackRedirect is a temporary HTTP listener on the same port of 127.0.0.1
+// and, where available, ::1, so that "localhost" reaches it whichever address
+// the browser resolves it to. It receives the authorization redirect of the Authorization Code Grant. Create it with
+// ListenLoopback, set the OAuth redirect URI to RedirectURL, then call Wait.
+//
+// Example:
+//
+//	state, _ := onedrive.NewAuthState()
+//	redirect, err := onedrive.ListenLoopback(state)
+//	if err != nil { log.Fatal(err) }
+//	defer redirect.Close()
+//	ctx, oauthCfg := onedrive.GetOauth2Config(onedrive.AuthConfig{ClientID: "YOUR_CLIENT_ID"})
+//	oauthCfg.RedirectURL = redirect.RedirectURL()
+//	authURL, verifier, _ := onedrive.StartAuthenticationWithState(ctx, oauthCfg, state)
+//	// Open authURL in the user's browser, then:
+//	code, err := redirect.Wait(ctx)
+//	token, err := onedrive.CompleteAuthentication(ctx, oauthCfg, code, verifier)
+type LoopbackRedirect struct {
+	listener net.Listener // The IPv4 listener, whose port RedirectURL advertises.
+	server   *http.Server
+	state    string // Expected state parameter of the redirect.
+	results  chan loopbackResult
+}
+
+// ListenLoopback starts listening on a free port of the loopback interface
+// for an authorization redirect carrying the state parameter `state`.
+func ListenLoopback(state string) (*LoopbackRedirect, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("%w: listening on loopback interface for authorization redirect: %w", ErrNetworkFailed, err)
+	}
+	l := &LoopbackRedirect{
+		listener: listener,
+		state:    state,
+		results:  make(chan loopbackResult, 1),
+	}
+	l.server = &http.Server{
+		Handler:           http.HandlerFunc(l.handleRedirect),
+		ReadHeaderTimeout: loopbackReadHeaderTimeout,
+	}
+	listeners := []net.Listener{listener}
+	// Hosts without IPv6 have no ::1, and "localhost" then resolves to
+	// 127.0.0.1 alone.
+	port := listener.Addr().(*net.TCPAddr).Port
+	if listener6, err := net.Listen("tcp", fmt.Sprintf("[::1]:%d", port)); err == nil {
+		listeners = append(listeners, listener6)
+	}
+	for _, ln := range listeners {
+		go func(ln net.Listener) {
+			if serveErr := l.server.Serve(ln); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+				l.deliver(loopbackResult{err: fmt.Errorf("%w: serving authorization redirect: %w", ErrNetworkFailed, serveErr)})
+			}
+		}(ln)
+	}
+	return l, nil
+}
+
+// RedirectURL returns the redirect URI to register with the authorization
+// request. Microsoft accepts any port for "http://localhost" redirect URIs of
+// public clients, so the port chosen by ListenLoopback is always allowed.
+func (l *LoopbackRedirect) RedirectURL() string {
+	return fmt.Sprintf("http://localhost:%d/", l.listener.Addr().(*net.TCPAddr).Port)
+}
+
+// Wait blocks until the browser is redirected to the listener with the
+// expected state and returns the authorization code. It fails with
+// ErrAuthorizationDeclined if the user declined consent, and with the
+// context's error if `ctx` ends first. Redirects with a missing or wrong state
+// do not end the wait.
+func (l *LoopbackRedirect) Wait(ctx context.Context) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("waiting for authorization redirect: %w", ctx.Err())
+	case res := <-l.results:
+		return res.code, res.err
+	}
+}
+
+// Close stops the listeners.
+func (l *LoopbackRedirect) Close() error {
+	return l.server.Close()
+}
+
+// handleRedirect handles a request to the listener. Requests without an
+// authorization response, such as the browser asking for a favicon, are
+// answered with 404 and otherwise ignored; redirects with a missing or wrong
+// state are answered with 400 and ignored too, so that a forged request
+// cannot end the sign-in.
+func (l *LoopbackRedirect) handleRedirect(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if r.URL.Path != "/" || (query.Get("code") == "" && query.Get("error") == "") {
+		http.NotFound(w, r)
+		return
+	}
+
+	res := l.checkRedirect(r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if errors.Is(res.err, ErrStateMismatch) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, loopbackResponsePage, "Sign-in failed: the request does not belong to this sign-in.")
+		return
+	}
+	if res.err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, loopbackResponsePage, "Sign-in failed.")
+	} else {
+		fmt.Fprintf(w, loopbackResponsePage, "Sign-in complete.")
+	}
+	l.deliver(res)
+}
+
+// checkRedirect extracts the authorization code from a redirect request,
+// mapping OAuth error responses to the SDK's sentinel errors.
+func (l *LoopbackRedirect) checkRedirect(r *http.Request) loopbackResult {
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(l.state)) != 1 {
+		return loopbackResult{err: ErrStateMismatch}
+	}
+	if oauthErr := query.Get("error"); oauthErr != "" {
+		description := query.Get("error_description")
+		if oauthErr == "access_denied" {
+			return loopbackResult{err: fmt.Errorf("%w: %s", ErrAuthorizationDeclined, description)}
+		}
+		return loopbackResult{err: fmt.Errorf("OAuth authentication error '%s': %s", oauthErr, description)}
+	}
+	return loopbackResult{code: query.Get("code")}
+}
+
+// deliver passes a result to Wait without blocking if one is already queued.
+func (l *LoopbackRedirect) deliver(res loopbackResult) {
+	select {
+	case l.results <- res:
+	default:
+	}
+}
@@ -0,0 +1,121 @@
+package onedrive
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartAuthenticationWithState(t *testing.T) {
+	_, oauthConfig := GetOauth2Config(AuthConfig{ClientID: "test-client-id"})
+	oauthConfig.RedirectURL = "http://localhost:1234/"
+	authURL, verifier, err := StartAuthenticationWithState(context.Background(), oauthConfig, "my-state")
+	require.NoError(t, err)
+	assert.NotEmpty(t, verifier)
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := u.Query()
+	assert.Equal(t, "my-state", query.Get("state"))
+	assert.Equal(t, "http://localhost:1234/", query.Get("redirect_uri"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.NotEmpty(t, query.Get("code_challenge"))
+	assert.NotEqual(t, verifier, query.Get("code_challenge"))
+}
+
+func TestLoopbackRedirect(t *testing.T) {
+	// redirect starts a listener and sends the browser's redirect with `query`.
+	redirect := func(t *testing.T, query string) (string, int, error) {
+		t.Helper()
+		l, err := ListenLoopback("expected-state")
+		require.NoError(t, err)
+		defer l.Close()
+		assert.Regexp(t, `^http://localhost:\d+/$`, l.RedirectURL())
+
+		// Requests that are not redirects, such as favicon lookups, are ignored.
+		res, err := http.Get(l.RedirectURL() + "favicon.ico")
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		res, err = http.Get(l.RedirectURL() + "?" + query)
+		require.NoError(t, err)
+		res.Body.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		code, err := l.Wait(ctx)
+		return code, res.StatusCode, err
+	}
+
+	t.Run("returns the authorization code", func(t *testing.T) {
+		code, status, err := redirect(t, "code=auth-code&state=expected-state")
+		require.NoError(t, err)
+		assert.Equal(t, "auth-code", code)
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("ignores redirects with a wrong or missing state", func(t *testing.T) {
+		l, err := ListenLoopback("expected-state")
+		require.NoError(t, err)
+		defer l.Close()
+
+		for _, query := range []string{"code=forged-code&state=forged", "code=forged-code"} {
+			res, err := http.Get(l.RedirectURL() + "?" + query)
+			require.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err = l.Wait(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "forged redirects do not end the sign-in")
+
+		// The genuine redirect is still accepted.
+		res, err := http.Get(l.RedirectURL() + "?code=auth-code&state=expected-state")
+		require.NoError(t, err)
+		res.Body.Close()
+		code, err := l.Wait(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "auth-code", code)
+	})
+
+	t.Run("listens on both loopback addresses", func(t *testing.T) {
+		l, err := ListenLoopback("expected-state")
+		require.NoError(t, err)
+		defer l.Close()
+		port := l.listener.Addr().(*net.TCPAddr).Port
+
+		for _, host := range []string{"127.0.0.1", "[::1]"} {
+			res, err := http.Get(fmt.Sprintf("http://%s:%d/favicon.ico", host, port))
+			if host == "[::1]" && err != nil {
+				t.Skipf("no IPv6 loopback: %v", err)
+			}
+			require.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, http.StatusNotFound, res.StatusCode, host)
+		}
+	})
+
+	t.Run("reports declined consent", func(t *testing.T) {
+		_, _, err := redirect(t, "error=access_denied&error_description=declined&state=expected-state")
+		assert.ErrorIs(t, err, ErrAuthorizationDeclined)
+	})
+
+	t.Run("stops waiting when the context ends", func(t *testing.T) {
+		l, err := ListenLoopback("expected-state")
+		require.NoError(t, err)
+		defer l.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = l.Wait(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -83,12 +83,18 @@ type FolderFacet struct {
 
 // FileFacet provides metadata specific to items that are files.
 type FileFacet struct {
-	MimeType string    `json:"mimeType"` // The MIME type for the file.
-	Hashes   *struct { // Hashes of the file content.
-		Sha1Hash   string `json:"sha1Hash,omitempty"`   // SHA1 hash for the contents of the file (if available).
-		Sha256Hash string `json:"sha256Hash,omitempty"` // SHA256 hash for the contents of the file (if available).
-		Crc32Hash  string `json:"crc32Hash,omitempty"`  // CRC32 hash for the contents of the file (if available).
-	} `json:"hashes,omitempty"`
+	MimeType string      `json:"mimeType"`         // The MIME type for the file.
+	Hashes   *FileHashes `json:"hashes,omitempty"` // Hashes of the file content.
+}
+
+// FileHashes holds the content hashes the service reports for a file. Which
+// ones are present depends on the account type; QuickXorHash is reported on
+// both Personal and Business accounts.
+type FileHashes struct {
+	Sha1Hash     string `json:"sha1Hash,omitempty"`     // SHA1 hash for the contents of the file (if available).
+	Sha256Hash   string `json:"sha256Hash,omitempty"`   // SHA256 hash for the contents of the file (if available).
+	Crc32Hash    string `json:"crc32Hash,omitempty"`    // CRC32 hash for the contents of the file (if available).
+	QuickXorHash string `json:"quickXorHash,omitempty"` // Base64-encoded QuickXorHash for the contents of the file (if available).
 }
 
 // ImageFacet provides metadata specific to items that are images.
@@ -25,12 +25,9 @@ func TestVerifyDeviceCodeSetsExpiry(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	// Temporarily override the token URL to point to our mock server
-	originalTokenURL := customTokenURL
-	SetCustomEndpoints(oAuthAuthURL, ts.URL, oAuthDeviceURL)
-	defer SetCustomEndpoints(oAuthAuthURL, originalTokenURL, oAuthDeviceURL)
-
-	token, err := VerifyDeviceCode("test-client-id", "test-device-code", false)
+	// Point the token URL at our mock server
+	auth := AuthConfig{ClientID: "test-client-id", Endpoints: Endpoints{TokenURL: ts.URL}}
+	token, err := VerifyDeviceCode(auth, "test-device-code", false)
 	assert.NoError(t, err)
 	assert.NotNil(t, token)
 
@@ -40,7 +40,7 @@ func (c *Client) CreateSharingLink(ctx context.Context, path, linkType, scope st
 	}
 
 	// The createLink action is performed on the DriveItem's path.
-	url := BuildPathURL(path) + ":/createLink"
+	url := c.actionURL(path, "createLink")
 	res, err := c.apiCall(ctx, "POST", url, "application/json", bytes.NewReader(data))
 	if err != nil {
 		return link, err
@@ -80,7 +80,7 @@ func (c *Client) InviteUsers(ctx context.Context, remotePath string, request Inv
 
 	// First, get the DriveItem to resolve its ID from the path.
 	// The invite action is performed on the item's ID.
-	item, err := c.GetDriveItemByPath(ctx, remotePath)
+	itemID, err := c.resolveItemID(ctx, remotePath)
 	if err != nil {
 		return invite, fmt.Errorf("getting DriveItem ID for path '%s' to invite users: %w", remotePath, err)
 	}
@@ -91,7 +91,7 @@ func (c *Client) InviteUsers(ctx context.Context, remotePath string, request Inv
 	}
 
 	// The invite action is performed on the item's ID.
-	url := customRootURL + "me/drive/items/" + url.PathEscape(item.ID) + "/invite"
+	url := c.itemURL(itemID) + "/invite"
 	res, err := c.apiCall(ctx, "POST", url, "application/json", bytes.NewReader(data))
 	if err != nil {
 		return invite, err
@@ -29,7 +29,7 @@ func (c *Client) SearchDriveItems(ctx context.Context, query string) (DriveItemL
 	var items DriveItemList
 
 	// Endpoint for searching the root of the drive. Query string needs URL escaping.
-	searchURL := customRootURL + "me/drive/root/search(q='" + url.QueryEscape(query) + "')"
+	searchURL := c.driveURL() + "/root/search(q='" + url.QueryEscape(query) + "')"
 	res, err := c.apiCall(ctx, "GET", searchURL, "", nil)
 	if err != nil {
 		return items, err
@@ -64,13 +64,13 @@ func (c *Client) SearchDriveItemsInFolder(ctx context.Context, folderPath, query
 
 	// First, get the DriveItem for the folder to obtain its ID.
 	// The search-in-folder endpoint requires the folder's item ID.
-	folderItem, err := c.GetDriveItemByPath(ctx, folderPath)
+	folderID, err := c.resolveItemID(ctx, folderPath)
 	if err != nil {
 		return items, "", fmt.Errorf("getting folder item ID for path '%s' to search: %w", folderPath, err)
 	}
 
 	// Construct the base URL for searching within a folder.
-	baseURL := customRootURL + "me/drive/items/" + folderItem.ID + "/search(q='" + url.QueryEscape(query) + "')"
+	baseURL := c.itemURL(folderID) + "/search(q='" + url.QueryEscape(query) + "')"
 	var queryParams []string
 	if paging.Top > 0 {
 		// The $top parameter for search results is typically applied outside the search() parentheses.
@@ -125,7 +125,7 @@ func (c *Client) SearchDriveItemsWithPaging(ctx context.Context, query string, p
 	var items DriveItemList
 
 	// Construct the base URL for searching the root of the drive.
-	baseURL := customRootURL + "me/drive/root/search(q='" + url.QueryEscape(query) + "')"
+	baseURL := c.driveURL() + "/root/search(q='" + url.QueryEscape(query) + "')"
 	var queryParams []string
 	if paging.Top > 0 {
 		// The $top parameter for search results is applied outside the search() parentheses.
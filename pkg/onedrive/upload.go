@@ -30,7 +30,7 @@ func (c *Client) CreateUploadSession(ctx context.Context, remotePath string) (Up
 	var session UploadSession
 
 	// The endpoint for creating an upload session is on the item's path with ":/createUploadSession".
-	url := BuildPathURL(remotePath) + ":/createUploadSession"
+	url := c.actionURL(remotePath, "createUploadSession")
 	// The request body can be empty or contain item metadata for conflict resolution if needed.
 	// For a simple session creation, an empty body (nil) is sufficient.
 	res, err := c.apiCall(ctx, "POST", url, "application/json", nil)
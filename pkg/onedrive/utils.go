@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/url"
 )
 
 // closeBodySafely closes an HTTP response body and logs any error.
@@ -38,15 +37,16 @@ func seekToStart(body io.ReadSeeker) error {
 // getItemAndBuildURL is a helper function that retrieves a DriveItem by path
 // and constructs a URL for the given endpoint. This reduces code duplication
 // across permissions, thumbnails, and other item-based operations.
+// Item references (see ItemIDPath) are used directly, without a lookup.
 func (c *Client) getItemAndBuildURL(ctx context.Context, remotePath, endpoint string) (string, error) {
 	// Get the DriveItem ID from the path.
-	item, err := c.GetDriveItemByPath(ctx, remotePath)
+	itemID, err := c.resolveItemID(ctx, remotePath)
 	if err != nil {
 		return "", fmt.Errorf("getting DriveItem ID for path '%s': %w", remotePath, err)
 	}
 
 	// Build the URL using the item ID and provided endpoint.
-	itemURL := customRootURL + "me/drive/items/" + url.PathEscape(item.ID) + endpoint
+	itemURL := c.itemURL(itemID) + endpoint
 	return itemURL, nil
 }
 
@@ -66,14 +66,6 @@ func (c *Client) makeAPICallAndDecode(ctx context.Context, method, apiURL, conte
 	return nil
 }
 
-// buildSimpleURL constructs a URL from the custom root URL and endpoint, with optional ID escaping.
-func buildSimpleURL(endpoint string, id string) string {
-	if id != "" {
-		return customRootURL + endpoint + "/" + url.PathEscape(id)
-	}
-	return customRootURL + endpoint
-}
-
 // readErrorBody reads and returns the error body from an HTTP response, with safe error handling.
 func readErrorBody(body io.Reader) string {
 	if body == nil {